@@ -1,21 +1,133 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"rtsback/config"
 	"rtsback/internal/handlers"
 	"rtsback/internal/middlewares"
 	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+	"rtsback/internal/services"
+	"rtsback/pkg/utils"
+	"strconv"
+	"syscall"
+	"time"
+	_ "time/tzdata" // IANA saat dilimleri sistemde yoksa da çözülebilsin
 
 	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func main() {
-	client := config.ConnectDB()
-	models.EnsureCollections(client)
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flag.Parse()
 
-	log.Println("Koleksiyonlar oluşturuldu ve sistem çalışıyor...")
+	cfg, err := config.Read(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Ayarlar hatalı olsa da yazdırılır ki hangi değerin nereden geldiği görülsün
+	if *printConfig {
+		out, err := cfg.Redacted().YAML()
+		if err != nil {
+			log.Fatalf("Ayarlar yazdırılamadı: %v", err)
+		}
+		fmt.Print(out)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if *printConfig {
+		return
+	}
+
+	utils.SetJWTSecret([]byte(cfg.Auth.JWTSecret))
+	utils.SetTokenTTL(cfg.Auth.AccessTokenTTL)
+	utils.SetRefreshTokenTTL(cfg.Auth.RefreshTokenTTL)
+
+	var (
+		client *mongo.Client
+		repos  *repositories.Repositories
+		db     *mongo.Database
+	)
+	if cfg.Database.Driver == "memory" {
+		// Veritabanı olmadan çalışır; veriler süreç kapanınca kaybolur
+		repos = repositories.NewMemory()
+		log.Println("Bellek içi veri deposu kullanılıyor, veriler kalıcı değil")
+	} else {
+		// Tüm repository'ler tek bir MongoDB istemcisini paylaşır
+		client, err = config.Connect(cfg.Database)
+		if err != nil {
+			log.Fatalf("MongoDB bağlantısı kurulamadı: %v", err)
+		}
+		models.EnsureCollections(client, cfg.Database.Name)
+
+		log.Println("Koleksiyonlar oluşturuldu ve sistem çalışıyor...")
+
+		db = client.Database(cfg.Database.Name)
+		indexCtx, cancelIndex := context.WithTimeout(context.Background(), cfg.Database.QueryTimeout)
+		err = repositories.EnsureIndexes(indexCtx, db)
+		cancelIndex()
+		if err != nil {
+			log.Fatalf("İndeksler oluşturulamadı: %v", err)
+		}
+		repos = repositories.NewMongo(db)
+	}
+
+	// İş kuralları servislerde; handler'lar yalnızca HTTP katmanıdır
+	svc := services.New(repos, cfg, services.NewSMTPMailer(cfg.Mail))
+
+	// activate alanından durum alanına geçiş; tekrar çalıştırmak güvenlidir
+	migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), cfg.Database.QueryTimeout)
+	migrated, err := svc.Appointments.MigrateStatuses(migrateCtx)
+	cancelMigrate()
+	if err != nil {
+		log.Fatalf("Randevu durumları taşınamadı: %v", err)
+	}
+	if migrated > 0 {
+		log.Printf("%d randevuya durum atandı", migrated)
+	}
+
+	// Eski randevulara saat dilimi atanır, yalnızca saat taşıyan zamanlar tam ana çevrilir
+	migrateCtx, cancelMigrate = context.WithTimeout(context.Background(), cfg.Database.QueryTimeout)
+	normalized, err := svc.Appointments.NormalizeTimes(migrateCtx)
+	cancelMigrate()
+	if err != nil {
+		log.Fatalf("Randevu saatleri taşınamadı: %v", err)
+	}
+	if normalized > 0 {
+		log.Printf("%d randevunun saatleri şirket saat dilimine taşındı", normalized)
+	}
+	deps := handlers.NewDeps(svc, cfg)
+	if db != nil && cfg.Auth.AttemptStore == "mongo" {
+		attempts := db.Collection("login_attempts")
+		deps.SetAttemptLimiters(
+			middlewares.NewMongoLimiter(attempts, middlewares.DefaultAccountPolicy),
+			middlewares.NewMongoLimiter(attempts, middlewares.DefaultIPPolicy),
+		)
+	}
+
+	userHandler := handlers.NewUserHandler(deps)
+	adminHandler := handlers.NewAdminHandler(deps)
+	managerHandler := handlers.NewManagerHandler(deps)
+	providerHandler := handlers.NewProviderHandler(deps)
+	companyHandler := handlers.NewCompanyHandler(deps)
+	appointmentHandler := handlers.NewAppointmentHandler(deps)
+	verificationHandler := handlers.NewVerificationHandler(deps)
+	authHandler := handlers.NewAuthHandler(deps)
+	catalogHandler := handlers.NewCatalogHandler(deps)
+	resourceHandler := handlers.NewResourceHandler(deps)
+
+	middlewares.SetRevocationChecker(authHandler.IsSessionRevoked)
+
+	allRoles := []string{utils.RoleUser, utils.RoleProvider, utils.RoleManager, utils.RoleAdmin, utils.RoleSuperUser}
 
 	r := mux.NewRouter()
 	protected := r.PathPrefix("/protected").Subrouter()
@@ -24,78 +136,250 @@ func main() {
 	provider := r.PathPrefix("/provider").Subrouter()
 	manager := r.PathPrefix("/manager").Subrouter()
 
-	protected.Use(middlewares.JwtVerify)
-	admin.Use(middlewares.AdminJWT)
-	superuser.Use(middlewares.SuperUserJWT)
-	provider.Use(middlewares.ProviderJWT)
-	manager.Use(middlewares.ManagerJWT)
+	// Superuser her korumalı rotaya erişebilir; kaynak bazlı yetki
+	// kontrolleri handler'larda yapılır
+	protected.Use(middlewares.Authenticate(utils.RoleUser, utils.RoleSuperUser))
+	admin.Use(middlewares.Authenticate(utils.RoleAdmin, utils.RoleSuperUser))
+	superuser.Use(middlewares.Authenticate(utils.RoleSuperUser))
+	provider.Use(middlewares.Authenticate(utils.RoleProvider, utils.RoleSuperUser))
+	manager.Use(middlewares.Authenticate(utils.RoleManager, utils.RoleSuperUser))
 
 	// Genel Rotlar
-	r.HandleFunc("/user", handlers.CreateUser).Methods("POST")
-	r.HandleFunc("/login", handlers.Login).Methods("POST")
-	r.HandleFunc("/provider/login", handlers.ProviderLogin).Methods("POST")
-	r.HandleFunc("/adminlogin", handlers.LoginAdmin).Methods("POST")
-	r.HandleFunc("/superuserlogin", handlers.SuperUserLogin).Methods("POST")
-	r.HandleFunc("/managerlogin", handlers.ManagerLogin).Methods("POST")
-	r.HandleFunc("/getproviderbycompany", handlers.GetProvidersByCompanyId).Methods("GET")
-	r.HandleFunc("/getprovidersapp", handlers.GetInactiveAppointmentsOfProvider).Methods("GET")
-	r.HandleFunc("/getallcompanies", handlers.GetAllCompanies).Methods("GET")
-	r.HandleFunc("/makeappointment", handlers.UpdateAppointmentFieldsByID).Methods("PUT")
-	r.HandleFunc("/getuserbyemail", handlers.GetUserByEmail).Methods("GET")
-	r.HandleFunc("/createusernopassword", handlers.CreateUserWithoutPassword).Methods("POST")
-	r.HandleFunc("/updateapp", handlers.UpdateAppointment).Methods("PUT")
-	r.HandleFunc("/sendemailvercode", handlers.SendVerificationCode).Methods("POST")
-	r.HandleFunc("/veremailCode", handlers.VerifyCode).Methods("POST")
-	r.HandleFunc("/getverbyuserid", handlers.GetVerificationByUserIDHandler).Methods("GET")
+	r.HandleFunc("/user", userHandler.CreateUser).Methods("POST")
+	r.HandleFunc("/login", userHandler.Login).Methods("POST")
+	r.HandleFunc("/provider/login", providerHandler.ProviderLogin).Methods("POST")
+	r.HandleFunc("/adminlogin", adminHandler.LoginAdmin).Methods("POST")
+	r.HandleFunc("/superuserlogin", userHandler.SuperUserLogin).Methods("POST")
+	r.HandleFunc("/managerlogin", managerHandler.ManagerLogin).Methods("POST")
+	r.HandleFunc("/getproviderbycompany", providerHandler.GetProvidersByCompanyId).Methods("GET")
+	r.HandleFunc("/getprovidersapp", appointmentHandler.GetInactiveAppointmentsOfProvider).Methods("GET")
+	r.HandleFunc("/getallcompanies", companyHandler.GetAllCompanies).Methods("GET")
+	r.HandleFunc("/makeappointment", appointmentHandler.UpdateAppointmentFieldsByID).Methods("PUT")
+	r.HandleFunc("/getuserbyemail", userHandler.GetUserByEmail).Methods("GET")
+	r.HandleFunc("/createusernopassword", userHandler.CreateUserWithoutPassword).Methods("POST")
+	r.HandleFunc("/updateapp", appointmentHandler.UpdateAppointment).Methods("PUT")
+	r.HandleFunc("/availability", appointmentHandler.GetAvailability).Methods("GET")
+	r.HandleFunc("/bookslot", appointmentHandler.BookSlot).Methods("POST")
+	r.HandleFunc("/companyservices", catalogHandler.GetCompanyServices).Methods("GET")
+	r.HandleFunc("/providerservices", catalogHandler.GetProviderServices).Methods("GET")
+	r.HandleFunc("/companyclosures", appointmentHandler.GetClosures).Methods("GET")
+	r.HandleFunc("/waitlist/claim", appointmentHandler.ClaimWaitlistOffer).Methods("POST")
+	r.HandleFunc("/groupsessions", appointmentHandler.GetGroupSessions).Methods("GET")
+	r.HandleFunc("/calendar.ics", appointmentHandler.CalendarFeed).Methods("GET")
+	r.HandleFunc("/sendemailvercode", verificationHandler.SendVerificationCode).Methods("POST")
+	r.HandleFunc("/veremailCode", verificationHandler.VerifyCode).Methods("POST")
+	r.HandleFunc("/getverbyuserid", verificationHandler.GetVerificationByUserIDHandler).Methods("GET")
+
+	// Oturum Rotaları
+	r.HandleFunc("/auth/refresh", authHandler.RefreshToken).Methods("POST")
+	r.HandleFunc("/auth/logout", authHandler.Logout).Methods("POST")
+	r.Handle("/auth/logoutall", middlewares.Authenticate(allRoles...)(http.HandlerFunc(authHandler.LogoutAll))).Methods("POST")
+
+	// Şifre Rotaları
+	r.HandleFunc("/password/forgot", authHandler.ForgotPassword).Methods("POST")
+	r.HandleFunc("/password/reset", authHandler.ResetPassword).Methods("POST")
+	r.Handle("/password/change", middlewares.Authenticate(allRoles...)(http.HandlerFunc(authHandler.ChangePassword))).Methods("POST")
+
+	// İki Adımlı Doğrulama Rotaları
+	r.HandleFunc("/2fa/verify", authHandler.VerifyTwoFactor).Methods("POST")
+	r.HandleFunc("/2fa/enroll", authHandler.EnrollTwoFactor).Methods("POST")
+	r.HandleFunc("/2fa/confirm", authHandler.ConfirmTwoFactor).Methods("POST")
+	r.Handle("/2fa/disable", middlewares.Authenticate(utils.RoleAdmin, utils.RoleSuperUser)(http.HandlerFunc(authHandler.DisableTwoFactor))).Methods("POST")
 
 	// Korumalı Rotlar Admin
-	admin.HandleFunc("/provider/add", handlers.AddProvider).Methods("POST")
-	admin.HandleFunc("/manager/add", handlers.AddManager).Methods("POST")
-	admin.HandleFunc("/adminsget", handlers.GetAdminByEmail).Methods("GET")
-	admin.HandleFunc("/user/update", handlers.UpdateUserProfile).Methods("PUT")
-	admin.HandleFunc("/getallproviderapp", handlers.GetAppointmentsByProviderEmail).Methods("GET")
-	admin.HandleFunc("/getcompanybyid", handlers.GetCompanyByID).Methods("GET")
-	admin.HandleFunc("/deleteservice", handlers.RemoveServiceFromProvider).Methods("DELETE")
-	admin.HandleFunc("/getmanagerbycompany", handlers.GetManagersByCompanyId).Methods("GET")
-	admin.HandleFunc("/getappointments", handlers.GetProviderAppointments).Methods("GET")
-	admin.HandleFunc("/getprovidersemails", handlers.GetProviderEmailsByCompanyID).Methods("GET")
+	admin.HandleFunc("/provider/add", providerHandler.AddProvider).Methods("POST")
+	admin.HandleFunc("/manager/add", managerHandler.AddManager).Methods("POST")
+	admin.HandleFunc("/adminsget", adminHandler.GetAdminByEmail).Methods("GET")
+	admin.HandleFunc("/user/update", userHandler.UpdateUserProfile).Methods("PUT")
+	admin.HandleFunc("/getallproviderapp", providerHandler.GetAppointmentsByProviderEmail).Methods("GET")
+	admin.HandleFunc("/getcompanybyid", companyHandler.GetCompanyByID).Methods("GET")
+	admin.HandleFunc("/deleteservice", providerHandler.RemoveServiceFromProvider).Methods("DELETE")
+	admin.HandleFunc("/getmanagerbycompany", managerHandler.GetManagersByCompanyId).Methods("GET")
+	admin.HandleFunc("/getappointments", appointmentHandler.GetProviderAppointments).Methods("GET")
+	admin.HandleFunc("/getprovidersemails", providerHandler.GetProviderEmailsByCompanyID).Methods("GET")
+	admin.HandleFunc("/unlockaccount", authHandler.UnlockAccount).Methods("POST")
+	admin.HandleFunc("/confirmapp", appointmentHandler.ConfirmAppointment).Methods("PUT")
+	admin.HandleFunc("/cancelapp", appointmentHandler.CancelAppointment).Methods("PUT")
+	admin.HandleFunc("/completeapp", appointmentHandler.CompleteAppointment).Methods("PUT")
+	admin.HandleFunc("/noshowapp", appointmentHandler.NoShowAppointment).Methods("PUT")
+	admin.HandleFunc("/reopenapp", appointmentHandler.ReopenAppointment).Methods("PUT")
+	admin.HandleFunc("/company/policy", companyHandler.SetBookingPolicy).Methods("PUT")
+	admin.HandleFunc("/company/timezone", companyHandler.SetTimeZone).Methods("PUT")
+	admin.HandleFunc("/workinghours", appointmentHandler.GetWorkingHours).Methods("GET")
+	admin.HandleFunc("/workinghours", appointmentHandler.SetWorkingHours).Methods("PUT")
+	admin.HandleFunc("/services", catalogHandler.GetServices).Methods("GET")
+	admin.HandleFunc("/services", catalogHandler.AddService).Methods("POST")
+	admin.HandleFunc("/services", catalogHandler.UpdateService).Methods("PUT")
+	admin.HandleFunc("/services", catalogHandler.DeleteService).Methods("DELETE")
+	admin.HandleFunc("/offerings", catalogHandler.SetProviderOffering).Methods("PUT")
+	admin.HandleFunc("/offerings", catalogHandler.RemoveProviderOffering).Methods("DELETE")
+	admin.HandleFunc("/timeoff", appointmentHandler.GetTimeOff).Methods("GET")
+	admin.HandleFunc("/timeoff", appointmentHandler.AddTimeOff).Methods("POST")
+	admin.HandleFunc("/timeoff", appointmentHandler.DeleteTimeOff).Methods("DELETE")
+	admin.HandleFunc("/timeoff/conflicts", appointmentHandler.GetTimeOffConflicts).Methods("GET")
+	admin.HandleFunc("/closures", appointmentHandler.AddClosures).Methods("POST")
+	admin.HandleFunc("/closures", appointmentHandler.DeleteClosure).Methods("DELETE")
+	admin.HandleFunc("/closures/import", appointmentHandler.ImportHolidays).Methods("POST")
+	admin.HandleFunc("/waitlist", appointmentHandler.GetCompanyWaitlist).Methods("GET")
+	admin.HandleFunc("/series", appointmentHandler.GetSeries).Methods("GET")
+	admin.HandleFunc("/series", appointmentHandler.BookSeries).Methods("POST")
+	admin.HandleFunc("/series/cancel", appointmentHandler.CancelSeries).Methods("PUT")
+	admin.HandleFunc("/groupsession", appointmentHandler.CreateGroupSession).Methods("POST")
+	admin.HandleFunc("/groupsession/join", appointmentHandler.JoinGroupSession).Methods("POST")
+	admin.HandleFunc("/groupsession/attendees", appointmentHandler.GetAttendees).Methods("GET")
+	admin.HandleFunc("/groupsession/attendance", appointmentHandler.MarkAttendance).Methods("PUT")
+	admin.HandleFunc("/resources", resourceHandler.GetResources).Methods("GET")
+	admin.HandleFunc("/resources", resourceHandler.AddResource).Methods("POST")
+	admin.HandleFunc("/resources", resourceHandler.UpdateResource).Methods("PUT")
+	admin.HandleFunc("/resources", resourceHandler.DeleteResource).Methods("DELETE")
+	admin.HandleFunc("/resources/schedule", resourceHandler.GetResourceSchedule).Methods("GET")
+	admin.HandleFunc("/calendar/external", appointmentHandler.GetCalendarSources).Methods("GET")
+	admin.HandleFunc("/calendar/external", appointmentHandler.AddCalendarSource).Methods("POST")
+	admin.HandleFunc("/calendar/external", appointmentHandler.DeleteCalendarSource).Methods("DELETE")
+	admin.HandleFunc("/calendar/external/sync", appointmentHandler.SyncCalendarSource).Methods("POST")
+	admin.HandleFunc("/calendar/import", appointmentHandler.ImportCalendar).Methods("POST")
+	admin.HandleFunc("/calendar/busy", appointmentHandler.GetBusyBlocks).Methods("GET")
 
 	// Korumalı Rotlar Provider
-	provider.HandleFunc("/providers", handlers.GetProviders).Methods("GET")
-	provider.HandleFunc("/getbyemail", handlers.GetProviderByEmail).Methods("GET")
-	provider.HandleFunc("/addappauto", handlers.AutoCreateAppointment).Methods("POST")
-	provider.HandleFunc("/getappointments", handlers.GetProviderAppointments).Methods("GET")
-	provider.HandleFunc("/getcompanyforprovider", handlers.GetCompanyNameByProviderEmail).Methods("GET")
-	provider.HandleFunc("/addproviderapp", handlers.AddProviderApp).Methods("POST")
-	provider.HandleFunc("/updateapp", handlers.UpdateAppointmentByID).Methods("PUT")
-	provider.HandleFunc("/deleteapp", handlers.DeleteAppointmentByID).Methods("DELETE")
-	provider.HandleFunc("/getallproviderapp", handlers.GetAppointmentsByProviderEmail).Methods("GET")
-	provider.HandleFunc("/addservices", handlers.AddServiceToProvider).Methods("PUT")
-	provider.HandleFunc("/getservicesforprovider", handlers.GetServicesOfProvider).Methods("GET")
-	provider.HandleFunc("/deleteservice", handlers.RemoveServiceFromProvider).Methods("DELETE")
+	provider.HandleFunc("/providers", providerHandler.GetProviders).Methods("GET")
+	provider.HandleFunc("/getbyemail", providerHandler.GetProviderByEmail).Methods("GET")
+	provider.HandleFunc("/addappauto", appointmentHandler.AutoCreateAppointment).Methods("POST")
+	provider.HandleFunc("/appbatch", appointmentHandler.GetSlotBatch).Methods("GET")
+	provider.HandleFunc("/appbatch", appointmentHandler.DeleteSlotBatch).Methods("DELETE")
+	provider.HandleFunc("/getappointments", appointmentHandler.GetProviderAppointments).Methods("GET")
+	provider.HandleFunc("/getcompanyforprovider", providerHandler.GetCompanyNameByProviderEmail).Methods("GET")
+	provider.HandleFunc("/addproviderapp", appointmentHandler.AddProviderApp).Methods("POST")
+	provider.HandleFunc("/updateapp", appointmentHandler.UpdateAppointmentByID).Methods("PUT")
+	provider.HandleFunc("/deleteapp", appointmentHandler.DeleteAppointmentByID).Methods("DELETE")
+	provider.HandleFunc("/confirmapp", appointmentHandler.ConfirmAppointment).Methods("PUT")
+	provider.HandleFunc("/cancelapp", appointmentHandler.CancelAppointment).Methods("PUT")
+	provider.HandleFunc("/completeapp", appointmentHandler.CompleteAppointment).Methods("PUT")
+	provider.HandleFunc("/noshowapp", appointmentHandler.NoShowAppointment).Methods("PUT")
+	provider.HandleFunc("/reopenapp", appointmentHandler.ReopenAppointment).Methods("PUT")
+	provider.HandleFunc("/workinghours", appointmentHandler.GetWorkingHours).Methods("GET")
+	provider.HandleFunc("/workinghours", appointmentHandler.SetWorkingHours).Methods("PUT")
+	provider.HandleFunc("/getallproviderapp", providerHandler.GetAppointmentsByProviderEmail).Methods("GET")
+	provider.HandleFunc("/addservices", providerHandler.AddServiceToProvider).Methods("PUT")
+	provider.HandleFunc("/getservicesforprovider", providerHandler.GetServicesOfProvider).Methods("GET")
+	provider.HandleFunc("/deleteservice", providerHandler.RemoveServiceFromProvider).Methods("DELETE")
+	provider.HandleFunc("/offerings", catalogHandler.SetProviderOffering).Methods("PUT")
+	provider.HandleFunc("/offerings", catalogHandler.RemoveProviderOffering).Methods("DELETE")
+	provider.HandleFunc("/timeoff", appointmentHandler.GetTimeOff).Methods("GET")
+	provider.HandleFunc("/timeoff", appointmentHandler.AddTimeOff).Methods("POST")
+	provider.HandleFunc("/timeoff", appointmentHandler.DeleteTimeOff).Methods("DELETE")
+	provider.HandleFunc("/timeoff/conflicts", appointmentHandler.GetTimeOffConflicts).Methods("GET")
+	provider.HandleFunc("/series", appointmentHandler.GetSeries).Methods("GET")
+	provider.HandleFunc("/series", appointmentHandler.BookSeries).Methods("POST")
+	provider.HandleFunc("/series/cancel", appointmentHandler.CancelSeries).Methods("PUT")
+	provider.HandleFunc("/groupsession", appointmentHandler.CreateGroupSession).Methods("POST")
+	provider.HandleFunc("/groupsession/join", appointmentHandler.JoinGroupSession).Methods("POST")
+	provider.HandleFunc("/groupsession/attendees", appointmentHandler.GetAttendees).Methods("GET")
+	provider.HandleFunc("/groupsession/attendance", appointmentHandler.MarkAttendance).Methods("PUT")
+	provider.HandleFunc("/calendar/feed", appointmentHandler.GetCalendarFeed).Methods("GET")
+	provider.HandleFunc("/calendar/feed", appointmentHandler.CreateCalendarFeed).Methods("POST")
+	provider.HandleFunc("/calendar/feed", appointmentHandler.RevokeCalendarFeed).Methods("DELETE")
+	provider.HandleFunc("/calendar/external", appointmentHandler.GetCalendarSources).Methods("GET")
+	provider.HandleFunc("/calendar/external", appointmentHandler.AddCalendarSource).Methods("POST")
+	provider.HandleFunc("/calendar/external", appointmentHandler.DeleteCalendarSource).Methods("DELETE")
+	provider.HandleFunc("/calendar/external/sync", appointmentHandler.SyncCalendarSource).Methods("POST")
+	provider.HandleFunc("/calendar/import", appointmentHandler.ImportCalendar).Methods("POST")
+	provider.HandleFunc("/calendar/busy", appointmentHandler.GetBusyBlocks).Methods("GET")
 
 	// Korumalı Rotlar SuperUser
-	superuser.HandleFunc("/users", handlers.GetUsers).Methods("GET")
-	superuser.HandleFunc("/users/update", handlers.UpdateUsers).Methods("PUT")
-	superuser.HandleFunc("/admins", handlers.GetAdmins).Methods("GET")
-	superuser.HandleFunc("/companies", handlers.GetCompanies).Methods("GET")
-	superuser.HandleFunc("/companies", handlers.AddCompany).Methods("POST")
-	superuser.HandleFunc("/adminadd", handlers.AddAdmin).Methods("POST")
-	superuser.HandleFunc("/company/update", handlers.UpdateCompanyByName).Methods("PUT")
-	superuser.HandleFunc("/admins/update", handlers.UpdateAdminByEmail).Methods("PUT")
-	superuser.HandleFunc("/adminsget", handlers.GetAdminByEmail).Methods("GET")
-	superuser.HandleFunc("/companyget", handlers.GetCompanyByName).Methods("GET")
+	superuser.HandleFunc("/users", userHandler.GetUsers).Methods("GET")
+	superuser.HandleFunc("/users/update", userHandler.UpdateUsers).Methods("PUT")
+	superuser.HandleFunc("/admins", adminHandler.GetAdmins).Methods("GET")
+	superuser.HandleFunc("/companies", companyHandler.GetCompanies).Methods("GET")
+	superuser.HandleFunc("/companies", companyHandler.AddCompany).Methods("POST")
+	superuser.HandleFunc("/adminadd", adminHandler.AddAdmin).Methods("POST")
+	superuser.HandleFunc("/company/update", companyHandler.UpdateCompanyByName).Methods("PUT")
+	superuser.HandleFunc("/admins/update", adminHandler.UpdateAdminByEmail).Methods("PUT")
+	superuser.HandleFunc("/adminsget", adminHandler.GetAdminByEmail).Methods("GET")
+	superuser.HandleFunc("/companyget", companyHandler.GetCompanyByName).Methods("GET")
+	superuser.HandleFunc("/unlockaccount", authHandler.UnlockAccount).Methods("POST")
+	superuser.HandleFunc("/securityevents", authHandler.GetSecurityEvents).Methods("GET")
+	superuser.HandleFunc("/company/require2fa", authHandler.SetCompanyAdmin2FA).Methods("PUT")
 
 	// Korumalı Rotlar User
-	protected.HandleFunc("/userprofile", handlers.GetUserProfile).Methods("GET")
-	protected.HandleFunc("/appointments", handlers.GetAppointments).Methods("GET")
+	protected.HandleFunc("/userprofile", userHandler.GetUserProfile).Methods("GET")
+	protected.HandleFunc("/appointments", appointmentHandler.GetAppointments).Methods("GET")
+	protected.HandleFunc("/appointments/cancel", appointmentHandler.CancelMyAppointment).Methods("PUT")
+	protected.HandleFunc("/appointments/reschedule", appointmentHandler.RescheduleMyAppointment).Methods("PUT")
+	protected.HandleFunc("/waitlist", appointmentHandler.GetMyWaitlist).Methods("GET")
+	protected.HandleFunc("/waitlist", appointmentHandler.JoinWaitlist).Methods("POST")
+	protected.HandleFunc("/waitlist", appointmentHandler.LeaveWaitlist).Methods("DELETE")
+	protected.HandleFunc("/series", appointmentHandler.GetSeries).Methods("GET")
+	protected.HandleFunc("/series", appointmentHandler.BookSeries).Methods("POST")
+	protected.HandleFunc("/series/cancel", appointmentHandler.CancelSeries).Methods("PUT")
+	protected.HandleFunc("/groupsession/join", appointmentHandler.JoinGroupSession).Methods("POST")
+	protected.HandleFunc("/groupsession/leave", appointmentHandler.LeaveGroupSession).Methods("PUT")
+	protected.HandleFunc("/calendar/feed", appointmentHandler.GetCalendarFeed).Methods("GET")
+	protected.HandleFunc("/calendar/feed", appointmentHandler.CreateCalendarFeed).Methods("POST")
+	protected.HandleFunc("/calendar/feed", appointmentHandler.RevokeCalendarFeed).Methods("DELETE")
 
 	// CORS Ayarları
-	corsRouter := middlewares.EnableCORS(r)
+	corsRouter := middlewares.EnableCORS(cfg.CORS.AllowedOrigins)(r)
+
+	server := &http.Server{
+		Addr:         ":" + strconv.Itoa(cfg.Server.Port),
+		Handler:      corsRouter,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
 
 	// Sunucuyu başlat
-	log.Println("Sunucu 8080 portunda çalışıyor...")
-	if err := http.ListenAndServe(":8080", corsRouter); err != nil {
-		log.Fatalf("Sunucu başlatılamadı: %v", err)
+	go func() {
+		log.Printf("Sunucu %d portunda çalışıyor...", cfg.Server.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Sunucu başlatılamadı: %v", err)
+		}
+	}()
+
+	// Süresi dolan bekleme listesi teklifleri sıradaki müşteriye geçer
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			expireCtx, cancelExpire := context.WithTimeout(context.Background(), cfg.Database.QueryTimeout)
+			if _, err := svc.Appointments.ExpireWaitlistOffers(expireCtx, now); err != nil {
+				log.Printf("Bekleme listesi teklifleri kapatılamadı: %v", err)
+			}
+			cancelExpire()
+		}
+	}()
+
+	// Dış takvimler yeniden okunur; her takvimin kendi süresi vardır.
+	// Kapanışta süren eşitleme iptal edilir.
+	syncCtx, cancelSync := context.WithCancel(context.Background())
+	defer cancelSync()
+	go func() {
+		ticker := time.NewTicker(cfg.Scheduling.CalendarSyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-syncCtx.Done():
+				return
+			case now := <-ticker.C:
+				if _, err := svc.Appointments.SyncCalendarSources(syncCtx, now); err != nil && syncCtx.Err() == nil {
+					log.Printf("Dış takvimler eşitlenemedi: %v", err)
+				}
+			}
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	cancelSync()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Sunucu düzgün kapatılamadı: %v", err)
+	}
+	if client != nil {
+		if err := client.Disconnect(ctx); err != nil {
+			log.Printf("MongoDB bağlantısı kapatılamadı: %v", err)
+		}
 	}
 }
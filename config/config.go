@@ -2,40 +2,368 @@ package config
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/yaml.v3"
 )
 
-var DB *mongo.Client
+// Config uygulamanın tüm ayarlarıdır. Değerler önce varsayılanlardan,
+// sonra isteğe bağlı YAML dosyasından, en son ortam değişkenlerinden okunur.
+type Config struct {
+	Server     ServerConfig     `yaml:"server"`
+	Database   DatabaseConfig   `yaml:"database"`
+	Auth       AuthConfig       `yaml:"auth"`
+	CORS       CORSConfig       `yaml:"cors"`
+	Mail       MailConfig       `yaml:"mail"`
+	Scheduling SchedulingConfig `yaml:"scheduling"`
+}
 
-func ConnectDB() *mongo.Client {
-	clientOptions := options.Client().ApplyURI("mongodb://localhost:27017/rtsdatabase")
-	client, err := mongo.NewClient(clientOptions)
-	if err != nil {
-		log.Fatal(err)
+type ServerConfig struct {
+	Environment     string        `yaml:"environment"` // development veya production
+	Port            int           `yaml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type DatabaseConfig struct {
+	Driver         string        `yaml:"driver"` // mongo veya memory
+	URI            string        `yaml:"uri"`
+	Name           string        `yaml:"name"`
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	QueryTimeout   time.Duration `yaml:"query_timeout"`
+}
+
+type AuthConfig struct {
+	JWTSecret        string        `yaml:"jwt_secret"`
+	AccessTokenTTL   time.Duration `yaml:"access_token_ttl"`
+	RefreshTokenTTL  time.Duration `yaml:"refresh_token_ttl"`
+	PasswordResetTTL time.Duration `yaml:"password_reset_ttl"`
+	VerifyCodeTTL    time.Duration `yaml:"verification_code_ttl"`
+	AttemptStore     string        `yaml:"attempt_store"` // mongo veya memory
+}
+
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+type MailConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+type SchedulingConfig struct {
+	// DefaultTimeZone saat dilimi ayarlanmamış şirketlerin IANA saat dilimidir
+	DefaultTimeZone string `yaml:"default_time_zone"`
+	// WaitlistOfferTTL bekleme listesindeki müşteriye gönderilen teklifin geçerlilik süresidir
+	WaitlistOfferTTL time.Duration `yaml:"waitlist_offer_ttl"`
+	// WaitlistClaimURL teklif e-postasındaki bağlantının adresidir; token
+	// ?token= ile eklenir. Boşsa e-postada yalnızca kod gönderilir.
+	WaitlistClaimURL string `yaml:"waitlist_claim_url"`
+	// CalendarFeedURL takvim aboneliği bağlantısının adresidir, örneğin
+	// https://api.example.com/calendar.ics; token ?token= ile eklenir
+	CalendarFeedURL string `yaml:"calendar_feed_url"`
+	// CalendarImportDir sağlayıcıların dosya yolu olarak dış takvim
+	// ekleyebileceği dizindir; boşsa dış takvimler yalnızca adresten okunur
+	CalendarImportDir string `yaml:"calendar_import_dir"`
+	// CalendarSyncInterval dış takvimlerin yeniden okunma aralığıdır
+	CalendarSyncInterval time.Duration `yaml:"calendar_sync_interval"`
+}
+
+// Default varsayılan ayarları döner
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Environment:     "production",
+			Port:            8080,
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			ShutdownTimeout: 10 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver:         "mongo",
+			URI:            "mongodb://localhost:27017",
+			Name:           "rtsdatabase",
+			ConnectTimeout: 10 * time.Second,
+			QueryTimeout:   10 * time.Second,
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:   15 * time.Minute,
+			RefreshTokenTTL:  30 * 24 * time.Hour,
+			PasswordResetTTL: time.Hour,
+			VerifyCodeTTL:    15 * time.Minute,
+			AttemptStore:     "mongo",
+		},
+		Mail: MailConfig{
+			Host: "smtp.gmail.com",
+			Port: 587,
+		},
+		Scheduling: SchedulingConfig{
+			DefaultTimeZone:      "Europe/Istanbul",
+			WaitlistOfferTTL:     2 * time.Hour,
+			CalendarSyncInterval: time.Hour,
+		},
 	}
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// Read ayarları varsayılanlar, path'teki YAML dosyası (boş ise atlanır) ve
+// ortam değişkenlerinden okur; doğrulamaz
+func Read(path string) (Config, error) {
+	cfg := Default()
 
-	err = client.Connect(ctx)
-	if err != nil {
-		log.Fatal(err)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("config file %s could not be read: %w", path, err)
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return Config{}, fmt.Errorf("config file %s is not valid YAML: %w", path, err)
+		}
 	}
 
-	err = client.Ping(ctx, nil)
-	if err != nil {
-		log.Fatal(err)
+	if err := applyEnv(&cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// envBinder ortam değişkenlerini ayar alanlarına bağlar ve hataları biriktirir
+type envBinder struct {
+	errs []error
+}
+
+func (b *envBinder) string(key string, dst *string) {
+	if v, ok := os.LookupEnv(key); ok {
+		*dst = v
 	}
+}
+
+func (b *envBinder) int(key string, dst *int) {
+	if v, ok := os.LookupEnv(key); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			b.errs = append(b.errs, fmt.Errorf("%s must be an integer, got %q", key, v))
+			return
+		}
+		*dst = n
+	}
+}
+
+func (b *envBinder) duration(key string, dst *time.Duration) {
+	if v, ok := os.LookupEnv(key); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			b.errs = append(b.errs, fmt.Errorf("%s must be a duration such as 15m or 24h, got %q", key, v))
+			return
+		}
+		*dst = d
+	}
+}
 
-	//log.Println("Connected to MongoDB!")
-	DB = client
-	return client
+func (b *envBinder) list(key string, dst *[]string) {
+	if v, ok := os.LookupEnv(key); ok {
+		var items []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*dst = items
+	}
 }
 
-func GetCollection(client *mongo.Client, collectionName string) *mongo.Collection {
-	return client.Database("rtsdatabase").Collection(collectionName)
+func applyEnv(cfg *Config) error {
+	b := &envBinder{}
+
+	b.string("APP_ENV", &cfg.Server.Environment)
+	b.int("PORT", &cfg.Server.Port)
+	b.duration("SERVER_READ_TIMEOUT", &cfg.Server.ReadTimeout)
+	b.duration("SERVER_WRITE_TIMEOUT", &cfg.Server.WriteTimeout)
+	b.duration("SERVER_SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout)
+
+	b.string("DB_DRIVER", &cfg.Database.Driver)
+	b.string("MONGO_URI", &cfg.Database.URI)
+	b.string("DB_NAME", &cfg.Database.Name)
+	b.duration("DB_CONNECT_TIMEOUT", &cfg.Database.ConnectTimeout)
+	b.duration("DB_QUERY_TIMEOUT", &cfg.Database.QueryTimeout)
+
+	b.string("JWT_SECRET", &cfg.Auth.JWTSecret)
+	b.duration("ACCESS_TOKEN_TTL", &cfg.Auth.AccessTokenTTL)
+	b.duration("REFRESH_TOKEN_TTL", &cfg.Auth.RefreshTokenTTL)
+	b.duration("PASSWORD_RESET_TTL", &cfg.Auth.PasswordResetTTL)
+	b.duration("VERIFICATION_CODE_TTL", &cfg.Auth.VerifyCodeTTL)
+	b.string("ATTEMPT_STORE", &cfg.Auth.AttemptStore)
+
+	b.list("CORS_ALLOWED_ORIGINS", &cfg.CORS.AllowedOrigins)
+
+	b.string("MAIL_HOST", &cfg.Mail.Host)
+	b.int("MAIL_PORT", &cfg.Mail.Port)
+	b.string("MAIL_USERNAME", &cfg.Mail.Username)
+	b.string("MAIL_PASSWORD", &cfg.Mail.Password)
+	b.string("MAIL_FROM", &cfg.Mail.From)
+
+	b.string("DEFAULT_TIME_ZONE", &cfg.Scheduling.DefaultTimeZone)
+	b.duration("WAITLIST_OFFER_TTL", &cfg.Scheduling.WaitlistOfferTTL)
+	b.string("WAITLIST_CLAIM_URL", &cfg.Scheduling.WaitlistClaimURL)
+	b.string("CALENDAR_FEED_URL", &cfg.Scheduling.CalendarFeedURL)
+	b.string("CALENDAR_IMPORT_DIR", &cfg.Scheduling.CalendarImportDir)
+	b.duration("CALENDAR_SYNC_INTERVAL", &cfg.Scheduling.CalendarSyncInterval)
+
+	return errors.Join(b.errs...)
+}
+
+// Validate tüm hataları tek seferde, alan adlarıyla birlikte döner
+func (c Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Environment != "development" && c.Server.Environment != "production" {
+		add("server.environment (APP_ENV) must be \"development\" or \"production\", got %q", c.Server.Environment)
+	}
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		add("server.port (PORT) must be between 1 and 65535, got %d", c.Server.Port)
+	}
+	if c.Server.ReadTimeout <= 0 || c.Server.WriteTimeout <= 0 || c.Server.ShutdownTimeout <= 0 {
+		add("server timeouts must be positive durations")
+	}
+
+	if c.Database.Driver != "mongo" && c.Database.Driver != "memory" {
+		add("database.driver (DB_DRIVER) must be \"mongo\" or \"memory\", got %q", c.Database.Driver)
+	}
+	if u, err := url.Parse(c.Database.URI); err != nil || (u.Scheme != "mongodb" && u.Scheme != "mongodb+srv") {
+		add("database.uri (MONGO_URI) must be a mongodb:// or mongodb+srv:// URI")
+	}
+	if c.Database.Name == "" {
+		add("database.name (DB_NAME) is required")
+	}
+	if c.Database.ConnectTimeout <= 0 || c.Database.QueryTimeout <= 0 {
+		add("database timeouts must be positive durations")
+	}
+
+	if c.Auth.JWTSecret == "" {
+		add("auth.jwt_secret (JWT_SECRET) is required")
+	} else if len(c.Auth.JWTSecret) < 32 {
+		add("auth.jwt_secret (JWT_SECRET) must be at least 32 characters")
+	}
+	if c.Auth.AccessTokenTTL <= 0 || c.Auth.RefreshTokenTTL <= 0 || c.Auth.PasswordResetTTL <= 0 || c.Auth.VerifyCodeTTL <= 0 {
+		add("auth token lifetimes must be positive durations")
+	}
+	if c.Auth.AccessTokenTTL >= c.Auth.RefreshTokenTTL {
+		add("auth.access_token_ttl must be shorter than auth.refresh_token_ttl")
+	}
+	if c.Auth.AttemptStore != "mongo" && c.Auth.AttemptStore != "memory" {
+		add("auth.attempt_store (ATTEMPT_STORE) must be \"mongo\" or \"memory\", got %q", c.Auth.AttemptStore)
+	}
+
+	if len(c.CORS.AllowedOrigins) == 0 && !c.Development() {
+		add("cors.allowed_origins (CORS_ALLOWED_ORIGINS) must list at least one origin or \"*\" outside development")
+	}
+
+	if c.Mail.Port < 1 || c.Mail.Port > 65535 {
+		add("mail.port (MAIL_PORT) must be between 1 and 65535, got %d", c.Mail.Port)
+	}
+	if (c.Mail.Username == "") != (c.Mail.Password == "") {
+		add("mail.username (MAIL_USERNAME) and mail.password (MAIL_PASSWORD) must be set together")
+	}
+	if c.Mail.From != "" {
+		if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+			add("mail.from (MAIL_FROM) is not a valid address: %v", err)
+		}
+	}
+
+	if _, err := time.LoadLocation(c.Scheduling.DefaultTimeZone); err != nil || c.Scheduling.DefaultTimeZone == "" {
+		add("scheduling.default_time_zone (DEFAULT_TIME_ZONE) must be an IANA time zone such as Europe/Istanbul, got %q", c.Scheduling.DefaultTimeZone)
+	}
+	if c.Scheduling.WaitlistOfferTTL <= 0 {
+		add("scheduling.waitlist_offer_ttl (WAITLIST_OFFER_TTL) must be a positive duration")
+	}
+	if c.Scheduling.WaitlistClaimURL != "" {
+		if u, err := url.Parse(c.Scheduling.WaitlistClaimURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			add("scheduling.waitlist_claim_url (WAITLIST_CLAIM_URL) must be an http:// or https:// URL")
+		}
+	}
+	if c.Scheduling.CalendarFeedURL != "" {
+		if u, err := url.Parse(c.Scheduling.CalendarFeedURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			add("scheduling.calendar_feed_url (CALENDAR_FEED_URL) must be an http:// or https:// URL")
+		}
+	}
+	if c.Scheduling.CalendarImportDir != "" && !filepath.IsAbs(c.Scheduling.CalendarImportDir) {
+		add("scheduling.calendar_import_dir (CALENDAR_IMPORT_DIR) must be an absolute path")
+	}
+	if c.Scheduling.CalendarSyncInterval <= 0 {
+		add("scheduling.calendar_sync_interval (CALENDAR_SYNC_INTERVAL) must be a positive duration")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n  - %w", joinLines(errs))
+	}
+	return nil
+}
+
+func joinLines(errs []error) error {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = err.Error()
+	}
+	return errors.New(strings.Join(lines, "\n  - "))
+}
+
+// Development sunucunun geliştirme ortamında çalışıp çalışmadığını döner
+func (c Config) Development() bool {
+	return c.Server.Environment == "development"
+}
+
+const redacted = "***"
+
+// Redacted gizli değerleri maskelenmiş bir kopya döner
+func (c Config) Redacted() Config {
+	if c.Auth.JWTSecret != "" {
+		c.Auth.JWTSecret = redacted
+	}
+	if c.Mail.Password != "" {
+		c.Mail.Password = redacted
+	}
+	if u, err := url.Parse(c.Database.URI); err == nil {
+		c.Database.URI = u.Redacted()
+	}
+	return c
+}
+
+// YAML ayarları YAML olarak döner
+func (c Config) YAML() (string, error) {
+	out, err := yaml.Marshal(c)
+	return string(out), err
+}
+
+// Connect tek bir MongoDB istemcisi açar ve bağlantıyı doğrular. İstemci
+// tüm süreç boyunca paylaşılır ve kapanışta Disconnect edilmelidir.
+func Connect(cfg DatabaseConfig) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.URI))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+	return client, nil
 }
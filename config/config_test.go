@@ -0,0 +1,219 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func validConfig() Config {
+	cfg := Default()
+	cfg.Auth.JWTSecret = strings.Repeat("s", 32)
+	cfg.CORS.AllowedOrigins = []string{"https://app.example.com"}
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string // boşsa hata beklenmez
+	}{
+		{"valid", func(c *Config) {}, ""},
+		{"bad environment", func(c *Config) { c.Server.Environment = "staging" }, "server.environment"},
+		{"bad port", func(c *Config) { c.Server.Port = 0 }, "server.port"},
+		{"zero timeout", func(c *Config) { c.Server.ReadTimeout = 0 }, "server timeouts"},
+		{"bad driver", func(c *Config) { c.Database.Driver = "sqlite" }, "database.driver"},
+		{"bad uri", func(c *Config) { c.Database.URI = "postgres://localhost" }, "database.uri"},
+		{"missing secret", func(c *Config) { c.Auth.JWTSecret = "" }, "is required"},
+		{"short secret", func(c *Config) { c.Auth.JWTSecret = "short" }, "at least 32 characters"},
+		{"access longer than refresh", func(c *Config) { c.Auth.AccessTokenTTL = c.Auth.RefreshTokenTTL }, "shorter than"},
+		{"no origins in production", func(c *Config) { c.CORS.AllowedOrigins = nil }, "cors.allowed_origins"},
+		{"no origins in development", func(c *Config) {
+			c.Server.Environment = "development"
+			c.CORS.AllowedOrigins = nil
+		}, ""},
+		{"wildcard origin", func(c *Config) { c.CORS.AllowedOrigins = []string{"*"} }, ""},
+		{"mail user without password", func(c *Config) { c.Mail.Username = "user" }, "set together"},
+		{"bad mail from", func(c *Config) { c.Mail.From = "not an address" }, "mail.from"},
+		{"bad time zone", func(c *Config) { c.Scheduling.DefaultTimeZone = "Mars/Olympus" }, "default_time_zone"},
+		{"relative import dir", func(c *Config) { c.Scheduling.CalendarImportDir = "calendars" }, "absolute path"},
+		{"bad claim url", func(c *Config) { c.Scheduling.WaitlistClaimURL = "ftp://example.com" }, "waitlist_claim_url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Validate() = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateListsAllErrors(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Port = -1
+	cfg.Database.Name = ""
+	cfg.Auth.JWTSecret = ""
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil, want error")
+	}
+	for _, want := range []string{"server.port", "database.name", "auth.jwt_secret"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, missing %q", err, want)
+		}
+	}
+}
+
+func TestDefaultHasNoOrigins(t *testing.T) {
+	cfg := Default()
+	if len(cfg.CORS.AllowedOrigins) != 0 {
+		t.Fatalf("default origins = %v, want none", cfg.CORS.AllowedOrigins)
+	}
+	if cfg.Development() {
+		t.Fatal("default environment is development, want production")
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	tests := []struct {
+		name                     string
+		secret, password, uri    string
+		wantSecret, wantPassword string
+		wantURI                  string
+	}{
+		{
+			name:   "secrets set",
+			secret: "jwt-secret", password: "smtp-pass", uri: "mongodb://admin:hunter2@db:27017",
+			wantSecret: redacted, wantPassword: redacted, wantURI: "mongodb://admin:xxxxx@db:27017",
+		},
+		{
+			name:   "secrets empty",
+			secret: "", password: "", uri: "mongodb://localhost:27017",
+			wantSecret: "", wantPassword: "", wantURI: "mongodb://localhost:27017",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Auth.JWTSecret = tt.secret
+			cfg.Mail.Password = tt.password
+			cfg.Database.URI = tt.uri
+
+			got := cfg.Redacted()
+			if got.Auth.JWTSecret != tt.wantSecret || got.Mail.Password != tt.wantPassword || got.Database.URI != tt.wantURI {
+				t.Fatalf("Redacted() = (%q, %q, %q), want (%q, %q, %q)",
+					got.Auth.JWTSecret, got.Mail.Password, got.Database.URI,
+					tt.wantSecret, tt.wantPassword, tt.wantURI)
+			}
+			if cfg.Auth.JWTSecret != tt.secret {
+				t.Fatal("Redacted() modified the original config")
+			}
+
+			out, err := got.YAML()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, secret := range []string{"jwt-secret", "smtp-pass", "hunter2"} {
+				if strings.Contains(out, secret) {
+					t.Fatalf("YAML() leaks %q:\n%s", secret, out)
+				}
+			}
+		})
+	}
+}
+
+func TestReadEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		check   func(Config) bool
+		wantErr string
+	}{
+		{
+			name:  "string",
+			env:   map[string]string{"APP_ENV": "development", "DB_NAME": "other"},
+			check: func(c Config) bool { return c.Development() && c.Database.Name == "other" },
+		},
+		{
+			name:  "int",
+			env:   map[string]string{"PORT": "9090"},
+			check: func(c Config) bool { return c.Server.Port == 9090 },
+		},
+		{
+			name:  "duration",
+			env:   map[string]string{"ACCESS_TOKEN_TTL": "5m"},
+			check: func(c Config) bool { return c.Auth.AccessTokenTTL == 5*time.Minute },
+		},
+		{
+			name: "list",
+			env:  map[string]string{"CORS_ALLOWED_ORIGINS": " https://a.example.com, ,https://b.example.com "},
+			check: func(c Config) bool {
+				return reflect.DeepEqual(c.CORS.AllowedOrigins, []string{"https://a.example.com", "https://b.example.com"})
+			},
+		},
+		{
+			name:    "bad int",
+			env:     map[string]string{"PORT": "http"},
+			wantErr: "PORT must be an integer",
+		},
+		{
+			name:    "bad duration",
+			env:     map[string]string{"DB_QUERY_TIMEOUT": "10"},
+			wantErr: "DB_QUERY_TIMEOUT must be a duration",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			cfg, err := Read("")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Read() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if !tt.check(cfg) {
+				t.Fatalf("Read() = %+v, env not applied", cfg)
+			}
+		})
+	}
+}
+
+func TestReadEnvOverridesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "server:\n  environment: development\n  port: 7000\ndatabase:\n  name: fromfile\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PORT", "7001")
+
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Development() || cfg.Database.Name != "fromfile" || cfg.Server.Port != 7001 {
+		t.Fatalf("Read() = %+v, want file values with PORT from env", cfg.Server)
+	}
+	if cfg.Database.Driver != "mongo" {
+		t.Fatalf("driver = %q, want default kept", cfg.Database.Driver)
+	}
+}
@@ -3,29 +3,15 @@ package handlers
 import (
 	"context"
 	"encoding/json"
-	"time"
 
-	"rtsback/config"
 	"rtsback/internal/models"
+	"rtsback/internal/services"
+	"rtsback/pkg/utils"
 
 	"net/http"
-
-	"github.com/dgrijalva/jwt-go"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"golang.org/x/crypto/bcrypt"
 )
 
-var adminCollection *mongo.Collection
-
-func init() {
-	client := config.ConnectDB()
-	adminCollection = config.GetCollection(client, "admin")
-	userCollection = config.GetCollection(client, "user")
-}
-
-func LoginAdmin(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) LoginAdmin(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var creds struct {
@@ -39,110 +25,68 @@ func LoginAdmin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var admin models.Admin
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	// Admini e-posta ile bulma
-	err = adminCollection.FindOne(ctx, bson.M{"email": creds.Email}).Decode(&admin)
-	if err != nil {
-		http.Error(w, "Kullanıcı bulunamadı", http.StatusUnauthorized)
+	account, ok := h.login(ctx, w, r, utils.RoleAdmin, creds.Email, creds.Password)
+	if !ok {
 		return
 	}
 
-	// Şifre doğrulama
-	err = bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(creds.Password))
-	if err != nil {
-		http.Error(w, "Geçersiz şifre", http.StatusUnauthorized)
+	// 2FA açıksa veya şirket zorunlu kıldıysa token yerine challenge döner
+	if h.startSecondFactor(ctx, w, account) {
 		return
 	}
 
 	// Token oluşturma
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &jwt.StandardClaims{
-		ExpiresAt: expirationTime.Unix(),
-		Subject:   admin.ID.Hex(),
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte("admin_secret_key"))
-	if err != nil {
-		http.Error(w, "Token oluşturulamadı", http.StatusInternalServerError)
+	sess, ok := h.issueSession(ctx, w, account.Actor)
+	if !ok {
 		return
 	}
-	adminID := (admin.ID).Hex()
 
 	// Yanıt
-	json.NewEncoder(w).Encode(map[string]string{"token": tokenString, "ID": adminID})
+	json.NewEncoder(w).Encode(map[string]string{"token": sess.AccessToken, "refreshToken": sess.RefreshToken, "ID": account.ID})
 }
 
 // Admin verilerini çekme
-func GetAdmins(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) GetAdmins(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var admins []models.Admin
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	cursor, err := adminCollection.Find(ctx, bson.M{})
+	admins, err := h.admins.List(ctx)
 	if err != nil {
 		http.Error(w, "Veri çekme hatası", http.StatusInternalServerError)
 		return
 	}
-	defer cursor.Close(ctx)
-
-	if err = cursor.All(ctx, &admins); err != nil {
-		http.Error(w, "Veri çözümleme hatası", http.StatusInternalServerError)
-		return
-	}
 
 	json.NewEncoder(w).Encode(admins)
 }
 
-// Şifreyi hash'leyen fonksiyon
-func hashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
-}
-
-func AddAdmin(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) AddAdmin(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var admin models.Admin
-	err := json.NewDecoder(r.Body).Decode(&admin)
+	// Şifre hash alanı yanıtlara yazılmadığı için ayrı okunur
+	var req struct {
+		models.Admin
+		Password string `json:"password"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, "Geçersiz veri formatı", http.StatusBadRequest)
 		return
 	}
+	admin := req.Admin
+	admin.Password = req.Password
 
-	// Kullanıcıyı email üzerinden eşleştirin
-	var user models.User
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	err = userCollection.FindOne(ctx, bson.M{"email": admin.Email}).Decode(&user)
-	if err != nil {
-		http.Error(w, "Kullanıcı bulunamadı", http.StatusBadRequest)
-		return
-	}
-
-	// Şifreyi hash'leyin
-	hashedPassword, err := hashPassword(admin.Password)
+	// Admin yalnızca aynı e-postayla kayıtlı bir kullanıcı için eklenir
+	_, err = h.admins.Create(ctx, admin)
 	if err != nil {
-		http.Error(w, "Şifre hash'lemesi başarısız oldu", http.StatusInternalServerError)
-		return
-	}
-	admin.Password = hashedPassword
-
-	// Adminin ek bilgilerini ayarlayın
-	admin.ID = primitive.NewObjectID()
-	admin.CreatedAt = time.Now()
-	admin.UpdatedAt = time.Now()
-
-	// Admin belgesini veritabanına ekleyin
-	_, err = adminCollection.InsertOne(ctx, admin)
-	if err != nil {
-		http.Error(w, "Veritabanına eklenemedi", http.StatusInternalServerError)
+		writeError(w, err, "Veritabanına eklenemedi")
 		return
 	}
 
@@ -150,28 +94,32 @@ func AddAdmin(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Admin başarıyla eklendi"})
 }
 
-func UpdateAdmins(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) UpdateAdmins(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var admins []models.Admin
-	err := json.NewDecoder(r.Body).Decode(&admins)
+	var req []struct {
+		models.Admin
+		Password string `json:"password"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, "Geçersiz veri formatı", http.StatusBadRequest)
 		return
 	}
+	admins := make([]models.Admin, 0, len(req))
+	for _, a := range req {
+		a.Admin.Password = a.Password
+		admins = append(admins, a.Admin)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	for _, admin := range admins {
-		filter := bson.M{"_id": admin.ID}
-		update := bson.M{"$set": admin}
-
-		_, err := adminCollection.UpdateOne(ctx, filter, update)
-		if err != nil {
-			http.Error(w, "Veritabanı güncelleme hatası", http.StatusInternalServerError)
-			return
-		}
+	// Eşleşmeyen kayıtlar atlanır
+	err = h.admins.UpdateMany(ctx, admins)
+	if err != nil {
+		writeError(w, err, "Veritabanı güncelleme hatası")
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -179,30 +127,23 @@ func UpdateAdmins(w http.ResponseWriter, r *http.Request) {
 }
 
 
-func GetAdminByEmail(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) GetAdminByEmail(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// URL parametrelerinden email'i al
-	email := r.URL.Query().Get("email")
+	// Admin kendi kaydını token'dan, superuser ise parametreden okur
+	email := principalEmailOr(r, utils.RoleAdmin, r.URL.Query().Get("email"))
 	if email == "" {
 		http.Error(w, "Email parametresi eksik", http.StatusBadRequest)
 		return
 	}
 
-	// Admin bilgisini tutacak değişken
-	var admin models.Admin
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
 	// Email ile admini bul
-	err := adminCollection.FindOne(ctx, bson.M{"email": email}).Decode(&admin)
+	admin, err := h.admins.FindByEmail(ctx, email)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			http.Error(w, "Admin bulunamadı", http.StatusNotFound)
-		} else {
-			http.Error(w, "Veritabanı hatası", http.StatusInternalServerError)
-		}
+		writeError(w, err, "Veritabanı hatası")
 		return
 	}
 
@@ -210,7 +151,7 @@ func GetAdminByEmail(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(admin)
 }
 
-func UpdateAdminByEmail(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) UpdateAdminByEmail(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// URL parametrelerinden email'i al
@@ -228,29 +169,17 @@ func UpdateAdminByEmail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	// Güncelleme verisi
-	update := bson.M{
-		"$set": bson.M{
-			"name":      updatedAdmin.Name,
-			"password":  updatedAdmin.Password, // Şifre güncelleniyorsa hash'lenmiş şekilde gelmeli
-			"role":      updatedAdmin.Role,
-			"updatedAt": time.Now(),
-		},
-	}
-
-	// Email ile admini güncelle
-	result, err := adminCollection.UpdateOne(ctx, bson.M{"email": email}, update)
+	// Şifre düz metin olarak gelir ve her zaman hash'lenerek saklanır
+	err = h.admins.UpdateByEmail(ctx, email, services.AdminUpdate{
+		Name:     updatedAdmin.Name,
+		Role:     updatedAdmin.Role,
+		Password: updatedAdmin.Password,
+	})
 	if err != nil {
-		http.Error(w, "Veritabanı güncelleme hatası", http.StatusInternalServerError)
-		return
-	}
-
-	// Eğer güncelleme yapılmadıysa
-	if result.MatchedCount == 0 {
-		http.Error(w, "Admin bulunamadı", http.StatusNotFound)
+		writeError(w, err, "Veritabanı güncelleme hatası")
 		return
 	}
 
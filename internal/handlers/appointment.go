@@ -3,55 +3,40 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
-	"rtsback/config"
 	"time"
 
+	"rtsback/internal/middlewares"
 	"rtsback/internal/models"
+	"rtsback/internal/services"
+	"rtsback/pkg/utils"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-var appointmentCollection *mongo.Collection
-
-func init() {
-	client := config.ConnectDB()
-	appointmentCollection = config.GetCollection(client, "appointment")
-}
-
-// Tüm randevuları çeken handler
-func GetAppointments(w http.ResponseWriter, r *http.Request) {
+// Token sahibinin müşteri olarak aldığı randevuları çeken handler
+func (h *AppointmentHandler) GetAppointments(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var appointments []models.Appointment
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	principal, ok := middlewares.CurrentPrincipal(r)
+	if !ok || principal.Email == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	cursor, err := appointmentCollection.Find(ctx, bson.M{})
+	appointments, err := h.appointments.ListForCustomer(ctx, principal.Email)
 	if err != nil {
 		http.Error(w, "Veri çekme hatası", http.StatusInternalServerError)
 		return
 	}
-	defer cursor.Close(ctx)
-
-	if err = cursor.All(ctx, &appointments); err != nil {
-		http.Error(w, "Veri çözümleme hatası", http.StatusInternalServerError)
-		return
-	}
 
 	json.NewEncoder(w).Encode(appointments)
 }
-func contains(slice []string, item string) bool {
-	for _, v := range slice {
-		if v == item {
-			return true
-		}
-	}
-	return false
-}
-func AutoCreateAppointment(w http.ResponseWriter, r *http.Request) {
+func (h *AppointmentHandler) AutoCreateAppointment(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var autoAdd models.AutoAddRequest
@@ -60,63 +45,103 @@ func AutoCreateAppointment(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Geçersiz veri formatı", http.StatusBadRequest)
 		return
 	}
+	autoAdd.ProviderEmail = principalEmailOr(r, utils.RoleProvider, autoAdd.ProviderEmail)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
 
-	// Geçerli tarihi al ve bir ay sonrasını hesapla
-	currentDate := time.Now()
-	endDate := currentDate.AddDate(0, 1, 0) // Bir ay sonrası
-
-	// Belirtilen günlerde ve saatlerde randevu oluşturma işlemi
-	for currentDate.Before(endDate) {
-		currentWeekday := currentDate.Weekday().String()
-
-		// Eğer geçerli gün belirtilen günler arasında varsa, randevu oluştur
-		if contains(autoAdd.Weekdays, currentWeekday) {
-			startTime, _ := time.Parse("15:04", autoAdd.ShiftStart)
-			endTime, _ := time.Parse("15:04", autoAdd.ShiftEnd)
-
-			start := time.Date(currentDate.Year(), currentDate.Month(), currentDate.Day(),
-				startTime.Hour(), startTime.Minute(), 0, 0, currentDate.Location())
-
-			end := time.Date(currentDate.Year(), currentDate.Month(), currentDate.Day(),
-				endTime.Hour(), endTime.Minute(), 0, 0, currentDate.Location())
-
-			for start.Before(end) {
-				appointment := models.Appointment{
-					ID:            primitive.NewObjectID(),
-					ProviderEmail: autoAdd.ProviderEmail,
-					CompanyName:   autoAdd.CompanyName,
-					Date:          start,
-					StartTime:     start,
-					EndTime:       start.Add(time.Duration(autoAdd.Period) * time.Minute),
-					Activate:      false,
-					CreatedAt:     time.Now(),
-					UpdatedAt:     time.Now(),
-				}
-
-				// Randevuyu MongoDB'ye ekle
-				_, err := appointmentCollection.InsertOne(ctx, appointment)
-				if err != nil {
-					http.Error(w, "Veritabanına randevu eklenemedi", http.StatusInternalServerError)
-					return
-				}
-
-				// Başlangıç saatini periyot kadar artır
-				start = start.Add(time.Duration(autoAdd.Period) * time.Minute)
-			}
-		}
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
 
-		// Tarihi bir gün ileri al
-		currentDate = currentDate.AddDate(0, 0, 1)
+	// Tarih aralığı verilmezse bugünden itibaren bir ay boyunca slot oluşturulur
+	batch, err := h.appointments.GenerateSlots(ctx, actor, autoAdd, time.Now())
+	if err != nil {
+		writeError(w, err, "Veritabanına randevu eklenemedi")
+		return
 	}
 
+	message := "Otomatik randevular başarıyla oluşturuldu"
+	if batch.DryRun {
+		message = "Önizleme: randevular kaydedilmedi"
+	}
+	response := map[string]interface{}{
+		"message": message,
+		"dryRun":  batch.DryRun,
+		"created": batch.Created,
+		"skipped": batch.Skipped,
+		"blocked": batch.Blocked,
+	}
+	// Önizlemede üretilecek slotlar döner; gerçek üretimde slotlar
+	// batchID ile ayrıca listelenebilir
+	if batch.DryRun {
+		response["slots"] = batch.Slots
+	} else {
+		response["batchID"] = batch.BatchID
+	}
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Otomatik randevular başarıyla oluşturuldu"})
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetSlotBatch bir toplu üretimden gelen slotları listeler
+func (h *AppointmentHandler) GetSlotBatch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	email := principalEmailOr(r, utils.RoleProvider, r.URL.Query().Get("email"))
+	batchID := r.URL.Query().Get("batchID")
+	if email == "" || batchID == "" {
+		http.Error(w, "Email and batchID are required", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	appointments, err := h.appointments.ListBatch(ctx, actor, email, batchID)
+	if err != nil {
+		writeError(w, err, "Failed to fetch batch")
+		return
+	}
+
+	json.NewEncoder(w).Encode(appointments)
+}
+
+// DeleteSlotBatch bir toplu üretimin hâlâ boş olan slotlarını siler;
+// alınmış slotlar yerinde kalır
+func (h *AppointmentHandler) DeleteSlotBatch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	email := principalEmailOr(r, utils.RoleProvider, r.URL.Query().Get("email"))
+	batchID := r.URL.Query().Get("batchID")
+	if email == "" || batchID == "" {
+		http.Error(w, "Email and batchID are required", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	deleted, kept, err := h.appointments.DeleteBatch(ctx, actor, email, batchID)
+	if err != nil {
+		writeError(w, err, "Failed to delete batch")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"deleted": deleted, "kept": kept})
 }
 
-func CreateAppointment(w http.ResponseWriter, r *http.Request) {
+func (h *AppointmentHandler) CreateAppointment(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var appointment models.Appointment
@@ -126,26 +151,22 @@ func CreateAppointment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	appointment.ID = primitive.NewObjectID()
-	appointment.CreatedAt = time.Now()
-	appointment.UpdatedAt = time.Now()
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	_, err = appointmentCollection.InsertOne(ctx, appointment)
+	_, err = h.appointments.Create(ctx, appointment)
 	if err != nil {
-		http.Error(w, "Veritabanına eklenemedi", http.StatusInternalServerError)
+		writeError(w, err, "Veritabanına eklenemedi")
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Randevu başarıyla oluşturuldu"})
 }
-func GetProviderAppointments(w http.ResponseWriter, r *http.Request) {
+func (h *AppointmentHandler) GetProviderAppointments(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	email := r.URL.Query().Get("email")
+	email := principalEmailOr(r, utils.RoleProvider, r.URL.Query().Get("email"))
 	date := r.URL.Query().Get("date")
 
 	if email == "" || date == "" {
@@ -160,34 +181,18 @@ func GetProviderAppointments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Define the start and end of the day for the date
-	startOfDay := time.Date(parsedDate.Year(), parsedDate.Month(), parsedDate.Day(), 0, 0, 0, 0, parsedDate.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
-
-	// Create a filter to find appointments within the specified date range
-	filter := bson.M{
-		"provider_email": email,
-		"date": bson.M{
-			"$gte": startOfDay,
-			"$lt":  endOfDay,
-		},
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
 	}
 
-	// Fetch appointments from the database
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Fetch the appointments within the day
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	cursor, err := appointmentCollection.Find(ctx, filter)
+	appointments, err := h.appointments.ProviderDay(ctx, actor, email, parsedDate)
 	if err != nil {
-		http.Error(w, "Failed to fetch appointments", http.StatusInternalServerError)
-		return
-	}
-	defer cursor.Close(ctx)
-
-	// Decode the fetched appointments into a slice
-	var appointments []models.Appointment
-	if err := cursor.All(ctx, &appointments); err != nil {
-		http.Error(w, "Failed to decode appointments", http.StatusInternalServerError)
+		writeError(w, err, "Failed to fetch appointments")
 		return
 	}
 
@@ -196,7 +201,7 @@ func GetProviderAppointments(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(appointments)
 }
 
-func AddProviderApp(w http.ResponseWriter, r *http.Request) {
+func (h *AppointmentHandler) AddProviderApp(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Temporary struct for decoding JSON
@@ -217,6 +222,9 @@ func AddProviderApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Sağlayıcı kendi adına randevu ekler, kimlik token'dan gelir
+	appointmentData.ProviderEmail = principalEmailOr(r, utils.RoleProvider, appointmentData.ProviderEmail)
+
 	// Parse the date and time fields
 	parsedDate, err := time.Parse("2006-01-02", appointmentData.Date)
 	if err != nil {
@@ -236,27 +244,31 @@ func AddProviderApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create the appointment object
-	appointment := models.Appointment{
-		ID:            primitive.NewObjectID(),
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	// activate ile eklenen randevu sağlayıcının doğrudan yazdığı onaylı randevudur
+	status := models.StatusOpen
+	if appointmentData.Activate {
+		status = models.StatusConfirmed
+	}
+
+	// Randevunun şirketi her zaman sağlayıcının kaydından alınır
+	_, err = h.appointments.AddForProvider(ctx, actor, models.Appointment{
 		ProviderEmail: appointmentData.ProviderEmail,
-		CompanyID:	   appointmentData.CompanyID,
 		CompanyName:   appointmentData.CompanyName,
 		Date:          parsedDate,
 		StartTime:     parsedStartTime,
 		EndTime:       parsedEndTime,
-		Activate:      appointmentData.Activate,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	// Insert the appointment into the database
-	_, err = appointmentCollection.InsertOne(ctx, appointment)
+		Status:        status,
+	})
 	if err != nil {
-		http.Error(w, "Failed to add appointment to database", http.StatusInternalServerError)
+		writeError(w, err, "Failed to add appointment to database")
 		return
 	}
 
@@ -264,7 +276,7 @@ func AddProviderApp(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Appointment added successfully"})
 }
 
-func UpdateAppointmentByID(w http.ResponseWriter, r *http.Request) {
+func (h *AppointmentHandler) UpdateAppointmentByID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Appointment ID'yi URL parametresinden alın
@@ -286,6 +298,7 @@ func UpdateAppointmentByID(w http.ResponseWriter, r *http.Request) {
 		// Tarih string olarak alınıyor
 		StartTime string `json:"startTime"` // Başlangıç saati string olarak alınıyor
 		EndTime   string `json:"endTime"`   // Bitiş saati string olarak alınıyor
+		Version   *int64 `json:"version"`   // Verilirse randevu bu sürümde olmalı
 	}
 
 	err = json.NewDecoder(r.Body).Decode(&updateData)
@@ -306,31 +319,25 @@ func UpdateAppointmentByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create the appointment object
-	updatement := models.Appointment{
-		StartTime: parsedStartTime,
-		EndTime:   parsedEndTime,
-		UpdatedAt: time.Now(),
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
 	}
 
-	// MongoDB güncelleme işlemi
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	filter := bson.M{"_id": objID}
-	update := bson.M{"$set": updatement}
-
-	_, err = appointmentCollection.UpdateOne(ctx, filter, update)
+	appointment, err := h.appointments.Reschedule(ctx, actor, objID, parsedStartTime, parsedEndTime, updateData.Version)
 	if err != nil {
-		http.Error(w, "Failed to update appointment", http.StatusInternalServerError)
+		writeError(w, err, "Failed to update appointment")
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Appointment updated successfully"})
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Appointment updated successfully", "version": appointment.Version})
 }
 
-func DeleteAppointmentByID(w http.ResponseWriter, r *http.Request) {
+func (h *AppointmentHandler) DeleteAppointmentByID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Appointment ID'yi URL parametresinden alın
@@ -347,15 +354,17 @@ func DeleteAppointmentByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// MongoDB silme işlemi
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
 
-	filter := bson.M{"_id": objID}
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
 
-	_, err = appointmentCollection.DeleteOne(ctx, filter)
+	err = h.appointments.Delete(ctx, actor, objID)
 	if err != nil {
-		http.Error(w, "Failed to delete appointment", http.StatusInternalServerError)
+		writeError(w, err, "Failed to delete appointment")
 		return
 	}
 
@@ -363,7 +372,7 @@ func DeleteAppointmentByID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Appointment deleted successfully"})
 }
 
-func GetInactiveAppointmentsOfProvider(w http.ResponseWriter, r *http.Request) {
+func (h *AppointmentHandler) GetInactiveAppointmentsOfProvider(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// URL'den `providerEmail` ve `date` parametrelerini al
@@ -382,44 +391,22 @@ func GetInactiveAppointmentsOfProvider(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Tarihin başlangıcı ve bitişi
-	startOfDay := time.Date(parsedDate.Year(), parsedDate.Month(), parsedDate.Day(), 0, 0, 0, 0, time.UTC)
-	endOfDay := startOfDay.Add(24 * time.Hour)
-
-	// Veritabanı sorgusu: providerEmail'e, tarihe ve activate=false olma durumuna göre
-	filter := bson.M{
-		"provider_email": providerEmail,
-		"date": bson.M{
-			"$gte": startOfDay,
-			"$lt":  endOfDay,
-		},
-		"activate": false,
-	}
-
-	// Randevuları çek
-	var appointments []models.Appointment
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Randevuları çek: sağlayıcının o günkü henüz alınmamış (activate=false) slotları
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	cursor, err := appointmentCollection.Find(ctx, filter)
+	appointments, err := h.appointments.AvailableSlots(ctx, providerEmail, parsedDate)
 	if err != nil {
 		http.Error(w, "Failed to fetch appointments", http.StatusInternalServerError)
 		return
 	}
-	defer cursor.Close(ctx)
-
-	// Cursor'u slice'a dekode et
-	if err = cursor.All(ctx, &appointments); err != nil {
-		http.Error(w, "Failed to decode appointments", http.StatusInternalServerError)
-		return
-	}
 
 	// Randevuları JSON formatında döndür
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(appointments)
 }
 
-func UpdateAppointmentFieldsByID(w http.ResponseWriter, r *http.Request) {
+func (h *AppointmentHandler) UpdateAppointmentFieldsByID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// URL'den `appointmentID` parametresini al
@@ -442,6 +429,8 @@ func UpdateAppointmentFieldsByID(w http.ResponseWriter, r *http.Request) {
 		CustomerEmail string   `json:"customer_email,omitempty"`
 		Services      []string `json:"services,omitempty"`
 		Activate      *bool    `json:"activate"`
+		Version       *int64   `json:"version"`
+		ServiceIDs    []string `json:"serviceIDs,omitempty"`
 	}
 
 	// Gelen JSON verisini decode et
@@ -450,39 +439,35 @@ func UpdateAppointmentFieldsByID(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
-
-	// Güncelleme için `UpdatedAt` alanını ayarlayın
-	update := bson.M{
-		"$set": bson.M{
-			"customer_name":  updateData.CustomerName,
-			"customer_email": updateData.CustomerEmail,
-			"services":       updateData.Services,
-			"activate":       updateData.Activate,
-			"updated_at":     time.Now(),
-		},
+	serviceIDs, err := parseObjectIDs(updateData.ServiceIDs)
+	if err != nil {
+		http.Error(w, "Invalid service ID", http.StatusBadRequest)
+		return
 	}
 
-	// MongoDB güncelleme işlemi
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	result, err := appointmentCollection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	// Slot doluysa ya da sürüm eşleşmiyorsa güncel haliyle 409 döner
+	appointment, err := h.appointments.Update(ctx, objID, services.AppointmentUpdate{
+		CustomerName:  updateData.CustomerName,
+		CustomerEmail: updateData.CustomerEmail,
+		Services:      updateData.Services,
+		Activate:      updateData.Activate,
+		Version:       updateData.Version,
+		ServiceIDs:    serviceIDs,
+	})
 	if err != nil {
-		http.Error(w, "Failed to update appointment", http.StatusInternalServerError)
-		return
-	}
-
-	if result.MatchedCount == 0 {
-		http.Error(w, "Appointment not found", http.StatusNotFound)
+		writeError(w, err, "Failed to update appointment")
 		return
 	}
 
 	// Başarı durumunda mesaj döndür
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Appointment fields updated successfully"})
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Appointment fields updated successfully", "version": appointment.Version})
 }
 
-func UpdateAppointment(w http.ResponseWriter, r *http.Request) {
+func (h *AppointmentHandler) UpdateAppointment(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// URL'den Appointment ID'yi al
@@ -504,6 +489,8 @@ func UpdateAppointment(w http.ResponseWriter, r *http.Request) {
 		CustomerName  string   `json:"customer_name"`
 		CustomerEmail string   `json:"customer_email"`
 		Services      []string `json:"services"`
+		Version       *int64   `json:"version"`
+		ServiceIDs    []string `json:"serviceIDs"`
 	}
 
 	var updateReq UpdateRequest
@@ -512,35 +499,193 @@ func UpdateAppointment(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
+	serviceIDs, err := parseObjectIDs(updateReq.ServiceIDs)
+	if err != nil {
+		http.Error(w, "Invalid service ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	// Müşteri slota yalnızca slot hâlâ boşsa yazılır; aksi halde 409
+	appointment, err := h.appointments.Book(ctx, objID, services.Booking{
+		CustomerName:  updateReq.CustomerName,
+		CustomerEmail: updateReq.CustomerEmail,
+		Services:      updateReq.Services,
+		Version:       updateReq.Version,
+		ServiceIDs:    serviceIDs,
+	})
+	if err != nil {
+		writeError(w, err, "Failed to update appointment")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Appointment updated successfully", "version": appointment.Version})
+}
+
+// transitionAppointment randevuyu verilen duruma geçiren uç noktaların
+// ortak gövdesidir. İstek gövdesi isteğe bağlıdır: version verilirse
+// randevu o sürümde olmalı, reason geçmişe yazılır.
+func (h *AppointmentHandler) transitionAppointment(w http.ResponseWriter, r *http.Request, status string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	appointmentID := r.URL.Query().Get("id")
+	if appointmentID == "" {
+		http.Error(w, "Appointment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	objID, err := primitive.ObjectIDFromHex(appointmentID)
+	if err != nil {
+		http.Error(w, "Invalid Appointment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Version *int64 `json:"version"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	// İzin verilmeyen geçişlerde randevunun güncel haliyle 409 döner
+	appointment, err := h.appointments.Transition(ctx, actor, objID, status, req.Reason, req.Version)
+	if err != nil {
+		writeError(w, err, "Failed to update appointment status")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appointment)
+}
+
+// ConfirmAppointment müşterinin aldığı randevuyu onaylar
+func (h *AppointmentHandler) ConfirmAppointment(w http.ResponseWriter, r *http.Request) {
+	h.transitionAppointment(w, r, models.StatusConfirmed)
+}
+
+// CancelAppointment alınmış ya da onaylanmış randevuyu iptal eder
+func (h *AppointmentHandler) CancelAppointment(w http.ResponseWriter, r *http.Request) {
+	h.transitionAppointment(w, r, models.StatusCancelled)
+}
+
+// CompleteAppointment onaylanmış randevuyu tamamlandı olarak işaretler
+func (h *AppointmentHandler) CompleteAppointment(w http.ResponseWriter, r *http.Request) {
+	h.transitionAppointment(w, r, models.StatusCompleted)
+}
+
+// NoShowAppointment müşterinin gelmediği randevuyu işaretler
+func (h *AppointmentHandler) NoShowAppointment(w http.ResponseWriter, r *http.Request) {
+	h.transitionAppointment(w, r, models.StatusNoShow)
+}
+
+// ReopenAppointment iptal edilen slotu müşteri bilgilerini temizleyerek tekrar açar
+func (h *AppointmentHandler) ReopenAppointment(w http.ResponseWriter, r *http.Request) {
+	h.transitionAppointment(w, r, models.StatusOpen)
+}
+
+// CancelMyAppointment müşterinin kendi randevusunu iptal eder; slot tekrar
+// müsait olur ve sağlayıcıya e-posta gider
+func (h *AppointmentHandler) CancelMyAppointment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-	// MongoDB güncelleme işlemi
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid Appointment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Version *int64 `json:"version"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	// Güncelleme verisi
-	update := bson.M{
-		"$set": bson.M{
-			"customer_name":  updateReq.CustomerName,
-			"customer_email": updateReq.CustomerEmail,
-			"services":       updateReq.Services,
-			"activate":       true,
-			"updated_at":     time.Now(),
-		},
+	appointment, err := h.appointments.CancelByCustomer(ctx, actor, objID, req.Reason, req.Version)
+	if err != nil {
+		writeError(w, err, "Failed to cancel appointment")
+		return
 	}
 
-	// Veritabanındaki randevuyu güncelle
-	result, err := appointmentCollection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appointment)
+}
+
+// RescheduleMyAppointment müşterinin randevusunu aynı sağlayıcının başka
+// bir boş slotuna taşır ve yeni randevuyu döner
+func (h *AppointmentHandler) RescheduleMyAppointment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
 	if err != nil {
-		http.Error(w, "Failed to update appointment", http.StatusInternalServerError)
+		http.Error(w, "Invalid Appointment ID", http.StatusBadRequest)
 		return
 	}
 
-	// Eğer randevu bulunamadıysa
-	if result.MatchedCount == 0 {
-		http.Error(w, "Appointment not found", http.StatusNotFound)
+	// Hedef ya var olan boş bir slotun ID'si ya da çalışma saatlerinden
+	// hesaplanan bir slotun başlangıcıdır (RFC3339)
+	var req struct {
+		TargetID string `json:"targetID"`
+		Start    string `json:"start"`
+		Version  *int64 `json:"version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	var target services.RescheduleTarget
+	if req.TargetID != "" {
+		target.ID, err = primitive.ObjectIDFromHex(req.TargetID)
+		if err != nil {
+			http.Error(w, "Invalid target slot ID", http.StatusBadRequest)
+			return
+		}
+	} else {
+		target.Start, err = time.Parse(time.RFC3339, req.Start)
+		if err != nil {
+			http.Error(w, "Target slot ID or start time (RFC3339) is required", http.StatusBadRequest)
+			return
+		}
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	appointment, err := h.appointments.RescheduleByCustomer(ctx, actor, objID, target, req.Version)
+	if err != nil {
+		writeError(w, err, "Failed to reschedule appointment")
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Appointment updated successfully"})
-}
\ No newline at end of file
+	json.NewEncoder(w).Encode(appointment)
+}
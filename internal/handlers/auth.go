@@ -1 +1,86 @@
-package handlers
\ No newline at end of file
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// RefreshToken yenileme token'ını döndürür (rotation) ve yeni bir erişim
+// token'ı verir. Daha önce kullanılmış bir token tekrar gelirse token
+// çalınmış kabul edilir ve tüm aile iptal edilir.
+func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Refresh token is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	sess, err := h.auth.Refresh(ctx, req.RefreshToken)
+	if err != nil {
+		writeError(w, err, "Failed to rotate refresh token")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":        sess.AccessToken,
+		"refreshToken": sess.RefreshToken,
+		"expiresAt":    sess.ExpiresAt,
+	})
+}
+
+// Logout verilen yenileme token'ının ait olduğu oturumu kapatır
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Refresh token is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	if err := h.auth.Logout(ctx, req.RefreshToken); err != nil {
+		writeError(w, err, "Failed to revoke session")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out successfully"})
+}
+
+// LogoutAll token sahibinin bu roldeki tüm oturumlarını kapatır
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	revoked, err := h.auth.LogoutAll(ctx, actor)
+	if err != nil {
+		writeError(w, err, "Failed to revoke sessions")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":      "All sessions logged out",
+		"revokedCount": revoked,
+	})
+}
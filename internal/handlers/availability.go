@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/services"
+	"rtsback/pkg/utils"
+)
+
+// timeRangePayload gün içi saat aralığının istek/yanıt biçimidir
+type timeRangePayload struct {
+	Start string `json:"start"` // "09:00"
+	End   string `json:"end"`   // "12:30"
+}
+
+// workingHoursPayload çalışma saatlerinin istek ve yanıt biçimidir.
+// Geçerlilik tarihleri YYYY-MM-DD'dir ve boş bırakılabilir.
+type workingHoursPayload struct {
+	ProviderEmail string                    `json:"providerEmail"`
+	SlotMinutes   int                       `json:"slotMinutes"`
+	Rules         []workingHoursRulePayload `json:"rules"`
+}
+
+type workingHoursRulePayload struct {
+	Weekdays      []string           `json:"weekdays"`
+	Intervals     []timeRangePayload `json:"intervals"`
+	Breaks        []timeRangePayload `json:"breaks,omitempty"`
+	EffectiveFrom string             `json:"effectiveFrom,omitempty"`
+	EffectiveTo   string             `json:"effectiveTo,omitempty"`
+}
+
+func toTimeRanges(in []timeRangePayload) []models.TimeRange {
+	out := make([]models.TimeRange, 0, len(in))
+	for _, r := range in {
+		out = append(out, models.TimeRange{Start: r.Start, End: r.End})
+	}
+	return out
+}
+
+func fromTimeRanges(in []models.TimeRange) []timeRangePayload {
+	out := make([]timeRangePayload, 0, len(in))
+	for _, r := range in {
+		out = append(out, timeRangePayload{Start: r.Start, End: r.End})
+	}
+	return out
+}
+
+// parseDay boş değilse YYYY-MM-DD tarihini takvim günü olarak UTC gece
+// yarısına çevirir. Günün hangi saat diliminde yorumlanacağına servis karar verir.
+func parseDay(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func formatDay(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02")
+}
+
+// slotPayload müsait bir slotun hem UTC hem de şirketin saat dilimindeki gösterimidir
+type slotPayload struct {
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	LocalStart string    `json:"localStart"`
+	LocalEnd   string    `json:"localEnd"`
+	TimeZone   string    `json:"timeZone"`
+}
+
+func slotResponse(slots []services.Slot) []slotPayload {
+	out := make([]slotPayload, 0, len(slots))
+	for _, slot := range slots {
+		out = append(out, slotPayload{
+			Start:      slot.Start.UTC(),
+			End:        slot.End.UTC(),
+			LocalStart: slot.Start.Format(time.RFC3339),
+			LocalEnd:   slot.End.Format(time.RFC3339),
+			TimeZone:   slot.Start.Location().String(),
+		})
+	}
+	return out
+}
+
+func (p workingHoursPayload) model() (models.WorkingHours, error) {
+	hours := models.WorkingHours{SlotMinutes: p.SlotMinutes}
+	for _, rule := range p.Rules {
+		from, err := parseDay(rule.EffectiveFrom)
+		if err != nil {
+			return models.WorkingHours{}, err
+		}
+		to, err := parseDay(rule.EffectiveTo)
+		if err != nil {
+			return models.WorkingHours{}, err
+		}
+		hours.Rules = append(hours.Rules, models.WorkingHoursRule{
+			Weekdays:      rule.Weekdays,
+			Intervals:     toTimeRanges(rule.Intervals),
+			Breaks:        toTimeRanges(rule.Breaks),
+			EffectiveFrom: from,
+			EffectiveTo:   to,
+		})
+	}
+	return hours, nil
+}
+
+func workingHoursResponse(hours models.WorkingHours) workingHoursPayload {
+	out := workingHoursPayload{
+		ProviderEmail: hours.ProviderEmail,
+		SlotMinutes:   hours.SlotMinutes,
+		Rules:         []workingHoursRulePayload{},
+	}
+	for _, rule := range hours.Rules {
+		out.Rules = append(out.Rules, workingHoursRulePayload{
+			Weekdays:      rule.Weekdays,
+			Intervals:     fromTimeRanges(rule.Intervals),
+			Breaks:        fromTimeRanges(rule.Breaks),
+			EffectiveFrom: formatDay(rule.EffectiveFrom),
+			EffectiveTo:   formatDay(rule.EffectiveTo),
+		})
+	}
+	return out
+}
+
+// GetWorkingHours sağlayıcının haftalık çalışma saatleri kurallarını döner
+func (h *AppointmentHandler) GetWorkingHours(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	email := principalEmailOr(r, utils.RoleProvider, r.URL.Query().Get("email"))
+	if email == "" {
+		http.Error(w, "Email parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	hours, err := h.appointments.WorkingHours(ctx, actor, email)
+	if err != nil {
+		writeError(w, err, "Failed to fetch working hours")
+		return
+	}
+
+	json.NewEncoder(w).Encode(workingHoursResponse(hours))
+}
+
+// SetWorkingHours sağlayıcının haftalık çalışma saatleri kurallarını
+// tamamen değiştirir. Boş slotlar bu kurallardan anlık hesaplanır.
+func (h *AppointmentHandler) SetWorkingHours(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req workingHoursPayload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	// Sağlayıcı kendi saatlerini ayarlar, kimlik token'dan gelir
+	email := principalEmailOr(r, utils.RoleProvider, req.ProviderEmail)
+	if email == "" {
+		http.Error(w, "Provider email is required", http.StatusBadRequest)
+		return
+	}
+
+	hours, err := req.model()
+	if err != nil {
+		http.Error(w, "Invalid effective date format, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	saved, err := h.appointments.SetWorkingHours(ctx, actor, email, hours)
+	if err != nil {
+		writeError(w, err, "Failed to save working hours")
+		return
+	}
+
+	json.NewEncoder(w).Encode(workingHoursResponse(saved))
+}
+
+// GetAvailability sağlayıcının verilen tarih aralığındaki boş slotlarını
+// çalışma saatlerinden hesaplayarak döner. from ve to şirketin saat
+// dilimindeki YYYY-MM-DD günleridir, to dahildir; to verilmezse yalnızca
+// from günü döner.
+func (h *AppointmentHandler) GetAvailability(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	providerEmail := r.URL.Query().Get("providerEmail")
+	if providerEmail == "" {
+		http.Error(w, "Provider Email is required", http.StatusBadRequest)
+		return
+	}
+
+	from, err := parseDay(r.URL.Query().Get("from"))
+	if err != nil || from.IsZero() {
+		http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	to, err := parseDay(r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if to.IsZero() {
+		to = from
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	slots, err := h.appointments.Availability(ctx, providerEmail, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		writeError(w, err, "Failed to compute availability")
+		return
+	}
+
+	json.NewEncoder(w).Encode(slotResponse(slots))
+}
+
+// BookSlot müşterinin hesaplanan bir slotu almasıdır; randevu kaydı bu
+// anda oluşur. Slot artık müsait değilse 409 döner.
+func (h *AppointmentHandler) BookSlot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		ProviderEmail string   `json:"providerEmail"`
+		Start         string   `json:"start"` // RFC3339, örneğin 2024-05-06T09:30:00+03:00
+		CustomerName  string   `json:"customerName"`
+		CustomerEmail string   `json:"customerEmail"`
+		Services      []string `json:"services"`
+		ServiceIDs    []string `json:"serviceIDs"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	serviceIDs, err := parseObjectIDs(req.ServiceIDs)
+	if err != nil {
+		http.Error(w, "Invalid service ID", http.StatusBadRequest)
+		return
+	}
+	if req.ProviderEmail == "" || req.CustomerEmail == "" {
+		http.Error(w, "Provider and customer email are required", http.StatusBadRequest)
+		return
+	}
+
+	start, err := time.Parse(time.RFC3339, req.Start)
+	if err != nil {
+		http.Error(w, "Invalid start time, expected RFC3339", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	appointment, err := h.appointments.BookSlot(ctx, services.SlotBooking{
+		ProviderEmail: req.ProviderEmail,
+		Start:         start,
+		CustomerName:  req.CustomerName,
+		CustomerEmail: req.CustomerEmail,
+		Services:      req.Services,
+		ServiceIDs:    serviceIDs,
+	})
+	if err != nil {
+		writeError(w, err, "Failed to book slot")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(appointment)
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxCalendarUpload yüklenen takvim isteğinin en büyük boyutudur; dosyanın
+// sınırı serviste kontrol edilir
+const maxCalendarUpload = 6 << 20
+
+// calendarReadTimeout takvim adresten okunurken sorgu süresine eklenen süredir
+const calendarReadTimeout = 30 * time.Second
+
+// busySourcePayload dış takvimin yanıt biçimidir. location yüklenen
+// dosyalarda boştur.
+type busySourcePayload struct {
+	ID            string     `json:"id"`
+	ProviderEmail string     `json:"providerEmail"`
+	Name          string     `json:"name"`
+	Location      string     `json:"location,omitempty"`
+	Blocks        int        `json:"blocks"`
+	SyncedAt      *time.Time `json:"syncedAt,omitempty"`
+	SyncError     string     `json:"syncError,omitempty"`
+}
+
+func busySourceResponse(source models.BusySource) busySourcePayload {
+	out := busySourcePayload{
+		ID:            source.ID.Hex(),
+		ProviderEmail: source.ProviderEmail,
+		Name:          source.Name,
+		Location:      source.Location,
+		Blocks:        source.Blocks,
+		SyncError:     source.SyncError,
+	}
+	if !source.SyncedAt.IsZero() {
+		synced := source.SyncedAt.UTC()
+		out.SyncedAt = &synced
+	}
+	return out
+}
+
+// busyBlockPayload dış takvimden alınan dolu aralığın yanıt biçimidir; zamanlar UTC'dir
+type busyBlockPayload struct {
+	CalendarID string    `json:"calendarID"`
+	Summary    string    `json:"summary,omitempty"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+}
+
+// ImportCalendar sağlayıcının .ics dosyasını içe aktarır. Dosya
+// multipart/form-data ile "file" alanında ya da doğrudan istek gövdesinde
+// gönderilir; name ve providerEmail form alanı ya da sorgu parametresidir.
+// Aynı adla yüklenmiş takvim varsa yeni dosya onun yerine geçer.
+func (h *AppointmentHandler) ImportCalendar(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxCalendarUpload)
+	var data []byte
+	var err error
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, _, ferr := r.FormFile("file")
+		if ferr != nil {
+			http.Error(w, "Calendar file is required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, err = io.ReadAll(file)
+	} else {
+		data, err = io.ReadAll(r.Body)
+	}
+	if err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	email := principalEmailOr(r, utils.RoleProvider, r.FormValue("providerEmail"))
+	if email == "" {
+		http.Error(w, "Provider Email is required", http.StatusBadRequest)
+		return
+	}
+	name := r.FormValue("name")
+	if strings.TrimSpace(name) == "" {
+		name = "Imported calendar"
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	source, err := h.appointments.ImportCalendar(ctx, actor, email, name, data)
+	if err != nil {
+		writeError(w, err, "Failed to import calendar")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(busySourceResponse(source))
+}
+
+// AddCalendarSource sağlayıcıya düzenli olarak yeniden okunan bir dış
+// takvim ekler. location bir http(s)/webcal adresi ya da sunucudaki
+// içe aktarma dizininde bir dosya yoludur.
+func (h *AppointmentHandler) AddCalendarSource(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		ProviderEmail string `json:"providerEmail"`
+		Name          string `json:"name"`
+		Location      string `json:"location"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	email := principalEmailOr(r, utils.RoleProvider, req.ProviderEmail)
+	if email == "" {
+		http.Error(w, "Provider Email is required", http.StatusBadRequest)
+		return
+	}
+	if req.Location == "" {
+		http.Error(w, "Calendar location is required", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	// Takvim eklenirken okunduğu için sorgu süresine okuma süresi eklenir
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout+calendarReadTimeout)
+	defer cancel()
+
+	source, err := h.appointments.AddCalendarSource(ctx, actor, email, req.Name, req.Location)
+	if err != nil {
+		writeError(w, err, "Failed to add calendar")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(busySourceResponse(source))
+}
+
+// GetCalendarSources sağlayıcının dış takvimlerini ve son eşitleme durumlarını listeler
+func (h *AppointmentHandler) GetCalendarSources(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	email := principalEmailOr(r, utils.RoleProvider, r.URL.Query().Get("email"))
+	if email == "" {
+		http.Error(w, "Email parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	list, err := h.appointments.ListCalendarSources(ctx, actor, email)
+	if err != nil {
+		writeError(w, err, "Failed to fetch calendars")
+		return
+	}
+
+	out := make([]busySourcePayload, 0, len(list))
+	for _, source := range list {
+		out = append(out, busySourceResponse(source))
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
+// SyncCalendarSource dış takvimi beklemeden yeniden okur
+func (h *AppointmentHandler) SyncCalendarSource(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid calendar ID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout+calendarReadTimeout)
+	defer cancel()
+
+	source, err := h.appointments.SyncCalendarSource(ctx, actor, objID)
+	if err != nil {
+		writeError(w, err, "Failed to sync calendar")
+		return
+	}
+
+	json.NewEncoder(w).Encode(busySourceResponse(source))
+}
+
+// DeleteCalendarSource dış takvimi ve ondan alınan dolu aralıkları siler
+func (h *AppointmentHandler) DeleteCalendarSource(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid calendar ID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	if err := h.appointments.DeleteCalendarSource(ctx, actor, objID); err != nil {
+		writeError(w, err, "Failed to delete calendar")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Calendar deleted"})
+}
+
+// GetBusyBlocks sağlayıcının dış takvimlerinden alınan dolu aralıkları
+// from ile to (dahil, YYYY-MM-DD, UTC) arasında listeler. from verilmezse
+// bugünden, to verilmezse from'dan 30 gün sonrasına kadardır. id verilirse
+// yalnızca o takvimin aralıkları döner.
+func (h *AppointmentHandler) GetBusyBlocks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	email := principalEmailOr(r, utils.RoleProvider, r.URL.Query().Get("email"))
+	if email == "" {
+		http.Error(w, "Email parameter is required", http.StatusBadRequest)
+		return
+	}
+	var sourceID primitive.ObjectID
+	if id := r.URL.Query().Get("id"); id != "" {
+		var err error
+		if sourceID, err = primitive.ObjectIDFromHex(id); err != nil {
+			http.Error(w, "Invalid calendar ID", http.StatusBadRequest)
+			return
+		}
+	}
+	from, err := parseDay(r.URL.Query().Get("from"))
+	if err != nil {
+		http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if from.IsZero() {
+		from = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+	to, err := parseDay(r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if to.IsZero() {
+		to = from.AddDate(0, 0, 30)
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	list, err := h.appointments.BusyBlocks(ctx, actor, email, sourceID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		writeError(w, err, "Failed to fetch busy times")
+		return
+	}
+
+	out := make([]busyBlockPayload, 0, len(list))
+	for _, b := range list {
+		out = append(out, busyBlockPayload{
+			CalendarID: b.SourceID.Hex(),
+			Summary:    b.Summary,
+			Start:      b.Start.UTC(),
+			End:        b.End.UTC(),
+		})
+	}
+	json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// GetCalendarFeed sağlayıcının ya da müşterinin takvim aboneliğinin olup
+// olmadığını döner; token yalnızca oluşturulurken gösterildiği için yanıtta yoktur
+func (h *AppointmentHandler) GetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	feed, err := h.appointments.CalendarFeedStatus(ctx, actor)
+	if err != nil {
+		writeError(w, err, "Failed to fetch calendar feed")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"active":    true,
+		"createdAt": feed.CreatedAt.UTC(),
+	})
+}
+
+// CreateCalendarFeed yeni bir takvim aboneliği bağlantısı üretir. Varsa
+// eski bağlantı geçersiz olur; bağlantı yalnızca bu yanıtta gösterilir.
+func (h *AppointmentHandler) CreateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	feed, err := h.appointments.RotateCalendarFeed(ctx, actor)
+	if err != nil {
+		writeError(w, err, "Failed to create calendar feed")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":     feed.Token,
+		"url":       feed.URL,
+		"createdAt": feed.CreatedAt.UTC(),
+	})
+}
+
+// RevokeCalendarFeed takvim aboneliğini iptal eder; bağlantı artık çalışmaz
+func (h *AppointmentHandler) RevokeCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	if err := h.appointments.RevokeCalendarFeed(ctx, actor); err != nil {
+		writeError(w, err, "Failed to revoke calendar feed")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Calendar feed revoked"})
+}
+
+// CalendarFeed takvim uygulamalarının abone olduğu iCalendar dosyasıdır.
+// Takvim uygulamaları başlık gönderemediği için kimlik doğrulama yerine
+// bağlantıdaki token kullanılır.
+func (h *AppointmentHandler) CalendarFeed(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	data, err := h.appointments.CalendarFeed(ctx, r.URL.Query().Get("token"))
+	if err != nil {
+		writeError(w, err, "Failed to build calendar feed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="appointments.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	w.Write(data)
+}
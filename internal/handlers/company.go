@@ -4,46 +4,28 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"time"
 
-	"rtsback/config"
 	"rtsback/internal/models"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var companyCollection *mongo.Collection
-
-func init() {
-	client := config.ConnectDB()
-	companyCollection = config.GetCollection(client, "company")
-}
-func GetCompanies(w http.ResponseWriter, r *http.Request) {
+func (h *CompanyHandler) GetCompanies(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var companies []models.Company
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	cursor, err := companyCollection.Find(ctx, bson.M{})
+	companies, err := h.companies.List(ctx)
 	if err != nil {
 		http.Error(w, "Veri çekme hatası", http.StatusInternalServerError)
 		return
 	}
-	defer cursor.Close(ctx)
-
-	if err = cursor.All(ctx, &companies); err != nil {
-		http.Error(w, "Veri çözümleme hatası", http.StatusInternalServerError)
-		return
-	}
 
 	json.NewEncoder(w).Encode(companies)
 }
 
-func AddCompany(w http.ResponseWriter, r *http.Request) {
+func (h *CompanyHandler) AddCompany(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var company models.Company
@@ -53,16 +35,12 @@ func AddCompany(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	company.ID = primitive.NewObjectID() // Yeni bir ObjectID oluşturur
-	company.CreatedAt = time.Now()
-	company.UpdatedAt = time.Now()
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	_, err = companyCollection.InsertOne(ctx, company)
+	_, err = h.companies.Create(ctx, company)
 	if err != nil {
-		http.Error(w, "Veritabanına eklenemedi", http.StatusInternalServerError)
+		writeError(w, err, "Veritabanına eklenemedi")
 		return
 	}
 
@@ -70,7 +48,7 @@ func AddCompany(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Şirket başarıyla eklendi"})
 }
 
-func UpdateCompanies(w http.ResponseWriter, r *http.Request) {
+func (h *CompanyHandler) UpdateCompanies(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var companies []models.Company
@@ -80,18 +58,14 @@ func UpdateCompanies(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	for _, company := range companies {
-		filter := bson.M{"_id": company.ID}
-		update := bson.M{"$set": company}
-
-		_, err := companyCollection.UpdateOne(ctx, filter, update)
-		if err != nil {
-			http.Error(w, "Veritabanı güncelleme hatası", http.StatusInternalServerError)
-			return
-		}
+	// Eşleşmeyen kayıtlar atlanır
+	err = h.companies.UpdateMany(ctx, companies)
+	if err != nil {
+		writeError(w, err, "Veritabanı güncelleme hatası")
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -99,7 +73,7 @@ func UpdateCompanies(w http.ResponseWriter, r *http.Request) {
 }
 
 
-func GetCompanyByName(w http.ResponseWriter, r *http.Request) {
+func (h *CompanyHandler) GetCompanyByName(w http.ResponseWriter, r *http.Request) {
     w.Header().Set("Content-Type", "application/json")
 
     // Query parameterden şirket ismini al
@@ -109,21 +83,20 @@ func GetCompanyByName(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    var company models.Company
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
     defer cancel()
 
-    // MongoDB'de şirketi isme göre ara
-    err := companyCollection.FindOne(ctx, bson.M{"name": companyName}).Decode(&company)
+    // Şirketi isme göre ara
+    company, err := h.companies.FindByName(ctx, companyName)
     if err != nil {
-        http.Error(w, "Şirket bulunamadı", http.StatusNotFound)
+        writeError(w, err, "Şirket bulunamadı")
         return
     }
 
     json.NewEncoder(w).Encode(company)
 }
 
-func UpdateCompanyByName(w http.ResponseWriter, r *http.Request) {
+func (h *CompanyHandler) UpdateCompanyByName(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var updatedCompany models.Company
@@ -133,29 +106,13 @@ func UpdateCompanyByName(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Şirket ismi boş ise hata döndür
-	if updatedCompany.Name == "" {
-		http.Error(w, "Şirket adı gerekli", http.StatusBadRequest)
-		return
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	// Şirket ismi ile güncelleme yap
-	filter := bson.M{"name": updatedCompany.Name}
-	update := bson.M{"$set": updatedCompany}
-
-	opts := options.Update().SetUpsert(false) // Eğer şirket yoksa oluşturulmasın
-	result, err := companyCollection.UpdateOne(ctx, filter, update, opts)
+	// Şirket ismi ile güncelleme yap; şirket yoksa oluşturulmaz
+	err = h.companies.UpdateByName(ctx, updatedCompany)
 	if err != nil {
-		http.Error(w, "Şirket güncellenemedi", http.StatusInternalServerError)
-		return
-	}
-
-	// Hiçbir belge güncellenmediyse, hata mesajı döndür
-	if result.MatchedCount == 0 {
-		http.Error(w, "Şirket bulunamadı", http.StatusNotFound)
+		writeError(w, err, "Şirket güncellenemedi")
 		return
 	}
 
@@ -163,7 +120,7 @@ func UpdateCompanyByName(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Şirket başarıyla güncellendi"})
 }
 
-func GetCompanyByID(w http.ResponseWriter, r *http.Request) {
+func (h *CompanyHandler) GetCompanyByID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// URL'den `companyID` parametresini al
@@ -173,6 +130,11 @@ func GetCompanyByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
 	// `companyID` string'ini `ObjectID`'ye çevir
 	objID, err := primitive.ObjectIDFromHex(companyID)
 	if err != nil {
@@ -181,17 +143,12 @@ func GetCompanyByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Şirket bilgilerini veritabanından çek
-	var company models.Company
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	err = companyCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&company)
+	company, err := h.companies.FindByID(ctx, actor, objID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			http.Error(w, "Company not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to fetch company information", http.StatusInternalServerError)
+		writeError(w, err, "Failed to fetch company information")
 		return
 	}
 
@@ -200,24 +157,105 @@ func GetCompanyByID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(company)
 }
 
-func GetAllCompanies(w http.ResponseWriter, r *http.Request) {
+// SetBookingPolicy şirketin müşteri iptal ve erteleme politikasını ayarlar
+func (h *CompanyHandler) SetBookingPolicy(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var companies []models.Company
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	companyID := r.URL.Query().Get("companyID")
+	if companyID == "" {
+		http.Error(w, "Company ID is required", http.StatusBadRequest)
+		return
+	}
+
+	objID, err := primitive.ObjectIDFromHex(companyID)
+	if err != nil {
+		http.Error(w, "Invalid Company ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		MinNoticeHours  int  `json:"minNoticeHours"`
+		MaxReschedules  int  `json:"maxReschedules"`
+		BlockLateCancel bool `json:"blockLateCancel"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	// Veritabanından tüm şirketleri çek
-	cursor, err := companyCollection.Find(ctx, bson.M{})
+	err = h.companies.SetBookingPolicy(ctx, actor, objID, models.BookingPolicy{
+		MinNoticeHours:  req.MinNoticeHours,
+		MaxReschedules:  req.MaxReschedules,
+		BlockLateCancel: req.BlockLateCancel,
+	})
 	if err != nil {
-		http.Error(w, "Veri çekme hatası", http.StatusInternalServerError)
+		writeError(w, err, "Failed to update booking policy")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Booking policy updated"})
+}
+
+// SetTimeZone şirketin IANA saat dilimini ayarlar; randevuların yerel
+// gösterimi de yeni dilime geçer
+func (h *CompanyHandler) SetTimeZone(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	companyID := r.URL.Query().Get("companyID")
+	if companyID == "" {
+		http.Error(w, "Company ID is required", http.StatusBadRequest)
+		return
+	}
+
+	objID, err := primitive.ObjectIDFromHex(companyID)
+	if err != nil {
+		http.Error(w, "Invalid Company ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		TimeZone string `json:"timeZone"` // Örneğin: "Europe/Istanbul"
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	updated, err := h.companies.SetTimeZone(ctx, actor, objID, req.TimeZone)
+	if err != nil {
+		writeError(w, err, "Failed to update time zone")
 		return
 	}
-	defer cursor.Close(ctx)
 
-	// Cursor'u slice'a dekode et
-	if err = cursor.All(ctx, &companies); err != nil {
-		http.Error(w, "Veri çözümleme hatası", http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Time zone updated", "appointments": updated})
+}
+
+func (h *CompanyHandler) GetAllCompanies(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	// Veritabanından tüm şirketleri çek
+	companies, err := h.companies.List(ctx)
+	if err != nil {
+		http.Error(w, "Veri çekme hatası", http.StatusInternalServerError)
 		return
 	}
 
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"rtsback/config"
+	"rtsback/internal/middlewares"
+	"rtsback/internal/services"
+	"rtsback/pkg/utils"
+)
+
+// Deps handler'ların paylaştığı bağımlılıklardır. main içinde bir kez
+// oluşturulur ve her handler yapısına constructor ile verilir. İş kuralları
+// servislerdedir; handler'lar isteği çözüp servisi çağırır ve sonucu yazar.
+type Deps struct {
+	queryTimeout time.Duration
+
+	auth          *services.AuthService
+	users         *services.UserService
+	admins        *services.AdminService
+	managers      *services.ManagerService
+	providers     *services.ProviderService
+	companies     *services.CompanyService
+	appointments  *services.AppointmentService
+	verifications *services.VerificationService
+	catalog       *services.CatalogService
+	resources     *services.ResourceService
+
+	accountLimiter middlewares.AttemptLimiter
+	ipLimiter      middlewares.AttemptLimiter
+}
+
+// NewDeps handler'ları verilen servisler üzerine kurar. Deneme sayaçları
+// bellek içi başlar; paylaşılan sayaç gerekiyorsa main SetAttemptLimiters
+// ile değiştirir.
+func NewDeps(svc *services.Services, cfg config.Config) *Deps {
+	return &Deps{
+		queryTimeout:   cfg.Database.QueryTimeout,
+		auth:           svc.Auth,
+		users:          svc.Users,
+		admins:         svc.Admins,
+		managers:       svc.Managers,
+		providers:      svc.Providers,
+		companies:      svc.Companies,
+		appointments:   svc.Appointments,
+		verifications:  svc.Verifications,
+		catalog:        svc.Catalog,
+		resources:      svc.Resources,
+		accountLimiter: middlewares.NewMemoryLimiter(middlewares.DefaultAccountPolicy),
+		ipLimiter:      middlewares.NewMemoryLimiter(middlewares.DefaultIPPolicy),
+	}
+}
+
+// SetAttemptLimiters hesap ve IP sayaçlarını değiştirir, örneğin birden
+// fazla sunucunun aynı sayaçları paylaşması için Mongo sayaçlarıyla
+func (h *Deps) SetAttemptLimiters(account, ip middlewares.AttemptLimiter) {
+	h.accountLimiter = account
+	h.ipLimiter = ip
+}
+
+// IsSessionRevoked JWT middleware'inin her korumalı istekte çağırdığı
+// oturum iptal kontrolüdür
+func (h *Deps) IsSessionRevoked(ctx context.Context, claims *utils.Claims) (bool, error) {
+	return h.auth.IsSessionRevoked(ctx, claims)
+}
+
+// Her dosyadaki uç noktalar kendi handler yapısında toplanır; hepsi
+// paylaşılan bağımlılıkları gömer.
+
+type UserHandler struct{ *Deps }
+
+func NewUserHandler(d *Deps) *UserHandler { return &UserHandler{d} }
+
+type AdminHandler struct{ *Deps }
+
+func NewAdminHandler(d *Deps) *AdminHandler { return &AdminHandler{d} }
+
+type ManagerHandler struct{ *Deps }
+
+func NewManagerHandler(d *Deps) *ManagerHandler { return &ManagerHandler{d} }
+
+type ProviderHandler struct{ *Deps }
+
+func NewProviderHandler(d *Deps) *ProviderHandler { return &ProviderHandler{d} }
+
+type CompanyHandler struct{ *Deps }
+
+func NewCompanyHandler(d *Deps) *CompanyHandler { return &CompanyHandler{d} }
+
+type AppointmentHandler struct{ *Deps }
+
+func NewAppointmentHandler(d *Deps) *AppointmentHandler { return &AppointmentHandler{d} }
+
+type VerificationHandler struct{ *Deps }
+
+func NewVerificationHandler(d *Deps) *VerificationHandler { return &VerificationHandler{d} }
+
+// CatalogHandler şirket hizmet kataloğu ve sağlayıcıların sunduğu hizmetlerdir
+type CatalogHandler struct{ *Deps }
+
+func NewCatalogHandler(d *Deps) *CatalogHandler { return &CatalogHandler{d} }
+
+// ResourceHandler şirketlerin oda ve cihaz gibi kaynaklarıdır
+type ResourceHandler struct{ *Deps }
+
+func NewResourceHandler(d *Deps) *ResourceHandler { return &ResourceHandler{d} }
+
+// AuthHandler oturum, şifre, iki adımlı doğrulama ve hesap kilidi uç noktalarıdır
+type AuthHandler struct{ *Deps }
+
+func NewAuthHandler(d *Deps) *AuthHandler { return &AuthHandler{d} }
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"rtsback/internal/services"
+)
+
+// statusOf servis hatası türünün HTTP durum kodudur
+func statusOf(kind services.Kind) int {
+	switch kind {
+	case services.KindValidation:
+		return http.StatusBadRequest
+	case services.KindUnauthorized:
+		return http.StatusUnauthorized
+	case services.KindForbidden:
+		return http.StatusForbidden
+	case services.KindNotFound:
+		return http.StatusNotFound
+	case services.KindConflict:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError servis hatasını durum koduyla yazar. Hata kaydın güncel
+// halini taşıyorsa yanıt JSON olarak {"error", "current"} döner. Servis
+// hatası olmayan (veritabanı vb.) hatalar loglanır ve istemciye fallback
+// mesajıyla 500 olarak döner.
+func writeError(w http.ResponseWriter, err error, fallback string) {
+	var e *services.Error
+	if errors.As(err, &e) {
+		if e.Current != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(statusOf(e.Kind))
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": e.Message, "current": e.Current})
+			return
+		}
+		http.Error(w, e.Message, statusOf(e.Kind))
+		return
+	}
+	log.Printf("%s: %v", fallback, err)
+	http.Error(w, fallback, http.StatusInternalServerError)
+}
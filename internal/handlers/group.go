@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"rtsback/internal/services"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateGroupSession sağlayıcının takvimine birden fazla müşterinin
+// katılabileceği bir grup seansı ekler. Sağlayıcı kendi adına ekler;
+// yöneticiler providerEmail verir.
+func (h *AppointmentHandler) CreateGroupSession(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		ProviderEmail string   `json:"providerEmail"`
+		Title         string   `json:"title"`
+		Start         string   `json:"start"` // RFC3339
+		End           string   `json:"end"`   // RFC3339
+		Capacity      int      `json:"capacity"`
+		Services      []string `json:"services"`
+		ServiceIDs    []string `json:"serviceIDs"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	req.ProviderEmail = principalEmailOr(r, utils.RoleProvider, req.ProviderEmail)
+	if req.ProviderEmail == "" {
+		http.Error(w, "Provider Email is required", http.StatusBadRequest)
+		return
+	}
+	start, err := time.Parse(time.RFC3339, req.Start)
+	if err != nil {
+		http.Error(w, "Invalid start time, expected RFC3339", http.StatusBadRequest)
+		return
+	}
+	end, err := time.Parse(time.RFC3339, req.End)
+	if err != nil {
+		http.Error(w, "Invalid end time, expected RFC3339", http.StatusBadRequest)
+		return
+	}
+	serviceIDs, err := parseObjectIDs(req.ServiceIDs)
+	if err != nil {
+		http.Error(w, "Invalid service ID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	session, err := h.appointments.CreateGroupSession(ctx, actor, services.GroupSession{
+		ProviderEmail: req.ProviderEmail,
+		Title:         req.Title,
+		Start:         start,
+		End:           end,
+		Capacity:      req.Capacity,
+		Services:      req.Services,
+		ServiceIDs:    serviceIDs,
+	})
+	if err != nil {
+		writeError(w, err, "Failed to create group session")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+// GetGroupSessions sağlayıcının verilen gündeki boş yeri olan grup
+// seanslarını döner; katılımcılar gösterilmez
+func (h *AppointmentHandler) GetGroupSessions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	providerEmail := r.URL.Query().Get("providerEmail")
+	if providerEmail == "" {
+		http.Error(w, "Provider Email is required", http.StatusBadRequest)
+		return
+	}
+	day, err := parseDay(r.URL.Query().Get("date"))
+	if err != nil {
+		http.Error(w, "Invalid date format, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	sessions, err := h.appointments.GroupSessions(ctx, providerEmail, day)
+	if err != nil {
+		writeError(w, err, "Failed to fetch group sessions")
+		return
+	}
+
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// JoinGroupSession grup seansında yer ayırır. Müşteriler kendi adlarına,
+// sağlayıcı ve yöneticiler customer_email ile müşteri adına katılır.
+// Seans dolmuşsa 409 döner.
+func (h *AppointmentHandler) JoinGroupSession(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid Appointment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		CustomerEmail string `json:"customerEmail"`
+		CustomerName  string `json:"customerName"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	session, err := h.appointments.JoinGroup(ctx, actor, objID, services.GroupJoin{
+		CustomerEmail: req.CustomerEmail,
+		CustomerName:  req.CustomerName,
+	})
+	if err != nil {
+		writeError(w, err, "Failed to join group session")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+// LeaveGroupSession müşterinin grup seansındaki yerini iptal eder; yer
+// tekrar müsait olur ve sağlayıcıya e-posta gider
+func (h *AppointmentHandler) LeaveGroupSession(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid Appointment ID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	session, err := h.appointments.LeaveGroup(ctx, actor, objID)
+	if err != nil {
+		writeError(w, err, "Failed to leave group session")
+		return
+	}
+
+	json.NewEncoder(w).Encode(session)
+}
+
+// GetAttendees grup seansının katılımcılarını durumlarıyla döner
+func (h *AppointmentHandler) GetAttendees(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid Appointment ID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	attendees, err := h.appointments.Attendees(ctx, actor, objID)
+	if err != nil {
+		writeError(w, err, "Failed to fetch attendees")
+		return
+	}
+
+	json.NewEncoder(w).Encode(attendees)
+}
+
+// MarkAttendance katılımcıyı seansa geldi (attended) ya da gelmedi
+// (no_show) olarak işaretler
+func (h *AppointmentHandler) MarkAttendance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid Appointment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Email  string `json:"email"`
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.Email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	session, err := h.appointments.MarkAttendance(ctx, actor, objID, req.Email, req.Status)
+	if err != nil {
+		writeError(w, err, "Failed to mark attendance")
+		return
+	}
+
+	json.NewEncoder(w).Encode(session)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"rtsback/config"
+	"rtsback/internal/middlewares"
+	"rtsback/internal/repositories"
+	"rtsback/internal/services"
+)
+
+// discardMailer e-postaları göndermeden kabul eder
+type discardMailer struct{}
+
+func (discardMailer) Send(to, subject, body string) error { return nil }
+
+// newTestDeps handler bağımlılıklarını bellek repository'leri üzerine kurar
+func newTestDeps(t *testing.T) (*Deps, *repositories.Repositories) {
+	t.Helper()
+	repos := repositories.NewMemory()
+	cfg := config.Config{}
+	cfg.Database.QueryTimeout = time.Second
+	return NewDeps(services.New(repos, cfg, discardMailer{}), cfg), repos
+}
+
+// serve handler'ı verilen kimlikle ve JSON gövdeyle çağırır
+func serve(t *testing.T, handler http.HandlerFunc, principal *middlewares.Principal, method string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := httptest.NewRequest(method, "/", &buf)
+	if principal != nil {
+		r = r.WithContext(middlewares.WithPrincipal(r.Context(), *principal))
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"rtsback/internal/middlewares"
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+)
+
+// attemptGuard tek bir istekteki hesap ve IP sayaç anahtarlarını tutar
+type attemptGuard struct {
+	deps       *Deps
+	scope      string
+	subject    string
+	ip         string
+	subjectKey string
+	ipKey      string
+}
+
+// newAttemptGuard scope (login, verify) ve hesap anahtarı için koruma oluşturur
+func (h *Deps) newAttemptGuard(r *http.Request, scope, subject string) attemptGuard {
+	subject = strings.ToLower(strings.TrimSpace(subject))
+	ip := middlewares.ClientIP(r)
+	return attemptGuard{
+		deps:       h,
+		scope:      scope,
+		subject:    subject,
+		ip:         ip,
+		subjectKey: scope + ":account:" + subject,
+		ipKey:      scope + ":ip:" + ip,
+	}
+}
+
+// allow hesap veya IP kilitliyse ya da bekleme süresi dolmadıysa 429 yazar
+func (g attemptGuard) allow(ctx context.Context, w http.ResponseWriter) bool {
+	wait := time.Duration(0)
+	for _, check := range []struct {
+		limiter middlewares.AttemptLimiter
+		key     string
+	}{{g.deps.accountLimiter, g.subjectKey}, {g.deps.ipLimiter, g.ipKey}} {
+		retryAfter, err := check.limiter.Check(ctx, check.key)
+		if err != nil {
+			http.Error(w, "Failed to check login attempts", http.StatusInternalServerError)
+			return false
+		}
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+	}
+
+	if wait > 0 {
+		middlewares.WriteTooManyAttempts(w, wait)
+		return false
+	}
+	return true
+}
+
+// fail başarısız denemeyi kaydeder ve kilitlenme olursa olay kaydı açar
+func (g attemptGuard) fail(ctx context.Context) {
+	if locked, err := g.deps.accountLimiter.Fail(ctx, g.subjectKey); err != nil {
+		log.Printf("Başarısız deneme kaydedilemedi: %v", err)
+	} else if locked {
+		g.recordLockout(ctx, g.subjectKey)
+	}
+
+	if locked, err := g.deps.ipLimiter.Fail(ctx, g.ipKey); err != nil {
+		log.Printf("Başarısız deneme kaydedilemedi: %v", err)
+	} else if locked {
+		g.recordLockout(ctx, g.ipKey)
+	}
+}
+
+// succeed başarılı denemeden sonra hesabın sayacını sıfırlar
+func (g attemptGuard) succeed(ctx context.Context) {
+	if err := g.deps.accountLimiter.Reset(ctx, g.subjectKey); err != nil {
+		log.Printf("Deneme sayacı sıfırlanamadı: %v", err)
+	}
+}
+
+func (g attemptGuard) recordLockout(ctx context.Context, key string) {
+	event := models.SecurityEvent{
+		Type:  models.SecurityEventLockout,
+		Scope: g.scope,
+		Key:   key,
+		Email: g.subject,
+		IP:    g.ip,
+	}
+	if err := g.deps.auth.RecordSecurityEvent(ctx, event); err != nil {
+		log.Printf("Kilitleme olayı kaydedilemedi: %v", err)
+	}
+	log.Printf("Hesap kilitlendi: %s (%s)", key, g.ip)
+}
+
+// UnlockAccount bir hesabın giriş kilidini kaldırır. Hesap için kaydedilen
+// kilitleme olaylarındaki IP kilitleri de kaldırılır; aksi halde kullanıcı
+// aynı ağdan yine giriş yapamaz. Admin yalnızca kendi şirketindeki
+// hesapları açabilir.
+func (h *AuthHandler) UnlockAccount(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	email := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("email")))
+	if email == "" {
+		http.Error(w, "Email parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	if err := h.auth.AuthorizeUnlock(ctx, actor, email); err != nil {
+		writeError(w, err, "Failed to unlock account")
+		return
+	}
+
+	if err := h.accountLimiter.Reset(ctx, "login:account:"+email); err != nil {
+		http.Error(w, "Failed to unlock account", http.StatusInternalServerError)
+		return
+	}
+	lockouts, err := h.auth.SecurityEvents(ctx, repositories.SecurityEventFilter{
+		Email: email,
+		Type:  models.SecurityEventLockout,
+	}, 200)
+	if err != nil {
+		http.Error(w, "Failed to unlock account", http.StatusInternalServerError)
+		return
+	}
+	cleared := map[string]bool{}
+	for _, e := range lockouts {
+		if !strings.HasPrefix(e.Key, "login:ip:") || cleared[e.Key] {
+			continue
+		}
+		cleared[e.Key] = true
+		if err := h.ipLimiter.Reset(ctx, e.Key); err != nil {
+			http.Error(w, "Failed to unlock account", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	event := models.SecurityEvent{
+		Type:    models.SecurityEventUnlock,
+		Scope:   "login",
+		Key:     "login:account:" + email,
+		Email:   email,
+		IP:      middlewares.ClientIP(r),
+		ActorID: actor.ID,
+	}
+	if err := h.auth.RecordSecurityEvent(ctx, event); err != nil {
+		log.Printf("Kilit açma olayı kaydedilemedi: %v", err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Account unlocked successfully"})
+}
+
+// GetSecurityEvents kilitleme olaylarını en yeniden eskiye listeler
+func (h *AuthHandler) GetSecurityEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filter := repositories.SecurityEventFilter{
+		Email: strings.ToLower(r.URL.Query().Get("email")),
+		Type:  r.URL.Query().Get("type"),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	events, err := h.auth.SecurityEvents(ctx, filter, 200)
+	if err != nil {
+		http.Error(w, "Failed to fetch security events", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(events)
+}
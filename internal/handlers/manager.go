@@ -4,60 +4,46 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"time"
 
-	"rtsback/config"
 	"rtsback/internal/models"
-
-	"github.com/dgrijalva/jwt-go"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"golang.org/x/crypto/bcrypt"
+	"rtsback/pkg/utils"
 )
 
-var managerCollection *mongo.Collection
-
-func init() {
-	client := config.ConnectDB()
-	managerCollection = config.GetCollection(client, "manager")
-}
-func AddManager(w http.ResponseWriter, r *http.Request) {
+func (h *ManagerHandler) AddManager(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var manager models.Manager
-	err := json.NewDecoder(r.Body).Decode(&manager)
+	// Şifre hash alanı yanıtlara yazılmadığı için ayrı okunur
+	var req struct {
+		models.Manager
+		Password string `json:"password"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, "Invalid data format", http.StatusBadRequest)
 		return
 	}
+	manager := req.Manager
+	manager.Password = req.Password
 
-	// Hash the password before storing
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(manager.Password), bcrypt.DefaultCost)
-	if err != nil {
-		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+	actor, ok := requireActor(w, r)
+	if !ok {
 		return
 	}
-	manager.Password = string(hashedPassword)
 
-	// Assign a new ObjectID and timestamps
-	manager.ID = primitive.NewObjectID()
-	manager.CreatedAt = time.Now()
-	manager.UpdatedAt = time.Now()
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	_, err = managerCollection.InsertOne(ctx, manager)
+	// Only allow adding managers to the caller's own company
+	_, err = h.managers.Create(ctx, actor, manager)
 	if err != nil {
-		http.Error(w, "Failed to add manager to the database", http.StatusInternalServerError)
+		writeError(w, err, "Failed to add manager to the database")
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Manager added successfully"})
 }
-func ManagerLogin(w http.ResponseWriter, r *http.Request) {
+func (h *ManagerHandler) ManagerLogin(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var creds struct {
@@ -71,44 +57,25 @@ func ManagerLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var manager models.Manager
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	// Email ile manager'ı bul
-	err = managerCollection.FindOne(ctx, bson.M{"email": creds.Email}).Decode(&manager)
-	if err != nil {
-		http.Error(w, "Manager not found", http.StatusUnauthorized)
-		return
-	}
-
-	// Şifreyi doğrula
-	err = bcrypt.CompareHashAndPassword([]byte(manager.Password), []byte(creds.Password))
-	if err != nil {
-		http.Error(w, "Invalid password", http.StatusUnauthorized)
+	account, ok := h.login(ctx, w, r, utils.RoleManager, creds.Email, creds.Password)
+	if !ok {
 		return
 	}
 
 	// JWT token oluşturma
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &jwt.StandardClaims{
-		ExpiresAt: expirationTime.Unix(),
-		Subject:   manager.ID.Hex(),
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte("manager_secret_key"))
-	if err != nil {
-		http.Error(w, "Token oluşturulamadı", http.StatusInternalServerError)
+	sess, ok := h.issueSession(ctx, w, account.Actor)
+	if !ok {
 		return
 	}
 
-	managerID := manager.ID.Hex()
 	// Başarılı yanıt ve token gönder
-	json.NewEncoder(w).Encode(map[string]string{"token": tokenString, "ID": managerID})
+	json.NewEncoder(w).Encode(map[string]string{"token": sess.AccessToken, "refreshToken": sess.RefreshToken, "ID": account.ID})
 }
 
-func GetManagersByCompanyId(w http.ResponseWriter, r *http.Request) {
+func (h *ManagerHandler) GetManagersByCompanyId(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// URL'den `companyID` parametresini al
@@ -118,22 +85,19 @@ func GetManagersByCompanyId(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
 	// Sağlayıcıları veritabanından çek
-	var managers []models.Manager
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
 	// `companyID`'yi string olarak kullanarak sorgu yapıyoruz
-	cursor, err := managerCollection.Find(ctx, bson.M{"company_id": companyID})
+	managers, err := h.managers.ListByCompany(ctx, actor, companyID)
 	if err != nil {
-		http.Error(w, "Failed to fetch providers", http.StatusInternalServerError)
-		return
-	}
-	defer cursor.Close(ctx)
-
-	// Cursor'u slice'a dekode et
-	if err = cursor.All(ctx, &managers); err != nil {
-		http.Error(w, "Failed to decode providers", http.StatusInternalServerError)
+		writeError(w, err, "Failed to fetch providers")
 		return
 	}
 
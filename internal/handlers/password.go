@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"rtsback/internal/middlewares"
+	"rtsback/internal/services"
+)
+
+// ForgotPassword şifre sıfırlama bağlantısı gönderir. Hesabın var olup
+// olmadığı yanıttan anlaşılmasın diye her durumda aynı mesaj döner.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	if err := h.auth.RequestPasswordReset(ctx, req.Email, req.Role); err != nil {
+		writeError(w, err, "Failed to send reset link")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "If the account exists, a reset link has been sent"})
+}
+
+// ResetPassword sıfırlama token'ını tüketir ve yeni şifreyi kaydeder
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Token and new password are required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	// Sıfırlama token'ı tahminine karşı denemeler IP bazında sınırlandırılır
+	guard := h.newAttemptGuard(r, "reset", middlewares.ClientIP(r))
+	if !guard.allow(ctx, w) {
+		return
+	}
+
+	reset, err := h.auth.ResetPassword(ctx, req.Token, req.NewPassword)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			guard.fail(ctx)
+		}
+		writeError(w, err, "Failed to update password")
+		return
+	}
+
+	// Şifre sıfırlandığında hesabın giriş kilidi de kalkar
+	if err := h.accountLimiter.Reset(ctx, "login:account:"+strings.ToLower(reset.Email)); err != nil {
+		log.Printf("Deneme sayacı sıfırlanamadı: %v", err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password reset successfully"})
+}
+
+// ChangePassword oturum açmış hesabın şifresini eski şifreyi doğrulayarak değiştirir
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		OldPassword string `json:"oldPassword"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.OldPassword == "" {
+		http.Error(w, "Old and new password are required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	guard := h.newAttemptGuard(r, "password", actor.ID)
+	if !guard.allow(ctx, w) {
+		return
+	}
+
+	err := h.auth.ChangePassword(ctx, actor, req.OldPassword, req.NewPassword)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPassword) {
+			guard.fail(ctx)
+		}
+		writeError(w, err, "Failed to update password")
+		return
+	}
+	guard.succeed(ctx)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password changed successfully, please log in again"})
+}
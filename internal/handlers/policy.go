@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+
+	"rtsback/internal/middlewares"
+	"rtsback/internal/services"
+)
+
+// Yetki kuralları servislerdedir (services.Actor). Handler'lar yalnızca
+// token'daki kimliği servislere aktarır.
+
+// actorOf token'dan çözülen kimliği servis kimliğine çevirir
+func actorOf(p middlewares.Principal) services.Actor {
+	return services.Actor{
+		ID:        p.ID,
+		Email:     p.Email,
+		Role:      p.Role,
+		CompanyID: p.CompanyID,
+	}
+}
+
+// requirePrincipal isteğin kimliğini döner, yoksa 401 yazar
+func requirePrincipal(w http.ResponseWriter, r *http.Request) (middlewares.Principal, bool) {
+	p, ok := middlewares.CurrentPrincipal(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return middlewares.Principal{}, false
+	}
+	return p, true
+}
+
+// requireActor isteğin kimliğini servis kimliği olarak döner, yoksa 401 yazar
+func requireActor(w http.ResponseWriter, r *http.Request) (services.Actor, bool) {
+	p, ok := requirePrincipal(w, r)
+	if !ok {
+		return services.Actor{}, false
+	}
+	return actorOf(p), true
+}
+
+// principalEmailOr çağıran selfRole rolündeyse token'daki e-postayı,
+// değilse istekten gelen değeri döner. Böylece sağlayıcılar başka bir
+// sağlayıcının e-postasını parametre olarak gönderemez.
+func principalEmailOr(r *http.Request, selfRole, fallback string) string {
+	if p, ok := middlewares.CurrentPrincipal(r); ok && p.Role == selfRole {
+		return p.Email
+	}
+	return fallback
+}
+
+// principalIDOr principalEmailOr ile aynı kuralı hesap ID'si için uygular
+func principalIDOr(r *http.Request, selfRole, fallback string) string {
+	if p, ok := middlewares.CurrentPrincipal(r); ok && p.Role == selfRole {
+		return p.ID
+	}
+	return fallback
+}
@@ -5,56 +5,42 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
-	"time"
 
-	"rtsback/config"
 	"rtsback/internal/models"
+	"rtsback/pkg/utils"
 
-	"github.com/dgrijalva/jwt-go"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"golang.org/x/crypto/bcrypt"
 )
 
-var providerCollection *mongo.Collection
-
-func init() {
-	client := config.ConnectDB()
-	providerCollection = config.GetCollection(client, "provider")
-}
-
 // AddProvider handles adding a new provider to the database
-func AddProvider(w http.ResponseWriter, r *http.Request) {
+func (h *ProviderHandler) AddProvider(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var provider models.Provider
-	err := json.NewDecoder(r.Body).Decode(&provider)
+	// Şifre hash alanı yanıtlara yazılmadığı için ayrı okunur
+	var req struct {
+		models.Provider
+		Password string `json:"password"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, "Invalid data format", http.StatusBadRequest)
 		return
 	}
+	provider := req.Provider
+	provider.Password = req.Password
 
-	// Hash the password before storing
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(provider.Password), bcrypt.DefaultCost)
-	if err != nil {
-		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+	actor, ok := requireActor(w, r)
+	if !ok {
 		return
 	}
-	provider.Password = string(hashedPassword)
-
-	// Assign a new ObjectID and timestamps
-	provider.ID = primitive.NewObjectID()
-	provider.CreatedAt = time.Now()
-	provider.UpdatedAt = time.Now()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	_, err = providerCollection.InsertOne(ctx, provider)
+	// Only allow adding providers to the caller's own company
+	_, err = h.providers.Create(ctx, actor, provider)
 	if err != nil {
-		http.Error(w, "Failed to add provider to the database", http.StatusInternalServerError)
+		writeError(w, err, "Failed to add provider to the database")
 		return
 	}
 
@@ -63,7 +49,7 @@ func AddProvider(w http.ResponseWriter, r *http.Request) {
 }
 
 // ProviderLogin handles provider login requests
-func ProviderLogin(w http.ResponseWriter, r *http.Request) {
+func (h *ProviderHandler) ProviderLogin(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var creds struct {
@@ -77,70 +63,39 @@ func ProviderLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var provider models.Provider
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	// Email ile provider'ı bul
-	err = providerCollection.FindOne(ctx, bson.M{"email": creds.Email}).Decode(&provider)
-	if err != nil {
-		http.Error(w, "Provider not found", http.StatusUnauthorized)
-		return
-	}
-
-	// Şifreyi doğrula
-	err = bcrypt.CompareHashAndPassword([]byte(provider.Password), []byte(creds.Password))
-	if err != nil {
-		http.Error(w, "Invalid password", http.StatusUnauthorized)
+	account, ok := h.login(ctx, w, r, utils.RoleProvider, creds.Email, creds.Password)
+	if !ok {
 		return
 	}
 
 	// JWT token oluşturma
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &jwt.StandardClaims{
-		ExpiresAt: expirationTime.Unix(),
-		Subject:   provider.ID.Hex(),
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte("provider_secret_key"))
-	if err != nil {
-		http.Error(w, "Token oluşturulamadı", http.StatusInternalServerError)
+	sess, ok := h.issueSession(ctx, w, account.Actor)
+	if !ok {
 		return
 	}
 
-	providerID := provider.ID.Hex()
-
 	// Başarılı yanıt ve token gönder
-	json.NewEncoder(w).Encode(map[string]string{"token": tokenString, "ID": providerID})
+	json.NewEncoder(w).Encode(map[string]string{"token": sess.AccessToken, "refreshToken": sess.RefreshToken, "ID": account.ID})
 }
 
-func GetProviders(w http.ResponseWriter, r *http.Request) {
+func (h *ProviderHandler) GetProviders(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	// Find all providers
-	cursor, err := providerCollection.Find(ctx, bson.M{})
-	if err != nil {
-		http.Error(w, "Failed to fetch providers", http.StatusInternalServerError)
+	actor, ok := requireActor(w, r)
+	if !ok {
 		return
 	}
-	defer cursor.Close(ctx)
 
-	var providers []models.Provider
-	for cursor.Next(ctx) {
-		var provider models.Provider
-		if err := cursor.Decode(&provider); err != nil {
-			http.Error(w, "Failed to decode provider", http.StatusInternalServerError)
-			return
-		}
-		providers = append(providers, provider)
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
 
-	if err := cursor.Err(); err != nil {
-		http.Error(w, "Cursor error", http.StatusInternalServerError)
+	// Superuser sees all providers, everyone else only their own company
+	providers, err := h.providers.List(ctx, actor)
+	if err != nil {
+		writeError(w, err, "Failed to fetch providers")
 		return
 	}
 
@@ -151,26 +106,26 @@ func GetProviders(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func GetProviderByEmail(w http.ResponseWriter, r *http.Request) {
+func (h *ProviderHandler) GetProviderByEmail(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	email := r.URL.Query().Get("email")
+	email := principalEmailOr(r, utils.RoleProvider, r.URL.Query().Get("email"))
 	if email == "" {
 		http.Error(w, "Email parameter is required", http.StatusBadRequest)
 		return
 	}
 
-	var provider models.Provider
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	err := providerCollection.FindOne(ctx, bson.M{"email": email}).Decode(&provider)
+	provider, err := h.providers.FindByEmail(ctx, actor, email)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			http.Error(w, "Provider not found", http.StatusNotFound)
-		} else {
-			http.Error(w, "Failed to fetch provider", http.StatusInternalServerError)
-		}
+		writeError(w, err, "Failed to fetch provider")
 		return
 	}
 
@@ -178,24 +133,28 @@ func GetProviderByEmail(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(provider)
 }
 
-func GetCompanyNameByProviderEmail(w http.ResponseWriter, r *http.Request) {
+func (h *ProviderHandler) GetCompanyNameByProviderEmail(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// Query parametrelerinden e-posta değerini al
-	email := r.URL.Query().Get("email")
+	// Sağlayıcı için e-posta token'dan, diğer roller için parametreden alınır
+	email := principalEmailOr(r, utils.RoleProvider, r.URL.Query().Get("email"))
 	if email == "" {
 		http.Error(w, "Email parameter is missing", http.StatusBadRequest)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
 	// Sağlayıcıyı email üzerinden bul
-	var provider models.Provider
-	err := providerCollection.FindOne(ctx, bson.M{"email": email}).Decode(&provider)
+	provider, err := h.providers.FindByEmail(ctx, actor, email)
 	if err != nil {
-		http.Error(w, "Provider not found", http.StatusNotFound)
+		writeError(w, err, "Failed to fetch provider")
 		return
 	}
 
@@ -203,35 +162,28 @@ func GetCompanyNameByProviderEmail(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(provider)
 }
 
-func GetAppointmentsByProviderEmail(w http.ResponseWriter, r *http.Request) {
+func (h *ProviderHandler) GetAppointmentsByProviderEmail(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// Query parametresinden email'i al
-	email := r.URL.Query().Get("email")
+	// Sağlayıcı için e-posta token'dan, diğer roller için parametreden alınır
+	email := principalEmailOr(r, utils.RoleProvider, r.URL.Query().Get("email"))
 	if email == "" {
 		http.Error(w, "Email parameter is required", http.StatusBadRequest)
 		return
 	}
 
-	// MongoDB query için context oluştur
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	// Email'e göre filtreleme yap
-	filter := bson.M{"provider_email": email}
-
-	// Randevuları filtreye göre bul
-	cursor, err := appointmentCollection.Find(ctx, filter, options.Find())
-	if err != nil {
-		http.Error(w, "Failed to fetch appointments", http.StatusInternalServerError)
+	actor, ok := requireActor(w, r)
+	if !ok {
 		return
 	}
-	defer cursor.Close(ctx)
 
-	// Randevuları bir slice'a decode et
-	var appointments []models.Appointment
-	if err = cursor.All(ctx, &appointments); err != nil {
-		http.Error(w, "Failed to decode appointments", http.StatusInternalServerError)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	// Randevuları email'e göre bul
+	appointments, err := h.appointments.ListForProvider(ctx, actor, email)
+	if err != nil {
+		writeError(w, err, "Failed to fetch appointments")
 		return
 	}
 
@@ -240,7 +192,7 @@ func GetAppointmentsByProviderEmail(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(appointments)
 }
 
-func GetProvidersByCompanyId(w http.ResponseWriter, r *http.Request) {
+func (h *ProviderHandler) GetProvidersByCompanyId(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// URL'den `companyID` parametresini al
@@ -251,34 +203,26 @@ func GetProvidersByCompanyId(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Sağlayıcıları veritabanından çek
-	var providers []models.Provider
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
 	// `companyID`'yi string olarak kullanarak sorgu yapıyoruz
-	cursor, err := providerCollection.Find(ctx, bson.M{"company_id": companyID})
+	providers, err := h.providers.ListByCompany(ctx, companyID)
 	if err != nil {
 		http.Error(w, "Failed to fetch providers", http.StatusInternalServerError)
 		return
 	}
-	defer cursor.Close(ctx)
-
-	// Cursor'u slice'a dekode et
-	if err = cursor.All(ctx, &providers); err != nil {
-		http.Error(w, "Failed to decode providers", http.StatusInternalServerError)
-		return
-	}
 
 	// Sağlayıcıları JSON formatında döndür
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(providers)
 }
 
-func AddServiceToProvider(w http.ResponseWriter, r *http.Request) {
+func (h *ProviderHandler) AddServiceToProvider(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// `providerID` parametresini al
-	providerID := r.URL.Query().Get("providerID")
+	// `providerID` sağlayıcı için token'dan, admin için parametreden alınır
+	providerID := principalIDOr(r, utils.RoleProvider, r.URL.Query().Get("providerID"))
 	if providerID == "" {
 		http.Error(w, "Provider ID is required", http.StatusBadRequest)
 		return
@@ -304,41 +248,18 @@ func AddServiceToProvider(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	// Mevcut sağlayıcıyı veritabanından bul
-	var provider models.Provider
-	err = providerCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&provider)
-	if err != nil {
-		http.Error(w, "Provider not found", http.StatusNotFound)
+	actor, ok := requireActor(w, r)
+	if !ok {
 		return
 	}
 
-	// Yeni hizmetleri mevcut hizmetler ile birleştir
-	existingServices := provider.Services
-	for _, newService := range serviceReq.Services {
-		if !contains(existingServices, newService) { // Eğer mevcut değilse ekle
-			existingServices = append(existingServices, newService)
-		}
-	}
-
-	// Güncellemeyi MongoDB'ye gönder
-	update := bson.M{
-		"$set": bson.M{
-			"services":   existingServices,
-			"updated_at": time.Now(),
-		},
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
 
-	result, err := providerCollection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	// Yeni hizmetler mevcut hizmetler ile birleştirilir
+	err = h.providers.AddServices(ctx, actor, objID, serviceReq.Services)
 	if err != nil {
-		http.Error(w, "Failed to update provider services", http.StatusInternalServerError)
-		return
-	}
-
-	if result.MatchedCount == 0 {
-		http.Error(w, "Provider not found", http.StatusNotFound)
+		writeError(w, err, "Failed to update provider services")
 		return
 	}
 
@@ -346,11 +267,11 @@ func AddServiceToProvider(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Service added successfully to the provider"})
 }
 
-func GetServicesOfProvider(w http.ResponseWriter, r *http.Request) {
+func (h *ProviderHandler) GetServicesOfProvider(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// URL'den `providerID` parametresini al
-	providerID := r.URL.Query().Get("providerID")
+	// `providerID` sağlayıcı için token'dan, admin için parametreden alınır
+	providerID := principalIDOr(r, utils.RoleProvider, r.URL.Query().Get("providerID"))
 	if providerID == "" {
 		http.Error(w, "Provider ID is required", http.StatusBadRequest)
 		return
@@ -363,14 +284,18 @@ func GetServicesOfProvider(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
 	// Veritabanından provider'ı çek
-	var provider models.Provider
-	err = providerCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&provider)
+	provider, err := h.providers.FindByID(ctx, actor, objID)
 	if err != nil {
-		http.Error(w, "Provider not found", http.StatusNotFound)
+		writeError(w, err, "Failed to fetch provider")
 		return
 	}
 
@@ -393,15 +318,16 @@ func GetServicesOfProvider(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(servicesWithIndex)
 }
 
-func RemoveServiceFromProvider(w http.ResponseWriter, r *http.Request) {
+func (h *ProviderHandler) RemoveServiceFromProvider(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// URL'den `providerID` ve `index` parametrelerini al
-	providerID := r.URL.Query().Get("providerID")
+	// URL'den `providerID` ile `service` (ya da eski istemciler için `index`) parametrelerini al
+	providerID := principalIDOr(r, utils.RoleProvider, r.URL.Query().Get("providerID"))
+	serviceName := r.URL.Query().Get("service")
 	indexStr := r.URL.Query().Get("index")
 
-	if providerID == "" || indexStr == "" {
-		http.Error(w, "Provider ID and index are required", http.StatusBadRequest)
+	if providerID == "" || (serviceName == "" && indexStr == "") {
+		http.Error(w, "Provider ID and service name are required", http.StatusBadRequest)
 		return
 	}
 
@@ -412,44 +338,27 @@ func RemoveServiceFromProvider(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Index'i integer olarak parse et
-	index, err := strconv.Atoi(indexStr)
-	if err != nil || index < 0 {
-		http.Error(w, "Invalid index", http.StatusBadRequest)
+	actor, ok := requireActor(w, r)
+	if !ok {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	// Provider'ı veritabanından bul
-	var provider struct {
-		Services []string `bson:"services"`
-	}
-
-	err = providerCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&provider)
-	if err != nil {
-		http.Error(w, "Provider not found", http.StatusNotFound)
-		return
-	}
-
-	// Eğer index geçersizse, hata döndür
-	if index >= len(provider.Services) {
-		http.Error(w, "Index out of range", http.StatusBadRequest)
-		return
-	}
-
-	// Belirtilen indexteki öğeyi sil
-	provider.Services = append(provider.Services[:index], provider.Services[index+1:]...)
-
-	// Güncellenmiş services dizisini veritabanına kaydet
-	update := bson.M{
-		"$set": bson.M{"services": provider.Services},
+	// Hizmet adıyla silinir; index yalnızca eski istemciler için desteklenir
+	if serviceName != "" {
+		err = h.providers.RemoveServiceByName(ctx, actor, objID, serviceName)
+	} else {
+		index, convErr := strconv.Atoi(indexStr)
+		if convErr != nil || index < 0 {
+			http.Error(w, "Invalid index", http.StatusBadRequest)
+			return
+		}
+		err = h.providers.RemoveService(ctx, actor, objID, index)
 	}
-
-	_, err = providerCollection.UpdateOne(ctx, bson.M{"_id": objID}, update)
 	if err != nil {
-		http.Error(w, "Failed to update provider services", http.StatusInternalServerError)
+		writeError(w, err, "Failed to update provider services")
 		return
 	}
 
@@ -457,40 +366,25 @@ func RemoveServiceFromProvider(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Service removed successfully"})
 }
 
-func GetProviderEmailsByCompanyID(w http.ResponseWriter, r *http.Request) {
+func (h *ProviderHandler) GetProviderEmailsByCompanyID(w http.ResponseWriter, r *http.Request) {
 	companyID := r.URL.Query().Get("companyID")
 	if companyID == "" {
 		http.Error(w, "CompanyID is required", http.StatusBadRequest)
 		return
 	}
 
-	// Sadece email alanını almak için projection kullan
-	projection := bson.D{{Key: "email", Value: 1}}
-
-	// Filter oluştur
-	filter := bson.M{"company_id": companyID}
-
-	cursor, err := providerCollection.Find(context.TODO(), filter, options.Find().SetProjection(projection))
-	if err != nil {
-		http.Error(w, "Failed to fetch providers", http.StatusInternalServerError)
+	actor, ok := requireActor(w, r)
+	if !ok {
 		return
 	}
-	defer cursor.Close(context.TODO())
 
-	var emails []string
-	for cursor.Next(context.TODO()) {
-		var provider struct {
-			Email string `bson:"email"`
-		}
-		if err := cursor.Decode(&provider); err != nil {
-			http.Error(w, "Error decoding provider data", http.StatusInternalServerError)
-			return
-		}
-		emails = append(emails, provider.Email)
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
 
-	if err := cursor.Err(); err != nil {
-		http.Error(w, "Cursor error", http.StatusInternalServerError)
+	// Yalnızca email alanları döner
+	emails, err := h.providers.EmailsByCompany(ctx, actor, companyID)
+	if err != nil {
+		writeError(w, err, "Failed to fetch providers")
 		return
 	}
 
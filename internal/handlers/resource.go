@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"rtsback/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// resourcePayload kaynağın istek ve yanıt biçimidir. Hours boşsa kaynak
+// sağlayıcıların çalıştığı her saatte ayrılabilir.
+type resourcePayload struct {
+	ID          string                    `json:"id,omitempty"`
+	CompanyID   string                    `json:"companyID"`
+	Name        string                    `json:"name"`
+	Type        string                    `json:"type"` // Hizmetlerin resourceTypes alanındaki tür, örneğin "room"
+	Description string                    `json:"description"`
+	Active      *bool                     `json:"active"`
+	Hours       []workingHoursRulePayload `json:"hours"`
+}
+
+func (p resourcePayload) model() (models.Resource, error) {
+	hours, err := workingHoursPayload{Rules: p.Hours}.model()
+	if err != nil {
+		return models.Resource{}, err
+	}
+	resource := models.Resource{
+		CompanyID:   p.CompanyID,
+		Name:        p.Name,
+		Type:        p.Type,
+		Description: p.Description,
+		Active:      true,
+		Hours:       hours.Rules,
+	}
+	// active verilmezse kaynak etkin kabul edilir
+	if p.Active != nil {
+		resource.Active = *p.Active
+	}
+	return resource, nil
+}
+
+func resourceResponse(resource models.Resource) resourcePayload {
+	active := resource.Active
+	return resourcePayload{
+		ID:          resource.ID.Hex(),
+		CompanyID:   resource.CompanyID,
+		Name:        resource.Name,
+		Type:        resource.Type,
+		Description: resource.Description,
+		Active:      &active,
+		Hours:       workingHoursResponse(models.WorkingHours{Rules: resource.Hours}).Rules,
+	}
+}
+
+// reservationPayload kaynağın bir randevuya ayrıldığı aralıktır; zamanlar UTC'dir
+type reservationPayload struct {
+	AppointmentID string    `json:"appointmentID"`
+	ProviderEmail string    `json:"providerEmail"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+}
+
+// GetResources şirketin kaynaklarını listeler; type verilirse yalnızca o türdekiler
+func (h *ResourceHandler) GetResources(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	list, err := h.resources.List(ctx, actor, actor.DefaultCompanyID(r.URL.Query().Get("companyID")), r.URL.Query().Get("type"))
+	if err != nil {
+		writeError(w, err, "Failed to fetch resources")
+		return
+	}
+
+	out := make([]resourcePayload, 0, len(list))
+	for _, resource := range list {
+		out = append(out, resourceResponse(resource))
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
+// AddResource şirkete yeni bir kaynak ekler
+func (h *ResourceHandler) AddResource(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req resourcePayload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	resource, err := req.model()
+	if err != nil {
+		http.Error(w, "Invalid date format, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	resource, err = h.resources.Create(ctx, actor, resource)
+	if err != nil {
+		writeError(w, err, "Failed to create resource")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resourceResponse(resource))
+}
+
+// UpdateResource kaynağın bilgilerini değiştirir; var olan ayırmalar korunur
+func (h *ResourceHandler) UpdateResource(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid Resource ID", http.StatusBadRequest)
+		return
+	}
+
+	var req resourcePayload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	resource, err := req.model()
+	if err != nil {
+		http.Error(w, "Invalid date format, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	resource, err = h.resources.Update(ctx, actor, objID, resource)
+	if err != nil {
+		writeError(w, err, "Failed to update resource")
+		return
+	}
+
+	json.NewEncoder(w).Encode(resourceResponse(resource))
+}
+
+// DeleteResource kaynağı siler; ileri tarihli ayırması olan kaynak için 409 döner
+func (h *ResourceHandler) DeleteResource(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid Resource ID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	if err := h.resources.Delete(ctx, actor, objID); err != nil {
+		writeError(w, err, "Failed to delete resource")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Resource deleted"})
+}
+
+// GetResourceSchedule kaynağın from ile to (dahil, YYYY-MM-DD) arasındaki
+// ayırmalarını döner; günler şirketin saat dilimindedir
+func (h *ResourceHandler) GetResourceSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid Resource ID", http.StatusBadRequest)
+		return
+	}
+	from, err := parseDay(r.URL.Query().Get("from"))
+	if err != nil || from.IsZero() {
+		http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	to, err := parseDay(r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if to.IsZero() {
+		to = from
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	list, err := h.appointments.ResourceSchedule(ctx, actor, objID, from, to)
+	if err != nil {
+		writeError(w, err, "Failed to fetch resource schedule")
+		return
+	}
+
+	out := make([]reservationPayload, 0, len(list))
+	for _, reservation := range list {
+		out = append(out, reservationPayload{
+			AppointmentID: reservation.AppointmentID.Hex(),
+			ProviderEmail: reservation.ProviderEmail,
+			Start:         reservation.Start.UTC(),
+			End:           reservation.End.UTC(),
+		})
+	}
+	json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/services"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// seriesPayload tekrarlayan randevu serisinin yanıt biçimidir
+type seriesPayload struct {
+	ID            string     `json:"id"`
+	CustomerEmail string     `json:"customerEmail"`
+	CustomerName  string     `json:"customerName,omitempty"`
+	ProviderEmail string     `json:"providerEmail"`
+	CompanyID     string     `json:"companyID,omitempty"`
+	Rule          string     `json:"rule"`
+	Start         time.Time  `json:"start"`
+	LocalStart    string     `json:"localStart"`
+	TimeZone      string     `json:"timeZone"`
+	Services      []string   `json:"services,omitempty"`
+	Status        string     `json:"status"`
+	EndsBefore    *time.Time `json:"endsBefore,omitempty"`
+}
+
+// seriesConflictPayload serinin alınamayan ya da iptal edilemeyen tekrarıdır
+type seriesConflictPayload struct {
+	Occurrence int       `json:"occurrence"`
+	Start      time.Time `json:"start"`
+	Reason     string    `json:"reason"`
+}
+
+func seriesResponse(series models.AppointmentSeries) seriesPayload {
+	loc := models.Location(series.TimeZone)
+	p := seriesPayload{
+		ID:            series.ID.Hex(),
+		CustomerEmail: series.CustomerEmail,
+		CustomerName:  series.CustomerName,
+		ProviderEmail: series.ProviderEmail,
+		CompanyID:     series.CompanyID,
+		Rule:          series.Rule,
+		Start:         series.Start.UTC(),
+		LocalStart:    series.Start.In(loc).Format(time.RFC3339),
+		TimeZone:      loc.String(),
+		Services:      series.Services,
+		Status:        series.Status,
+	}
+	if !series.EndsBefore.IsZero() {
+		endsBefore := series.EndsBefore.UTC()
+		p.EndsBefore = &endsBefore
+	}
+	return p
+}
+
+func seriesConflictResponse(list []services.SeriesConflict) []seriesConflictPayload {
+	out := make([]seriesConflictPayload, 0, len(list))
+	for _, c := range list {
+		out = append(out, seriesConflictPayload{Occurrence: c.Occurrence, Start: c.Start.UTC(), Reason: c.Reason})
+	}
+	return out
+}
+
+// writeSeriesError servis hatasını yazar; çakışma alınamayan tekrarları
+// taşıyorsa onları yanıt biçimine çevirir
+func writeSeriesError(w http.ResponseWriter, err error, fallback string) {
+	var e *services.Error
+	if errors.As(err, &e) {
+		if conflicts, ok := e.Current.([]services.SeriesConflict); ok {
+			err = services.ConflictWith(e.Message, seriesConflictResponse(conflicts))
+		}
+	}
+	writeError(w, err, fallback)
+}
+
+// BookSeries tekrarlayan bir randevu serisi alır. Kural ya rule alanında
+// RRULE olarak (örneğin FREQ=WEEKLY;INTERVAL=2;COUNT=10) ya da frequency
+// (weekly, biweekly, monthly) ile count veya until olarak verilir.
+// Müşteriler kendi adlarına, sağlayıcı ve yöneticiler customer_email ile
+// müşteri adına seri alır. Alınamayan tekrarlar yanıtta conflicts olarak
+// döner; all_or_nothing verilirse biri bile alınamazsa hiçbiri alınmaz ve 409 döner.
+func (h *AppointmentHandler) BookSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		ProviderEmail string   `json:"providerEmail"`
+		Start         string   `json:"start"` // RFC3339, ilk randevunun başlangıcı
+		Rule          string   `json:"rule"`
+		Frequency     string   `json:"frequency"`
+		Count         int      `json:"count"`
+		Until         string   `json:"until"` // YYYY-MM-DD, dahil
+		CustomerName  string   `json:"customerName"`
+		CustomerEmail string   `json:"customerEmail"`
+		Services      []string `json:"services"`
+		ServiceIDs    []string `json:"serviceIDs"`
+		AllOrNothing  bool     `json:"allOrNothing"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.ProviderEmail == "" {
+		http.Error(w, "Provider Email is required", http.StatusBadRequest)
+		return
+	}
+	start, err := time.Parse(time.RFC3339, req.Start)
+	if err != nil {
+		http.Error(w, "Invalid start time, expected RFC3339", http.StatusBadRequest)
+		return
+	}
+	serviceIDs, err := parseObjectIDs(req.ServiceIDs)
+	if err != nil {
+		http.Error(w, "Invalid service ID", http.StatusBadRequest)
+		return
+	}
+	rule := req.Rule
+	if rule == "" {
+		if rule, err = services.RecurrenceRule(req.Frequency, req.Count, req.Until); err != nil {
+			writeError(w, err, "Invalid recurrence")
+			return
+		}
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	result, err := h.appointments.BookSeries(ctx, actor, services.SeriesBooking{
+		ProviderEmail: req.ProviderEmail,
+		Start:         start,
+		Rule:          rule,
+		CustomerName:  req.CustomerName,
+		CustomerEmail: req.CustomerEmail,
+		Services:      req.Services,
+		ServiceIDs:    serviceIDs,
+		AllOrNothing:  req.AllOrNothing,
+	})
+	if err != nil {
+		writeSeriesError(w, err, "Failed to book series")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"series":       seriesResponse(result.Series),
+		"appointments": result.Appointments,
+		"conflicts":    seriesConflictResponse(result.Conflicts),
+	})
+}
+
+// GetSeries seriyi ve randevularını sırasıyla döner
+func (h *AppointmentHandler) GetSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid series ID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	result, err := h.appointments.Series(ctx, actor, objID)
+	if err != nil {
+		writeError(w, err, "Failed to fetch series")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"series":       seriesResponse(result.Series),
+		"appointments": result.Appointments,
+	})
+}
+
+// CancelSeries serinin bir randevusunu (scope=one), verilen randevu ve
+// sonrakileri (following) ya da tümünü (all) iptal eder. one ve following
+// için appointmentID serinin bir randevusu olmalıdır. Politika nedeniyle
+// iptal edilemeyen randevular skipped olarak döner.
+func (h *AppointmentHandler) CancelSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid series ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Scope         string `json:"scope"`
+		AppointmentID string `json:"appointmentID"`
+		Reason        string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	var appointmentID primitive.ObjectID
+	if req.AppointmentID != "" {
+		appointmentID, err = primitive.ObjectIDFromHex(req.AppointmentID)
+		if err != nil {
+			http.Error(w, "Invalid Appointment ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	result, err := h.appointments.CancelSeries(ctx, actor, objID, services.SeriesCancel{
+		Scope:         req.Scope,
+		AppointmentID: appointmentID,
+		Reason:        req.Reason,
+	})
+	if err != nil {
+		writeError(w, err, "Failed to cancel series")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"series":    seriesResponse(result.Series),
+		"cancelled": result.Cancelled,
+		"skipped":   seriesConflictResponse(result.Skipped),
+	})
+}
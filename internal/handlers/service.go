@@ -1,2 +1,348 @@
 package handlers
 
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"rtsback/internal/models"
+	"rtsback/internal/services"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// servicePayload katalog hizmetinin istek ve yanıt biçimidir. Fiyat para
+// biriminin en küçük birimindedir (15000 = 150,00 TRY).
+type servicePayload struct {
+	ID              string   `json:"id,omitempty"`
+	CompanyID       string   `json:"companyID"`
+	Name            string   `json:"name"`
+	Description     string   `json:"description"`
+	DurationMinutes int      `json:"durationMinutes"`
+	Price           int64    `json:"price"`
+	Currency        string   `json:"currency"`
+	Category        string   `json:"category"`
+	Active          *bool    `json:"active"`
+	BufferMinutes   int      `json:"bufferMinutes"` // Hizmetten sonraki temizlik süresi
+	ResourceTypes   []string `json:"resourceTypes"` // Randevuya ayrılacak kaynak türleri, örneğin "room"
+}
+
+func (p servicePayload) model() models.Service {
+	service := models.Service{
+		CompanyID:       p.CompanyID,
+		Name:            p.Name,
+		Description:     p.Description,
+		DurationMinutes: p.DurationMinutes,
+		Price:           p.Price,
+		Currency:        p.Currency,
+		Category:        p.Category,
+		Active:          true,
+		BufferMinutes:   p.BufferMinutes,
+		ResourceTypes:   p.ResourceTypes,
+	}
+	// active verilmezse hizmet etkin kabul edilir
+	if p.Active != nil {
+		service.Active = *p.Active
+	}
+	return service
+}
+
+func serviceResponse(service models.Service) servicePayload {
+	active := service.Active
+	return servicePayload{
+		ID:              service.ID.Hex(),
+		CompanyID:       service.CompanyID,
+		Name:            service.Name,
+		Description:     service.Description,
+		DurationMinutes: service.DurationMinutes,
+		Price:           service.Price,
+		Currency:        service.Currency,
+		Category:        service.Category,
+		Active:          &active,
+		BufferMinutes:   service.BufferMinutes,
+		ResourceTypes:   service.ResourceTypes,
+	}
+}
+
+func servicesResponse(list []models.Service) []servicePayload {
+	out := make([]servicePayload, 0, len(list))
+	for _, service := range list {
+		out = append(out, serviceResponse(service))
+	}
+	return out
+}
+
+// offeredServicePayload sağlayıcının sunduğu hizmetin, sağlayıcıya özel
+// fiyat ve süre uygulanmış halidir
+type offeredServicePayload struct {
+	ServiceID       string `json:"serviceID"`
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	Category        string `json:"category"`
+	DurationMinutes int    `json:"durationMinutes"`
+	Price           int64  `json:"price"`
+	Currency        string `json:"currency"`
+	BufferMinutes   int    `json:"bufferMinutes"`
+}
+
+// parseObjectIDs istekteki hex kimlik listesini çevirir
+func parseObjectIDs(values []string) ([]primitive.ObjectID, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		id, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// GetCompanyServices şirketin etkin hizmetlerini herkese açık olarak listeler
+func (h *CatalogHandler) GetCompanyServices(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	companyID := r.URL.Query().Get("companyID")
+	if companyID == "" {
+		http.Error(w, "Company ID is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	list, err := h.catalog.List(ctx, services.Actor{}, companyID, false)
+	if err != nil {
+		writeError(w, err, "Failed to fetch services")
+		return
+	}
+
+	json.NewEncoder(w).Encode(servicesResponse(list))
+}
+
+// GetServices yönetici için şirketin pasifler dahil tüm hizmetlerini listeler
+func (h *CatalogHandler) GetServices(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	list, err := h.catalog.List(ctx, actor, actor.DefaultCompanyID(r.URL.Query().Get("companyID")), true)
+	if err != nil {
+		writeError(w, err, "Failed to fetch services")
+		return
+	}
+
+	json.NewEncoder(w).Encode(servicesResponse(list))
+}
+
+// AddService şirketin kataloğuna yeni hizmet ekler
+func (h *CatalogHandler) AddService(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req servicePayload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	service, err := h.catalog.Create(ctx, actor, req.model())
+	if err != nil {
+		writeError(w, err, "Failed to create service")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(serviceResponse(service))
+}
+
+// UpdateService hizmetin bilgilerini değiştirir; alınmış randevular
+// rezervasyon anındaki fiyatı korur
+func (h *CatalogHandler) UpdateService(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid Service ID", http.StatusBadRequest)
+		return
+	}
+
+	var req servicePayload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	service, err := h.catalog.Update(ctx, actor, objID, req.model())
+	if err != nil {
+		writeError(w, err, "Failed to update service")
+		return
+	}
+
+	json.NewEncoder(w).Encode(serviceResponse(service))
+}
+
+// DeleteService hizmeti katalogdan ve onu sunan sağlayıcılardan kaldırır
+func (h *CatalogHandler) DeleteService(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid Service ID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	if err := h.catalog.Delete(ctx, actor, objID); err != nil {
+		writeError(w, err, "Failed to delete service")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Service deleted"})
+}
+
+// GetProviderServices sağlayıcının sunduğu hizmetleri kendi fiyat ve
+// süreleriyle döner; müşteriler rezervasyonda bu kimlikleri gönderir
+func (h *CatalogHandler) GetProviderServices(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("providerID"))
+	if err != nil {
+		http.Error(w, "Invalid Provider ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	offerings, err := h.catalog.ProviderOfferings(ctx, objID)
+	if err != nil {
+		writeError(w, err, "Failed to fetch provider services")
+		return
+	}
+
+	out := make([]offeredServicePayload, 0, len(offerings))
+	for _, o := range offerings {
+		out = append(out, offeredServicePayload{
+			ServiceID:       o.Service.ID.Hex(),
+			Name:            o.Service.Name,
+			Description:     o.Service.Description,
+			Category:        o.Service.Category,
+			DurationMinutes: o.DurationMinutes,
+			Price:           o.Price,
+			Currency:        o.Service.Currency,
+			BufferMinutes:   o.Service.BufferMinutes,
+		})
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
+// SetProviderOffering sağlayıcının katalogdan bir hizmeti sunmasını sağlar.
+// price ve durationMinutes verilirse katalogdaki değerlerin yerine geçer.
+func (h *CatalogHandler) SetProviderOffering(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(principalIDOr(r, utils.RoleProvider, r.URL.Query().Get("providerID")))
+	if err != nil {
+		http.Error(w, "Invalid Provider ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		ServiceID       string `json:"serviceID"`
+		Price           *int64 `json:"price"`
+		DurationMinutes *int   `json:"durationMinutes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	serviceID, err := primitive.ObjectIDFromHex(req.ServiceID)
+	if err != nil {
+		http.Error(w, "Invalid Service ID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	err = h.catalog.SetOffering(ctx, actor, objID, models.Offering{
+		ServiceID:       serviceID,
+		Price:           req.Price,
+		DurationMinutes: req.DurationMinutes,
+	})
+	if err != nil {
+		writeError(w, err, "Failed to update provider services")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Provider service updated"})
+}
+
+// RemoveProviderOffering hizmeti sağlayıcının sunduklarından çıkarır
+func (h *CatalogHandler) RemoveProviderOffering(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(principalIDOr(r, utils.RoleProvider, r.URL.Query().Get("providerID")))
+	if err != nil {
+		http.Error(w, "Invalid Provider ID", http.StatusBadRequest)
+		return
+	}
+	serviceID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("serviceID"))
+	if err != nil {
+		http.Error(w, "Invalid Service ID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	if err := h.catalog.RemoveOffering(ctx, actor, objID, serviceID); err != nil {
+		writeError(w, err, "Failed to update provider services")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Provider service removed"})
+}
@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/services"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// timeOffPayload izin kaydının yanıt biçimidir; zamanlar UTC'dir
+type timeOffPayload struct {
+	ID            string    `json:"id"`
+	ProviderEmail string    `json:"providerEmail"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	Reason        string    `json:"reason,omitempty"`
+}
+
+func timeOffResponse(list []models.TimeOff) []timeOffPayload {
+	out := make([]timeOffPayload, 0, len(list))
+	for _, t := range list {
+		out = append(out, timeOffPayload{
+			ID:            t.ID.Hex(),
+			ProviderEmail: t.ProviderEmail,
+			Start:         t.Start.UTC(),
+			End:           t.End.UTC(),
+			Reason:        t.Reason,
+		})
+	}
+	return out
+}
+
+// closurePayload şirketin kapalı gününün yanıt biçimidir
+type closurePayload struct {
+	Date   string `json:"date"` // YYYY-MM-DD
+	Name   string `json:"name,omitempty"`
+	Source string `json:"source,omitempty"`
+}
+
+func closureResponse(list []models.Closure) []closurePayload {
+	out := make([]closurePayload, 0, len(list))
+	for _, c := range list {
+		out = append(out, closurePayload{Date: formatDay(c.Date), Name: c.Name, Source: c.Source})
+	}
+	return out
+}
+
+func closureResultResponse(result services.ClosureResult) map[string]interface{} {
+	message := "Closures added"
+	if result.Partial {
+		message = "Closures added, but religious holidays for this year are not known and must be added manually"
+	}
+	return map[string]interface{}{
+		"message":   message,
+		"closures":  closureResponse(result.Closures),
+		"skipped":   result.Skipped,
+		"partial":   result.Partial,
+		"conflicts": result.Conflicts,
+	}
+}
+
+// AddTimeOff sağlayıcıya izin ekler. Yanıt, izin aralığında zaten alınmış
+// randevuları da içerir; bu randevular iptal edilmez, müşterilere ulaşmak
+// personele bırakılır.
+func (h *AppointmentHandler) AddTimeOff(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		ProviderEmail string `json:"providerEmail"`
+		Start         string `json:"start"` // RFC3339, örneğin 2024-07-01T00:00:00+03:00
+		End           string `json:"end"`   // RFC3339, hariç
+		Reason        string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	email := principalEmailOr(r, utils.RoleProvider, req.ProviderEmail)
+	if email == "" {
+		http.Error(w, "Provider Email is required", http.StatusBadRequest)
+		return
+	}
+	start, err := time.Parse(time.RFC3339, req.Start)
+	if err != nil {
+		http.Error(w, "Invalid start time, expected RFC3339", http.StatusBadRequest)
+		return
+	}
+	end, err := time.Parse(time.RFC3339, req.End)
+	if err != nil {
+		http.Error(w, "Invalid end time, expected RFC3339", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	result, err := h.appointments.AddTimeOff(ctx, actor, email, start, end, req.Reason)
+	if err != nil {
+		writeError(w, err, "Failed to add time off")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "Time off added",
+		"timeOff":   timeOffResponse([]models.TimeOff{result.TimeOff})[0],
+		"conflicts": result.Conflicts,
+	})
+}
+
+// GetTimeOff sağlayıcının izinlerini listeler; from ve to YYYY-MM-DD'dir ve isteğe bağlıdır
+func (h *AppointmentHandler) GetTimeOff(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	email := principalEmailOr(r, utils.RoleProvider, r.URL.Query().Get("email"))
+	if email == "" {
+		http.Error(w, "Email parameter is required", http.StatusBadRequest)
+		return
+	}
+	from, err := parseDay(r.URL.Query().Get("from"))
+	if err != nil {
+		http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	to, err := parseDay(r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	list, err := h.appointments.ListTimeOff(ctx, actor, email, from, to)
+	if err != nil {
+		writeError(w, err, "Failed to fetch time off")
+		return
+	}
+
+	json.NewEncoder(w).Encode(timeOffResponse(list))
+}
+
+// GetTimeOffConflicts iznin aralığında hâlâ alınmış olan randevuları döner
+func (h *AppointmentHandler) GetTimeOffConflicts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid time off ID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	conflicts, err := h.appointments.TimeOffConflicts(ctx, actor, objID)
+	if err != nil {
+		writeError(w, err, "Failed to fetch conflicts")
+		return
+	}
+
+	json.NewEncoder(w).Encode(conflicts)
+}
+
+// DeleteTimeOff izni siler
+func (h *AppointmentHandler) DeleteTimeOff(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid time off ID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	if err := h.appointments.DeleteTimeOff(ctx, actor, objID); err != nil {
+		writeError(w, err, "Failed to delete time off")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Time off deleted"})
+}
+
+// AddClosures şirketi verilen günlerde kapalı işaretler. Yanıt, o günlerde
+// zaten alınmış randevuları da içerir.
+func (h *AppointmentHandler) AddClosures(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		CompanyID string   `json:"companyID"`
+		Dates     []string `json:"dates"` // YYYY-MM-DD
+		Name      string   `json:"name"`  // Örneğin: "Bakım"
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	holidays := make([]services.Holiday, 0, len(req.Dates))
+	for _, value := range req.Dates {
+		day, err := parseDay(value)
+		if err != nil || day.IsZero() {
+			http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		holidays = append(holidays, services.Holiday{Date: day, Name: req.Name})
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	result, err := h.appointments.AddClosures(ctx, actor, req.CompanyID, holidays, models.ClosureManual)
+	if err != nil {
+		writeError(w, err, "Failed to add closures")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(closureResultResponse(result))
+}
+
+// ImportHolidays yılın Türkiye resmi tatillerini şirketin kapalı günlerine
+// ekler. Dini bayramlar o yıl için listede yoksa partial true olur ve
+// mesaj bunların elle eklenmesi gerektiğini söyler.
+func (h *AppointmentHandler) ImportHolidays(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	year, err := strconv.Atoi(r.URL.Query().Get("year"))
+	if err != nil {
+		http.Error(w, "Invalid year", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	result, err := h.appointments.ImportTurkishHolidays(ctx, actor, r.URL.Query().Get("companyID"), year)
+	if err != nil {
+		writeError(w, err, "Failed to import holidays")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(closureResultResponse(result))
+}
+
+// GetClosures şirketin kapalı günlerini herkese açık olarak listeler
+func (h *AppointmentHandler) GetClosures(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	from, err := parseDay(r.URL.Query().Get("from"))
+	if err != nil {
+		http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	to, err := parseDay(r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	list, err := h.appointments.ListClosures(ctx, r.URL.Query().Get("companyID"), from, to)
+	if err != nil {
+		writeError(w, err, "Failed to fetch closures")
+		return
+	}
+
+	json.NewEncoder(w).Encode(closureResponse(list))
+}
+
+// DeleteClosure şirketin kapalı gününü kaldırır
+func (h *AppointmentHandler) DeleteClosure(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	day, err := parseDay(r.URL.Query().Get("date"))
+	if err != nil || day.IsZero() {
+		http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	if err := h.appointments.DeleteClosure(ctx, actor, r.URL.Query().Get("companyID"), day); err != nil {
+		writeError(w, err, "Failed to delete closure")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Closure deleted"})
+}
@@ -1,45 +1,78 @@
 package handlers
 
 import (
-	"time"
-	"github.com/dgrijalva/jwt-go"
-)
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
 
-// Secret keys for different roles
-var superUserSecretKey = []byte("superuser_secret_key")
-var adminSecretKey = []byte("admin_secret_key")
-var providerSecretKey = []byte("provider_secret_key")
-var userSecretKey = []byte("user_secret_key")
+	"rtsback/internal/services"
+	"rtsback/pkg/utils"
+)
 
-// Token oluşturma fonksiyonları
-func GenerateSuperUserToken(userID string) (string, error) {
-	return generateToken(userID, "superuser", superUserSecretKey)
+// issueSession giriş yapan hesap için yeni bir oturum açar.
+// Hata durumunda yanıtı kendisi yazar ve ok false döner.
+func (h *Deps) issueSession(ctx context.Context, w http.ResponseWriter, actor services.Actor) (services.Session, bool) {
+	sess, err := h.auth.StartSession(ctx, actor)
+	if err != nil {
+		writeError(w, err, "Token oluşturulamadı")
+		return services.Session{}, false
+	}
+	return sess, true
 }
 
-func GenerateAdminToken(userID string) (string, error) {
-	return generateToken(userID, "admin", adminSecretKey)
-}
+// login deneme sayaçlarını uygulayarak verilen roldeki hesabın şifresini
+// doğrular. Yalnızca bulunamayan hesap ve yanlış şifre sayaca yazılır.
+// Hata durumunda yanıtı kendisi yazar ve ok false döner.
+func (h *Deps) login(ctx context.Context, w http.ResponseWriter, r *http.Request, role, email, password string) (services.Authenticated, bool) {
+	// Başarısız denemeler hesap ve IP bazında sınırlandırılır
+	guard := h.newAttemptGuard(r, "login", email)
+	if !guard.allow(ctx, w) {
+		return services.Authenticated{}, false
+	}
 
-func GenerateProviderToken(userID string) (string, error) {
-	return generateToken(userID, "provider", providerSecretKey)
-}
+	account, err := h.auth.Login(ctx, role, email, password)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			guard.fail(ctx)
+		}
+		writeError(w, err, "Giriş yapılamadı")
+		return services.Authenticated{}, false
+	}
 
-func GenerateUserToken(userID string) (string, error) {
-	return generateToken(userID, "user", userSecretKey)
+	guard.succeed(ctx)
+	return account, true
 }
 
-func generateToken(userID, role string, secretKey []byte) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := jwt.MapClaims{
-		"exp":  expirationTime.Unix(),
-		"sub":  userID,
-		"role": role, // Token'a rol bilgisini ekler
+// startSecondFactor şifresi doğrulanmış hesap için ikinci adım gerekiyorsa
+// challenge token'ını yazar ve true döner
+func (h *Deps) startSecondFactor(ctx context.Context, w http.ResponseWriter, account services.Authenticated) bool {
+	purpose, err := h.auth.SecondFactorPurpose(ctx, account)
+	if err != nil {
+		writeError(w, err, "Failed to fetch company")
+		return true
+	}
+	if purpose == "" {
+		return false
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(secretKey)
+	challenge, expiresAt, err := utils.GenerateChallengeToken(account.ID, account.Role, account.Email, account.CompanyID, purpose)
 	if err != nil {
-		return "", err
+		http.Error(w, "Token oluşturulamadı", http.StatusInternalServerError)
+		return true
 	}
-	return tokenString, nil
+
+	response := map[string]interface{}{
+		"challengeToken": challenge,
+		"expiresAt":      expiresAt,
+	}
+	if purpose == utils.PurposeTwoFactor {
+		response["twoFactorRequired"] = true
+	} else {
+		response["twoFactorEnrollmentRequired"] = true
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+	return true
 }
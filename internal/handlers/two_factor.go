@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"rtsback/internal/services"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// claimsActor token'daki kimliği servis kimliğine çevirir
+func claimsActor(claims *utils.Claims) services.Actor {
+	return services.Actor{
+		ID:        claims.Subject,
+		Email:     claims.Email,
+		Role:      claims.Role,
+		CompanyID: claims.CompanyID,
+	}
+}
+
+// twoFactorIdentity kayıt uç noktaları için kimliği çözer. Normal bir
+// erişim token'ı ya da zorunlu kayıt için verilmiş challenge token'ı kabul
+// edilir; ikinci durumda enrolling true döner.
+func (h *Deps) twoFactorIdentity(ctx context.Context, r *http.Request) (*utils.Claims, bool, error) {
+	parts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return nil, false, errors.New("authorization header missing")
+	}
+
+	if claims, err := utils.ParseChallengeToken(parts[1], utils.PurposeTwoFactorEnroll); err == nil {
+		return claims, true, nil
+	}
+
+	claims, err := utils.ParseToken(parts[1])
+	if err != nil {
+		return nil, false, err
+	}
+	revoked, err := h.IsSessionRevoked(ctx, claims)
+	if err != nil {
+		return nil, false, err
+	}
+	if revoked {
+		return nil, false, errors.New("token has been revoked")
+	}
+	return claims, false, nil
+}
+
+// EnrollTwoFactor yeni bir TOTP anahtarı üretir ve onay bekleyen olarak saklar
+func (h *AuthHandler) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	claims, _, err := h.twoFactorIdentity(ctx, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	enrollment, err := h.auth.EnrollTwoFactor(ctx, claimsActor(claims))
+	if err != nil {
+		writeError(w, err, "Failed to save secret")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret":     enrollment.Secret,
+		"otpauthURI": enrollment.OTPAuthURI,
+	})
+}
+
+// ConfirmTwoFactor bekleyen anahtarı bir kodla doğrulayıp etkinleştirir ve
+// kurtarma kodlarını bir kez döner. Zorunlu kayıt akışında oturum da açar.
+func (h *AuthHandler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	claims, enrolling, err := h.twoFactorIdentity(ctx, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	actor := claimsActor(claims)
+
+	guard := h.newAttemptGuard(r, "2fa", claims.Subject)
+	if !guard.allow(ctx, w) {
+		return
+	}
+
+	codes, err := h.auth.ConfirmTwoFactor(ctx, actor, req.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCode) {
+			guard.fail(ctx)
+		}
+		writeError(w, err, "Failed to enable two-factor authentication")
+		return
+	}
+	guard.succeed(ctx)
+
+	response := map[string]interface{}{
+		"message":       "Two-factor authentication enabled",
+		"recoveryCodes": codes,
+	}
+
+	if enrolling {
+		sess, ok := h.issueSession(ctx, w, actor)
+		if !ok {
+			return
+		}
+		response["token"] = sess.AccessToken
+		response["refreshToken"] = sess.RefreshToken
+		response["ID"] = claims.Subject
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// VerifyTwoFactor girişin ikinci adımıdır: challenge token'ı ile TOTP
+// kodunu veya bir kurtarma kodunu alıp gerçek oturumu açar
+func (h *AuthHandler) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		ChallengeToken string `json:"challengeToken"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recoveryCode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChallengeToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		http.Error(w, "Challenge token and code are required", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := utils.ParseChallengeToken(req.ChallengeToken, utils.PurposeTwoFactor)
+	if err != nil {
+		http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+	actor := claimsActor(claims)
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	guard := h.newAttemptGuard(r, "2fa", claims.Subject)
+	if !guard.allow(ctx, w) {
+		return
+	}
+
+	err = h.auth.VerifyTwoFactor(ctx, actor, req.Code, req.RecoveryCode)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCode) {
+			guard.fail(ctx)
+		}
+		writeError(w, err, "Failed to verify code")
+		return
+	}
+	guard.succeed(ctx)
+
+	sess, ok := h.issueSession(ctx, w, actor)
+	if !ok {
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"token": sess.AccessToken, "refreshToken": sess.RefreshToken, "ID": claims.Subject})
+}
+
+// DisableTwoFactor geçerli bir kodla 2FA'yı kapatır. Şirketi 2FA'yı zorunlu
+// kılmış adminler kapatamaz.
+func (h *AuthHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	guard := h.newAttemptGuard(r, "2fa", actor.ID)
+	if !guard.allow(ctx, w) {
+		return
+	}
+
+	err := h.auth.DisableTwoFactor(ctx, actor, req.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCode) {
+			guard.fail(ctx)
+		}
+		writeError(w, err, "Failed to disable two-factor authentication")
+		return
+	}
+	guard.succeed(ctx)
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+// SetCompanyAdmin2FA superuser'ın bir şirketin tüm adminleri için 2FA'yı
+// zorunlu kılmasını sağlar
+func (h *AuthHandler) SetCompanyAdmin2FA(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		CompanyID string `json:"companyID"`
+		Required  bool   `json:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CompanyID == "" {
+		http.Error(w, "Company ID is required", http.StatusBadRequest)
+		return
+	}
+
+	objID, err := primitive.ObjectIDFromHex(req.CompanyID)
+	if err != nil {
+		http.Error(w, "Invalid Company ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	if err := h.companies.SetRequireAdmin2FA(ctx, objID, req.Required); err != nil {
+		writeError(w, err, "Failed to update company")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Company two-factor policy updated", "required": req.Required})
+}
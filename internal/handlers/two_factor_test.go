@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestVerifyTwoFactorChallengePurpose(t *testing.T) {
+	utils.SetJWTSecret([]byte("test-secret-that-is-at-least-32-bytes"))
+	deps, _ := newTestDeps(t)
+	h := NewAuthHandler(deps)
+	id := primitive.NewObjectID().Hex()
+
+	enroll, _, err := utils.GenerateChallengeToken(id, utils.RoleAdmin, "a@example.com", "c1", utils.PurposeTwoFactorEnroll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	access, _, err := utils.GenerateToken(id, utils.RoleAdmin, "a@example.com", "c1", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, token := range map[string]string{"enrollment challenge": enroll, "access token": access} {
+		w := serve(t, h.VerifyTwoFactor, nil, http.MethodPost, map[string]string{"challengeToken": token, "code": "123456"})
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status %d: %s", name, w.Code, w.Body)
+		}
+	}
+}
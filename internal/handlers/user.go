@@ -3,56 +3,42 @@ package handlers
 import (
 	"context"
 	"encoding/json"
-	"log"
 	"net/http"
-	"time"
 
-	"rtsback/config"
+	"rtsback/internal/middlewares"
 	"rtsback/internal/models"
-
-	"github.com/dgrijalva/jwt-go"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"golang.org/x/crypto/bcrypt"
+	"rtsback/internal/services"
+	"rtsback/pkg/utils"
 )
 
-var userCollection *mongo.Collection
-
-func init() {
-	client := config.ConnectDB()
-	userCollection = config.GetCollection(client, "user")
-}
-
-func CreateUser(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var user models.User
-
-	err := json.NewDecoder(r.Body).Decode(&user)
-	if err != nil {
-		http.Error(w, "Geçersiz veri formatı", http.StatusBadRequest)
-		return
+	// Yalnızca kayıt formunun alanları okunur; yetki ve şirket alanları
+	// gönderilse de yok sayılır
+	var req struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Phone    string `json:"phone"`
+		Password string `json:"password"`
 	}
-
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.PasswordHash), bcrypt.DefaultCost)
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		http.Error(w, "Şifre hash'lemesi başarısız oldu", http.StatusInternalServerError)
+		http.Error(w, "Geçersiz veri formatı", http.StatusBadRequest)
 		return
 	}
-	user.PasswordHash = string(hashedPassword)
-
-	user.ID = primitive.NewObjectID()
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = time.Now()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	_, err = userCollection.InsertOne(ctx, user)
+	user, err := h.users.Create(ctx, services.Registration{
+		Name:     req.Name,
+		Email:    req.Email,
+		Phone:    req.Phone,
+		Password: req.Password,
+	})
 	if err != nil {
-		http.Error(w, "Veritabanına eklenemedi", http.StatusInternalServerError)
+		writeError(w, err, "Veritabanına eklenemedi")
 		return
 	}
 
@@ -60,9 +46,7 @@ func CreateUser(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
-var jwtKey = []byte("your_secret_key") // JWT için gizli anahtar
-
-func Login(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var creds struct {
@@ -76,86 +60,67 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var user models.User
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	err = userCollection.FindOne(ctx, bson.M{"email": creds.Email}).Decode(&user)
-	if err != nil {
-		http.Error(w, "Kullanıcı bulunamadı", http.StatusUnauthorized)
+	account, ok := h.login(ctx, w, r, utils.RoleUser, creds.Email, creds.Password)
+	if !ok {
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(creds.Password))
-	if err != nil {
-		http.Error(w, "Geçersiz şifre", http.StatusUnauthorized)
-		return
-	}
-
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &jwt.StandardClaims{
-		ExpiresAt: expirationTime.Unix(),
-		Subject:   user.ID.Hex(),
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtKey)
-	if err != nil {
-		http.Error(w, "Token oluşturulamadı", http.StatusInternalServerError)
+	sess, ok := h.issueSession(ctx, w, account.Actor)
+	if !ok {
 		return
 	}
 
 	http.SetCookie(w, &http.Cookie{
 		Name:    "token",
-		Value:   tokenString,
-		Expires: expirationTime,
+		Value:   sess.AccessToken,
+		Expires: sess.ExpiresAt,
 	})
 
-	json.NewEncoder(w).Encode(map[string]string{"token": tokenString})
+	json.NewEncoder(w).Encode(map[string]string{"token": sess.AccessToken, "refreshToken": sess.RefreshToken})
 }
 
-func GetUserProfile(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	email := r.URL.Query().Get("email")
-	if email == "" {
+	// Profil her zaman token sahibinin e-postasıyla çekilir
+	principal, ok := middlewares.CurrentPrincipal(r)
+	if !ok || principal.Email == "" {
 		http.Error(w, "E-posta belirtilmedi", http.StatusBadRequest)
 		return
 	}
+	email := principal.Email
 
-	var user models.User
-	err := userCollection.FindOne(context.Background(), bson.M{"email": email}).Decode(&user)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, email)
 	if err != nil {
-		http.Error(w, "Kullanıcı bulunamadı veya veritabanı hatası", http.StatusNotFound)
+		writeError(w, err, "Kullanıcı bulunamadı veya veritabanı hatası")
 		return
 	}
 
 	json.NewEncoder(w).Encode(user)
 }
 
-func GetUsers(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var users []models.User
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	cursor, err := userCollection.Find(ctx, bson.M{})
+	users, err := h.users.List(ctx)
 	if err != nil {
 		http.Error(w, "Veri çekme hatası", http.StatusInternalServerError)
 		return
 	}
-	defer cursor.Close(ctx)
-
-	if err = cursor.All(ctx, &users); err != nil {
-		http.Error(w, "Veri çözümleme hatası", http.StatusInternalServerError)
-		return
-	}
 
 	json.NewEncoder(w).Encode(users)
 }
 
-func GetUserByEmail(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) GetUserByEmail(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// URL'den email parametresini al
@@ -165,20 +130,13 @@ func GetUserByEmail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Kullanıcı modelini temsil eden yapı
-	var user models.User
-
 	// Kullanıcıyı veritabanından email'e göre çek
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	err := userCollection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	user, err := h.users.FindByEmail(ctx, email)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			http.Error(w, "User not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		writeError(w, err, "Failed to fetch user")
 		return
 	}
 
@@ -187,64 +145,66 @@ func GetUserByEmail(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
-func UpdateUsers(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) UpdateUsers(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var users []models.User
-	err := json.NewDecoder(r.Body).Decode(&users)
+	var req []struct {
+		models.User
+		Password string `json:"password"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, "Veri çözümleme hatası: Geçersiz veri formatı", http.StatusBadRequest)
 		return
 	}
+	users := make([]models.User, 0, len(req))
+	for _, u := range req {
+		u.User.PasswordHash = u.Password
+		users = append(users, u.User)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	for _, user := range users {
-		filter := bson.M{"_id": user.ID}
-		update := bson.M{"$set": bson.M{
-			"name":         user.Name,
-			"email":        user.Email,
-			"passwordHash": user.PasswordHash,
-			"role":         user.Role,
-			"phone":        user.Phone,
-			"company_id":   user.CompanyID,
-			"super_user":   user.SuperUser,
-			"updatedAt":    time.Now(),
-		}}
-
-		opts := options.Update().SetUpsert(true)
-		_, err := userCollection.UpdateOne(ctx, filter, update, opts)
-		if err != nil {
-			log.Printf("Güncelleme hatası: %v", err)
-			http.Error(w, "Veritabanı güncelleme hatası", http.StatusInternalServerError)
-			return
-		}
+	// Şifre düz metin olarak gelir ve her zaman hash'lenerek saklanır
+	err = h.users.UpdateMany(ctx, users)
+	if err != nil {
+		writeError(w, err, "Veritabanı güncelleme hatası")
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Kullanıcılar başarıyla güncellendi"})
 }
 
-func UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
+// UpdateUserProfile kullanıcının adını ve telefonunu günceller. Admin
+// yalnızca kendi şirketindeki kullanıcıları güncelleyebilir; diğer alanlar
+// istekte gelse de yok sayılır.
+func (h *UserHandler) UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var user models.User
-	err := json.NewDecoder(r.Body).Decode(&user)
+	var req struct {
+		Email string `json:"email"`
+		Name  string `json:"name"`
+		Phone string `json:"phone"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, "Invalid data format", http.StatusBadRequest)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
 
-	filter := bson.M{"email": user.Email}
-	update := bson.M{"$set": user}
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
 
-	_, err = userCollection.UpdateOne(ctx, filter, update)
+	err = h.users.UpdateProfile(ctx, actor, req.Email, services.ProfileUpdate{Name: req.Name, Phone: req.Phone})
 	if err != nil {
-		http.Error(w, "Database update error", http.StatusInternalServerError)
+		writeError(w, err, "Database update error")
 		return
 	}
 
@@ -252,7 +212,7 @@ func UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "User updated successfully"})
 }
 
-func SuperUserLogin(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) SuperUserLogin(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var creds struct {
@@ -267,82 +227,54 @@ func SuperUserLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Find the user by email
-	var user models.User
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	err = userCollection.FindOne(ctx, bson.M{"email": creds.Email}).Decode(&user)
-	if err != nil {
-		http.Error(w, "User not found", http.StatusUnauthorized)
-		return
-	}
-
-	// Check if the password matches
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(creds.Password))
-	if err != nil {
-		http.Error(w, "Invalid password", http.StatusUnauthorized)
+	// Superuser olmayan hesaplar şifre doğru olsa da reddedilir
+	account, ok := h.login(ctx, w, r, utils.RoleSuperUser, creds.Email, creds.Password)
+	if !ok {
 		return
 	}
 
-	// Check if the user is a SuperUser
-	if !user.SuperUser {
-		http.Error(w, "Access denied: User is not a SuperUser", http.StatusForbidden)
+	// 2FA açıksa token yerine challenge döner
+	if h.startSecondFactor(ctx, w, account) {
 		return
 	}
 
 	// Create JWT token
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &jwt.StandardClaims{
-		ExpiresAt: expirationTime.Unix(),
-		Subject:   user.ID.Hex(),
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte("super_user_secret_key"))
-	if err != nil {
-		http.Error(w, "Could not create token", http.StatusInternalServerError)
+	sess, ok := h.issueSession(ctx, w, account.Actor)
+	if !ok {
 		return
 	}
 
 	// Return the token
-	json.NewEncoder(w).Encode(map[string]string{"token": tokenString})
+	json.NewEncoder(w).Encode(map[string]string{"token": sess.AccessToken, "refreshToken": sess.RefreshToken})
 }
 
-func CreateUserWithoutPassword(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) CreateUserWithoutPassword(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// Kullanıcı modelini temsil eden yapı
-	type User struct {
-		ID        primitive.ObjectID `bson:"_id,omitempty"`
-		Email     string             `bson:"email,omitempty"`
-		FirstName string             `bson:"first_name,omitempty"`
-		Phone     string             `bson:"phone,omitempty"`
-		CreatedAt time.Time          `bson:"created_at,omitempty"`
-		UpdatedAt time.Time          `bson:"updated_at,omitempty"`
+	// İstekte beklenen alanlar
+	var req struct {
+		Email     string
+		FirstName string
+		Phone     string
 	}
 
-	var user User
-
 	// JSON verisini parse et
-	err := json.NewDecoder(r.Body).Decode(&user)
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, "Geçersiz veri formatı", http.StatusBadRequest)
 		return
 	}
 
-	// ID, CreatedAt ve UpdatedAt alanlarını ayarla
-	user.ID = primitive.NewObjectID()
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = time.Now()
-
-	// Kullanıcıyı veritabanına ekle
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Kullanıcıyı veritabanına ekle; ad, kullanıcı modelinin name alanında saklanır
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
 	defer cancel()
 
-	_, err = userCollection.InsertOne(ctx, user)
+	_, err = h.users.CreateWithoutPassword(ctx, req.Email, req.FirstName, req.Phone)
 	if err != nil {
-		http.Error(w, "Kullanıcı eklenemedi", http.StatusInternalServerError)
+		writeError(w, err, "Kullanıcı eklenemedi")
 		return
 	}
 
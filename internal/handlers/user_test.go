@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"rtsback/internal/middlewares"
+	"rtsback/internal/models"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUpdateUserProfileScope(t *testing.T) {
+	deps, repos := newTestDeps(t)
+	h := NewUserHandler(deps)
+	ctx := context.Background()
+	for _, u := range []models.User{
+		{ID: primitive.NewObjectID(), Email: "own@example.com", Name: "Own", CompanyID: "c1", PasswordHash: "hash"},
+		{ID: primitive.NewObjectID(), Email: "other@example.com", Name: "Other", CompanyID: "c2", PasswordHash: "hash"},
+	} {
+		if err := repos.Users.Create(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	admin := &middlewares.Principal{ID: primitive.NewObjectID().Hex(), Email: "admin@example.com", Role: utils.RoleAdmin, CompanyID: "c1"}
+
+	w := serve(t, h.UpdateUserProfile, admin, http.MethodPut, map[string]interface{}{"email": "other@example.com", "name": "Changed"})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("cross-company update: status %d", w.Code)
+	}
+	if other, _ := repos.Users.FindByEmail(ctx, "other@example.com"); other.Name != "Other" {
+		t.Fatalf("cross-company user renamed to %q", other.Name)
+	}
+
+	// Yetki, şirket ve şifre alanları yok sayılır
+	w = serve(t, h.UpdateUserProfile, admin, http.MethodPut, map[string]interface{}{
+		"email": "own@example.com", "name": "Renamed",
+		"SuperUser": true, "super_user": true, "CompanyID": "c2", "PasswordHash": "plain", "Role": utils.RoleSuperUser,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("own-company update: status %d: %s", w.Code, w.Body)
+	}
+	own, err := repos.Users.FindByEmail(ctx, "own@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if own.Name != "Renamed" || own.SuperUser || own.CompanyID != "c1" || own.PasswordHash != "hash" || own.Role != "" {
+		t.Fatalf("user after update: %+v", own)
+	}
+
+	w = serve(t, h.UpdateUserProfile, admin, http.MethodPut, map[string]interface{}{"email": "missing@example.com", "name": "X"})
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("missing user: status %d", w.Code)
+	}
+}
+
+func TestCreateUserIgnoresPrivilegedFields(t *testing.T) {
+	deps, repos := newTestDeps(t)
+	h := NewUserHandler(deps)
+
+	w := serve(t, h.CreateUser, nil, http.MethodPost, map[string]interface{}{
+		"name": "New", "email": "new@example.com", "password": "correct horse battery",
+		"SuperUser": true, "Role": utils.RoleSuperUser, "CompanyID": "c1", "EmailVerification": true, "TOTPEnabled": true,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	user, err := repos.Users.FindByEmail(context.Background(), "new@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.SuperUser || user.Role != "" || user.CompanyID != "" || user.EmailVerification || user.TOTPEnabled {
+		t.Fatalf("privileged fields stored: %+v", user)
+	}
+	if user.Name != "New" || user.PasswordHash == "" || user.PasswordHash == "correct horse battery" {
+		t.Fatalf("user not stored as expected: %+v", user)
+	}
+
+	w = serve(t, h.CreateUser, nil, http.MethodPost, map[string]interface{}{"email": "short@example.com", "password": "x"})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("short password: status %d", w.Code)
+	}
+}
@@ -2,34 +2,17 @@ package handlers
 
 import (
 	"context"
-	"crypto/rand"
 	"encoding/json"
-	"fmt"
-	"math/big"
+	"errors"
 	"net/http"
-	"os"
-	"rtsback/config"
-	"rtsback/internal/models"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"gopkg.in/gomail.v2"
-)
-
-var verificationCollection *mongo.Collection
-
-func init() {
-	client := config.ConnectDB()
-	verificationCollection = config.GetCollection(client, "verification")
-}
+	"rtsback/internal/services"
 
-func generateCode() string {
-	n, _ := rand.Int(rand.Reader, big.NewInt(1000000))
-	return fmt.Sprintf("%06d", n.Int64()) // 6 haneli kod
-}
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
-func SendVerificationCode(w http.ResponseWriter, r *http.Request) {
+func (h *VerificationHandler) SendVerificationCode(w http.ResponseWriter, r *http.Request) {
 	userEmail := r.URL.Query().Get("email")
 	userID := r.URL.Query().Get("userID")
 
@@ -38,28 +21,13 @@ func SendVerificationCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	code := generateCode()
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
 
-	// Verification kaydı oluşturma
-	verification := models.Verification{
-		UserID:     userID,
-		Email:      userEmail,
-		EmailCode:  code,
-		EmailVer:   false,
-		CreateTime: time.Now(),
-	}
-
-	// Veritabanına kaydet
-	_, err := verificationCollection.InsertOne(context.TODO(), verification)
+	// Kod kaydedilir ve e-postayla gönderilir
+	verification, err := h.verifications.SendCode(ctx, userID, userEmail)
 	if err != nil {
-		http.Error(w, "Failed to save verification code", http.StatusInternalServerError)
-		return
-	}
-
-	// E-posta gönderimi
-	err = sendVerificationEmail(userEmail, code)
-	if err != nil {
-		http.Error(w, "Error sending email", http.StatusInternalServerError)
+		writeError(w, err, "Failed to send verification code")
 		return
 	}
 
@@ -67,7 +35,7 @@ func SendVerificationCode(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Verification code sent to your email!"))
 	json.NewEncoder(w).Encode(map[string]string{"userID": verification.UserID})
 }
-func VerifyCode(w http.ResponseWriter, r *http.Request) {
+func (h *VerificationHandler) VerifyCode(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("userID")
 	code := r.URL.Query().Get("code")
 
@@ -76,58 +44,44 @@ func VerifyCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Kullanıcıyı çek
-	var verification models.Verification
-	err := verificationCollection.FindOne(context.TODO(), bson.M{"user_id": userID}).Decode(&verification)
-	if err != nil {
-		http.Error(w, "Verification not found", http.StatusNotFound)
-		return
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
 
-	// Kodu kontrol et
-	if verification.EmailCode != code {
-		http.Error(w, "Invalid verification code", http.StatusBadRequest)
+	// Kod tahminine karşı denemeler kullanıcı ve IP bazında sınırlandırılır
+	guard := h.newAttemptGuard(r, "verify", userID)
+	if !guard.allow(ctx, w) {
 		return
 	}
 
-	// Doğrulama işlemini güncelle
-	update := bson.M{
-		"$set": bson.M{
-			"email_ver":      true,
-			"email_ver_time": time.Now(),
-		},
+	err := h.verifications.VerifyCode(ctx, userID, code)
+	if errors.Is(err, services.ErrInvalidVerificationCode) {
+		guard.fail(ctx)
 	}
-
-	_, err = verificationCollection.UpdateOne(context.TODO(), bson.M{"user_id": userID}, update)
 	if err != nil {
-		http.Error(w, "Failed to update verification status", http.StatusInternalServerError)
+		writeError(w, err, "Failed to update verification status")
 		return
 	}
 
+	guard.succeed(ctx)
+
 	w.Write([]byte("Email verified successfully!"))
 }
 
-// E-posta gönderimi fonksiyonu
-func sendVerificationEmail(email, code string) error {
-	MailPassword := os.Getenv("MAIL_PASSWORD")
-	m := gomail.NewMessage()
-	m.SetHeader("From", "sahmedkuddusi@gmial.com")
-	m.SetHeader("To", email)
-	m.SetHeader("Subject", "Email Verification Code")
-	m.SetBody("text/plain", fmt.Sprintf("Your verification code is: %s", code))
-
-	// SMTP sunucu ayarları
-	d := gomail.NewDialer("smtp.gmail.com", 587, "sahmedkuddusi@gmail.com", MailPassword)
-
-	// E-posta gönderme işlemi
-	if err := d.DialAndSend(m); err != nil {
-		fmt.Printf("Error sending email: %v\n", err)
-		return err
-	}
-	return nil
+// verificationPayload doğrulama kaydının yanıt biçimidir. Kodlar yanıta
+// hiç konmaz; alan adları önceki yanıtla aynıdır.
+type verificationPayload struct {
+	ID           primitive.ObjectID
+	UserID       string
+	Email        string
+	EmailVer     bool
+	EmailVerTime time.Time
+	Phone        string
+	PhoneVer     bool
+	PhoneVerTime time.Time
+	CreateTime   time.Time
 }
 
-func GetVerificationByUserIDHandler(w http.ResponseWriter, r *http.Request) {
+func (h *VerificationHandler) GetVerificationByUserIDHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// URL'den userID parametresini alıyoruz
@@ -137,23 +91,26 @@ func GetVerificationByUserIDHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Veriyi çekmek için filtre oluşturma
-	filter := bson.M{"user_id": userID}
-
-	// Verification belgesini tutmak için boş bir değişken oluşturma
-	var verification models.Verification
-
 	// Belgeyi çekme
-	err := verificationCollection.FindOne(context.TODO(), filter).Decode(&verification)
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	verification, err := h.verifications.FindByUserID(ctx, userID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			http.Error(w, "Verification data not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Error fetching verification data", http.StatusInternalServerError)
+		writeError(w, err, "Error fetching verification data")
 		return
 	}
 
-	// Verification verisini JSON formatında döner
-	json.NewEncoder(w).Encode(verification)
+	// Verification verisini kodlar olmadan JSON formatında döner
+	json.NewEncoder(w).Encode(verificationPayload{
+		ID:           verification.ID,
+		UserID:       verification.UserID,
+		Email:        verification.Email,
+		EmailVer:     verification.EmailVer,
+		EmailVerTime: verification.EmailVerTime,
+		Phone:        verification.Phone,
+		PhoneVer:     verification.PhoneVer,
+		PhoneVerTime: verification.PhoneVerTime,
+		CreateTime:   verification.CreateTime,
+	})
 }
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/services"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// waitlistPayload bekleme listesi kaydının yanıt biçimidir. Teklif
+// token'ı yanıtta yer almaz; yalnızca müşteriye e-postayla gider.
+type waitlistPayload struct {
+	ID             string     `json:"id"`
+	CustomerEmail  string     `json:"customerEmail"`
+	CustomerName   string     `json:"customerName,omitempty"`
+	CompanyID      string     `json:"companyID"`
+	ProviderEmail  string     `json:"providerEmail,omitempty"`
+	From           string     `json:"from"` // YYYY-MM-DD
+	To             string     `json:"to"`
+	ServiceIDs     []string   `json:"serviceIDs,omitempty"`
+	Status         string     `json:"status"`
+	OfferStart     *time.Time `json:"offerStart,omitempty"`
+	OfferExpiresAt *time.Time `json:"offerExpiresAt,omitempty"`
+	AppointmentID  string     `json:"appointmentID,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+func waitlistResponse(list []models.WaitlistEntry) []waitlistPayload {
+	out := make([]waitlistPayload, 0, len(list))
+	for _, e := range list {
+		p := waitlistPayload{
+			ID:            e.ID.Hex(),
+			CustomerEmail: e.CustomerEmail,
+			CustomerName:  e.CustomerName,
+			CompanyID:     e.CompanyID,
+			ProviderEmail: e.ProviderEmail,
+			From:          formatDay(e.From),
+			To:            formatDay(e.To),
+			Status:        e.Status,
+			CreatedAt:     e.CreatedAt,
+		}
+		for _, id := range e.ServiceIDs {
+			p.ServiceIDs = append(p.ServiceIDs, id.Hex())
+		}
+		if e.Status == models.WaitlistOffered {
+			start, expiresAt := e.OfferStart.UTC(), e.OfferExpiresAt.UTC()
+			p.OfferStart, p.OfferExpiresAt = &start, &expiresAt
+		}
+		if !e.AppointmentID.IsZero() {
+			p.AppointmentID = e.AppointmentID.Hex()
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// JoinWaitlist giriş yapmış müşteriyi sağlayıcının ya da şirketin bekleme
+// listesine ekler. Sırası geldiğinde boşalan slot müşteriye e-postayla
+// teklif edilir.
+func (h *AppointmentHandler) JoinWaitlist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		CompanyID     string   `json:"companyID"`
+		ProviderEmail string   `json:"providerEmail"` // Boşsa şirketin herhangi bir sağlayıcısı
+		From          string   `json:"from"`          // YYYY-MM-DD
+		To            string   `json:"to"`            // YYYY-MM-DD, dahil
+		ServiceIDs    []string `json:"serviceIDs"`
+		CustomerName  string   `json:"customerName"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	from, err := parseDay(req.From)
+	if err != nil {
+		http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	to, err := parseDay(req.To)
+	if err != nil {
+		http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	serviceIDs, err := parseObjectIDs(req.ServiceIDs)
+	if err != nil {
+		http.Error(w, "Invalid service ID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	entry, err := h.appointments.JoinWaitlist(ctx, actor, services.WaitlistRequest{
+		CompanyID:     req.CompanyID,
+		ProviderEmail: req.ProviderEmail,
+		From:          from,
+		To:            to,
+		ServiceIDs:    serviceIDs,
+		CustomerName:  req.CustomerName,
+	})
+	if err != nil {
+		writeError(w, err, "Failed to join waitlist")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(waitlistResponse([]models.WaitlistEntry{entry})[0])
+}
+
+// GetMyWaitlist müşterinin bekleme listesi kayıtlarını döner
+func (h *AppointmentHandler) GetMyWaitlist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	list, err := h.appointments.MyWaitlist(ctx, actor)
+	if err != nil {
+		writeError(w, err, "Failed to fetch waitlist")
+		return
+	}
+
+	json.NewEncoder(w).Encode(waitlistResponse(list))
+}
+
+// LeaveWaitlist müşteriyi bekleme listesinden çıkarır
+func (h *AppointmentHandler) LeaveWaitlist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	objID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid waitlist entry ID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	if err := h.appointments.LeaveWaitlist(ctx, actor, objID); err != nil {
+		writeError(w, err, "Failed to leave waitlist")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Left the waitlist"})
+}
+
+// GetCompanyWaitlist şirketin bekleme listesini sıra düzeniyle döner
+func (h *AppointmentHandler) GetCompanyWaitlist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	list, err := h.appointments.CompanyWaitlist(ctx, actor, r.URL.Query().Get("companyID"))
+	if err != nil {
+		writeError(w, err, "Failed to fetch waitlist")
+		return
+	}
+
+	json.NewEncoder(w).Encode(waitlistResponse(list))
+}
+
+// ClaimWaitlistOffer teklif e-postasındaki token ile slotu alır. Token
+// müşteriyi tanımladığı için giriş gerektirmez ve tek kullanımlıktır.
+func (h *AppointmentHandler) ClaimWaitlistOffer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.queryTimeout)
+	defer cancel()
+
+	appointment, err := h.appointments.ClaimWaitlistOffer(ctx, req.Token)
+	if err != nil {
+		writeError(w, err, "Failed to claim offer")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(appointment)
+}
@@ -0,0 +1,174 @@
+package middlewares
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LimiterPolicy başarısız deneme sınırlarını tanımlar
+type LimiterPolicy struct {
+	MaxAttempts     int           // Kilitlenmeden önce izin verilen başarısız deneme sayısı
+	BaseDelay       time.Duration // İlk başarısız denemeden sonraki bekleme, her denemede iki katına çıkar
+	MaxDelay        time.Duration // Kademeli beklemenin üst sınırı
+	LockoutDuration time.Duration // MaxAttempts aşıldığında uygulanan kilit süresi
+	Window          time.Duration // Son başarısız denemeden bu süre geçince sayaç sıfırlanır
+}
+
+// DefaultAccountPolicy e-posta bazlı varsayılan sınırlardır
+var DefaultAccountPolicy = LimiterPolicy{
+	MaxAttempts:     5,
+	BaseDelay:       time.Second,
+	MaxDelay:        30 * time.Second,
+	LockoutDuration: 15 * time.Minute,
+	Window:          15 * time.Minute,
+}
+
+// DefaultIPPolicy IP bazlı varsayılan sınırlardır; aynı ağı paylaşan
+// kullanıcılar olabileceği için daha geniştir
+var DefaultIPPolicy = LimiterPolicy{
+	MaxAttempts:     20,
+	BaseDelay:       0,
+	MaxDelay:        0,
+	LockoutDuration: 15 * time.Minute,
+	Window:          15 * time.Minute,
+}
+
+// AttemptLimiter anahtar (e-posta, IP vb.) bazında başarısız denemeleri sayar
+type AttemptLimiter interface {
+	// Check anahtar için yeni bir denemeye ne kadar süre sonra izin
+	// verileceğini döner; sıfır ise hemen denenebilir
+	Check(ctx context.Context, key string) (time.Duration, error)
+	// Fail başarısız bir denemeyi kaydeder, anahtar bu denemeyle
+	// kilitlendiyse locked true döner
+	Fail(ctx context.Context, key string) (locked bool, err error)
+	// Reset anahtarın sayacını ve kilidini kaldırır
+	Reset(ctx context.Context, key string) error
+}
+
+// AttemptState bir anahtarın sayaç durumudur
+type AttemptState struct {
+	Failures    int       `bson:"failures"`
+	LastFailure time.Time `bson:"last_failure"`
+	LockedUntil time.Time `bson:"locked_until,omitempty"`
+}
+
+// RetryAfter duruma göre kalan bekleme süresini hesaplar
+func (p LimiterPolicy) RetryAfter(state AttemptState, now time.Time) time.Duration {
+	if now.Before(state.LockedUntil) {
+		return state.LockedUntil.Sub(now)
+	}
+	if state.Failures == 0 || now.Sub(state.LastFailure) > p.Window {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < state.Failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	next := state.LastFailure.Add(delay)
+	if now.Before(next) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+// apply başarısız denemeyi duruma işler ve kilitlenip kilitlenmediğini döner
+func (p LimiterPolicy) apply(state AttemptState, now time.Time) (AttemptState, bool) {
+	if p.expired(state, now) {
+		state = AttemptState{}
+	}
+	state.Failures++
+	state.LastFailure = now
+
+	if p.MaxAttempts > 0 && state.Failures >= p.MaxAttempts && !now.Before(state.LockedUntil) {
+		state.LockedUntil = now.Add(p.LockoutDuration)
+		return state, true
+	}
+	return state, false
+}
+
+// expired durumun artık hiçbir etkisi kalmadığını söyler: pencere geçmiş
+// ve kilit bitmiştir
+func (p LimiterPolicy) expired(state AttemptState, now time.Time) bool {
+	return now.Sub(state.LastFailure) > p.Window && !now.Before(state.LockedUntil)
+}
+
+// MemoryLimiter tek süreçlik kurulumlar ve geliştirme için bellek içi
+// sayaçtır. Süresi dolan anahtarlar başarısız denemeler kaydedilirken
+// en fazla Window aralıkla temizlenir; böylece farklı e-posta ve IP'lerle
+// yapılan denemeler belleği doldurmaz.
+type MemoryLimiter struct {
+	policy LimiterPolicy
+	mu     sync.Mutex
+	states map[string]AttemptState
+	swept  time.Time
+}
+
+// NewMemoryLimiter verilen politika ile bellek içi sayaç oluşturur
+func NewMemoryLimiter(policy LimiterPolicy) *MemoryLimiter {
+	return &MemoryLimiter{policy: policy, states: make(map[string]AttemptState), swept: time.Now()}
+}
+
+// sweep süresi dolan anahtarları siler; kilit altında çağrılır
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < l.policy.Window {
+		return
+	}
+	l.swept = now
+	for key, state := range l.states {
+		if l.policy.expired(state, now) {
+			delete(l.states, key)
+		}
+	}
+}
+
+func (l *MemoryLimiter) Check(ctx context.Context, key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.policy.RetryAfter(l.states[key], time.Now()), nil
+}
+
+func (l *MemoryLimiter) Fail(ctx context.Context, key string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.sweep(now)
+	state, locked := l.policy.apply(l.states[key], now)
+	l.states[key] = state
+	return locked, nil
+}
+
+func (l *MemoryLimiter) Reset(ctx context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.states, key)
+	return nil
+}
+
+// ClientIP isteğin geldiği adresi döner. Proxy başlıklarına güvenilmez,
+// böylece saldırgan her denemede farklı bir IP uyduramaz.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// WriteTooManyAttempts 429 yanıtını Retry-After başlığıyla yazar
+func WriteTooManyAttempts(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(retryAfter.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, "Too many failed attempts, try again later", http.StatusTooManyRequests)
+}
@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var testPolicy = LimiterPolicy{
+	MaxAttempts:     3,
+	BaseDelay:       time.Second,
+	MaxDelay:        3 * time.Second,
+	LockoutDuration: time.Minute,
+	Window:          10 * time.Minute,
+}
+
+func TestRetryAfterBacksOff(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		failures int
+		want     time.Duration
+	}{{0, 0}, {1, time.Second}, {2, 2 * time.Second}, {3, 3 * time.Second}, {6, 3 * time.Second}} {
+		got := testPolicy.RetryAfter(AttemptState{Failures: c.failures, LastFailure: now}, now)
+		if got != c.want {
+			t.Errorf("%d failures: retry after %v, want %v", c.failures, got, c.want)
+		}
+	}
+	// Pencere geçince sayaç etkisizdir
+	old := AttemptState{Failures: 2, LastFailure: now.Add(-testPolicy.Window - time.Second)}
+	if got := testPolicy.RetryAfter(old, now); got != 0 {
+		t.Errorf("expired window: retry after %v, want 0", got)
+	}
+}
+
+func TestMemoryLimiterLocksAndResets(t *testing.T) {
+	l := NewMemoryLimiter(testPolicy)
+	ctx := context.Background()
+
+	for i := 1; i <= testPolicy.MaxAttempts; i++ {
+		locked, err := l.Fail(ctx, "login:account:a@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if locked != (i == testPolicy.MaxAttempts) {
+			t.Fatalf("attempt %d: locked=%v", i, locked)
+		}
+	}
+	wait, err := l.Check(ctx, "login:account:a@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait <= testPolicy.MaxDelay || wait > testPolicy.LockoutDuration {
+		t.Fatalf("locked account waits %v, want the lockout", wait)
+	}
+	// Kilitliyken gelen yeni hata kilidi yeniden kaydetmez
+	if locked, _ := l.Fail(ctx, "login:account:a@example.com"); locked {
+		t.Fatal("already locked key reported a new lockout")
+	}
+	if wait, _ := l.Check(ctx, "login:account:b@example.com"); wait != 0 {
+		t.Fatalf("other key waits %v", wait)
+	}
+
+	if err := l.Reset(ctx, "login:account:a@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _ := l.Check(ctx, "login:account:a@example.com"); wait != 0 {
+		t.Fatalf("reset key waits %v", wait)
+	}
+}
+
+func TestMemoryLimiterEvictsExpiredKeys(t *testing.T) {
+	l := NewMemoryLimiter(testPolicy)
+	ctx := context.Background()
+	now := time.Now()
+
+	l.states["old"] = AttemptState{Failures: 2, LastFailure: now.Add(-time.Hour)}
+	l.states["locked"] = AttemptState{Failures: 3, LastFailure: now.Add(-time.Hour), LockedUntil: now.Add(time.Hour)}
+	l.states["recent"] = AttemptState{Failures: 1, LastFailure: now.Add(-time.Minute)}
+	l.swept = now.Add(-testPolicy.Window - time.Second)
+
+	if _, err := l.Fail(ctx, "new"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.states["old"]; ok {
+		t.Error("expired key was kept")
+	}
+	for _, key := range []string{"locked", "recent", "new"} {
+		if _, ok := l.states[key]; !ok {
+			t.Errorf("%s key was evicted", key)
+		}
+	}
+}
@@ -1,143 +1,78 @@
 package middlewares
 
 import (
-    "net/http"
-    "strings"
+	"context"
+	"net/http"
+	"strings"
 
-    "github.com/dgrijalva/jwt-go"
+	"rtsback/pkg/utils"
 )
 
-func JwtVerify(next http.Handler) http.Handler {
-    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        authHeader := r.Header.Get("Authorization")
-        if authHeader == "" {
-            http.Error(w, "Authorization header missing", http.StatusUnauthorized)
-            return
-        }
+// RevocationChecker erişim token'ının sunucu tarafında iptal edilip
+// edilmediğini bildirir
+type RevocationChecker func(ctx context.Context, claims *utils.Claims) (bool, error)
 
-        bearerToken := strings.Split(authHeader, " ")
-        if len(bearerToken) != 2 {
-            http.Error(w, "Invalid token format", http.StatusUnauthorized)
-            return
-        }
+var revocationChecker RevocationChecker
 
-        token, err := jwt.Parse(bearerToken[1], func(token *jwt.Token) (interface{}, error) {
-            return []byte("your_secret_key"), nil
-        })
-
-        if err != nil || !token.Valid {
-            http.Error(w, "Invalid token", http.StatusUnauthorized)
-            return
-        }
-
-        next.ServeHTTP(w, r)
-    })
-}
-func SuperUserJWT(next http.Handler) http.Handler{
-    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        authHeader := r.Header.Get("Authorization")
-        if authHeader == "" {
-            http.Error(w, "Authorization header missing", http.StatusUnauthorized)
-            return
-        }
-
-        bearerToken := strings.Split(authHeader, " ")
-        if len(bearerToken) != 2 {
-            http.Error(w, "Invalid token format", http.StatusUnauthorized)
-            return
-        }
-
-        token, err := jwt.Parse(bearerToken[1], func(token *jwt.Token) (interface{}, error) {
-            return []byte("super_user_secret_key"), nil
-        })
-
-        if err != nil || !token.Valid {
-            http.Error(w, "Invalid token", http.StatusUnauthorized)
-            return
-        }
-
-        next.ServeHTTP(w, r)
-    })
+// SetRevocationChecker Authenticate'in her istekte kullanacağı iptal
+// kontrolünü ayarlar
+func SetRevocationChecker(checker RevocationChecker) {
+	revocationChecker = checker
 }
 
-func AdminJWT(next http.Handler) http.Handler{
-    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        authHeader := r.Header.Get("Authorization")
-        if authHeader == "" {
-            http.Error(w, "Authorization header missing", http.StatusUnauthorized)
-            return
-        }
-
-        bearerToken := strings.Split(authHeader, " ")
-        if len(bearerToken) != 2 {
-            http.Error(w, "Invalid token format", http.StatusUnauthorized)
-            return
-        }
-
-        token, err := jwt.Parse(bearerToken[1], func(token *jwt.Token) (interface{}, error) {
-            return []byte("admin_secret_key"), nil
-        })
-
-        if err != nil || !token.Valid {
-            http.Error(w, "Invalid token", http.StatusUnauthorized)
-            return
-        }
-
-        next.ServeHTTP(w, r)
-    })
+// Authenticate Bearer token'ı doğrular, rolün izin verilenler arasında olduğunu
+// kontrol eder ve kimliği isteğin context'ine ekler.
+func Authenticate(roles ...string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		allowed[role] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				http.Error(w, "Authorization header missing", http.StatusUnauthorized)
+				return
+			}
+
+			bearerToken := strings.Split(authHeader, " ")
+			if len(bearerToken) != 2 || !strings.EqualFold(bearerToken[0], "Bearer") {
+				http.Error(w, "Invalid token format", http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := utils.ParseToken(bearerToken[1])
+			if err != nil {
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+
+			if revocationChecker != nil {
+				revoked, err := revocationChecker(r.Context(), claims)
+				if err != nil {
+					http.Error(w, "Token could not be verified", http.StatusInternalServerError)
+					return
+				}
+				if revoked {
+					http.Error(w, "Token has been revoked", http.StatusUnauthorized)
+					return
+				}
+			}
+
+			if !allowed[claims.Role] {
+				http.Error(w, "Access denied for this role", http.StatusForbidden)
+				return
+			}
+
+			principal := Principal{
+				ID:        claims.Subject,
+				Email:     claims.Email,
+				Role:      claims.Role,
+				CompanyID: claims.CompanyID,
+				SessionID: claims.SessionID,
+			}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
 }
-
-func ProviderJWT(next http.Handler) http.Handler {
-    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        authHeader := r.Header.Get("Authorization")
-        if authHeader == "" {
-            http.Error(w, "Authorization header missing", http.StatusUnauthorized)
-            return
-        }
-
-        bearerToken := strings.Split(authHeader, " ")
-        if len(bearerToken) != 2 {
-            http.Error(w, "Invalid token format", http.StatusUnauthorized)
-            return
-        }
-
-        token, err := jwt.Parse(bearerToken[1], func(token *jwt.Token) (interface{}, error) {
-            return []byte("provider_secret_key"), nil
-        })
-
-        if err != nil || !token.Valid {
-            http.Error(w, "Invalid token", http.StatusUnauthorized)
-            return
-        }
-
-        next.ServeHTTP(w, r)
-    })
-}
-
-
-func ManagerJWT(next http.Handler) http.Handler {
-    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        authHeader := r.Header.Get("Authorization")
-        if authHeader == "" {
-            http.Error(w, "Authorization header missing", http.StatusUnauthorized)
-            return
-        }
-
-        bearerToken := strings.Split(authHeader, " ")
-        if len(bearerToken) != 2 {
-            http.Error(w, "Invalid token format", http.StatusUnauthorized)
-            return
-        }
-
-        token, err := jwt.Parse(bearerToken[1], func(token *jwt.Token) (interface{}, error) {
-            return []byte("manager_secret_key"), nil
-        })
-
-        if err != nil || !token.Valid {
-            http.Error(w, "Invalid token", http.StatusUnauthorized)
-            return
-        }
-
-        next.ServeHTTP(w, r)
-    })
-}
\ No newline at end of file
@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"rtsback/pkg/utils"
+)
+
+func TestAuthenticate(t *testing.T) {
+	utils.SetJWTSecret([]byte("test-secret-that-is-at-least-32-bytes"))
+	defer SetRevocationChecker(nil)
+
+	var seen Principal
+	handler := Authenticate(utils.RoleAdmin, utils.RoleSuperUser)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = CurrentPrincipal(r)
+	}))
+	token := func(role string) string {
+		s, _, err := utils.GenerateToken("u1", role, "u@example.com", "c1", "s1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + s
+	}
+	challenge, _, err := utils.GenerateChallengeToken("u1", utils.RoleAdmin, "u@example.com", "c1", utils.PurposeTwoFactor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		header  string
+		revoked func(context.Context, *utils.Claims) (bool, error)
+		want    int
+	}{
+		{"admin", token(utils.RoleAdmin), nil, http.StatusOK},
+		{"superuser", token(utils.RoleSuperUser), nil, http.StatusOK},
+		{"other role", token(utils.RoleUser), nil, http.StatusForbidden},
+		{"no header", "", nil, http.StatusUnauthorized},
+		{"no bearer", token(utils.RoleAdmin)[len("Bearer "):], nil, http.StatusUnauthorized},
+		{"challenge token", "Bearer " + challenge, nil, http.StatusUnauthorized},
+		{"revoked", token(utils.RoleAdmin), func(context.Context, *utils.Claims) (bool, error) { return true, nil }, http.StatusUnauthorized},
+		{"revocation unknown", token(utils.RoleAdmin), func(context.Context, *utils.Claims) (bool, error) { return false, errors.New("down") }, http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		SetRevocationChecker(c.revoked)
+		seen = Principal{}
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if c.header != "" {
+			r.Header.Set("Authorization", c.header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != c.want {
+			t.Errorf("%s: status %d, want %d", c.name, w.Code, c.want)
+		}
+		if c.want == http.StatusOK && (seen.ID != "u1" || seen.CompanyID != "c1" || seen.SessionID != "s1") {
+			t.Errorf("%s: principal %+v", c.name, seen)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package middlewares
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoLimiter sayaçları bir koleksiyonda tutar; birden fazla sunucu
+// örneği aynı sınırları paylaşabilir
+type MongoLimiter struct {
+	policy     LimiterPolicy
+	collection *mongo.Collection
+}
+
+// NewMongoLimiter verilen koleksiyonu kullanan bir sayaç oluşturur
+func NewMongoLimiter(collection *mongo.Collection, policy LimiterPolicy) *MongoLimiter {
+	return &MongoLimiter{policy: policy, collection: collection}
+}
+
+func (l *MongoLimiter) load(ctx context.Context, key string) (AttemptState, error) {
+	var state AttemptState
+	err := l.collection.FindOne(ctx, bson.M{"_id": key}).Decode(&state)
+	if err == mongo.ErrNoDocuments {
+		return AttemptState{}, nil
+	}
+	return state, err
+}
+
+func (l *MongoLimiter) Check(ctx context.Context, key string) (time.Duration, error) {
+	state, err := l.load(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	return l.policy.RetryAfter(state, time.Now()), nil
+}
+
+func (l *MongoLimiter) Fail(ctx context.Context, key string) (bool, error) {
+	now := time.Now()
+
+	// Pencere dolduysa ve kilit yoksa sayacı sıfırla
+	_, err := l.collection.UpdateOne(ctx,
+		bson.M{
+			"_id":          key,
+			"last_failure": bson.M{"$lt": now.Add(-l.policy.Window)},
+			"$or": bson.A{
+				bson.M{"locked_until": bson.M{"$exists": false}},
+				bson.M{"locked_until": bson.M{"$lte": now}},
+			},
+		},
+		bson.M{"$set": bson.M{"failures": 0}, "$unset": bson.M{"locked_until": ""}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	// Sayacı atomik olarak artır
+	var state AttemptState
+	err = l.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": key},
+		bson.M{"$inc": bson.M{"failures": 1}, "$set": bson.M{"last_failure": now}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&state)
+	if err != nil {
+		return false, err
+	}
+
+	if l.policy.MaxAttempts == 0 || state.Failures < l.policy.MaxAttempts || now.Before(state.LockedUntil) {
+		return false, nil
+	}
+
+	// Kilidi yalnızca bir istek koyar, böylece olay bir kez kaydedilir
+	result, err := l.collection.UpdateOne(ctx,
+		bson.M{
+			"_id": key,
+			"$or": bson.A{
+				bson.M{"locked_until": bson.M{"$exists": false}},
+				bson.M{"locked_until": bson.M{"$lte": now}},
+			},
+		},
+		bson.M{"$set": bson.M{"locked_until": now.Add(l.policy.LockoutDuration)}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (l *MongoLimiter) Reset(ctx context.Context, key string) error {
+	_, err := l.collection.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+)
+
+// Principal token'dan çözülen, isteği yapan kimliktir
+type Principal struct {
+	ID        string
+	Email     string
+	Role      string
+	CompanyID string
+	SessionID string
+}
+
+type principalKey struct{}
+
+// WithPrincipal kimliği context'e ekler
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext context'teki kimliği döner
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// CurrentPrincipal isteğin kimliğini döner; korumasız rotalarda ok false olur
+func CurrentPrincipal(r *http.Request) (Principal, bool) {
+	return PrincipalFromContext(r.Context())
+}
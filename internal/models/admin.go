@@ -7,10 +7,10 @@ import (
 
 type Admin struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty"`
-	UserID      string             `bson:"user_id,omitempty`
+	UserID      string             `bson:"user_id,omitempty"`
 	Name        string             `bson:"name,omitempty"`
 	Email       string             `bson:"email,omitempty"`
-	Password    string             `bson:"password_hash,omitempty"`
+	Password    string             `bson:"password_hash,omitempty" json:"-"`
 	Phone       string             `bson:"phone,omitempty"`
 	Role        string             `bson:"role,omitempty"`
 	CompanyName string             `bson:"company_name,omitempty"`
@@ -18,4 +18,11 @@ type Admin struct {
 	CompanyID   string             `bson:"company_id,omitempty"`
 	CreatedAt   time.Time          `bson:"created_at,omitempty"`
 	UpdatedAt   time.Time          `bson:"updated_at,omitempty"`
+
+	// İki adımlı doğrulama alanları yanıtlara yazılmaz
+	TOTPSecret        string   `bson:"totp_secret,omitempty" json:"-"`
+	TOTPPendingSecret string   `bson:"totp_pending_secret,omitempty" json:"-"`
+	TOTPEnabled       bool     `bson:"totp_enabled,omitempty"`
+	TOTPLastStep      int64    `bson:"totp_last_step,omitempty" json:"-"`
+	RecoveryCodes     []string `bson:"recovery_codes,omitempty" json:"-"`
 }
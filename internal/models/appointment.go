@@ -6,6 +6,18 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Randevu durumları. Boş slot open'dır; müşteri aldığında requested,
+// sağlayıcı onayladığında confirmed olur. İptal edilen slot reopen ile
+// tekrar açılabilir.
+const (
+	StatusOpen      = "open"
+	StatusRequested = "requested"
+	StatusConfirmed = "confirmed"
+	StatusCancelled = "cancelled"
+	StatusCompleted = "completed"
+	StatusNoShow    = "no_show"
+)
+
 type Appointment struct {
 	ID            primitive.ObjectID `bson:"_id,omitempty"`
 	CustomerEmail string             `bson:"customer_email,omitempty"`
@@ -13,23 +25,133 @@ type Appointment struct {
 	CustomerName  string             `bson:"customer_name,omitempty"`
 	ProviderName  string             `bson:"provider_name,omitempty"`
 	CompanyName   string             `bson:"company_name,omitempty"`
-	CompanyID	  string			 `bson:"company_id,omitempty"`
+	CompanyID     string             `bson:"company_id,omitempty"`
 	Services      []string           `bson:"services,omitempty"`
 	Date          time.Time          `bson:"date,omitempty"`
 	StartTime     time.Time          `bson:"start_time,omitempty"`
 	EndTime       time.Time          `bson:"end_time,omitempty"`
-	Activate      bool               `bson:"activate"`
+	Status        string             `bson:"status"`
+	History       []StatusChange     `bson:"history,omitempty"`
 	Notes         string             `bson:"notes,omitempty"`
 	CreatedAt     time.Time          `bson:"created_at,omitempty"`
 	UpdatedAt     time.Time          `bson:"updated_at,omitempty"`
+	// Version her güncellemede bir artar; eşzamanlı değişiklikleri
+	// yakalamak için güncellemeler okunan sürümle koşullu yapılır
+	Version int64 `bson:"version"`
+	// Müşteri ertelemesi ve iptali: yeni randevu eskisini, eskisi yenisini
+	// gösterir; politika süresi içindeki iptaller geç iptal işaretlenir
+	RescheduleCount  int                `bson:"reschedule_count,omitempty"`
+	RescheduledFrom  primitive.ObjectID `bson:"rescheduled_from,omitempty"`
+	RescheduledTo    primitive.ObjectID `bson:"rescheduled_to,omitempty"`
+	LateCancellation bool               `bson:"late_cancellation,omitempty"`
+	// Çalışma saatlerinden alınan randevularda SlotKey sağlayıcı ve
+	// başlangıç anıdır; SlotActive iken aynı anahtarla ikinci randevu
+	// oluşturulamaz. İptal slotu bırakır. Slot alanları yalnızca
+	// tekilliği korur ve yanıtlara yazılmaz.
+	SlotKey    string `bson:"slot_key,omitempty" json:"-"`
+	SlotActive bool   `bson:"slot_active,omitempty" json:"-"`
+	// BatchID toplu üretilen boş slotların hangi üretimden geldiğidir;
+	// bir üretimin slotları birlikte listelenip silinebilir
+	BatchID string `bson:"batch_id,omitempty"`
+	// TimeZone randevunun şirketinin IANA saat dilimidir. Tarih ve saatler
+	// her zaman UTC an olarak saklanır; yerel gösterim bu dilimle yapılır.
+	TimeZone string `bson:"time_zone,omitempty"`
+	// ServiceIDs katalogdaki hizmetlerdir. Fiyat, para birimi ve süre
+	// rezervasyon anında sağlayıcının fiyatlarından hesaplanıp saklanır;
+	// sonraki fiyat değişiklikleri randevuyu etkilemez.
+	ServiceIDs      []primitive.ObjectID `bson:"service_ids,omitempty"`
+	Price           int64                `bson:"price,omitempty"`
+	Currency        string               `bson:"currency,omitempty"`
+	DurationMinutes int                  `bson:"duration_minutes,omitempty"`
+	// BufferMinutes hizmetlerden sonraki temizlik/hazırlık süresidir.
+	// Randevu, süre ve bu tampon kadar ardışık slotu kaplar.
+	BufferMinutes int `bson:"buffer_minutes,omitempty"`
+	// SlotKeys çalışma saatlerinden birden fazla slot kaplayan randevunun
+	// tüm slot anahtarlarıdır; her biri SlotKey gibi tekildir
+	SlotKeys []string `bson:"slot_keys,omitempty" json:"-"`
+	// MergedSlots uzun bir rezervasyonun kapladığı ardışık boş slot
+	// kayıtlarıdır. Slot tekrar açılırsa bu kayıtlar geri oluşturulur.
+	MergedSlots []MergedSlot `bson:"merged_slots,omitempty" json:"-"`
+	// SeriesID randevunun ait olduğu tekrarlayan seridir; Occurrence
+	// serideki sırasıdır ve 1'den başlar
+	SeriesID   primitive.ObjectID `bson:"series_id,omitempty"`
+	Occurrence int                `bson:"occurrence,omitempty"`
+	// Capacity sıfırdan büyükse randevu birden fazla müşterinin katıldığı
+	// bir grup seansıdır (örneğin yoga dersi). Müşteri alanları boş kalır;
+	// katılımcılar Participants'ta, dolu yer sayısı SeatsTaken'dadır.
+	Title        string        `bson:"title,omitempty"`
+	Capacity     int           `bson:"capacity,omitempty"`
+	SeatsTaken   int           `bson:"seats_taken,omitempty"`
+	Participants []Participant `bson:"participants,omitempty"`
+	// ResourceIDs randevunun hizmetleri için ayrılan kaynaklardır. Ayrılan
+	// zaman resource_reservations'ta tutulur ve iptalde bırakılır.
+	ResourceIDs []primitive.ObjectID `bson:"resource_ids,omitempty"`
+	// CalendarUID ve CalendarSequence takvim dosyalarındaki UID ve
+	// SEQUENCE'ın temelidir. Ertelenen randevunun yeni kaydı eskisinin
+	// UID'sini taşır; böylece takvimdeki etkinlik kopyalanmaz, güncellenir.
+	CalendarUID      string `bson:"calendar_uid,omitempty"`
+	CalendarSequence int64  `bson:"calendar_sequence,omitempty"`
+}
+
+// Grup seansı katılımcı durumları. Yalnızca booked katılımcılar yer tutar.
+const (
+	ParticipantBooked    = "booked"
+	ParticipantCancelled = "cancelled"
+	ParticipantAttended  = "attended"
+	ParticipantNoShow    = "no_show"
+)
+
+// Participant grup seansındaki bir müşterinin kaydıdır. İptal eden
+// müşterinin kaydı silinmez; tekrar katılırsa yeni bir kayıt eklenir.
+type Participant struct {
+	Email     string    `bson:"email"`
+	Name      string    `bson:"name,omitempty"`
+	Status    string    `bson:"status"`
+	BookedAt  time.Time `bson:"booked_at"`
+	UpdatedAt time.Time `bson:"updated_at,omitempty"`
+}
+
+// Seats grup seansında kalan boş yer sayısıdır
+func (a Appointment) Seats() int {
+	if a.Capacity <= a.SeatsTaken {
+		return 0
+	}
+	return a.Capacity - a.SeatsTaken
+}
+
+// MergedSlot rezervasyona katılan boş slot kaydının özgün halidir
+type MergedSlot struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	StartTime time.Time          `bson:"start_time"`
+	EndTime   time.Time          `bson:"end_time"`
+	BatchID   string             `bson:"batch_id,omitempty"`
+}
+
+// StatusChange bir durum geçişinin kaydıdır: ne zaman, kim tarafından ve
+// hangi durumdan hangisine
+type StatusChange struct {
+	From       string    `bson:"from,omitempty"`
+	To         string    `bson:"to"`
+	At         time.Time `bson:"at"`
+	ActorID    string    `bson:"actor_id,omitempty"`
+	ActorEmail string    `bson:"actor_email,omitempty"`
+	ActorRole  string    `bson:"actor_role,omitempty"`
+	Reason     string    `bson:"reason,omitempty"`
 }
 
+// AutoAddRequest toplu boş slot üretim isteğidir. Tarih aralığı verilmezse
+// bugünden itibaren bir ay üretilir; EndDate dahildir.
 type AutoAddRequest struct {
-	ProviderEmail string   `bson:"providerEmail,omitempty"`
-	CompanyName   string   `bson:"companyName,omitempty"`
-	Weekdays      []string `bson:"weekdays,omitempty"`   // Örneğin: ["Monday", "Wednesday"]
-	ShiftStart    string   `bson:"shiftStart,omitempty"` // Örneğin: "09:00"
-	ShiftEnd      string   `bson:"shiftEnd,omitempty"`   // Örneğin: "17:00"
-	Period        int      `bson:"period,omitempty"`     // Dakika cinsinden periyot, örneğin: 30
-	Activate      bool     `bson:"activate"`
+	ProviderEmail string      `bson:"providerEmail,omitempty"`
+	CompanyName   string      `bson:"companyName,omitempty"`
+	Weekdays      []string    `bson:"weekdays,omitempty"`      // Örneğin: ["Monday", "Wednesday"]
+	ShiftStart    string      `bson:"shiftStart,omitempty"`    // Örneğin: "09:00"
+	ShiftEnd      string      `bson:"shiftEnd,omitempty"`      // Örneğin: "17:00"
+	Period        int         `bson:"period,omitempty"`        // Dakika cinsinden periyot, örneğin: 30
+	StartDate     string      `bson:"startDate,omitempty"`     // Örneğin: "2024-05-01"
+	EndDate       string      `bson:"endDate,omitempty"`       // Örneğin: "2024-05-31"
+	ExcludedDates []string    `bson:"excludedDates,omitempty"` // Slot üretilmeyecek günler, YYYY-MM-DD
+	Breaks        []TimeRange `bson:"breaks,omitempty"`        // Vardiya içindeki molalar
+	DryRun        bool        `bson:"dryRun,omitempty"`        // true ise kaydetmeden üretilecek slotları döner
+	Activate      bool        `bson:"activate"`
 }
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tekrarlayan randevu serisinin durumları
+const (
+	SeriesActive    = "active"
+	SeriesCancelled = "cancelled"
+)
+
+// AppointmentSeries bir müşterinin aynı sağlayıcıdan düzenli aralıklarla
+// aldığı randevuların kuralıdır. Rule RFC 5545 RRULE'un bir alt kümesidir,
+// örneğin "FREQ=WEEKLY;INTERVAL=2;COUNT=10". Start ilk randevunun UTC
+// anıdır; sonraki randevular şirketin saat diliminde aynı saate denk gelir.
+// Serinin randevuları SeriesID ile bu kayda bağlanır.
+type AppointmentSeries struct {
+	ID            primitive.ObjectID   `bson:"_id,omitempty"`
+	CustomerEmail string               `bson:"customer_email"`
+	CustomerName  string               `bson:"customer_name,omitempty"`
+	ProviderEmail string               `bson:"provider_email"`
+	CompanyID     string               `bson:"company_id,omitempty"`
+	Rule          string               `bson:"rule"`
+	Start         time.Time            `bson:"start"`
+	TimeZone      string               `bson:"time_zone,omitempty"`
+	Services      []string             `bson:"services,omitempty"`
+	ServiceIDs    []primitive.ObjectID `bson:"service_ids,omitempty"`
+	Status        string               `bson:"status"`
+	// EndsBefore "bu ve sonrakiler" iptalinde serinin kesildiği andır
+	EndsBefore time.Time `bson:"ends_before,omitempty"`
+	CreatedBy  string    `bson:"created_by,omitempty"`
+	CreatedAt  time.Time `bson:"created_at,omitempty"`
+	UpdatedAt  time.Time `bson:"updated_at,omitempty"`
+}
@@ -1 +1,26 @@
-package models
\ No newline at end of file
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken `auth` koleksiyonunda tutulan yenileme token kaydıdır.
+// Token'ın kendisi değil SHA-256 özeti saklanır. Aynı girişten türeyen
+// tüm token'lar aynı FamilyID'yi paylaşır; ailede iptal edilmemiş bir
+// token kalmadığında o oturumun erişim token'ları da geçersiz sayılır.
+type RefreshToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	TokenHash  string             `bson:"token_hash"`
+	FamilyID   string             `bson:"family_id"`
+	Subject    string             `bson:"subject"`
+	Role       string             `bson:"role"`
+	Email      string             `bson:"email,omitempty"`
+	CompanyID  string             `bson:"company_id,omitempty"`
+	Revoked    bool               `bson:"revoked"`
+	RevokedAt  time.Time          `bson:"revoked_at,omitempty"`
+	ReplacedBy primitive.ObjectID `bson:"replaced_by,omitempty"`
+	ExpiresAt  time.Time          `bson:"expires_at"`
+	CreatedAt  time.Time          `bson:"created_at,omitempty"`
+}
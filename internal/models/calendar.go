@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CalendarFeed bir sağlayıcının ya da müşterinin iCalendar aboneliğidir.
+// Hesap başına tek kayıt olur; kaydın ID'si hesabın ID'sidir. Token'ın
+// kendisi değil SHA-256 özeti saklanır; yeni token eskisini geçersiz kılar.
+type CalendarFeed struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	TokenHash string             `bson:"token_hash"`
+	Role      string             `bson:"role"`
+	Email     string             `bson:"email"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// BusySource sağlayıcının başka bir yerdeki takvimidir, örneğin kişisel
+// takvimi. Location takvimin düzenli olarak yeniden okunduğu dosya yolu
+// ya da adresidir; yüklenen dosyalarda boştur ve dosya Data'da saklanır.
+// Takvimdeki etkinlikler BusyBlock olarak saklanır.
+type BusySource struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	ProviderID    primitive.ObjectID `bson:"provider_id"`
+	ProviderEmail string             `bson:"provider_email"`
+	CompanyID     string             `bson:"company_id,omitempty"`
+	Name          string             `bson:"name"`
+	Location      string             `bson:"location,omitempty"`
+	Data          []byte             `bson:"data,omitempty"`
+	Blocks        int                `bson:"blocks"`
+	SyncedAt      time.Time          `bson:"synced_at,omitempty"`
+	SyncError     string             `bson:"sync_error,omitempty"`
+	CreatedBy     string             `bson:"created_by,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at,omitempty"`
+}
+
+// BusyBlock dış takvimden alınan, sağlayıcının dolu olduğu aralıktır.
+// Tekrarlayan etkinliklerin her tekrarı ayrı bir bloktur. Start dahil,
+// End hariçtir; bu aralıkla çakışan slotlar müsait gösterilmez.
+type BusyBlock struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	SourceID   primitive.ObjectID `bson:"source_id"`
+	ProviderID primitive.ObjectID `bson:"provider_id"`
+	UID        string             `bson:"uid,omitempty"`
+	Summary    string             `bson:"summary,omitempty"`
+	Start      time.Time          `bson:"start"`
+	End        time.Time          `bson:"end"`
+}
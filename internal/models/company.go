@@ -17,5 +17,22 @@ type Company struct {
 	UpdatedAt       time.Time          `bson:"updated_at,omitempty"`
 	ManagersNumber  int                `bson:"managers_number, omitempty"`
 	ProvidersNumber int                `bson:"providers_number, omitempty"`
-	Services        []string           `bson:"services,omitempty`
+	Services        []string           `bson:"services,omitempty"`
+	RequireAdmin2FA bool               `bson:"require_admin_2fa,omitempty"`
+	BookingPolicy   *BookingPolicy     `bson:"booking_policy,omitempty"`
+	// TimeZone şirketin IANA saat dilimidir, örneğin "Europe/Istanbul".
+	// Boşsa ayarlardaki varsayılan dilim kullanılır.
+	TimeZone string `bson:"time_zone,omitempty"`
+}
+
+// BookingPolicy müşterilerin randevu iptal ve erteleme kurallarıdır.
+// Politikası olmayan şirketlerde kısıtlama yoktur.
+type BookingPolicy struct {
+	// MinNoticeHours randevuya bu kadar saatten az kala erteleme yapılamaz
+	// ve iptaller geç iptal olarak işaretlenir
+	MinNoticeHours int `bson:"min_notice_hours"`
+	// MaxReschedules bir randevunun en fazla kaç kez ertelenebileceğidir; 0 sınırsız
+	MaxReschedules int `bson:"max_reschedules"`
+	// BlockLateCancel true ise süre içindeki iptaller reddedilir
+	BlockLateCancel bool `bson:"block_late_cancel"`
 }
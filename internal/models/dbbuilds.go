@@ -8,13 +8,13 @@ import (
     "go.mongodb.org/mongo-driver/mongo"
 )
 
-var collections = []string{"admin", "appointment", "auth", "company", "manager", "provider", "user", "services", "verification"}
+var collections = []string{"admin", "appointment", "auth", "company", "manager", "provider", "user", "services", "verification", "login_attempts", "security_events", "password_resets", "working_hours", "time_off", "closures", "waitlist", "appointment_series", "resources", "resource_reservations", "calendar_feeds", "busy_sources", "busy_blocks"}
 
-func EnsureCollections(db *mongo.Client) {
+func EnsureCollections(db *mongo.Client, dbName string) {
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
     defer cancel()
 
-    database := db.Database("rtsdatabase")
+    database := db.Database(dbName)
 
     for _, colName := range collections {
         if err := CreateCollectionIfNotExists(database, colName, ctx); err != nil {
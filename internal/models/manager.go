@@ -9,7 +9,7 @@ type Manager struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty"`
 	Name        string             `bson:"name,omitempty"`
 	Email       string             `bson:"email,omitempty"`
-	Password    string             `bson:"password,omitempty"`
+	Password    string             `bson:"password,omitempty" json:"-"`
 	Phone       string             `bson:"phone,omitempty"`
 	Role        string             `bson:"role,omitempty"`
 	Services    []string           `bson:"services,omitempty"`
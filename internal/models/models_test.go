@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPasswordHashesNotEncoded(t *testing.T) {
+	const hash = "$2a$10$secret-hash"
+	for name, v := range map[string]interface{}{
+		"user":     User{Email: "u@example.com", PasswordHash: hash, TOTPSecret: hash},
+		"provider": Provider{Email: "p@example.com", Password: hash},
+		"admin":    Admin{Email: "a@example.com", Password: hash, TOTPSecret: hash},
+		"manager":  Manager{Email: "m@example.com", Password: hash},
+	} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), hash) {
+			t.Errorf("%s JSON contains the password hash: %s", name, data)
+		}
+		if !strings.Contains(string(data), "@example.com") {
+			t.Errorf("%s JSON lost its other fields: %s", name, data)
+		}
+	}
+}
+
+func TestAppointmentSlotFieldsNotEncoded(t *testing.T) {
+	data, err := json.Marshal(Appointment{
+		ProviderEmail: "p@example.com",
+		SlotKey:       "slot-key",
+		SlotKeys:      []string{"slot-key"},
+		SlotActive:    true,
+		MergedSlots:   []MergedSlot{{}},
+		History:       []StatusChange{{To: StatusRequested}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"SlotKey", "SlotKeys", "SlotActive", "MergedSlots", "slot-key"} {
+		if strings.Contains(string(data), field) {
+			t.Errorf("appointment JSON contains %s: %s", field, data)
+		}
+	}
+	// Durum geçmişi (kim, ne zaman) yanıtlarda bilerek yer alır
+	if !strings.Contains(string(data), "History") {
+		t.Errorf("appointment JSON lost its history: %s", data)
+	}
+}
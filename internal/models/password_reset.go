@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PasswordReset tek kullanımlık şifre sıfırlama token kaydıdır.
+// Token'ın kendisi değil SHA-256 özeti saklanır.
+type PasswordReset struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	TokenHash string             `bson:"token_hash"`
+	AccountID primitive.ObjectID `bson:"account_id"`
+	Role      string             `bson:"role"`
+	Email     string             `bson:"email"`
+	Used      bool               `bson:"used"`
+	UsedAt    time.Time          `bson:"used_at,omitempty"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+	CreatedAt time.Time          `bson:"created_at,omitempty"`
+}
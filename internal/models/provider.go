@@ -10,7 +10,7 @@ type Provider struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty"`
 	Name        string             `bson:"name,omitempty"`
 	Email       string             `bson:"email,omitempty"`
-	Password    string             `bson:"password_hash,omitempty"`
+	Password    string             `bson:"password_hash,omitempty" json:"-"`
 	Phone       string             `bson:"phone,omitempty"`
 	Role        string             `bson:"role,omitempty"`
 	Services    []string           `bson:"services,omitempty"`
@@ -18,4 +18,7 @@ type Provider struct {
 	CompanyId   string             `bson:"company_id,omitempty"`
 	CreatedAt   time.Time          `bson:"created_at,omitempty"`
 	UpdatedAt   time.Time          `bson:"updated_at,omitempty"`
+	// Offerings sağlayıcının katalogdan sunduğu hizmetlerdir; Services
+	// yalnızca eski istemciler için serbest metin listesidir
+	Offerings []Offering `bson:"offerings,omitempty"`
 }
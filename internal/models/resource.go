@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Resource şirketin sağlayıcılar arasında paylaşılan bir kaynağıdır: oda,
+// koltuk ya da cihaz gibi. Type hizmetlerin istediği kaynak türüdür
+// (örneğin "room"). Hours boşsa kaynak sağlayıcıların çalıştığı her saatte
+// kullanılabilir; doluysa yalnızca bu saatlerde ayrılabilir.
+type Resource struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	CompanyID   string             `bson:"company_id,omitempty"`
+	Name        string             `bson:"name,omitempty"`
+	Type        string             `bson:"type,omitempty"`
+	Description string             `bson:"description,omitempty"`
+	Active      bool               `bson:"active"`
+	Hours       []WorkingHoursRule `bson:"hours,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at,omitempty"`
+	UpdatedAt   time.Time          `bson:"updated_at,omitempty"`
+}
+
+// ResourceReservation bir randevunun kaynağı [Start, End) aralığında
+// tutmasıdır. Keys aralığın kapladığı zaman dilimleridir; her biri kaynak
+// başına tekildir, böylece aynı kaynağı aynı anda iki randevu tutamaz.
+type ResourceReservation struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	ResourceID    primitive.ObjectID `bson:"resource_id"`
+	AppointmentID primitive.ObjectID `bson:"appointment_id"`
+	CompanyID     string             `bson:"company_id,omitempty"`
+	ProviderEmail string             `bson:"provider_email,omitempty"`
+	Start         time.Time          `bson:"start"`
+	End           time.Time          `bson:"end"`
+	Keys          []string           `bson:"keys"`
+	CreatedAt     time.Time          `bson:"created_at,omitempty"`
+}
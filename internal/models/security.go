@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Güvenlik olay türleri
+const (
+	SecurityEventLockout = "lockout"
+	SecurityEventUnlock  = "unlock"
+)
+
+// SecurityEvent kilitleme ve kilit açma gibi olayların kaydıdır
+type SecurityEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Type      string             `bson:"type,omitempty"`
+	Scope     string             `bson:"scope,omitempty"` // login, verification
+	Key       string             `bson:"key,omitempty"`
+	Email     string             `bson:"email,omitempty"`
+	IP        string             `bson:"ip,omitempty"`
+	ActorID   string             `bson:"actor_id,omitempty"`
+	CreatedAt time.Time          `bson:"created_at,omitempty"`
+}
@@ -1,14 +1,37 @@
 package models
 
 import (
-	"go.mongodb.org/mongo-driver/bson/primitive"
 	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Service şirketin hizmet kataloğundaki bir kayıttır. Fiyat para biriminin
+// en küçük biriminde tutulur (örneğin 15000 = 150,00 TRY).
 type Service struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty"`
-	ServiceName string             `bson:"service_name,omitempty"`
-	ProviderID  string             `bson:"provider_id,omitempty"`
-	Price       int                `bson:"price,omitempty"`
-	CreatedTime time.Time          `bson:"created_time,omitempty"`
+	ID              primitive.ObjectID `bson:"_id,omitempty"`
+	CompanyID       string             `bson:"company_id,omitempty"`
+	Name            string             `bson:"name,omitempty"`
+	Description     string             `bson:"description,omitempty"`
+	DurationMinutes int                `bson:"duration_minutes"`
+	Price           int64              `bson:"price"`
+	Currency        string             `bson:"currency,omitempty"` // ISO 4217, örneğin "TRY"
+	Category        string             `bson:"category,omitempty"`
+	Active          bool               `bson:"active"`
+	CreatedAt       time.Time          `bson:"created_at,omitempty"`
+	UpdatedAt       time.Time          `bson:"updated_at,omitempty"`
+	// BufferMinutes hizmetten sonra sağlayıcının temizlik ya da hazırlık
+	// için ayırdığı süredir; rezervasyonun kapladığı süreye eklenir
+	BufferMinutes int `bson:"buffer_minutes,omitempty"`
+	// ResourceTypes hizmet için ayrılması gereken kaynak türleridir
+	// (örneğin "room"); rezervasyon her tür için boş bir kaynak tutar
+	ResourceTypes []string `bson:"resource_types,omitempty"`
+}
+
+// Offering sağlayıcının katalogdan sunduğu bir hizmettir. Fiyat ve süre
+// verilmezse katalogdaki değerler geçerlidir.
+type Offering struct {
+	ServiceID       primitive.ObjectID `bson:"service_id"`
+	Price           *int64             `bson:"price,omitempty"`
+	DurationMinutes *int               `bson:"duration_minutes,omitempty"`
 }
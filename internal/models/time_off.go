@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TimeOff sağlayıcının izinli olduğu zaman aralığıdır. Start dahil, End
+// hariçtir; ikisi de UTC an olarak saklanır. Bu aralıktaki slotlar
+// üretilmez ve müsait gösterilmez.
+type TimeOff struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	ProviderID    primitive.ObjectID `bson:"provider_id"`
+	ProviderEmail string             `bson:"provider_email,omitempty"`
+	CompanyID     string             `bson:"company_id,omitempty"`
+	Start         time.Time          `bson:"start"`
+	End           time.Time          `bson:"end"`
+	Reason        string             `bson:"reason,omitempty"`
+	CreatedBy     string             `bson:"created_by,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at,omitempty"`
+}
+
+// Kapalı gün kaynakları
+const (
+	ClosureManual     = "manual"
+	ClosureTRHolidays = "tr_public_holidays"
+)
+
+// Closure şirketin kapalı olduğu takvim günüdür, örneğin resmi tatil. Date
+// günün UTC gece yarısıdır ve şirketin saat diliminde o tarih olarak
+// yorumlanır. Bir şirketin aynı gün için tek kaydı olur.
+type Closure struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	CompanyID string             `bson:"company_id"`
+	Date      time.Time          `bson:"date"`
+	Name      string             `bson:"name,omitempty"`
+	Source    string             `bson:"source,omitempty"`
+	CreatedAt time.Time          `bson:"created_at,omitempty"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+var locations sync.Map
+
+// Location IANA saat dilimi adını çözer ve sonucu önbelleğe alır. Boş ya
+// da tanınmayan adlarda UTC döner.
+func Location(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	locations.Store(name, loc)
+	return loc
+}
+
+// Location randevunun saat dilimidir
+func (a Appointment) Location() *time.Location {
+	return Location(a.TimeZone)
+}
+
+// LocalTimes randevu zamanlarının şirketin saat dilimindeki gösterimidir
+type LocalTimes struct {
+	TimeZone  string
+	Date      string // 2006-01-02
+	StartTime string // RFC3339, dilimin ofsetiyle
+	EndTime   string
+}
+
+// appointmentJSON MarshalJSON'ın kendini çağırmaması için kullanılır
+type appointmentJSON Appointment
+
+// MarshalJSON randevuyu UTC zamanlarla ve ayrıca Local alanında şirketin
+// saat dilimindeki karşılıklarıyla yazar
+func (a Appointment) MarshalJSON() ([]byte, error) {
+	loc := a.Location()
+	out := struct {
+		appointmentJSON
+		Local LocalTimes
+	}{appointmentJSON: appointmentJSON(a)}
+
+	out.Date = a.Date.UTC()
+	out.StartTime = a.StartTime.UTC()
+	out.EndTime = a.EndTime.UTC()
+	out.Local.TimeZone = loc.String()
+	if !a.StartTime.IsZero() {
+		out.Local.Date = a.StartTime.In(loc).Format("2006-01-02")
+		out.Local.StartTime = a.StartTime.In(loc).Format(time.RFC3339)
+	}
+	if !a.EndTime.IsZero() {
+		out.Local.EndTime = a.EndTime.In(loc).Format(time.RFC3339)
+	}
+	return json.Marshal(out)
+}
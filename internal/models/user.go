@@ -11,7 +11,7 @@ type User struct {
 	Name              string             `bson:"name,omitempty"`
 	Email             string             `bson:"email,omitempty"`
 	EmailVerification bool               `bson:"email_verification,omitempty"`
-	PasswordHash      string             `bson:"password_hash,omitempty"`
+	PasswordHash      string             `bson:"password_hash,omitempty" json:"-"`
 	Role              string             `bson:"role,omitempty"`
 	Phone             string             `bson:"phone,omitempty"`
 	PhoneVerification bool               `bson:"phone_verification,omitempty"`
@@ -19,4 +19,11 @@ type User struct {
 	CreatedAt         time.Time          `bson:"created_at,omitempty"`
 	UpdatedAt         time.Time          `bson:"updated_at,omitempty"`
 	SuperUser         bool               `bson:"super_user,omitempty"`
+
+	// İki adımlı doğrulama alanları yanıtlara yazılmaz
+	TOTPSecret        string   `bson:"totp_secret,omitempty" json:"-"`
+	TOTPPendingSecret string   `bson:"totp_pending_secret,omitempty" json:"-"`
+	TOTPEnabled       bool     `bson:"totp_enabled,omitempty"`
+	TOTPLastStep      int64    `bson:"totp_last_step,omitempty" json:"-"`
+	RecoveryCodes     []string `bson:"recovery_codes,omitempty" json:"-"`
 }
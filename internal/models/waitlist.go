@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Bekleme listesi kaydının durumları
+const (
+	WaitlistWaiting   = "waiting"   // Sırada, teklif bekliyor
+	WaitlistOffered   = "offered"   // Boşalan slot teklif edildi, yanıt bekleniyor
+	WaitlistBooked    = "booked"    // Teklif kabul edildi ve slot alındı
+	WaitlistExpired   = "expired"   // Teklif süresinde kabul edilmedi
+	WaitlistCancelled = "cancelled" // Müşteri listeden çıktı
+)
+
+// WaitlistEntry müşterinin dolu bir sağlayıcı ya da şirket için bekleme
+// listesi kaydıdır. ProviderEmail boşsa şirketin herhangi bir sağlayıcısı
+// kabul edilir. From ve To dahil takvim günleridir, UTC gece yarısı olarak
+// saklanır ve şirketin saat diliminde yorumlanır. Teklif token'ının
+// kendisi değil SHA-256 özeti saklanır.
+type WaitlistEntry struct {
+	ID            primitive.ObjectID   `bson:"_id,omitempty"`
+	CustomerEmail string               `bson:"customer_email"`
+	CustomerName  string               `bson:"customer_name,omitempty"`
+	CompanyID     string               `bson:"company_id"`
+	ProviderEmail string               `bson:"provider_email,omitempty"`
+	From          time.Time            `bson:"from"`
+	To            time.Time            `bson:"to"`
+	ServiceIDs    []primitive.ObjectID `bson:"service_ids,omitempty"`
+	Status        string               `bson:"status"`
+
+	// Teklif edilen slot. OfferAppointmentID önceden üretilmiş slot
+	// kaydıdır; boşsa slot çalışma saatlerinden hesaplanır.
+	OfferTokenHash     string             `bson:"offer_token_hash,omitempty"`
+	OfferAppointmentID primitive.ObjectID `bson:"offer_appointment_id,omitempty"`
+	OfferProviderEmail string             `bson:"offer_provider_email,omitempty"`
+	OfferStart         time.Time          `bson:"offer_start,omitempty"`
+	OfferEnd           time.Time          `bson:"offer_end,omitempty"`
+	OfferExpiresAt     time.Time          `bson:"offer_expires_at,omitempty"`
+
+	// AppointmentID teklif kabul edildiğinde alınan randevudur
+	AppointmentID primitive.ObjectID `bson:"appointment_id,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at,omitempty"`
+	UpdatedAt     time.Time          `bson:"updated_at,omitempty"`
+}
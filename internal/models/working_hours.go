@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WorkingHours sağlayıcının haftalık çalışma saatleri kurallarıdır. Boş
+// slotlar bu kurallardan istek anında hesaplanır; randevu kaydı yalnızca
+// müşteri bir slotu aldığında oluşur.
+type WorkingHours struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"` // sağlayıcının ID'si
+	ProviderEmail string             `bson:"provider_email,omitempty"`
+	SlotMinutes   int                `bson:"slot_minutes"`
+	Rules         []WorkingHoursRule `bson:"rules"`
+	UpdatedAt     time.Time          `bson:"updated_at,omitempty"`
+}
+
+// WorkingHoursRule seçilen hafta günlerindeki çalışma aralıklarıdır. Bir
+// güne birden fazla kural uyuyorsa en geç başlayan geçerlilik aralığı
+// kazanır; aynı başlangıçlı kuralların aralıkları birleştirilir.
+type WorkingHoursRule struct {
+	Weekdays      []string    `bson:"weekdays"` // Örneğin: ["Monday", "Wednesday"]
+	Intervals     []TimeRange `bson:"intervals"`
+	Breaks        []TimeRange `bson:"breaks,omitempty"`
+	EffectiveFrom time.Time   `bson:"effective_from,omitempty"` // boşsa her zaman
+	EffectiveTo   time.Time   `bson:"effective_to,omitempty"`   // dahil, boşsa süresiz
+}
+
+// TimeRange gün içindeki bir saat aralığıdır, örneğin 09:00-12:30
+type TimeRange struct {
+	Start string `bson:"start"`
+	End   string `bson:"end"`
+}
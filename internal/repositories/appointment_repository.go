@@ -1 +1,585 @@
-package repositories
\ No newline at end of file
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"rtsback/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AppointmentFilter randevu sorgusudur. Boş alanlar filtreye katılmaz;
+// From dahil, To hariçtir ve randevunun `date` alanına uygulanır.
+type AppointmentFilter struct {
+	CustomerEmail string
+	ProviderEmail string
+	CompanyID     string
+	From          time.Time
+	To            time.Time
+	Status        string
+	BatchID       string
+	SeriesID      primitive.ObjectID
+}
+
+func (f AppointmentFilter) bson() bson.M {
+	filter := bson.M{}
+	if f.CustomerEmail != "" {
+		filter["customer_email"] = f.CustomerEmail
+	}
+	if f.ProviderEmail != "" {
+		filter["provider_email"] = f.ProviderEmail
+	}
+	if f.CompanyID != "" {
+		filter["company_id"] = f.CompanyID
+	}
+	if !f.From.IsZero() || !f.To.IsZero() {
+		date := bson.M{}
+		if !f.From.IsZero() {
+			date["$gte"] = f.From
+		}
+		if !f.To.IsZero() {
+			date["$lt"] = f.To
+		}
+		filter["date"] = date
+	}
+	if f.Status != "" {
+		filter["status"] = f.Status
+	}
+	if f.BatchID != "" {
+		filter["batch_id"] = f.BatchID
+	}
+	if !f.SeriesID.IsZero() {
+		filter["series_id"] = f.SeriesID
+	}
+	return filter
+}
+
+func (f AppointmentFilter) match(doc bson.M) bool {
+	if f.CustomerEmail != "" && docString(doc, "customer_email") != f.CustomerEmail {
+		return false
+	}
+	if f.ProviderEmail != "" && docString(doc, "provider_email") != f.ProviderEmail {
+		return false
+	}
+	if f.CompanyID != "" && docString(doc, "company_id") != f.CompanyID {
+		return false
+	}
+	if !f.From.IsZero() || !f.To.IsZero() {
+		// Mongo'da tarih koşulu alanı olmayan kayıtlarla eşleşmez
+		date, ok := docTime(doc, "date")
+		if !ok {
+			return false
+		}
+		if !f.From.IsZero() && date.Before(f.From) {
+			return false
+		}
+		if !f.To.IsZero() && !date.Before(f.To) {
+			return false
+		}
+	}
+	if f.Status != "" && docString(doc, "status") != f.Status {
+		return false
+	}
+	if f.BatchID != "" && docString(doc, "batch_id") != f.BatchID {
+		return false
+	}
+	if !f.SeriesID.IsZero() {
+		if id, _ := doc["series_id"].(primitive.ObjectID); id != f.SeriesID {
+			return false
+		}
+	}
+	return true
+}
+
+// AppointmentCondition koşullu güncellemenin ön şartıdır. Version nil
+// değilse kaydın sürümü eşleşmeli, Statuses boş değilse kaydın durumu
+// bunlardan biri olmalıdır.
+type AppointmentCondition struct {
+	Version  *int64
+	Statuses []string
+}
+
+func (c AppointmentCondition) bson(id primitive.ObjectID) bson.M {
+	filter := bson.M{"_id": id}
+	if c.Version != nil {
+		if *c.Version == 0 {
+			// Sürüm alanı eklenmeden önceki kayıtlarda alan yoktur
+			filter["version"] = bson.M{"$in": bson.A{0, nil}}
+		} else {
+			filter["version"] = *c.Version
+		}
+	}
+	if len(c.Statuses) > 0 {
+		filter["status"] = bson.M{"$in": c.Statuses}
+	}
+	return filter
+}
+
+func (c AppointmentCondition) match(id primitive.ObjectID) func(bson.M) bool {
+	return func(doc bson.M) bool {
+		if doc["_id"] != id {
+			return false
+		}
+		if c.Version != nil {
+			version, _ := docInt64(doc, "version")
+			if version != *c.Version {
+				return false
+			}
+		}
+		return len(c.Statuses) == 0 || containsString(c.Statuses, docString(doc, "status"))
+	}
+}
+
+type AppointmentRepository interface {
+	Create(ctx context.Context, appointment models.Appointment) error
+	// CreateMany kayıtları tek bir sırasız toplu yazmayla ekler ve
+	// eklenenleri döner. Tekil anahtarı başka bir kayıtta dolu olanlar
+	// hata sayılmaz, atlanır.
+	CreateMany(ctx context.Context, appointments []models.Appointment) ([]models.Appointment, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.Appointment, error)
+	Find(ctx context.Context, filter AppointmentFilter) ([]models.Appointment, error)
+	// UpdateIf koşul sağlanıyorsa alanları yazar ve sürümü bir artırır.
+	// Kontrol ve yazma tek işlemdir; koşula uyan kayıt yoksa false döner.
+	UpdateIf(ctx context.Context, id primitive.ObjectID, cond AppointmentCondition, fields Fields) (bool, error)
+	// Transition UpdateIf gibidir; ayrıca durumu change.To yapar ve geçişi
+	// geçmişe ekler
+	Transition(ctx context.Context, id primitive.ObjectID, cond AppointmentCondition, fields Fields, change models.StatusChange) (bool, error)
+	// MigrateStatuses durum alanı olmayan eski kayıtlara activate değerine
+	// göre durum atar ve activate alanını kaldırır. Güncellenen kayıt
+	// sayısını döner; tekrar çalıştırmak güvenlidir.
+	MigrateStatuses(ctx context.Context) (int64, error)
+	// FindWithoutTimeZone saat dilimi atanmamış eski kayıtları döner
+	FindWithoutTimeZone(ctx context.Context) ([]models.Appointment, error)
+	// SetCompanyTimeZone şirketin tüm randevularının saat dilimini değiştirir.
+	// Saklanan anlar değişmez, yalnızca yerel gösterim değişir.
+	SetCompanyTimeZone(ctx context.Context, companyID, zone string) (int64, error)
+	DeleteByID(ctx context.Context, id primitive.ObjectID) error
+	// DeleteIf koşul sağlanıyorsa kaydı siler; koşula uyan kayıt yoksa false döner
+	DeleteIf(ctx context.Context, id primitive.ObjectID, cond AppointmentCondition) (bool, error)
+	// DeleteMany filtreye uyan tüm kayıtları siler ve silinen sayısını döner
+	DeleteMany(ctx context.Context, filter AppointmentFilter) (int64, error)
+	// FindByParticipant e-postanın katılımcı olarak kayıtlı olduğu grup
+	// seanslarını döner
+	FindByParticipant(ctx context.Context, email string) ([]models.Appointment, error)
+	// AddParticipant grup seansında boş yer varsa ve e-posta zaten booked
+	// değilse katılımcıyı ekler ve dolu yer sayısını bir artırır. Kontrol ve
+	// yazma tek işlemdir; seans dolmuşsa ya da koşul sağlanmazsa false döner.
+	AddParticipant(ctx context.Context, id primitive.ObjectID, cond AppointmentCondition, participant models.Participant) (bool, error)
+	// SetParticipantStatus e-postanın from durumundaki katılımcı kaydını to
+	// durumuna geçirir. to cancelled ise bırakılan yer dolu yer sayısından
+	// düşülür. Böyle bir kayıt yoksa false döner.
+	SetParticipantStatus(ctx context.Context, id primitive.ObjectID, email, from, to string, at time.Time) (bool, error)
+}
+
+type mongoAppointments struct{ c *mongo.Collection }
+
+func (r *mongoAppointments) Create(ctx context.Context, appointment models.Appointment) error {
+	return mongoInsert(ctx, r.c, appointment)
+}
+
+func (r *mongoAppointments) CreateMany(ctx context.Context, appointments []models.Appointment) ([]models.Appointment, error) {
+	if len(appointments) == 0 {
+		return nil, nil
+	}
+	docs := make([]interface{}, len(appointments))
+	for i, appointment := range appointments {
+		docs[i] = appointment
+	}
+	_, err := r.c.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if err == nil {
+		return appointments, nil
+	}
+
+	// Yalnızca tekil anahtar hataları atlanır; başka bir hata varsa döner
+	var bulk mongo.BulkWriteException
+	if !errors.As(err, &bulk) || bulk.WriteConcernError != nil {
+		return nil, err
+	}
+	skipped := make(map[int]bool, len(bulk.WriteErrors))
+	for _, e := range bulk.WriteErrors {
+		if !mongo.IsDuplicateKeyError(e) {
+			return nil, err
+		}
+		skipped[e.Index] = true
+	}
+	created := make([]models.Appointment, 0, len(appointments)-len(skipped))
+	for i, appointment := range appointments {
+		if !skipped[i] {
+			created = append(created, appointment)
+		}
+	}
+	return created, nil
+}
+
+func (r *mongoAppointments) FindByID(ctx context.Context, id primitive.ObjectID) (models.Appointment, error) {
+	return mongoFindOne[models.Appointment](ctx, r.c, bson.M{"_id": id})
+}
+
+func (r *mongoAppointments) Find(ctx context.Context, filter AppointmentFilter) ([]models.Appointment, error) {
+	return mongoFindAll[models.Appointment](ctx, r.c, filter.bson())
+}
+
+func (r *mongoAppointments) UpdateIf(ctx context.Context, id primitive.ObjectID, cond AppointmentCondition, fields Fields) (bool, error) {
+	return mongoModified(ctx, r.c, cond.bson(id), bson.M{
+		"$set": bson.M(fields),
+		"$inc": bson.M{"version": 1},
+	})
+}
+
+func (r *mongoAppointments) Transition(ctx context.Context, id primitive.ObjectID, cond AppointmentCondition, fields Fields, change models.StatusChange) (bool, error) {
+	set := bson.M(fields)
+	set["status"] = change.To
+	return mongoModified(ctx, r.c, cond.bson(id), bson.M{
+		"$set":  set,
+		"$push": bson.M{"history": change},
+		"$inc":  bson.M{"version": 1},
+	})
+}
+
+func (r *mongoAppointments) MigrateStatuses(ctx context.Context) (int64, error) {
+	var migrated int64
+	// Önce alınmış slotlar: eski akışta onay adımı olmadığı için confirmed sayılır
+	for _, step := range []struct {
+		activate interface{}
+		status   string
+	}{
+		{true, models.StatusConfirmed},
+		{bson.M{"$ne": true}, models.StatusOpen},
+	} {
+		result, err := r.c.UpdateMany(ctx,
+			bson.M{"status": bson.M{"$exists": false}, "activate": step.activate},
+			bson.M{"$set": bson.M{"status": step.status}, "$unset": bson.M{"activate": ""}},
+		)
+		if err != nil {
+			return migrated, err
+		}
+		migrated += result.ModifiedCount
+	}
+	return migrated, nil
+}
+
+func (r *mongoAppointments) FindWithoutTimeZone(ctx context.Context) ([]models.Appointment, error) {
+	return mongoFindAll[models.Appointment](ctx, r.c, bson.M{"time_zone": bson.M{"$in": bson.A{"", nil}}})
+}
+
+func (r *mongoAppointments) SetCompanyTimeZone(ctx context.Context, companyID, zone string) (int64, error) {
+	result, err := r.c.UpdateMany(ctx, bson.M{"company_id": companyID}, bson.M{"$set": bson.M{"time_zone": zone}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *mongoAppointments) DeleteByID(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.c.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoAppointments) DeleteIf(ctx context.Context, id primitive.ObjectID, cond AppointmentCondition) (bool, error) {
+	result, err := r.c.DeleteOne(ctx, cond.bson(id))
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
+
+func (r *mongoAppointments) DeleteMany(ctx context.Context, filter AppointmentFilter) (int64, error) {
+	result, err := r.c.DeleteMany(ctx, filter.bson())
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *mongoAppointments) FindByParticipant(ctx context.Context, email string) ([]models.Appointment, error) {
+	return mongoFindAll[models.Appointment](ctx, r.c, bson.M{"participants.email": email})
+}
+
+func (r *mongoAppointments) AddParticipant(ctx context.Context, id primitive.ObjectID, cond AppointmentCondition, participant models.Participant) (bool, error) {
+	filter := cond.bson(id)
+	filter["capacity"] = bson.M{"$gt": 0}
+	filter["$expr"] = bson.M{"$lt": bson.A{bson.M{"$ifNull": bson.A{"$seats_taken", 0}}, "$capacity"}}
+	filter["participants"] = bson.M{"$not": bson.M{"$elemMatch": bson.M{
+		"email":  participant.Email,
+		"status": models.ParticipantBooked,
+	}}}
+	res, err := r.c.UpdateOne(ctx, filter, bson.M{
+		"$push": bson.M{"participants": participant},
+		"$inc":  bson.M{"seats_taken": 1, "version": 1},
+		"$set":  bson.M{"updated_at": participant.BookedAt},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *mongoAppointments) SetParticipantStatus(ctx context.Context, id primitive.ObjectID, email, from, to string, at time.Time) (bool, error) {
+	inc := bson.M{"version": 1}
+	if from == models.ParticipantBooked && to == models.ParticipantCancelled {
+		inc["seats_taken"] = -1
+	}
+	// $ işleci elemMatch'e uyan ilk katılımcı kaydını günceller
+	res, err := r.c.UpdateOne(ctx, bson.M{
+		"_id":          id,
+		"participants": bson.M{"$elemMatch": bson.M{"email": email, "status": from}},
+	}, bson.M{
+		"$set": bson.M{
+			"participants.$.status":     to,
+			"participants.$.updated_at": at,
+			"updated_at":                at,
+		},
+		"$inc": inc,
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+type memAppointments struct{ c *memCollection }
+
+func (r *memAppointments) Create(ctx context.Context, appointment models.Appointment) error {
+	if !appointment.SlotActive {
+		return r.c.insert(appointment)
+	}
+	return r.c.insertUnless(appointment, holdsSlot(appointment.SlotKey, appointment.SlotKeys))
+}
+
+// holdsSlot Mongo'daki slot_key ve slot_keys tekil indekslerinin
+// karşılığıdır: kayıt aktifse ve anahtarlardan birini tutuyorsa true döner
+func holdsSlot(key string, keys []string) func(bson.M) bool {
+	return func(doc bson.M) bool {
+		if !docBool(doc, "slot_active") {
+			return false
+		}
+		if docString(doc, "slot_key") == key {
+			return true
+		}
+		for _, k := range docStrings(doc, "slot_keys") {
+			if containsString(keys, k) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (r *memAppointments) CreateMany(ctx context.Context, appointments []models.Appointment) ([]models.Appointment, error) {
+	var created []models.Appointment
+	for _, appointment := range appointments {
+		err := r.Create(ctx, appointment)
+		if err == ErrDuplicate {
+			continue
+		}
+		if err != nil {
+			return created, err
+		}
+		created = append(created, appointment)
+	}
+	return created, nil
+}
+
+func (r *memAppointments) FindByID(ctx context.Context, id primitive.ObjectID) (models.Appointment, error) {
+	return memFindOne[models.Appointment](r.c, byID(id))
+}
+
+func (r *memAppointments) Find(ctx context.Context, filter AppointmentFilter) ([]models.Appointment, error) {
+	return memFindAll[models.Appointment](r.c, filter.match)
+}
+
+func (r *memAppointments) UpdateIf(ctx context.Context, id primitive.ObjectID, cond AppointmentCondition, fields Fields) (bool, error) {
+	var conflict error
+	_, modified, err := r.c.update(cond.match(id), func(doc bson.M) bool {
+		version, _ := docInt64(doc, "version")
+		setFields(doc, fields)
+		// Slot anahtarı değişen aktif kayıt da tekil indekse takılır
+		if key, ok := fields["slot_key"].(string); ok && docBool(doc, "slot_active") {
+			keys, _ := fields["slot_keys"].([]string)
+			taken, err := r.c.otherMatches(id, holdsSlot(key, keys))
+			if err != nil || taken {
+				conflict = err
+				if taken {
+					conflict = ErrDuplicate
+				}
+				return false
+			}
+		}
+		doc["version"] = version + 1
+		return true
+	}, false)
+	if conflict != nil {
+		return false, conflict
+	}
+	return modified == 1, err
+}
+
+func (r *memAppointments) Transition(ctx context.Context, id primitive.ObjectID, cond AppointmentCondition, fields Fields, change models.StatusChange) (bool, error) {
+	entry, err := bson.Marshal(change)
+	if err != nil {
+		return false, err
+	}
+	_, modified, err := r.c.update(cond.match(id), func(doc bson.M) bool {
+		version, _ := docInt64(doc, "version")
+		setFields(doc, fields)
+		history, _ := doc["history"].(bson.A)
+		doc["history"] = append(history, bson.Raw(entry))
+		doc["status"] = change.To
+		doc["version"] = version + 1
+		return true
+	}, false)
+	return modified == 1, err
+}
+
+func (r *memAppointments) MigrateStatuses(ctx context.Context) (int64, error) {
+	_, modified, err := r.c.update(
+		func(doc bson.M) bool { _, ok := doc["status"]; return !ok },
+		func(doc bson.M) bool {
+			status := models.StatusOpen
+			if docBool(doc, "activate") {
+				status = models.StatusConfirmed
+			}
+			doc["status"] = status
+			unsetFields(doc, "activate")
+			return true
+		}, true)
+	return int64(modified), err
+}
+
+func (r *memAppointments) FindWithoutTimeZone(ctx context.Context) ([]models.Appointment, error) {
+	return memFindAll[models.Appointment](r.c, func(doc bson.M) bool {
+		return docString(doc, "time_zone") == ""
+	})
+}
+
+func (r *memAppointments) SetCompanyTimeZone(ctx context.Context, companyID, zone string) (int64, error) {
+	_, modified, err := r.c.update(
+		func(doc bson.M) bool { return docString(doc, "company_id") == companyID },
+		func(doc bson.M) bool {
+			if docString(doc, "time_zone") == zone {
+				return false
+			}
+			doc["time_zone"] = zone
+			return true
+		}, true)
+	return int64(modified), err
+}
+
+func (r *memAppointments) DeleteByID(ctx context.Context, id primitive.ObjectID) error {
+	deleted, err := r.c.delete(byID(id))
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memAppointments) DeleteIf(ctx context.Context, id primitive.ObjectID, cond AppointmentCondition) (bool, error) {
+	return r.c.delete(cond.match(id))
+}
+
+func (r *memAppointments) DeleteMany(ctx context.Context, filter AppointmentFilter) (int64, error) {
+	deleted, err := r.c.deleteAll(filter.match)
+	return int64(deleted), err
+}
+
+// docParticipants kaydın katılımcı listesini çözer
+func docParticipants(doc bson.M) ([]models.Participant, error) {
+	raw, err := bson.Marshal(bson.M{"participants": doc["participants"]})
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Participants []models.Participant `bson:"participants"`
+	}
+	err = bson.Unmarshal(raw, &out)
+	return out.Participants, err
+}
+
+func (r *memAppointments) FindByParticipant(ctx context.Context, email string) ([]models.Appointment, error) {
+	return memFindAll[models.Appointment](r.c, func(doc bson.M) bool {
+		participants, _ := docParticipants(doc)
+		for _, p := range participants {
+			if p.Email == email {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (r *memAppointments) AddParticipant(ctx context.Context, id primitive.ObjectID, cond AppointmentCondition, participant models.Participant) (bool, error) {
+	var decodeErr error
+	_, modified, err := r.c.update(cond.match(id), func(doc bson.M) bool {
+		participants, err := docParticipants(doc)
+		if err != nil {
+			decodeErr = err
+			return false
+		}
+		capacity, _ := docInt64(doc, "capacity")
+		taken, _ := docInt64(doc, "seats_taken")
+		if capacity <= 0 || taken >= capacity {
+			return false
+		}
+		for _, p := range participants {
+			if p.Email == participant.Email && p.Status == models.ParticipantBooked {
+				return false
+			}
+		}
+		version, _ := docInt64(doc, "version")
+		doc["participants"] = append(participants, participant)
+		doc["seats_taken"] = taken + 1
+		doc["version"] = version + 1
+		doc["updated_at"] = participant.BookedAt
+		return true
+	}, false)
+	if err == nil {
+		err = decodeErr
+	}
+	return modified == 1, err
+}
+
+func (r *memAppointments) SetParticipantStatus(ctx context.Context, id primitive.ObjectID, email, from, to string, at time.Time) (bool, error) {
+	var decodeErr error
+	_, modified, err := r.c.update(byID(id), func(doc bson.M) bool {
+		participants, err := docParticipants(doc)
+		if err != nil {
+			decodeErr = err
+			return false
+		}
+		for i, p := range participants {
+			if p.Email != email || p.Status != from {
+				continue
+			}
+			participants[i].Status = to
+			participants[i].UpdatedAt = at
+			version, _ := docInt64(doc, "version")
+			if from == models.ParticipantBooked && to == models.ParticipantCancelled {
+				taken, _ := docInt64(doc, "seats_taken")
+				doc["seats_taken"] = taken - 1
+			}
+			doc["participants"] = participants
+			doc["version"] = version + 1
+			doc["updated_at"] = at
+			return true
+		}
+		return false
+	}, false)
+	if err == nil {
+		err = decodeErr
+	}
+	return modified == 1, err
+}
@@ -0,0 +1,261 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"rtsback/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func openSlot(provider string, start time.Time) models.Appointment {
+	return models.Appointment{
+		ID:            primitive.NewObjectID(),
+		ProviderEmail: provider,
+		Date:          start,
+		StartTime:     start,
+		EndTime:       start.Add(30 * time.Minute),
+		Status:        models.StatusOpen,
+	}
+}
+
+func TestMemAppointmentsSlotKeyUnique(t *testing.T) {
+	repo := NewMemory().Appointments
+	ctx := context.Background()
+	start := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+
+	first := openSlot("p@example.com", start)
+	first.SlotKey = "p@example.com|1"
+	first.SlotKeys = []string{first.SlotKey}
+	first.SlotActive = true
+	if err := repo.Create(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+
+	second := openSlot("p@example.com", start)
+	second.SlotKey = first.SlotKey
+	second.SlotActive = true
+	if err := repo.Create(ctx, second); err != ErrDuplicate {
+		t.Fatalf("active duplicate slot key: got %v, want ErrDuplicate", err)
+	}
+
+	// slot_keys dizisindeki anahtarlar da tekildir
+	spanning := openSlot("p@example.com", start.Add(-30*time.Minute))
+	spanning.SlotKey = "p@example.com|0"
+	spanning.SlotKeys = []string{"p@example.com|0", first.SlotKey}
+	spanning.SlotActive = true
+	if err := repo.Create(ctx, spanning); err != ErrDuplicate {
+		t.Fatalf("overlapping slot keys: got %v, want ErrDuplicate", err)
+	}
+
+	// Aktif olmayan kayıt anahtarı tutmaz
+	inactive := openSlot("p@example.com", start)
+	inactive.SlotKey = first.SlotKey
+	if err := repo.Create(ctx, inactive); err != nil {
+		t.Fatalf("inactive slot with same key: %v", err)
+	}
+}
+
+func TestMemAppointmentsUpdateIfSlotKeyUnique(t *testing.T) {
+	repo := NewMemory().Appointments
+	ctx := context.Background()
+	start := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+
+	first := openSlot("p@example.com", start)
+	first.SlotKey = "p@example.com|1"
+	first.SlotKeys = []string{first.SlotKey}
+	first.SlotActive = true
+	second := openSlot("p@example.com", start.Add(time.Hour))
+	second.SlotKey = "p@example.com|2"
+	second.SlotKeys = []string{second.SlotKey}
+	second.SlotActive = true
+	for _, a := range []models.Appointment{first, second} {
+		if err := repo.Create(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Taşınan randevu başka bir aktif kaydın anahtarını alamaz
+	updated, err := repo.UpdateIf(ctx, second.ID, AppointmentCondition{}, Fields{
+		"slot_key":  "p@example.com|3",
+		"slot_keys": []string{"p@example.com|3", first.SlotKey},
+	})
+	if err != ErrDuplicate || updated {
+		t.Fatalf("moving onto a held key: updated %v, err %v", updated, err)
+	}
+	if got, _ := repo.FindByID(ctx, second.ID); got.SlotKey != second.SlotKey || got.Version != 0 {
+		t.Fatalf("rejected update changed the record: %+v", got)
+	}
+
+	updated, err = repo.UpdateIf(ctx, second.ID, AppointmentCondition{}, Fields{
+		"slot_key":  "p@example.com|3",
+		"slot_keys": []string{"p@example.com|3"},
+	})
+	if err != nil || !updated {
+		t.Fatalf("moving onto a free key: updated %v, err %v", updated, err)
+	}
+}
+
+func TestMemAppointmentsCreateManySkipsDuplicates(t *testing.T) {
+	repo := NewMemory().Appointments
+	ctx := context.Background()
+	start := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+
+	var slots []models.Appointment
+	for i := 0; i < 3; i++ {
+		slot := openSlot("p@example.com", start.Add(time.Duration(i)*30*time.Minute))
+		slot.SlotKey = slot.ProviderEmail + "|" + slot.StartTime.Format(time.RFC3339)
+		slot.SlotActive = true
+		slots = append(slots, slot)
+	}
+	if err := repo.Create(ctx, slots[1]); err != nil {
+		t.Fatal(err)
+	}
+	retry := append([]models.Appointment{}, slots...)
+	retry[1].ID = primitive.NewObjectID()
+
+	created, err := repo.CreateMany(ctx, retry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 2 || created[0].ID != retry[0].ID || created[1].ID != retry[2].ID {
+		t.Fatalf("created %d slots, want the first and the last", len(created))
+	}
+}
+
+func TestMemAppointmentsUpdateIf(t *testing.T) {
+	repo := NewMemory().Appointments
+	ctx := context.Background()
+	slot := openSlot("p@example.com", time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC))
+	if err := repo.Create(ctx, slot); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := int64(3)
+	ok, err := repo.UpdateIf(ctx, slot.ID, AppointmentCondition{Version: &stale}, Fields{"customer_name": "A"})
+	if err != nil || ok {
+		t.Fatalf("stale version: ok=%v err=%v", ok, err)
+	}
+	ok, err = repo.UpdateIf(ctx, slot.ID, AppointmentCondition{Statuses: []string{models.StatusConfirmed}}, Fields{"customer_name": "A"})
+	if err != nil || ok {
+		t.Fatalf("wrong status: ok=%v err=%v", ok, err)
+	}
+
+	// Sürüm alanı olmayan kayıt sıfırıncı sürüm sayılır
+	zero := int64(0)
+	ok, err = repo.UpdateIf(ctx, slot.ID, AppointmentCondition{Version: &zero, Statuses: []string{models.StatusOpen}}, Fields{"customer_name": "A"})
+	if err != nil || !ok {
+		t.Fatalf("matching condition: ok=%v err=%v", ok, err)
+	}
+	got, err := repo.FindByID(ctx, slot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CustomerName != "A" || got.Version != 1 {
+		t.Fatalf("got name %q version %d, want A and 1", got.CustomerName, got.Version)
+	}
+
+	// Aynı sürümle yarışan güncellemelerden yalnızca biri kazanır
+	one := int64(1)
+	var wins int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := repo.UpdateIf(ctx, slot.ID, AppointmentCondition{Version: &one}, Fields{"customer_name": "B"})
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				mu.Lock()
+				wins++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if wins != 1 {
+		t.Fatalf("%d concurrent updates won, want 1", wins)
+	}
+}
+
+func TestMemAppointmentsFind(t *testing.T) {
+	repo := NewMemory().Appointments
+	ctx := context.Background()
+	day := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	for _, a := range []models.Appointment{
+		openSlot("p@example.com", day.Add(9*time.Hour)),
+		openSlot("p@example.com", day.Add(33*time.Hour)),
+		openSlot("q@example.com", day.Add(10*time.Hour)),
+	} {
+		if err := repo.Create(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Tarihi olmayan kayıt tarih aralığıyla eşleşmez
+	if err := repo.Create(ctx, models.Appointment{ProviderEmail: "p@example.com", Status: models.StatusOpen}); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := repo.Find(ctx, AppointmentFilter{ProviderEmail: "p@example.com", From: day, To: day.AddDate(0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || !found[0].Date.Equal(day.Add(9*time.Hour)) {
+		t.Fatalf("found %d appointments in the day, want 1", len(found))
+	}
+
+	found, err = repo.Find(ctx, AppointmentFilter{ProviderEmail: "p@example.com", Status: models.StatusOpen})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 3 {
+		t.Fatalf("found %d open appointments, want 3", len(found))
+	}
+
+	if _, err := repo.FindByID(ctx, primitive.NewObjectID()); err != ErrNotFound {
+		t.Fatalf("missing appointment: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemAppointmentsMigrateStatuses(t *testing.T) {
+	repo := NewMemory().Appointments.(*memAppointments)
+	ctx := context.Background()
+	booked, open, current := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	// Eski kayıtlarda status yoktur, activate alınmış slotu gösterir
+	for _, doc := range []bson.M{
+		{"_id": booked, "providerEmail": "p@example.com", "activate": true},
+		{"_id": open, "providerEmail": "p@example.com", "activate": false},
+		{"_id": current, "providerEmail": "p@example.com", "status": models.StatusCancelled},
+	} {
+		if err := repo.c.insert(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrated, err := repo.MigrateStatuses(ctx)
+	if err != nil || migrated != 2 {
+		t.Fatalf("migrated %d, err %v", migrated, err)
+	}
+	for id, want := range map[primitive.ObjectID]string{booked: models.StatusConfirmed, open: models.StatusOpen, current: models.StatusCancelled} {
+		got, err := repo.FindByID(ctx, id)
+		if err != nil || got.Status != want {
+			t.Errorf("%s: status %q err %v, want %q", id.Hex(), got.Status, err, want)
+		}
+	}
+	left, _, err := repo.c.update(func(doc bson.M) bool { _, ok := doc["activate"]; return ok }, func(bson.M) bool { return false }, true)
+	if err != nil || left != 0 {
+		t.Fatalf("%d records still have activate, err %v", left, err)
+	}
+
+	// Tekrar çalıştırmak bir şey değiştirmez
+	if migrated, err := repo.MigrateStatuses(ctx); err != nil || migrated != 0 {
+		t.Fatalf("second run migrated %d, err %v", migrated, err)
+	}
+}
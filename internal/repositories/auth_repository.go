@@ -1 +1,263 @@
-package repositories
\ No newline at end of file
+package repositories
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"rtsback/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token models.RefreshToken) error
+	FindByHash(ctx context.Context, tokenHash string) (models.RefreshToken, error)
+	// RevokeIfActive token hâlâ geçerliyse iptal edip replacedBy ile
+	// işaretler. Eşzamanlı iki yenilemeden yalnızca biri true alır.
+	RevokeIfActive(ctx context.Context, id, replacedBy primitive.ObjectID) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	// RevokeBySubject hesabın bu roldeki tüm oturumlarını kapatır ve
+	// iptal edilen token sayısını döner
+	RevokeBySubject(ctx context.Context, subject, role string) (int64, error)
+	// HasActive ailede süresi dolmamış ve iptal edilmemiş bir token olup olmadığını döner
+	HasActive(ctx context.Context, familyID, subject string, now time.Time) (bool, error)
+}
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset models.PasswordReset) error
+	// InvalidateForAccount hesabın kullanılmamış tüm sıfırlama token'larını kullanılmış sayar
+	InvalidateForAccount(ctx context.Context, accountID primitive.ObjectID, role string) error
+	// Consume geçerli token'ı atomik olarak kullanılmış işaretler ve döner;
+	// token yoksa, kullanılmışsa ya da süresi dolmuşsa ErrNotFound
+	Consume(ctx context.Context, tokenHash string, now time.Time) (models.PasswordReset, error)
+}
+
+// SecurityEventFilter boş alanlar filtreye katılmaz
+type SecurityEventFilter struct {
+	Email string
+	Type  string
+}
+
+type SecurityEventRepository interface {
+	Create(ctx context.Context, event models.SecurityEvent) error
+	// List olayları en yeniden eskiye, en fazla limit kadar döner
+	List(ctx context.Context, filter SecurityEventFilter, limit int) ([]models.SecurityEvent, error)
+}
+
+// Mongo
+
+type mongoRefreshTokens struct{ c *mongo.Collection }
+
+func (r *mongoRefreshTokens) Create(ctx context.Context, token models.RefreshToken) error {
+	return mongoInsert(ctx, r.c, token)
+}
+
+func (r *mongoRefreshTokens) FindByHash(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	return mongoFindOne[models.RefreshToken](ctx, r.c, bson.M{"token_hash": tokenHash})
+}
+
+func (r *mongoRefreshTokens) RevokeIfActive(ctx context.Context, id, replacedBy primitive.ObjectID) (bool, error) {
+	return mongoModified(ctx, r.c,
+		bson.M{"_id": id, "revoked": false},
+		bson.M{"$set": bson.M{"revoked": true, "revoked_at": time.Now(), "replaced_by": replacedBy}},
+	)
+}
+
+func (r *mongoRefreshTokens) RevokeFamily(ctx context.Context, familyID string) error {
+	_, err := r.revokeMany(ctx, bson.M{"family_id": familyID, "revoked": false})
+	return err
+}
+
+func (r *mongoRefreshTokens) RevokeBySubject(ctx context.Context, subject, role string) (int64, error) {
+	return r.revokeMany(ctx, bson.M{"subject": subject, "role": role, "revoked": false})
+}
+
+func (r *mongoRefreshTokens) revokeMany(ctx context.Context, filter bson.M) (int64, error) {
+	result, err := r.c.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked": true, "revoked_at": time.Now()}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *mongoRefreshTokens) HasActive(ctx context.Context, familyID, subject string, now time.Time) (bool, error) {
+	count, err := r.c.CountDocuments(ctx, bson.M{
+		"family_id":  familyID,
+		"subject":    subject,
+		"revoked":    false,
+		"expires_at": bson.M{"$gt": now},
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+type mongoPasswordResets struct{ c *mongo.Collection }
+
+func (r *mongoPasswordResets) Create(ctx context.Context, reset models.PasswordReset) error {
+	return mongoInsert(ctx, r.c, reset)
+}
+
+func (r *mongoPasswordResets) InvalidateForAccount(ctx context.Context, accountID primitive.ObjectID, role string) error {
+	_, err := r.c.UpdateMany(ctx,
+		bson.M{"account_id": accountID, "role": role, "used": false},
+		bson.M{"$set": bson.M{"used": true, "used_at": time.Now()}},
+	)
+	return err
+}
+
+func (r *mongoPasswordResets) Consume(ctx context.Context, tokenHash string, now time.Time) (models.PasswordReset, error) {
+	var reset models.PasswordReset
+	err := r.c.FindOneAndUpdate(ctx,
+		bson.M{
+			"token_hash": tokenHash,
+			"used":       false,
+			"expires_at": bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{"used": true, "used_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&reset)
+	if err == mongo.ErrNoDocuments {
+		return reset, ErrNotFound
+	}
+	return reset, err
+}
+
+type mongoSecurityEvents struct{ c *mongo.Collection }
+
+func (r *mongoSecurityEvents) Create(ctx context.Context, event models.SecurityEvent) error {
+	return mongoInsert(ctx, r.c, event)
+}
+
+func (r *mongoSecurityEvents) List(ctx context.Context, filter SecurityEventFilter, limit int) ([]models.SecurityEvent, error) {
+	query := bson.M{}
+	if filter.Email != "" {
+		query["email"] = filter.Email
+	}
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit))
+	return mongoFindAll[models.SecurityEvent](ctx, r.c, query, opts)
+}
+
+// Bellek içi
+
+type memRefreshTokens struct{ c *memCollection }
+
+func (r *memRefreshTokens) Create(ctx context.Context, token models.RefreshToken) error {
+	return r.c.insert(token)
+}
+
+func (r *memRefreshTokens) FindByHash(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	return memFindOne[models.RefreshToken](r.c, eq("token_hash", tokenHash))
+}
+
+func (r *memRefreshTokens) RevokeIfActive(ctx context.Context, id, replacedBy primitive.ObjectID) (bool, error) {
+	_, modified, err := r.c.update(
+		func(doc bson.M) bool { return doc["_id"] == id && !docBool(doc, "revoked") },
+		func(doc bson.M) bool {
+			setFields(doc, Fields{"revoked": true, "revoked_at": time.Now(), "replaced_by": replacedBy})
+			return true
+		}, false)
+	return modified == 1, err
+}
+
+func (r *memRefreshTokens) RevokeFamily(ctx context.Context, familyID string) error {
+	_, err := r.revokeMany(func(doc bson.M) bool {
+		return docString(doc, "family_id") == familyID && !docBool(doc, "revoked")
+	})
+	return err
+}
+
+func (r *memRefreshTokens) RevokeBySubject(ctx context.Context, subject, role string) (int64, error) {
+	return r.revokeMany(func(doc bson.M) bool {
+		return docString(doc, "subject") == subject && docString(doc, "role") == role && !docBool(doc, "revoked")
+	})
+}
+
+func (r *memRefreshTokens) revokeMany(match func(bson.M) bool) (int64, error) {
+	now := time.Now()
+	_, modified, err := r.c.update(match, func(doc bson.M) bool {
+		setFields(doc, Fields{"revoked": true, "revoked_at": now})
+		return true
+	}, true)
+	return int64(modified), err
+}
+
+func (r *memRefreshTokens) HasActive(ctx context.Context, familyID, subject string, now time.Time) (bool, error) {
+	tokens, err := memFindAll[models.RefreshToken](r.c, func(doc bson.M) bool {
+		expiresAt, ok := docTime(doc, "expires_at")
+		return docString(doc, "family_id") == familyID &&
+			docString(doc, "subject") == subject &&
+			!docBool(doc, "revoked") &&
+			ok && expiresAt.After(now)
+	})
+	return len(tokens) > 0, err
+}
+
+type memPasswordResets struct{ c *memCollection }
+
+func (r *memPasswordResets) Create(ctx context.Context, reset models.PasswordReset) error {
+	return r.c.insert(reset)
+}
+
+func (r *memPasswordResets) InvalidateForAccount(ctx context.Context, accountID primitive.ObjectID, role string) error {
+	now := time.Now()
+	_, _, err := r.c.update(
+		func(doc bson.M) bool {
+			return doc["account_id"] == accountID && docString(doc, "role") == role && !docBool(doc, "used")
+		},
+		func(doc bson.M) bool {
+			setFields(doc, Fields{"used": true, "used_at": now})
+			return true
+		}, true)
+	return err
+}
+
+func (r *memPasswordResets) Consume(ctx context.Context, tokenHash string, now time.Time) (models.PasswordReset, error) {
+	var consumed primitive.ObjectID
+	_, modified, err := r.c.update(
+		func(doc bson.M) bool {
+			expiresAt, ok := docTime(doc, "expires_at")
+			return docString(doc, "token_hash") == tokenHash && !docBool(doc, "used") && ok && expiresAt.After(now)
+		},
+		func(doc bson.M) bool {
+			setFields(doc, Fields{"used": true, "used_at": now})
+			consumed, _ = doc["_id"].(primitive.ObjectID)
+			return true
+		}, false)
+	if err != nil {
+		return models.PasswordReset{}, err
+	}
+	if modified == 0 {
+		return models.PasswordReset{}, ErrNotFound
+	}
+	return memFindOne[models.PasswordReset](r.c, byID(consumed))
+}
+
+type memSecurityEvents struct{ c *memCollection }
+
+func (r *memSecurityEvents) Create(ctx context.Context, event models.SecurityEvent) error {
+	return r.c.insert(event)
+}
+
+func (r *memSecurityEvents) List(ctx context.Context, filter SecurityEventFilter, limit int) ([]models.SecurityEvent, error) {
+	events, err := memFindAll[models.SecurityEvent](r.c, func(doc bson.M) bool {
+		return (filter.Email == "" || docString(doc, "email") == filter.Email) &&
+			(filter.Type == "" || docString(doc, "type") == filter.Type)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].CreatedAt.After(events[j].CreatedAt) })
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
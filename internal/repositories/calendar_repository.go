@@ -0,0 +1,256 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"rtsback/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CalendarFeedRepository hesap başına tek bir takvim aboneliği tutar;
+// kaydın ID'si hesabın ID'sidir
+type CalendarFeedRepository interface {
+	Get(ctx context.Context, accountID primitive.ObjectID) (models.CalendarFeed, error)
+	FindByTokenHash(ctx context.Context, tokenHash string) (models.CalendarFeed, error)
+	// Save aboneliği ekler ya da hesabın var olan aboneliğinin yerine yazar
+	Save(ctx context.Context, feed models.CalendarFeed) error
+	Delete(ctx context.Context, accountID primitive.ObjectID) error
+}
+
+type mongoCalendarFeeds struct{ c *mongo.Collection }
+
+func (r *mongoCalendarFeeds) Get(ctx context.Context, accountID primitive.ObjectID) (models.CalendarFeed, error) {
+	return mongoFindOne[models.CalendarFeed](ctx, r.c, bson.M{"_id": accountID})
+}
+
+func (r *mongoCalendarFeeds) FindByTokenHash(ctx context.Context, tokenHash string) (models.CalendarFeed, error) {
+	return mongoFindOne[models.CalendarFeed](ctx, r.c, bson.M{"token_hash": tokenHash})
+}
+
+func (r *mongoCalendarFeeds) Save(ctx context.Context, feed models.CalendarFeed) error {
+	_, err := r.c.ReplaceOne(ctx, bson.M{"_id": feed.ID}, feed, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *mongoCalendarFeeds) Delete(ctx context.Context, accountID primitive.ObjectID) error {
+	result, err := r.c.DeleteOne(ctx, bson.M{"_id": accountID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memCalendarFeeds struct{ c *memCollection }
+
+func (r *memCalendarFeeds) Get(ctx context.Context, accountID primitive.ObjectID) (models.CalendarFeed, error) {
+	return memFindOne[models.CalendarFeed](r.c, byID(accountID))
+}
+
+func (r *memCalendarFeeds) FindByTokenHash(ctx context.Context, tokenHash string) (models.CalendarFeed, error) {
+	return memFindOne[models.CalendarFeed](r.c, func(doc bson.M) bool {
+		return docString(doc, "token_hash") == tokenHash
+	})
+}
+
+func (r *memCalendarFeeds) Save(ctx context.Context, feed models.CalendarFeed) error {
+	raw, err := bson.Marshal(feed)
+	if err != nil {
+		return err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	return r.c.upsert(feed.ID, func(existing bson.M) {
+		for k := range existing {
+			delete(existing, k)
+		}
+		setFields(existing, Fields(doc))
+	})
+}
+
+func (r *memCalendarFeeds) Delete(ctx context.Context, accountID primitive.ObjectID) error {
+	deleted, err := r.c.delete(byID(accountID))
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// BusySourceRepository sağlayıcıların dış takvimleridir. Bir sağlayıcının
+// aynı adla tek takvimi olur; aynı adla ikinci kayıt ErrDuplicate döner.
+type BusySourceRepository interface {
+	Create(ctx context.Context, source models.BusySource) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.BusySource, error)
+	// FindByProvider sağlayıcının takvimlerini ada göre sıralı döner
+	FindByProvider(ctx context.Context, providerID primitive.ObjectID) ([]models.BusySource, error)
+	// FindAll tüm sağlayıcıların takvimlerini döner; düzenli eşitleme içindir
+	FindAll(ctx context.Context) ([]models.BusySource, error)
+	UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error
+	DeleteByID(ctx context.Context, id primitive.ObjectID) error
+}
+
+type mongoBusySources struct{ c *mongo.Collection }
+
+func (r *mongoBusySources) Create(ctx context.Context, source models.BusySource) error {
+	return mongoInsert(ctx, r.c, source)
+}
+
+func (r *mongoBusySources) FindByID(ctx context.Context, id primitive.ObjectID) (models.BusySource, error) {
+	return mongoFindOne[models.BusySource](ctx, r.c, bson.M{"_id": id})
+}
+
+func (r *mongoBusySources) FindByProvider(ctx context.Context, providerID primitive.ObjectID) ([]models.BusySource, error) {
+	return mongoFindAll[models.BusySource](ctx, r.c, bson.M{"provider_id": providerID}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+}
+
+func (r *mongoBusySources) FindAll(ctx context.Context) ([]models.BusySource, error) {
+	return mongoFindAll[models.BusySource](ctx, r.c, bson.M{})
+}
+
+func (r *mongoBusySources) UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return mongoSet(ctx, r.c, bson.M{"_id": id}, fields)
+}
+
+func (r *mongoBusySources) DeleteByID(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.c.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memBusySources struct{ c *memCollection }
+
+func (r *memBusySources) Create(ctx context.Context, source models.BusySource) error {
+	// Mongo'daki provider_name tekil indeksinin karşılığı
+	return r.c.insertUnless(source, func(doc bson.M) bool {
+		id, _ := doc["provider_id"].(primitive.ObjectID)
+		return id == source.ProviderID && docString(doc, "name") == source.Name
+	})
+}
+
+func (r *memBusySources) FindByID(ctx context.Context, id primitive.ObjectID) (models.BusySource, error) {
+	return memFindOne[models.BusySource](r.c, byID(id))
+}
+
+func (r *memBusySources) FindByProvider(ctx context.Context, providerID primitive.ObjectID) ([]models.BusySource, error) {
+	found, err := memFindAll[models.BusySource](r.c, func(doc bson.M) bool {
+		id, _ := doc["provider_id"].(primitive.ObjectID)
+		return id == providerID
+	})
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Name < found[j].Name })
+	return found, err
+}
+
+func (r *memBusySources) FindAll(ctx context.Context) ([]models.BusySource, error) {
+	return memFindAll[models.BusySource](r.c, all)
+}
+
+func (r *memBusySources) UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return memSet(r.c, byID(id), fields)
+}
+
+func (r *memBusySources) DeleteByID(ctx context.Context, id primitive.ObjectID) error {
+	deleted, err := r.c.delete(byID(id))
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// BusyBlockRepository dış takvimlerden alınan dolu aralıklardır
+type BusyBlockRepository interface {
+	// Replace takvimin bloklarını silip verilenleri ekler; takvim her
+	// eşitlemede baştan yazılır
+	Replace(ctx context.Context, sourceID primitive.ObjectID, blocks []models.BusyBlock) error
+	// Find sağlayıcının [from, to) ile çakışan bloklarını başlangıca göre
+	// sıralı döner. Boş sourceID tüm takvimleri kapsar.
+	Find(ctx context.Context, providerID, sourceID primitive.ObjectID, from, to time.Time) ([]models.BusyBlock, error)
+	DeleteBySource(ctx context.Context, sourceID primitive.ObjectID) error
+}
+
+type mongoBusyBlocks struct{ c *mongo.Collection }
+
+func (r *mongoBusyBlocks) Replace(ctx context.Context, sourceID primitive.ObjectID, blocks []models.BusyBlock) error {
+	if err := r.DeleteBySource(ctx, sourceID); err != nil {
+		return err
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(blocks))
+	for i, b := range blocks {
+		docs[i] = b
+	}
+	_, err := r.c.InsertMany(ctx, docs)
+	return err
+}
+
+func (r *mongoBusyBlocks) Find(ctx context.Context, providerID, sourceID primitive.ObjectID, from, to time.Time) ([]models.BusyBlock, error) {
+	filter := bson.M{"provider_id": providerID, "end": bson.M{"$gt": from}, "start": bson.M{"$lt": to}}
+	if !sourceID.IsZero() {
+		filter["source_id"] = sourceID
+	}
+	return mongoFindAll[models.BusyBlock](ctx, r.c, filter, options.Find().SetSort(bson.D{{Key: "start", Value: 1}}))
+}
+
+func (r *mongoBusyBlocks) DeleteBySource(ctx context.Context, sourceID primitive.ObjectID) error {
+	_, err := r.c.DeleteMany(ctx, bson.M{"source_id": sourceID})
+	return err
+}
+
+type memBusyBlocks struct{ c *memCollection }
+
+func (r *memBusyBlocks) Replace(ctx context.Context, sourceID primitive.ObjectID, blocks []models.BusyBlock) error {
+	if err := r.DeleteBySource(ctx, sourceID); err != nil {
+		return err
+	}
+	docs := make([]interface{}, len(blocks))
+	for i, b := range blocks {
+		docs[i] = b
+	}
+	return r.c.insertMany(docs)
+}
+
+func (r *memBusyBlocks) Find(ctx context.Context, providerID, sourceID primitive.ObjectID, from, to time.Time) ([]models.BusyBlock, error) {
+	found, err := memFindAll[models.BusyBlock](r.c, func(doc bson.M) bool {
+		if id, _ := doc["provider_id"].(primitive.ObjectID); id != providerID {
+			return false
+		}
+		if id, _ := doc["source_id"].(primitive.ObjectID); !sourceID.IsZero() && id != sourceID {
+			return false
+		}
+		start, _ := docTime(doc, "start")
+		end, _ := docTime(doc, "end")
+		return end.After(from) && start.Before(to)
+	})
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Start.Before(found[j].Start) })
+	return found, err
+}
+
+func (r *memBusyBlocks) DeleteBySource(ctx context.Context, sourceID primitive.ObjectID) error {
+	_, err := r.c.deleteAll(func(doc bson.M) bool {
+		id, _ := doc["source_id"].(primitive.ObjectID)
+		return id == sourceID
+	})
+	return err
+}
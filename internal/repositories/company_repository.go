@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+
+	"rtsback/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CompanyRepository interface {
+	Create(ctx context.Context, company models.Company) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.Company, error)
+	FindByName(ctx context.Context, name string) (models.Company, error)
+	List(ctx context.Context) ([]models.Company, error)
+	UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error
+	UpdateByName(ctx context.Context, name string, fields Fields) error
+}
+
+type mongoCompanies struct{ c *mongo.Collection }
+
+func (r *mongoCompanies) Create(ctx context.Context, company models.Company) error {
+	return mongoInsert(ctx, r.c, company)
+}
+
+func (r *mongoCompanies) FindByID(ctx context.Context, id primitive.ObjectID) (models.Company, error) {
+	return mongoFindOne[models.Company](ctx, r.c, bson.M{"_id": id})
+}
+
+func (r *mongoCompanies) FindByName(ctx context.Context, name string) (models.Company, error) {
+	return mongoFindOne[models.Company](ctx, r.c, bson.M{"name": name})
+}
+
+func (r *mongoCompanies) List(ctx context.Context) ([]models.Company, error) {
+	return mongoFindAll[models.Company](ctx, r.c, bson.M{})
+}
+
+func (r *mongoCompanies) UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return mongoSet(ctx, r.c, bson.M{"_id": id}, fields)
+}
+
+func (r *mongoCompanies) UpdateByName(ctx context.Context, name string, fields Fields) error {
+	return mongoSet(ctx, r.c, bson.M{"name": name}, fields)
+}
+
+type memCompanies struct{ c *memCollection }
+
+func (r *memCompanies) Create(ctx context.Context, company models.Company) error {
+	return r.c.insert(company)
+}
+
+func (r *memCompanies) FindByID(ctx context.Context, id primitive.ObjectID) (models.Company, error) {
+	return memFindOne[models.Company](r.c, byID(id))
+}
+
+func (r *memCompanies) FindByName(ctx context.Context, name string) (models.Company, error) {
+	return memFindOne[models.Company](r.c, eq("name", name))
+}
+
+func (r *memCompanies) List(ctx context.Context) ([]models.Company, error) {
+	return memFindAll[models.Company](r.c, all)
+}
+
+func (r *memCompanies) UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return memSet(r.c, byID(id), fields)
+}
+
+func (r *memCompanies) UpdateByName(ctx context.Context, name string, fields Fields) error {
+	return memSet(r.c, eq("name", name), fields)
+}
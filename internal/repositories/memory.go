@@ -0,0 +1,343 @@
+package repositories
+
+import (
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memCollection bir Mongo koleksiyonunun bellek içi karşılığıdır. Kayıtlar
+// bson olarak saklanır; böylece omitempty, zaman hassasiyeti (milisaniye)
+// ve alan adları Mongo'dakiyle aynı davranır ve çağıranlar saklanan
+// kayıtları değiştiremez. Tüm okuma ve yazmalar tek bir kilit altında
+// yapıldığı için koşullu güncellemeler atomiktir.
+type memCollection struct {
+	mu    sync.RWMutex
+	docs  map[primitive.ObjectID]bson.Raw
+	order []primitive.ObjectID
+}
+
+func newMemCollection() *memCollection {
+	return &memCollection{docs: make(map[primitive.ObjectID]bson.Raw)}
+}
+
+// insert kaydı ekler. Mongo gibi, _id boşsa yeni bir ObjectID atanır.
+func (c *memCollection) insert(doc interface{}) error {
+	return c.insertUnless(doc, nil)
+}
+
+// insertUnless kaydı ekler; conflict mevcut bir kayıt için true dönerse
+// eklemez ve ErrDuplicate döner. Mongo'daki tekil indekslerin karşılığıdır.
+func (c *memCollection) insertUnless(doc interface{}, conflict func(bson.M) bool) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	id, ok := bson.Raw(raw).Lookup("_id").ObjectIDOK()
+	if !ok || id.IsZero() {
+		var m bson.M
+		if err := bson.Unmarshal(raw, &m); err != nil {
+			return err
+		}
+		id = primitive.NewObjectID()
+		m["_id"] = id
+		if raw, err = bson.Marshal(m); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.docs[id]; exists {
+		return ErrDuplicate
+	}
+	if conflict != nil {
+		for _, existing := range c.docs {
+			var other bson.M
+			if err := bson.Unmarshal(existing, &other); err != nil {
+				return err
+			}
+			if conflict(other) {
+				return ErrDuplicate
+			}
+		}
+	}
+	c.docs[id] = raw
+	c.order = append(c.order, id)
+	return nil
+}
+
+// insertMany kayıtları sırayla ekler. Mongo'daki sıralı InsertMany gibi
+// ilk hatada durur; o ana kadar eklenenler kalır.
+func (c *memCollection) insertMany(docs []interface{}) error {
+	for _, doc := range docs {
+		if err := c.insert(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// update eşleşen kayıtlara mutate uygular ve eşleşen/değişen kayıt
+// sayılarını döner. mutate false dönerse kayıt değişmemiş sayılır.
+// many false ise ilk eşleşmeden sonra durur.
+func (c *memCollection) update(match func(bson.M) bool, mutate func(bson.M) bool, many bool) (matched, modified int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range c.order {
+		var doc bson.M
+		if err := bson.Unmarshal(c.docs[id], &doc); err != nil {
+			return matched, modified, err
+		}
+		if !match(doc) {
+			continue
+		}
+		matched++
+		if mutate(doc) {
+			doc["_id"] = id
+			raw, err := bson.Marshal(doc)
+			if err != nil {
+				return matched, modified, err
+			}
+			c.docs[id] = raw
+			modified++
+		}
+		if !many {
+			break
+		}
+	}
+	return matched, modified, nil
+}
+
+// otherMatches id dışında pred'i sağlayan bir kayıt olup olmadığını döner.
+// Kilit tutulurken, örneğin update'in mutate fonksiyonunda çağrılır.
+func (c *memCollection) otherMatches(id primitive.ObjectID, pred func(bson.M) bool) (bool, error) {
+	for otherID, raw := range c.docs {
+		if otherID == id {
+			continue
+		}
+		var doc bson.M
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return false, err
+		}
+		if pred(doc) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// upsert kayıt varsa mutate uygular, yoksa yalnızca _id ile yeni bir kayıt
+// oluşturup mutate uygular
+func (c *memCollection) upsert(id primitive.ObjectID, mutate func(bson.M)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	doc := bson.M{}
+	raw, exists := c.docs[id]
+	if exists {
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return err
+		}
+	}
+	mutate(doc)
+	doc["_id"] = id
+
+	out, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	c.docs[id] = out
+	if !exists {
+		c.order = append(c.order, id)
+	}
+	return nil
+}
+
+// delete eşleşen ilk kaydı siler
+func (c *memCollection) delete(match func(bson.M) bool) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, id := range c.order {
+		var doc bson.M
+		if err := bson.Unmarshal(c.docs[id], &doc); err != nil {
+			return false, err
+		}
+		if match(doc) {
+			delete(c.docs, id)
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// deleteAll eşleşen tüm kayıtları siler ve silinen sayısını döner
+func (c *memCollection) deleteAll(match func(bson.M) bool) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Önce eşleşenler bulunur ki okuma hatasında hiçbir kayıt silinmesin
+	kept := make([]primitive.ObjectID, 0, len(c.order))
+	var matched []primitive.ObjectID
+	for _, id := range c.order {
+		var doc bson.M
+		if err := bson.Unmarshal(c.docs[id], &doc); err != nil {
+			return 0, err
+		}
+		if match(doc) {
+			matched = append(matched, id)
+		} else {
+			kept = append(kept, id)
+		}
+	}
+	for _, id := range matched {
+		delete(c.docs, id)
+	}
+	c.order = kept
+	return len(matched), nil
+}
+
+// memFindAll eşleşen kayıtları ekleme sırasıyla döner
+func memFindAll[T any](c *memCollection, match func(bson.M) bool) ([]T, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var out []T
+	for _, id := range c.order {
+		raw := c.docs[id]
+		var doc bson.M
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		if !match(doc) {
+			continue
+		}
+		var item T
+		if err := bson.Unmarshal(raw, &item); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+// memFindOne eşleşen ilk kaydı döner, yoksa ErrNotFound
+func memFindOne[T any](c *memCollection, match func(bson.M) bool) (T, error) {
+	var zero T
+	items, err := memFindAll[T](c, match)
+	if err != nil {
+		return zero, err
+	}
+	if len(items) == 0 {
+		return zero, ErrNotFound
+	}
+	return items[0], nil
+}
+
+// memSet eşleşen ilk kaydın alanlarını $set gibi günceller
+func memSet(c *memCollection, match func(bson.M) bool, fields Fields) error {
+	matched, _, err := c.update(match, func(doc bson.M) bool {
+		setFields(doc, fields)
+		return true
+	}, false)
+	if err != nil {
+		return err
+	}
+	if matched == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func setFields(doc bson.M, fields Fields) {
+	for k, v := range fields {
+		doc[k] = v
+	}
+}
+
+func unsetFields(doc bson.M, keys ...string) {
+	for _, k := range keys {
+		delete(doc, k)
+	}
+}
+
+// Aşağıdaki yardımcılar bson'dan çözülmüş alanları Mongo'nun eşitlik ve
+// karşılaştırma kurallarına yakın biçimde okur
+
+func all(bson.M) bool { return true }
+
+func byID(id primitive.ObjectID) func(bson.M) bool {
+	return func(doc bson.M) bool { return doc["_id"] == id }
+}
+
+func eq(field string, value interface{}) func(bson.M) bool {
+	return func(doc bson.M) bool { return doc[field] == value }
+}
+
+func docString(doc bson.M, field string) string {
+	s, _ := doc[field].(string)
+	return s
+}
+
+func docBool(doc bson.M, field string) bool {
+	b, _ := doc[field].(bool)
+	return b
+}
+
+func docInt64(doc bson.M, field string) (int64, bool) {
+	switch v := doc[field].(type) {
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func docTime(doc bson.M, field string) (time.Time, bool) {
+	switch v := doc[field].(type) {
+	case primitive.DateTime:
+		return v.Time(), true
+	case time.Time:
+		return v, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func docStrings(doc bson.M, field string) []string {
+	arr, _ := doc[field].(bson.A)
+	out := make([]string, 0, len(arr))
+	for _, v := range arr {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(values []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, v := range values {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoFindOne filtreye uyan ilk kaydı döner, yoksa ErrNotFound
+func mongoFindOne[T any](ctx context.Context, c *mongo.Collection, filter interface{}) (T, error) {
+	var out T
+	err := c.FindOne(ctx, filter).Decode(&out)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return out, ErrNotFound
+	}
+	return out, err
+}
+
+// mongoFindAll filtreye uyan tüm kayıtları döner
+func mongoFindAll[T any](ctx context.Context, c *mongo.Collection, filter interface{}, opts ...*options.FindOptions) ([]T, error) {
+	cursor, err := c.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var out []T
+	if err := cursor.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// mongoInsert kaydı ekler; aynı _id varsa ErrDuplicate döner
+func mongoInsert(ctx context.Context, c *mongo.Collection, doc interface{}) error {
+	_, err := c.InsertOne(ctx, doc)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// mongoSet filtreye uyan ilk kaydın alanlarını günceller, eşleşme yoksa ErrNotFound
+func mongoSet(ctx context.Context, c *mongo.Collection, filter interface{}, fields Fields) error {
+	return mongoUpdate(ctx, c, filter, bson.M{"$set": bson.M(fields)})
+}
+
+func mongoUpdate(ctx context.Context, c *mongo.Collection, filter, update interface{}) error {
+	result, err := c.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// mongoModified koşullu bir güncellemenin bir kaydı değiştirip değiştirmediğini
+// döner; güncelleme tekil bir indekse takılırsa ErrDuplicate döner
+func mongoModified(ctx context.Context, c *mongo.Collection, filter, update interface{}) (bool, error) {
+	result, err := c.UpdateOne(ctx, filter, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, ErrDuplicate
+	}
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// EnsureIndexes repository'lerin doğruluk için dayandığı indeksleri
+// oluşturur. Var olan indeksler için tekrar çalıştırmak güvenlidir.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	// Aynı slotu tutan ikinci bir aktif randevu eklenemez
+	_, err := db.Collection("appointment").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "slot_key", Value: 1}},
+		Options: options.Index().
+			SetName("slot_key_active_unique").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"slot_active": true}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Birden fazla slot kaplayan randevuların her slotu da tekildir
+	_, err = db.Collection("appointment").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "slot_keys", Value: 1}},
+		Options: options.Index().
+			SetName("slot_keys_active_unique").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"slot_active": true, "slot_keys": bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Bir şirketin aynı gün için tek kapalı gün kaydı olur
+	_, err = db.Collection("closures").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "company_id", Value: 1}, {Key: "date", Value: 1}},
+		Options: options.Index().SetName("company_date_unique").SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Serinin randevuları birlikte listelenir ve iptal edilir
+	_, err = db.Collection("appointment").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "series_id", Value: 1}},
+		Options: options.Index().
+			SetName("series_id").
+			SetPartialFilterExpression(bson.M{"series_id": bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Müşterinin katıldığı grup seansları katılımcı e-postasıyla bulunur
+	_, err = db.Collection("appointment").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "participants.email", Value: 1}},
+		Options: options.Index().
+			SetName("participants_email").
+			SetPartialFilterExpression(bson.M{"capacity": bson.M{"$gt": 0}}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Bir kaynağın aynı zaman dilimi tek bir ayırmada olabilir; ayırmalar
+	// kaynağın takvimi için ve randevu bırakıldığında silinmek için aranır
+	_, err = db.Collection("resource_reservations").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "keys", Value: 1}},
+			Options: options.Index().SetName("keys_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "resource_id", Value: 1}, {Key: "start", Value: 1}},
+			Options: options.Index().SetName("resource_start"),
+		},
+		{
+			Keys:    bson.D{{Key: "appointment_id", Value: 1}},
+			Options: options.Index().SetName("appointment_id"),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Bekleme listesi şirket ve durum ile sırayla taranır, teklifler token özetiyle bulunur
+	_, err = db.Collection("waitlist").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "company_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("company_status_created"),
+		},
+		{
+			Keys: bson.D{{Key: "offer_token_hash", Value: 1}},
+			Options: options.Index().
+				SetName("offer_token_hash").
+				SetPartialFilterExpression(bson.M{"offer_token_hash": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Takvim aboneliği token özetiyle bulunur
+	_, err = db.Collection("calendar_feeds").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "token_hash", Value: 1}},
+		Options: options.Index().SetName("token_hash_unique").SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Sağlayıcının aynı adla tek dış takvimi olur
+	_, err = db.Collection("busy_sources").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "provider_id", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetName("provider_name").SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Dolu aralıklar müsaitlik hesaplanırken sağlayıcıya ve zamana göre,
+	// eşitlemede takvime göre aranır
+	_, err = db.Collection("busy_blocks").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "provider_id", Value: 1}, {Key: "start", Value: 1}},
+			Options: options.Index().SetName("provider_start"),
+		},
+		{
+			Keys:    bson.D{{Key: "source_id", Value: 1}},
+			Options: options.Index().SetName("source"),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Katalog her zaman şirkete göre listelenir
+	_, err = db.Collection("services").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "company_id", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetName("company_services"),
+	})
+	return err
+}
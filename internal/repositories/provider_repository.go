@@ -0,0 +1,239 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"rtsback/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ProviderRepository interface {
+	AccountRepository
+	Create(ctx context.Context, provider models.Provider) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.Provider, error)
+	FindByEmail(ctx context.Context, email string) (models.Provider, error)
+	// List companyID boşsa tüm sağlayıcıları, değilse şirketin sağlayıcılarını döner
+	List(ctx context.Context, companyID string) ([]models.Provider, error)
+	UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error
+	// AddServiceNames eski serbest metin hizmetleri listede yoksa ekler
+	AddServiceNames(ctx context.Context, id primitive.ObjectID, names []string) error
+	// RemoveServiceName eski serbest metin hizmetini listeden çıkarır
+	RemoveServiceName(ctx context.Context, id primitive.ObjectID, name string) error
+	// SetOffering sağlayıcının katalog hizmetini ekler ya da aynı hizmetin
+	// kaydını değiştirir. Diğer hizmetlere dokunmaz.
+	SetOffering(ctx context.Context, id primitive.ObjectID, offering models.Offering) error
+	// RemoveOffering hizmeti sağlayıcının sunduklarından çıkarır
+	RemoveOffering(ctx context.Context, id primitive.ObjectID, serviceID primitive.ObjectID) error
+	// RemoveOfferingEverywhere katalogdan silinen hizmeti tüm sağlayıcılardan çıkarır
+	RemoveOfferingEverywhere(ctx context.Context, serviceID primitive.ObjectID) error
+}
+
+type mongoProviders struct{ mongoAccounts }
+
+func (r *mongoProviders) Create(ctx context.Context, provider models.Provider) error {
+	return mongoInsert(ctx, r.c, provider)
+}
+
+func (r *mongoProviders) FindByID(ctx context.Context, id primitive.ObjectID) (models.Provider, error) {
+	return mongoFindOne[models.Provider](ctx, r.c, bson.M{"_id": id})
+}
+
+func (r *mongoProviders) FindByEmail(ctx context.Context, email string) (models.Provider, error) {
+	return mongoFindOne[models.Provider](ctx, r.c, bson.M{"email": email})
+}
+
+func (r *mongoProviders) List(ctx context.Context, companyID string) ([]models.Provider, error) {
+	filter := bson.M{}
+	if companyID != "" {
+		filter["company_id"] = companyID
+	}
+	return mongoFindAll[models.Provider](ctx, r.c, filter)
+}
+
+func (r *mongoProviders) UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return mongoSet(ctx, r.c, bson.M{"_id": id}, fields)
+}
+
+// Hizmet listeleri okuma-değiştirme-yazma yerine dizi operatörleriyle
+// güncellenir; eşzamanlı düzenlemeler birbirinin değişikliğini ezmez.
+
+func (r *mongoProviders) AddServiceNames(ctx context.Context, id primitive.ObjectID, names []string) error {
+	return mongoUpdate(ctx, r.c, bson.M{"_id": id}, bson.M{
+		"$addToSet": bson.M{"services": bson.M{"$each": names}},
+		"$set":      bson.M{"updated_at": time.Now()},
+	})
+}
+
+func (r *mongoProviders) RemoveServiceName(ctx context.Context, id primitive.ObjectID, name string) error {
+	return mongoUpdate(ctx, r.c, bson.M{"_id": id}, bson.M{
+		"$pull": bson.M{"services": name},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+}
+
+func (r *mongoProviders) SetOffering(ctx context.Context, id primitive.ObjectID, offering models.Offering) error {
+	// Önce var olan kayıt değiştirilir, yoksa eklenir. Arada aynı hizmeti
+	// başka bir istek eklediyse ikinci tur onu değiştirir.
+	for attempt := 0; attempt < 2; attempt++ {
+		replaced, err := r.c.UpdateOne(ctx,
+			bson.M{"_id": id, "offerings.service_id": offering.ServiceID},
+			bson.M{"$set": bson.M{"offerings.$": offering, "updated_at": time.Now()}})
+		if err != nil {
+			return err
+		}
+		if replaced.MatchedCount == 1 {
+			return nil
+		}
+		added, err := r.c.UpdateOne(ctx,
+			bson.M{"_id": id, "offerings.service_id": bson.M{"$ne": offering.ServiceID}},
+			bson.M{"$push": bson.M{"offerings": offering}, "$set": bson.M{"updated_at": time.Now()}})
+		if err != nil {
+			return err
+		}
+		if added.MatchedCount == 1 {
+			return nil
+		}
+	}
+	// Her iki güncelleme de eşleşmediyse ya sağlayıcı yoktur ya da hizmet
+	// her turda başka bir istekle eklenip çıkarılmıştır
+	if _, err := r.FindByID(ctx, id); err != nil {
+		return err
+	}
+	return errors.New("offering was modified concurrently")
+}
+
+func (r *mongoProviders) RemoveOffering(ctx context.Context, id primitive.ObjectID, serviceID primitive.ObjectID) error {
+	return mongoUpdate(ctx, r.c, bson.M{"_id": id}, bson.M{
+		"$pull": bson.M{"offerings": bson.M{"service_id": serviceID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+}
+
+func (r *mongoProviders) RemoveOfferingEverywhere(ctx context.Context, serviceID primitive.ObjectID) error {
+	_, err := r.c.UpdateMany(ctx,
+		bson.M{"offerings.service_id": serviceID},
+		bson.M{"$pull": bson.M{"offerings": bson.M{"service_id": serviceID}}})
+	return err
+}
+
+type memProviders struct{ memAccounts }
+
+func (r *memProviders) Create(ctx context.Context, provider models.Provider) error {
+	return r.c.insert(provider)
+}
+
+func (r *memProviders) FindByID(ctx context.Context, id primitive.ObjectID) (models.Provider, error) {
+	return memFindOne[models.Provider](r.c, byID(id))
+}
+
+func (r *memProviders) FindByEmail(ctx context.Context, email string) (models.Provider, error) {
+	return memFindOne[models.Provider](r.c, eq("email", email))
+}
+
+func (r *memProviders) List(ctx context.Context, companyID string) ([]models.Provider, error) {
+	if companyID == "" {
+		return memFindAll[models.Provider](r.c, all)
+	}
+	return memFindAll[models.Provider](r.c, eq("company_id", companyID))
+}
+
+func (r *memProviders) UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return memSet(r.c, byID(id), fields)
+}
+
+func (r *memProviders) AddServiceNames(ctx context.Context, id primitive.ObjectID, names []string) error {
+	return r.mutate(byID(id), func(provider *models.Provider) {
+		for _, name := range names {
+			if !containsString(provider.Services, name) {
+				provider.Services = append(provider.Services, name)
+			}
+		}
+	})
+}
+
+func (r *memProviders) RemoveServiceName(ctx context.Context, id primitive.ObjectID, name string) error {
+	return r.mutate(byID(id), func(provider *models.Provider) {
+		kept := []string{}
+		for _, s := range provider.Services {
+			if s != name {
+				kept = append(kept, s)
+			}
+		}
+		provider.Services = kept
+	})
+}
+
+func (r *memProviders) SetOffering(ctx context.Context, id primitive.ObjectID, offering models.Offering) error {
+	return r.mutate(byID(id), func(provider *models.Provider) {
+		for i := range provider.Offerings {
+			if provider.Offerings[i].ServiceID == offering.ServiceID {
+				provider.Offerings[i] = offering
+				return
+			}
+		}
+		provider.Offerings = append(provider.Offerings, offering)
+	})
+}
+
+func (r *memProviders) RemoveOffering(ctx context.Context, id primitive.ObjectID, serviceID primitive.ObjectID) error {
+	return r.mutate(byID(id), func(provider *models.Provider) {
+		provider.Offerings = withoutOffering(provider.Offerings, serviceID)
+	})
+}
+
+func (r *memProviders) RemoveOfferingEverywhere(ctx context.Context, serviceID primitive.ObjectID) error {
+	err := r.mutate(all, func(provider *models.Provider) {
+		provider.Offerings = withoutOffering(provider.Offerings, serviceID)
+	})
+	if err == ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// mutate eşleşen sağlayıcı kayıtlarını kilit altında çözer, fn ile değiştirir
+// ve hizmet listelerini geri yazar; Mongo'daki dizi operatörlerinin karşılığıdır
+func (r *memProviders) mutate(match func(bson.M) bool, fn func(*models.Provider)) error {
+	var decodeErr error
+	matched, _, err := r.c.update(match, func(doc bson.M) bool {
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			decodeErr = err
+			return false
+		}
+		var provider models.Provider
+		if err := bson.Unmarshal(raw, &provider); err != nil {
+			decodeErr = err
+			return false
+		}
+		fn(&provider)
+		doc["services"] = provider.Services
+		doc["offerings"] = provider.Offerings
+		doc["updated_at"] = time.Now()
+		return true
+	}, true)
+	if err != nil {
+		return err
+	}
+	if decodeErr != nil {
+		return decodeErr
+	}
+	if matched == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func withoutOffering(offerings []models.Offering, serviceID primitive.ObjectID) []models.Offering {
+	kept := []models.Offering{}
+	for _, o := range offerings {
+		if o.ServiceID != serviceID {
+			kept = append(kept, o)
+		}
+	}
+	return kept
+}
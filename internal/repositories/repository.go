@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrNotFound aranan kayıt yoksa ya da güncellenecek kayıt eşleşmezse döner
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate aynı ID ile ikinci bir kayıt eklenmeye çalışılırsa döner
+	ErrDuplicate = errors.New("duplicate record")
+)
+
+// Fields $set ile yazılacak alanlardır. Anahtarlar bson alan adlarıdır.
+type Fields map[string]interface{}
+
+// FieldsOf bir modeli bson etiketlerine göre Fields'a çevirir. omitempty
+// alanlar boşsa dahil edilmez, _id her zaman çıkarılır. Böylece
+// `$set: model` ile yapılan kısmi güncellemelerle aynı sonuç elde edilir.
+func FieldsOf(v interface{}) (Fields, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m bson.M
+	if err := bson.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	delete(m, "_id")
+	return Fields(m), nil
+}
+
+// Repositories uygulamanın tüm veri erişim katmanıdır
+type Repositories struct {
+	Users          UserRepository
+	Admins         AdminRepository
+	Managers       ManagerRepository
+	Providers      ProviderRepository
+	Companies      CompanyRepository
+	Appointments   AppointmentRepository
+	Services       ServiceRepository
+	Verifications  VerificationRepository
+	RefreshTokens  RefreshTokenRepository
+	PasswordResets PasswordResetRepository
+	SecurityEvents SecurityEventRepository
+	WorkingHours   WorkingHoursRepository
+	TimeOff        TimeOffRepository
+	Closures       ClosureRepository
+	Waitlist       WaitlistRepository
+	Series         SeriesRepository
+	Resources      ResourceRepository
+	Reservations   ReservationRepository
+	CalendarFeeds  CalendarFeedRepository
+	BusySources    BusySourceRepository
+	BusyBlocks     BusyBlockRepository
+}
+
+// NewMongo verilen veritabanının koleksiyonları üzerinde çalışan repository'leri döner
+func NewMongo(db *mongo.Database) *Repositories {
+	return &Repositories{
+		Users:          &mongoUsers{mongoAccounts{db.Collection("user"), "password_hash"}},
+		Admins:         &mongoAdmins{mongoAccounts{db.Collection("admin"), "password_hash"}},
+		Managers:       &mongoManagers{mongoAccounts{db.Collection("manager"), "password"}},
+		Providers:      &mongoProviders{mongoAccounts{db.Collection("provider"), "password_hash"}},
+		Companies:      &mongoCompanies{db.Collection("company")},
+		Appointments:   &mongoAppointments{db.Collection("appointment")},
+		Services:       &mongoServices{db.Collection("services")},
+		Verifications:  &mongoVerifications{db.Collection("verification")},
+		RefreshTokens:  &mongoRefreshTokens{db.Collection("auth")},
+		PasswordResets: &mongoPasswordResets{db.Collection("password_resets")},
+		SecurityEvents: &mongoSecurityEvents{db.Collection("security_events")},
+		WorkingHours:   &mongoWorkingHours{db.Collection("working_hours")},
+		TimeOff:        &mongoTimeOff{db.Collection("time_off")},
+		Closures:       &mongoClosures{db.Collection("closures")},
+		Waitlist:       &mongoWaitlist{db.Collection("waitlist")},
+		Series:         &mongoSeries{db.Collection("appointment_series")},
+		Resources:      &mongoResources{db.Collection("resources")},
+		Reservations:   &mongoReservations{db.Collection("resource_reservations")},
+		CalendarFeeds:  &mongoCalendarFeeds{db.Collection("calendar_feeds")},
+		BusySources:    &mongoBusySources{db.Collection("busy_sources")},
+		BusyBlocks:     &mongoBusyBlocks{db.Collection("busy_blocks")},
+	}
+}
+
+// NewMemory veritabanı gerektirmeyen, süreç belleğinde çalışan
+// repository'leri döner. Testler ve yerel demolar için kullanılır; veriler
+// süreç kapanınca kaybolur.
+func NewMemory() *Repositories {
+	return &Repositories{
+		Users:          &memUsers{memAccounts{newMemCollection(), "password_hash"}},
+		Admins:         &memAdmins{memAccounts{newMemCollection(), "password_hash"}},
+		Managers:       &memManagers{memAccounts{newMemCollection(), "password"}},
+		Providers:      &memProviders{memAccounts{newMemCollection(), "password_hash"}},
+		Companies:      &memCompanies{newMemCollection()},
+		Appointments:   &memAppointments{newMemCollection()},
+		Services:       &memServices{newMemCollection()},
+		Verifications:  &memVerifications{newMemCollection()},
+		RefreshTokens:  &memRefreshTokens{newMemCollection()},
+		PasswordResets: &memPasswordResets{newMemCollection()},
+		SecurityEvents: &memSecurityEvents{newMemCollection()},
+		WorkingHours:   &memWorkingHours{newMemCollection()},
+		TimeOff:        &memTimeOff{newMemCollection()},
+		Closures:       &memClosures{newMemCollection()},
+		Waitlist:       &memWaitlist{newMemCollection()},
+		Series:         &memSeries{newMemCollection()},
+		Resources:      &memResources{newMemCollection()},
+		Reservations:   &memReservations{newMemCollection()},
+		CalendarFeeds:  &memCalendarFeeds{newMemCollection()},
+		BusySources:    &memBusySources{newMemCollection()},
+		BusyBlocks:     &memBusyBlocks{newMemCollection()},
+	}
+}
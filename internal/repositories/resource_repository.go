@@ -0,0 +1,182 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"rtsback/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ResourceRepository şirketlerin oda, koltuk ve cihaz gibi kaynaklarıdır
+type ResourceRepository interface {
+	Create(ctx context.Context, resource models.Resource) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.Resource, error)
+	// List şirketin kaynaklarını döner. resourceType boş değilse yalnızca o
+	// türdekiler, activeOnly ise yalnızca etkin olanlar döner.
+	List(ctx context.Context, companyID, resourceType string, activeOnly bool) ([]models.Resource, error)
+	UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error
+	DeleteByID(ctx context.Context, id primitive.ObjectID) error
+}
+
+type mongoResources struct{ c *mongo.Collection }
+
+func (r *mongoResources) Create(ctx context.Context, resource models.Resource) error {
+	return mongoInsert(ctx, r.c, resource)
+}
+
+func (r *mongoResources) FindByID(ctx context.Context, id primitive.ObjectID) (models.Resource, error) {
+	return mongoFindOne[models.Resource](ctx, r.c, bson.M{"_id": id})
+}
+
+func (r *mongoResources) List(ctx context.Context, companyID, resourceType string, activeOnly bool) ([]models.Resource, error) {
+	filter := bson.M{"company_id": companyID}
+	if resourceType != "" {
+		filter["type"] = resourceType
+	}
+	if activeOnly {
+		filter["active"] = true
+	}
+	return mongoFindAll[models.Resource](ctx, r.c, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+}
+
+func (r *mongoResources) UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return mongoSet(ctx, r.c, bson.M{"_id": id}, fields)
+}
+
+func (r *mongoResources) DeleteByID(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.c.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memResources struct{ c *memCollection }
+
+func (r *memResources) Create(ctx context.Context, resource models.Resource) error {
+	return r.c.insert(resource)
+}
+
+func (r *memResources) FindByID(ctx context.Context, id primitive.ObjectID) (models.Resource, error) {
+	return memFindOne[models.Resource](r.c, byID(id))
+}
+
+func (r *memResources) List(ctx context.Context, companyID, resourceType string, activeOnly bool) ([]models.Resource, error) {
+	found, err := memFindAll[models.Resource](r.c, func(doc bson.M) bool {
+		if docString(doc, "company_id") != companyID {
+			return false
+		}
+		if resourceType != "" && docString(doc, "type") != resourceType {
+			return false
+		}
+		return !activeOnly || docBool(doc, "active")
+	})
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Name < found[j].Name })
+	return found, err
+}
+
+func (r *memResources) UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return memSet(r.c, byID(id), fields)
+}
+
+func (r *memResources) DeleteByID(ctx context.Context, id primitive.ObjectID) error {
+	deleted, err := r.c.delete(byID(id))
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ReservationRepository kaynakların randevulara ayrıldığı zamanlardır
+type ReservationRepository interface {
+	// Create ayırmayı ekler. Anahtarlarından biri başka bir ayırmada varsa
+	// kaynak o aralıkta doludur ve ErrDuplicate döner; kontrol ve kayıt
+	// tek işlemdir.
+	Create(ctx context.Context, reservation models.ResourceReservation) error
+	// FindByResource kaynağın [from, to) ile çakışan ayırmalarını
+	// başlangıca göre sıralı döner. Boş to sınır koymaz.
+	FindByResource(ctx context.Context, resourceID primitive.ObjectID, from, to time.Time) ([]models.ResourceReservation, error)
+	FindByAppointment(ctx context.Context, appointmentID primitive.ObjectID) ([]models.ResourceReservation, error)
+	// DeleteByAppointment randevunun tüm ayırmalarını siler ve silinen sayısını döner
+	DeleteByAppointment(ctx context.Context, appointmentID primitive.ObjectID) (int64, error)
+}
+
+type mongoReservations struct{ c *mongo.Collection }
+
+func (r *mongoReservations) Create(ctx context.Context, reservation models.ResourceReservation) error {
+	return mongoInsert(ctx, r.c, reservation)
+}
+
+func (r *mongoReservations) FindByResource(ctx context.Context, resourceID primitive.ObjectID, from, to time.Time) ([]models.ResourceReservation, error) {
+	filter := bson.M{"resource_id": resourceID, "end": bson.M{"$gt": from}}
+	if !to.IsZero() {
+		filter["start"] = bson.M{"$lt": to}
+	}
+	return mongoFindAll[models.ResourceReservation](ctx, r.c, filter, options.Find().SetSort(bson.D{{Key: "start", Value: 1}}))
+}
+
+func (r *mongoReservations) FindByAppointment(ctx context.Context, appointmentID primitive.ObjectID) ([]models.ResourceReservation, error) {
+	return mongoFindAll[models.ResourceReservation](ctx, r.c, bson.M{"appointment_id": appointmentID})
+}
+
+func (r *mongoReservations) DeleteByAppointment(ctx context.Context, appointmentID primitive.ObjectID) (int64, error) {
+	result, err := r.c.DeleteMany(ctx, bson.M{"appointment_id": appointmentID})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+type memReservations struct{ c *memCollection }
+
+func (r *memReservations) Create(ctx context.Context, reservation models.ResourceReservation) error {
+	// Mongo'daki keys tekil indeksinin karşılığı
+	return r.c.insertUnless(reservation, func(doc bson.M) bool {
+		for _, key := range docStrings(doc, "keys") {
+			if containsString(reservation.Keys, key) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (r *memReservations) FindByResource(ctx context.Context, resourceID primitive.ObjectID, from, to time.Time) ([]models.ResourceReservation, error) {
+	found, err := memFindAll[models.ResourceReservation](r.c, func(doc bson.M) bool {
+		if id, _ := doc["resource_id"].(primitive.ObjectID); id != resourceID {
+			return false
+		}
+		start, _ := docTime(doc, "start")
+		end, _ := docTime(doc, "end")
+		return end.After(from) && (to.IsZero() || start.Before(to))
+	})
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Start.Before(found[j].Start) })
+	return found, err
+}
+
+func (r *memReservations) FindByAppointment(ctx context.Context, appointmentID primitive.ObjectID) ([]models.ResourceReservation, error) {
+	return memFindAll[models.ResourceReservation](r.c, func(doc bson.M) bool {
+		id, _ := doc["appointment_id"].(primitive.ObjectID)
+		return id == appointmentID
+	})
+}
+
+func (r *memReservations) DeleteByAppointment(ctx context.Context, appointmentID primitive.ObjectID) (int64, error) {
+	deleted, err := r.c.deleteAll(func(doc bson.M) bool {
+		id, _ := doc["appointment_id"].(primitive.ObjectID)
+		return id == appointmentID
+	})
+	return int64(deleted), err
+}
@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"context"
+
+	"rtsback/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SeriesRepository tekrarlayan randevu serileridir. Serinin randevuları
+// appointment koleksiyonunda series_id ile bulunur.
+type SeriesRepository interface {
+	Create(ctx context.Context, series models.AppointmentSeries) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.AppointmentSeries, error)
+	UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error
+}
+
+type mongoSeries struct{ c *mongo.Collection }
+
+func (r *mongoSeries) Create(ctx context.Context, series models.AppointmentSeries) error {
+	return mongoInsert(ctx, r.c, series)
+}
+
+func (r *mongoSeries) FindByID(ctx context.Context, id primitive.ObjectID) (models.AppointmentSeries, error) {
+	return mongoFindOne[models.AppointmentSeries](ctx, r.c, bson.M{"_id": id})
+}
+
+func (r *mongoSeries) UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return mongoSet(ctx, r.c, bson.M{"_id": id}, fields)
+}
+
+type memSeries struct{ c *memCollection }
+
+func (r *memSeries) Create(ctx context.Context, series models.AppointmentSeries) error {
+	return r.c.insert(series)
+}
+
+func (r *memSeries) FindByID(ctx context.Context, id primitive.ObjectID) (models.AppointmentSeries, error) {
+	return memFindOne[models.AppointmentSeries](r.c, byID(id))
+}
+
+func (r *memSeries) UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return memSet(r.c, byID(id), fields)
+}
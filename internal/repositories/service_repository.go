@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+
+	"rtsback/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ServiceRepository şirketlerin hizmet kataloğudur
+type ServiceRepository interface {
+	Create(ctx context.Context, service models.Service) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.Service, error)
+	// FindByIDs verilen ID'lerden bulunanları döner; bulunamayanlar atlanır
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Service, error)
+	// List şirketin hizmetlerini döner; activeOnly ise pasif hizmetler atlanır
+	List(ctx context.Context, companyID string, activeOnly bool) ([]models.Service, error)
+	UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error
+	DeleteByID(ctx context.Context, id primitive.ObjectID) error
+}
+
+type mongoServices struct{ c *mongo.Collection }
+
+func (r *mongoServices) Create(ctx context.Context, service models.Service) error {
+	return mongoInsert(ctx, r.c, service)
+}
+
+func (r *mongoServices) FindByID(ctx context.Context, id primitive.ObjectID) (models.Service, error) {
+	return mongoFindOne[models.Service](ctx, r.c, bson.M{"_id": id})
+}
+
+func (r *mongoServices) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Service, error) {
+	return mongoFindAll[models.Service](ctx, r.c, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+func (r *mongoServices) List(ctx context.Context, companyID string, activeOnly bool) ([]models.Service, error) {
+	filter := bson.M{"company_id": companyID}
+	if activeOnly {
+		filter["active"] = true
+	}
+	return mongoFindAll[models.Service](ctx, r.c, filter)
+}
+
+func (r *mongoServices) UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return mongoSet(ctx, r.c, bson.M{"_id": id}, fields)
+}
+
+func (r *mongoServices) DeleteByID(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.c.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memServices struct{ c *memCollection }
+
+func (r *memServices) Create(ctx context.Context, service models.Service) error {
+	return r.c.insert(service)
+}
+
+func (r *memServices) FindByID(ctx context.Context, id primitive.ObjectID) (models.Service, error) {
+	return memFindOne[models.Service](r.c, byID(id))
+}
+
+func (r *memServices) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Service, error) {
+	return memFindAll[models.Service](r.c, func(doc bson.M) bool {
+		id, _ := doc["_id"].(primitive.ObjectID)
+		return containsID(ids, id)
+	})
+}
+
+func (r *memServices) List(ctx context.Context, companyID string, activeOnly bool) ([]models.Service, error) {
+	return memFindAll[models.Service](r.c, func(doc bson.M) bool {
+		return docString(doc, "company_id") == companyID && (!activeOnly || docBool(doc, "active"))
+	})
+}
+
+func (r *memServices) UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return memSet(r.c, byID(id), fields)
+}
+
+func (r *memServices) DeleteByID(ctx context.Context, id primitive.ObjectID) error {
+	deleted, err := r.c.delete(byID(id))
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"rtsback/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TimeOffRepository sağlayıcıların izin kayıtlarıdır
+type TimeOffRepository interface {
+	Create(ctx context.Context, timeOff models.TimeOff) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.TimeOff, error)
+	// Find sağlayıcının [from, to) ile çakışan izinlerini başlangıca göre
+	// sıralı döner. Boş from ya da to o yönde sınır koymaz.
+	Find(ctx context.Context, providerID primitive.ObjectID, from, to time.Time) ([]models.TimeOff, error)
+	DeleteByID(ctx context.Context, id primitive.ObjectID) error
+}
+
+type mongoTimeOff struct{ c *mongo.Collection }
+
+func (r *mongoTimeOff) Create(ctx context.Context, timeOff models.TimeOff) error {
+	return mongoInsert(ctx, r.c, timeOff)
+}
+
+func (r *mongoTimeOff) FindByID(ctx context.Context, id primitive.ObjectID) (models.TimeOff, error) {
+	return mongoFindOne[models.TimeOff](ctx, r.c, bson.M{"_id": id})
+}
+
+func (r *mongoTimeOff) Find(ctx context.Context, providerID primitive.ObjectID, from, to time.Time) ([]models.TimeOff, error) {
+	filter := bson.M{"provider_id": providerID}
+	if !from.IsZero() {
+		filter["end"] = bson.M{"$gt": from}
+	}
+	if !to.IsZero() {
+		filter["start"] = bson.M{"$lt": to}
+	}
+	return mongoFindAll[models.TimeOff](ctx, r.c, filter, options.Find().SetSort(bson.D{{Key: "start", Value: 1}}))
+}
+
+func (r *mongoTimeOff) DeleteByID(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.c.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memTimeOff struct{ c *memCollection }
+
+func (r *memTimeOff) Create(ctx context.Context, timeOff models.TimeOff) error {
+	return r.c.insert(timeOff)
+}
+
+func (r *memTimeOff) FindByID(ctx context.Context, id primitive.ObjectID) (models.TimeOff, error) {
+	return memFindOne[models.TimeOff](r.c, byID(id))
+}
+
+func (r *memTimeOff) Find(ctx context.Context, providerID primitive.ObjectID, from, to time.Time) ([]models.TimeOff, error) {
+	found, err := memFindAll[models.TimeOff](r.c, func(doc bson.M) bool {
+		if id, _ := doc["provider_id"].(primitive.ObjectID); id != providerID {
+			return false
+		}
+		start, _ := docTime(doc, "start")
+		end, _ := docTime(doc, "end")
+		return (from.IsZero() || end.After(from)) && (to.IsZero() || start.Before(to))
+	})
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Start.Before(found[j].Start) })
+	return found, err
+}
+
+func (r *memTimeOff) DeleteByID(ctx context.Context, id primitive.ObjectID) error {
+	deleted, err := r.c.delete(byID(id))
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ClosureRepository şirketlerin kapalı günleridir
+type ClosureRepository interface {
+	// Create kapalı günü ekler; şirketin o gün için kaydı varsa ErrDuplicate döner
+	Create(ctx context.Context, closure models.Closure) error
+	// Find şirketin [from, to) aralığındaki kapalı günlerini tarihe göre
+	// sıralı döner. Boş from ya da to o yönde sınır koymaz.
+	Find(ctx context.Context, companyID string, from, to time.Time) ([]models.Closure, error)
+	// Delete şirketin o günkü kaydını siler
+	Delete(ctx context.Context, companyID string, date time.Time) error
+}
+
+type mongoClosures struct{ c *mongo.Collection }
+
+func (r *mongoClosures) Create(ctx context.Context, closure models.Closure) error {
+	return mongoInsert(ctx, r.c, closure)
+}
+
+func (r *mongoClosures) Find(ctx context.Context, companyID string, from, to time.Time) ([]models.Closure, error) {
+	filter := bson.M{"company_id": companyID}
+	if !from.IsZero() || !to.IsZero() {
+		date := bson.M{}
+		if !from.IsZero() {
+			date["$gte"] = from
+		}
+		if !to.IsZero() {
+			date["$lt"] = to
+		}
+		filter["date"] = date
+	}
+	return mongoFindAll[models.Closure](ctx, r.c, filter, options.Find().SetSort(bson.D{{Key: "date", Value: 1}}))
+}
+
+func (r *mongoClosures) Delete(ctx context.Context, companyID string, date time.Time) error {
+	result, err := r.c.DeleteOne(ctx, bson.M{"company_id": companyID, "date": date})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memClosures struct{ c *memCollection }
+
+func sameClosure(companyID string, date time.Time) func(bson.M) bool {
+	return func(doc bson.M) bool {
+		d, _ := docTime(doc, "date")
+		return docString(doc, "company_id") == companyID && d.Equal(date)
+	}
+}
+
+func (r *memClosures) Create(ctx context.Context, closure models.Closure) error {
+	// Mongo'daki company_id+date tekil indeksinin karşılığı
+	return r.c.insertUnless(closure, sameClosure(closure.CompanyID, closure.Date))
+}
+
+func (r *memClosures) Find(ctx context.Context, companyID string, from, to time.Time) ([]models.Closure, error) {
+	found, err := memFindAll[models.Closure](r.c, func(doc bson.M) bool {
+		date, _ := docTime(doc, "date")
+		return docString(doc, "company_id") == companyID &&
+			(from.IsZero() || !date.Before(from)) && (to.IsZero() || date.Before(to))
+	})
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Date.Before(found[j].Date) })
+	return found, err
+}
+
+func (r *memClosures) Delete(ctx context.Context, companyID string, date time.Time) error {
+	deleted, err := r.c.delete(sameClosure(companyID, date))
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -1 +1,401 @@
-package repositories
\ No newline at end of file
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"rtsback/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Account şifre ve iki adımlı doğrulama işlemlerinde tüm rollerin ortak
+// hesap alanlarıdır
+type Account struct {
+	ID                primitive.ObjectID
+	Email             string
+	CompanyID         string
+	PasswordHash      string
+	TOTPSecret        string
+	TOTPPendingSecret string
+	TOTPEnabled       bool
+	TOTPLastStep      int64
+	RecoveryCodes     []string
+}
+
+// AccountRepository giriş yapabilen her rolün koleksiyonunda ortak olan
+// hesap işlemleridir
+type AccountRepository interface {
+	FindAccountByID(ctx context.Context, id primitive.ObjectID) (Account, error)
+	FindAccountByEmail(ctx context.Context, email string) (Account, error)
+	SetPassword(ctx context.Context, id primitive.ObjectID, hash string) error
+
+	// SetPendingTOTP onay bekleyen TOTP anahtarını kaydeder
+	SetPendingTOTP(ctx context.Context, id primitive.ObjectID, secret string) error
+	// EnableTOTP bekleyen anahtar hâlâ pendingSecret ise onu etkinleştirir
+	EnableTOTP(ctx context.Context, id primitive.ObjectID, pendingSecret string, step int64, recoveryHashes []string) error
+	DisableTOTP(ctx context.Context, id primitive.ObjectID) error
+	// ConsumeTOTPStep adım daha önce kullanılmadıysa kaydeder ve true döner
+	ConsumeTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error)
+	// ConsumeRecoveryCode kurtarma kodu özetini listeden düşer ve bulunduysa true döner
+	ConsumeRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) (bool, error)
+}
+
+type UserRepository interface {
+	AccountRepository
+	Create(ctx context.Context, user models.User) error
+	FindByEmail(ctx context.Context, email string) (models.User, error)
+	List(ctx context.Context) ([]models.User, error)
+	UpdateByEmail(ctx context.Context, email string, fields Fields) error
+	UpsertByID(ctx context.Context, id primitive.ObjectID, fields Fields) error
+}
+
+type AdminRepository interface {
+	AccountRepository
+	Create(ctx context.Context, admin models.Admin) error
+	FindByEmail(ctx context.Context, email string) (models.Admin, error)
+	List(ctx context.Context) ([]models.Admin, error)
+	UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error
+	UpdateByEmail(ctx context.Context, email string, fields Fields) error
+}
+
+type ManagerRepository interface {
+	AccountRepository
+	Create(ctx context.Context, manager models.Manager) error
+	FindByEmail(ctx context.Context, email string) (models.Manager, error)
+	ListByCompany(ctx context.Context, companyID string) ([]models.Manager, error)
+}
+
+// accountDoc hesap koleksiyonlarından okunan ortak alanlardır. Manager
+// kayıtları şifreyi `password`, diğerleri `password_hash` alanında tutar.
+type accountDoc struct {
+	ID                primitive.ObjectID `bson:"_id"`
+	Email             string             `bson:"email"`
+	CompanyID         string             `bson:"company_id"`
+	PasswordHash      string             `bson:"password_hash"`
+	Password          string             `bson:"password"`
+	TOTPSecret        string             `bson:"totp_secret"`
+	TOTPPendingSecret string             `bson:"totp_pending_secret"`
+	TOTPEnabled       bool               `bson:"totp_enabled"`
+	TOTPLastStep      int64              `bson:"totp_last_step"`
+	RecoveryCodes     []string           `bson:"recovery_codes"`
+}
+
+func (d accountDoc) account(passwordField string) Account {
+	hash := d.PasswordHash
+	if passwordField == "password" || hash == "" {
+		if d.Password != "" {
+			hash = d.Password
+		}
+	}
+	return Account{
+		ID:                d.ID,
+		Email:             d.Email,
+		CompanyID:         d.CompanyID,
+		PasswordHash:      hash,
+		TOTPSecret:        d.TOTPSecret,
+		TOTPPendingSecret: d.TOTPPendingSecret,
+		TOTPEnabled:       d.TOTPEnabled,
+		TOTPLastStep:      d.TOTPLastStep,
+		RecoveryCodes:     d.RecoveryCodes,
+	}
+}
+
+// Mongo
+
+type mongoAccounts struct {
+	c             *mongo.Collection
+	passwordField string
+}
+
+func (r mongoAccounts) findAccount(ctx context.Context, filter bson.M) (Account, error) {
+	doc, err := mongoFindOne[accountDoc](ctx, r.c, filter)
+	if err != nil {
+		return Account{}, err
+	}
+	return doc.account(r.passwordField), nil
+}
+
+func (r mongoAccounts) FindAccountByID(ctx context.Context, id primitive.ObjectID) (Account, error) {
+	return r.findAccount(ctx, bson.M{"_id": id})
+}
+
+func (r mongoAccounts) FindAccountByEmail(ctx context.Context, email string) (Account, error) {
+	return r.findAccount(ctx, bson.M{"email": email})
+}
+
+func (r mongoAccounts) SetPassword(ctx context.Context, id primitive.ObjectID, hash string) error {
+	return mongoSet(ctx, r.c, bson.M{"_id": id}, Fields{r.passwordField: hash, "updated_at": time.Now()})
+}
+
+func (r mongoAccounts) SetPendingTOTP(ctx context.Context, id primitive.ObjectID, secret string) error {
+	return mongoSet(ctx, r.c, bson.M{"_id": id}, Fields{"totp_pending_secret": secret, "updated_at": time.Now()})
+}
+
+func (r mongoAccounts) EnableTOTP(ctx context.Context, id primitive.ObjectID, pendingSecret string, step int64, recoveryHashes []string) error {
+	return mongoUpdate(ctx, r.c,
+		bson.M{"_id": id, "totp_pending_secret": pendingSecret},
+		bson.M{
+			"$set": bson.M{
+				"totp_secret":    pendingSecret,
+				"totp_enabled":   true,
+				"totp_last_step": step,
+				"recovery_codes": recoveryHashes,
+				"updated_at":     time.Now(),
+			},
+			"$unset": bson.M{"totp_pending_secret": ""},
+		},
+	)
+}
+
+func (r mongoAccounts) DisableTOTP(ctx context.Context, id primitive.ObjectID) error {
+	return mongoUpdate(ctx, r.c,
+		bson.M{"_id": id},
+		bson.M{
+			"$set":   bson.M{"totp_enabled": false, "updated_at": time.Now()},
+			"$unset": bson.M{"totp_secret": "", "totp_pending_secret": "", "totp_last_step": "", "recovery_codes": ""},
+		},
+	)
+}
+
+func (r mongoAccounts) ConsumeTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	return mongoModified(ctx, r.c,
+		bson.M{
+			"_id": id,
+			"$or": bson.A{
+				bson.M{"totp_last_step": bson.M{"$exists": false}},
+				bson.M{"totp_last_step": bson.M{"$lt": step}},
+			},
+		},
+		bson.M{"$set": bson.M{"totp_last_step": step}},
+	)
+}
+
+func (r mongoAccounts) ConsumeRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) (bool, error) {
+	return mongoModified(ctx, r.c,
+		bson.M{"_id": id, "recovery_codes": hash},
+		bson.M{"$pull": bson.M{"recovery_codes": hash}},
+	)
+}
+
+type mongoUsers struct{ mongoAccounts }
+
+func (r *mongoUsers) Create(ctx context.Context, user models.User) error {
+	return mongoInsert(ctx, r.c, user)
+}
+
+func (r *mongoUsers) FindByEmail(ctx context.Context, email string) (models.User, error) {
+	return mongoFindOne[models.User](ctx, r.c, bson.M{"email": email})
+}
+
+func (r *mongoUsers) List(ctx context.Context) ([]models.User, error) {
+	return mongoFindAll[models.User](ctx, r.c, bson.M{})
+}
+
+func (r *mongoUsers) UpdateByEmail(ctx context.Context, email string, fields Fields) error {
+	return mongoSet(ctx, r.c, bson.M{"email": email}, fields)
+}
+
+func (r *mongoUsers) UpsertByID(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	_, err := r.c.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M(fields)}, options.Update().SetUpsert(true))
+	return err
+}
+
+type mongoAdmins struct{ mongoAccounts }
+
+func (r *mongoAdmins) Create(ctx context.Context, admin models.Admin) error {
+	return mongoInsert(ctx, r.c, admin)
+}
+
+func (r *mongoAdmins) FindByEmail(ctx context.Context, email string) (models.Admin, error) {
+	return mongoFindOne[models.Admin](ctx, r.c, bson.M{"email": email})
+}
+
+func (r *mongoAdmins) List(ctx context.Context) ([]models.Admin, error) {
+	return mongoFindAll[models.Admin](ctx, r.c, bson.M{})
+}
+
+func (r *mongoAdmins) UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return mongoSet(ctx, r.c, bson.M{"_id": id}, fields)
+}
+
+func (r *mongoAdmins) UpdateByEmail(ctx context.Context, email string, fields Fields) error {
+	return mongoSet(ctx, r.c, bson.M{"email": email}, fields)
+}
+
+type mongoManagers struct{ mongoAccounts }
+
+func (r *mongoManagers) Create(ctx context.Context, manager models.Manager) error {
+	return mongoInsert(ctx, r.c, manager)
+}
+
+func (r *mongoManagers) FindByEmail(ctx context.Context, email string) (models.Manager, error) {
+	return mongoFindOne[models.Manager](ctx, r.c, bson.M{"email": email})
+}
+
+func (r *mongoManagers) ListByCompany(ctx context.Context, companyID string) ([]models.Manager, error) {
+	return mongoFindAll[models.Manager](ctx, r.c, bson.M{"company_id": companyID})
+}
+
+// Bellek içi
+
+type memAccounts struct {
+	c             *memCollection
+	passwordField string
+}
+
+func (r memAccounts) findAccount(match func(bson.M) bool) (Account, error) {
+	doc, err := memFindOne[accountDoc](r.c, match)
+	if err != nil {
+		return Account{}, err
+	}
+	return doc.account(r.passwordField), nil
+}
+
+func (r memAccounts) FindAccountByID(ctx context.Context, id primitive.ObjectID) (Account, error) {
+	return r.findAccount(byID(id))
+}
+
+func (r memAccounts) FindAccountByEmail(ctx context.Context, email string) (Account, error) {
+	return r.findAccount(eq("email", email))
+}
+
+func (r memAccounts) SetPassword(ctx context.Context, id primitive.ObjectID, hash string) error {
+	return memSet(r.c, byID(id), Fields{r.passwordField: hash, "updated_at": time.Now()})
+}
+
+func (r memAccounts) SetPendingTOTP(ctx context.Context, id primitive.ObjectID, secret string) error {
+	return memSet(r.c, byID(id), Fields{"totp_pending_secret": secret, "updated_at": time.Now()})
+}
+
+func (r memAccounts) EnableTOTP(ctx context.Context, id primitive.ObjectID, pendingSecret string, step int64, recoveryHashes []string) error {
+	matched, _, err := r.c.update(
+		func(doc bson.M) bool {
+			return doc["_id"] == id && docString(doc, "totp_pending_secret") == pendingSecret
+		},
+		func(doc bson.M) bool {
+			setFields(doc, Fields{
+				"totp_secret":    pendingSecret,
+				"totp_enabled":   true,
+				"totp_last_step": step,
+				"recovery_codes": recoveryHashes,
+				"updated_at":     time.Now(),
+			})
+			unsetFields(doc, "totp_pending_secret")
+			return true
+		}, false)
+	if err != nil {
+		return err
+	}
+	if matched == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r memAccounts) DisableTOTP(ctx context.Context, id primitive.ObjectID) error {
+	matched, _, err := r.c.update(byID(id), func(doc bson.M) bool {
+		setFields(doc, Fields{"totp_enabled": false, "updated_at": time.Now()})
+		unsetFields(doc, "totp_secret", "totp_pending_secret", "totp_last_step", "recovery_codes")
+		return true
+	}, false)
+	if err != nil {
+		return err
+	}
+	if matched == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r memAccounts) ConsumeTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	_, modified, err := r.c.update(
+		func(doc bson.M) bool {
+			last, ok := docInt64(doc, "totp_last_step")
+			return doc["_id"] == id && (!ok || last < step)
+		},
+		func(doc bson.M) bool {
+			doc["totp_last_step"] = step
+			return true
+		}, false)
+	return modified == 1, err
+}
+
+func (r memAccounts) ConsumeRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) (bool, error) {
+	_, modified, err := r.c.update(byID(id), func(doc bson.M) bool {
+		codes := docStrings(doc, "recovery_codes")
+		kept := codes[:0]
+		for _, code := range codes {
+			if code != hash {
+				kept = append(kept, code)
+			}
+		}
+		if len(kept) == len(codes) {
+			return false
+		}
+		doc["recovery_codes"] = kept
+		return true
+	}, false)
+	return modified == 1, err
+}
+
+type memUsers struct{ memAccounts }
+
+func (r *memUsers) Create(ctx context.Context, user models.User) error {
+	return r.c.insert(user)
+}
+
+func (r *memUsers) FindByEmail(ctx context.Context, email string) (models.User, error) {
+	return memFindOne[models.User](r.c, eq("email", email))
+}
+
+func (r *memUsers) List(ctx context.Context) ([]models.User, error) {
+	return memFindAll[models.User](r.c, all)
+}
+
+func (r *memUsers) UpdateByEmail(ctx context.Context, email string, fields Fields) error {
+	return memSet(r.c, eq("email", email), fields)
+}
+
+func (r *memUsers) UpsertByID(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return r.c.upsert(id, func(doc bson.M) { setFields(doc, fields) })
+}
+
+type memAdmins struct{ memAccounts }
+
+func (r *memAdmins) Create(ctx context.Context, admin models.Admin) error {
+	return r.c.insert(admin)
+}
+
+func (r *memAdmins) FindByEmail(ctx context.Context, email string) (models.Admin, error) {
+	return memFindOne[models.Admin](r.c, eq("email", email))
+}
+
+func (r *memAdmins) List(ctx context.Context) ([]models.Admin, error) {
+	return memFindAll[models.Admin](r.c, all)
+}
+
+func (r *memAdmins) UpdateByID(ctx context.Context, id primitive.ObjectID, fields Fields) error {
+	return memSet(r.c, byID(id), fields)
+}
+
+func (r *memAdmins) UpdateByEmail(ctx context.Context, email string, fields Fields) error {
+	return memSet(r.c, eq("email", email), fields)
+}
+
+type memManagers struct{ memAccounts }
+
+func (r *memManagers) Create(ctx context.Context, manager models.Manager) error {
+	return r.c.insert(manager)
+}
+
+func (r *memManagers) FindByEmail(ctx context.Context, email string) (models.Manager, error) {
+	return memFindOne[models.Manager](r.c, eq("email", email))
+}
+
+func (r *memManagers) ListByCompany(ctx context.Context, companyID string) ([]models.Manager, error) {
+	return memFindAll[models.Manager](r.c, eq("company_id", companyID))
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"rtsback/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type VerificationRepository interface {
+	// SaveCode kullanıcının doğrulama kaydındaki kodu değiştirir; kayıt
+	// yoksa oluşturur. Böylece her kullanıcının tek bir geçerli kodu olur.
+	SaveCode(ctx context.Context, verification models.Verification) error
+	FindByUserID(ctx context.Context, userID string) (models.Verification, error)
+	MarkEmailVerified(ctx context.Context, userID string, at time.Time) error
+}
+
+type mongoVerifications struct{ c *mongo.Collection }
+
+func (r *mongoVerifications) SaveCode(ctx context.Context, verification models.Verification) error {
+	_, err := r.c.UpdateOne(ctx,
+		bson.M{"user_id": verification.UserID},
+		bson.M{
+			"$set": bson.M{
+				"email":       verification.Email,
+				"email_code":  verification.EmailCode,
+				"create_time": verification.CreateTime,
+			},
+			"$setOnInsert": bson.M{"email_ver": false, "phone_ver": false},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *mongoVerifications) FindByUserID(ctx context.Context, userID string) (models.Verification, error) {
+	return mongoFindOne[models.Verification](ctx, r.c, bson.M{"user_id": userID})
+}
+
+func (r *mongoVerifications) MarkEmailVerified(ctx context.Context, userID string, at time.Time) error {
+	return mongoSet(ctx, r.c, bson.M{"user_id": userID}, Fields{"email_ver": true, "email_ver_time": at})
+}
+
+type memVerifications struct{ c *memCollection }
+
+func (r *memVerifications) SaveCode(ctx context.Context, verification models.Verification) error {
+	matched, _, err := r.c.update(eq("user_id", verification.UserID), func(doc bson.M) bool {
+		setFields(doc, Fields{
+			"email":       verification.Email,
+			"email_code":  verification.EmailCode,
+			"create_time": verification.CreateTime,
+		})
+		return true
+	}, false)
+	if err != nil || matched > 0 {
+		return err
+	}
+	return r.c.insert(verification)
+}
+
+func (r *memVerifications) FindByUserID(ctx context.Context, userID string) (models.Verification, error) {
+	return memFindOne[models.Verification](r.c, eq("user_id", userID))
+}
+
+func (r *memVerifications) MarkEmailVerified(ctx context.Context, userID string, at time.Time) error {
+	return memSet(r.c, eq("user_id", userID), Fields{"email_ver": true, "email_ver_time": at})
+}
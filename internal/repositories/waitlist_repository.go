@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"rtsback/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WaitlistFilter boş alanlar filtreye katılmaz. ProviderEmail yalnızca o
+// sağlayıcıya yazılmış kayıtları seçer; OfferExpiredBefore verilirse
+// yalnızca teklifi o andan önce dolmuş kayıtlar döner.
+type WaitlistFilter struct {
+	CompanyID          string
+	ProviderEmail      string
+	CustomerEmail      string
+	Statuses           []string
+	OfferExpiredBefore time.Time
+}
+
+func (f WaitlistFilter) bson() bson.M {
+	filter := bson.M{}
+	if f.CompanyID != "" {
+		filter["company_id"] = f.CompanyID
+	}
+	if f.ProviderEmail != "" {
+		filter["provider_email"] = f.ProviderEmail
+	}
+	if f.CustomerEmail != "" {
+		filter["customer_email"] = f.CustomerEmail
+	}
+	if len(f.Statuses) > 0 {
+		filter["status"] = bson.M{"$in": f.Statuses}
+	}
+	if !f.OfferExpiredBefore.IsZero() {
+		filter["offer_expires_at"] = bson.M{"$lte": f.OfferExpiredBefore}
+	}
+	return filter
+}
+
+func (f WaitlistFilter) match(doc bson.M) bool {
+	if f.CompanyID != "" && docString(doc, "company_id") != f.CompanyID {
+		return false
+	}
+	if f.ProviderEmail != "" && docString(doc, "provider_email") != f.ProviderEmail {
+		return false
+	}
+	if f.CustomerEmail != "" && docString(doc, "customer_email") != f.CustomerEmail {
+		return false
+	}
+	if len(f.Statuses) > 0 && !containsString(f.Statuses, docString(doc, "status")) {
+		return false
+	}
+	if !f.OfferExpiredBefore.IsZero() {
+		expiresAt, ok := docTime(doc, "offer_expires_at")
+		if !ok || expiresAt.After(f.OfferExpiredBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+// WaitlistCondition koşullu güncellemenin ön koşuludur. TokenHash
+// verilirse kayıt hâlâ o teklifi taşımalıdır.
+type WaitlistCondition struct {
+	Statuses  []string
+	TokenHash string
+}
+
+func (c WaitlistCondition) bson(id primitive.ObjectID) bson.M {
+	filter := bson.M{"_id": id}
+	if len(c.Statuses) > 0 {
+		filter["status"] = bson.M{"$in": c.Statuses}
+	}
+	if c.TokenHash != "" {
+		filter["offer_token_hash"] = c.TokenHash
+	}
+	return filter
+}
+
+func (c WaitlistCondition) match(id primitive.ObjectID) func(bson.M) bool {
+	return func(doc bson.M) bool {
+		if doc["_id"] != id {
+			return false
+		}
+		if c.TokenHash != "" && docString(doc, "offer_token_hash") != c.TokenHash {
+			return false
+		}
+		return len(c.Statuses) == 0 || containsString(c.Statuses, docString(doc, "status"))
+	}
+}
+
+// WaitlistRepository müşterilerin bekleme listesi kayıtlarıdır
+type WaitlistRepository interface {
+	Create(ctx context.Context, entry models.WaitlistEntry) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.WaitlistEntry, error)
+	FindByTokenHash(ctx context.Context, tokenHash string) (models.WaitlistEntry, error)
+	// Find kayıtları sıraya giriş zamanına göre, en eskiden başlayarak döner
+	Find(ctx context.Context, filter WaitlistFilter) ([]models.WaitlistEntry, error)
+	// UpdateIf koşul sağlanıyorsa alanları yazar ve kaydın değişip değişmediğini döner
+	UpdateIf(ctx context.Context, id primitive.ObjectID, cond WaitlistCondition, fields Fields) (bool, error)
+}
+
+type mongoWaitlist struct{ c *mongo.Collection }
+
+func (r *mongoWaitlist) Create(ctx context.Context, entry models.WaitlistEntry) error {
+	return mongoInsert(ctx, r.c, entry)
+}
+
+func (r *mongoWaitlist) FindByID(ctx context.Context, id primitive.ObjectID) (models.WaitlistEntry, error) {
+	return mongoFindOne[models.WaitlistEntry](ctx, r.c, bson.M{"_id": id})
+}
+
+func (r *mongoWaitlist) FindByTokenHash(ctx context.Context, tokenHash string) (models.WaitlistEntry, error) {
+	return mongoFindOne[models.WaitlistEntry](ctx, r.c, bson.M{"offer_token_hash": tokenHash})
+}
+
+func (r *mongoWaitlist) Find(ctx context.Context, filter WaitlistFilter) ([]models.WaitlistEntry, error) {
+	return mongoFindAll[models.WaitlistEntry](ctx, r.c, filter.bson(),
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+}
+
+func (r *mongoWaitlist) UpdateIf(ctx context.Context, id primitive.ObjectID, cond WaitlistCondition, fields Fields) (bool, error) {
+	return mongoModified(ctx, r.c, cond.bson(id), bson.M{"$set": bson.M(fields)})
+}
+
+type memWaitlist struct{ c *memCollection }
+
+func (r *memWaitlist) Create(ctx context.Context, entry models.WaitlistEntry) error {
+	return r.c.insert(entry)
+}
+
+func (r *memWaitlist) FindByID(ctx context.Context, id primitive.ObjectID) (models.WaitlistEntry, error) {
+	return memFindOne[models.WaitlistEntry](r.c, byID(id))
+}
+
+func (r *memWaitlist) FindByTokenHash(ctx context.Context, tokenHash string) (models.WaitlistEntry, error) {
+	return memFindOne[models.WaitlistEntry](r.c, eq("offer_token_hash", tokenHash))
+}
+
+func (r *memWaitlist) Find(ctx context.Context, filter WaitlistFilter) ([]models.WaitlistEntry, error) {
+	found, err := memFindAll[models.WaitlistEntry](r.c, filter.match)
+	sort.SliceStable(found, func(i, j int) bool { return found[i].CreatedAt.Before(found[j].CreatedAt) })
+	return found, err
+}
+
+func (r *memWaitlist) UpdateIf(ctx context.Context, id primitive.ObjectID, cond WaitlistCondition, fields Fields) (bool, error) {
+	_, modified, err := r.c.update(cond.match(id), func(doc bson.M) bool {
+		setFields(doc, fields)
+		return true
+	}, false)
+	return modified == 1, err
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+
+	"rtsback/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WorkingHoursRepository sağlayıcı başına tek bir çalışma saatleri kaydı
+// tutar; kaydın ID'si sağlayıcının ID'sidir
+type WorkingHoursRepository interface {
+	Get(ctx context.Context, providerID primitive.ObjectID) (models.WorkingHours, error)
+	Save(ctx context.Context, hours models.WorkingHours) error
+}
+
+type mongoWorkingHours struct{ c *mongo.Collection }
+
+func (r *mongoWorkingHours) Get(ctx context.Context, providerID primitive.ObjectID) (models.WorkingHours, error) {
+	return mongoFindOne[models.WorkingHours](ctx, r.c, bson.M{"_id": providerID})
+}
+
+func (r *mongoWorkingHours) Save(ctx context.Context, hours models.WorkingHours) error {
+	_, err := r.c.ReplaceOne(ctx, bson.M{"_id": hours.ID}, hours, options.Replace().SetUpsert(true))
+	return err
+}
+
+type memWorkingHours struct{ c *memCollection }
+
+func (r *memWorkingHours) Get(ctx context.Context, providerID primitive.ObjectID) (models.WorkingHours, error) {
+	return memFindOne[models.WorkingHours](r.c, byID(providerID))
+}
+
+func (r *memWorkingHours) Save(ctx context.Context, hours models.WorkingHours) error {
+	raw, err := bson.Marshal(hours)
+	if err != nil {
+		return err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	return r.c.upsert(hours.ID, func(existing bson.M) {
+		for k := range existing {
+			delete(existing, k)
+		}
+		setFields(existing, Fields(doc))
+	})
+}
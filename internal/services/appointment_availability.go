@@ -0,0 +1,468 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxAvailabilityDays tek bir müsaitlik sorgusunun kapsayabileceği gün sayısıdır
+const maxAvailabilityDays = 31
+
+// blockingStatuses slotu dolu tutan randevu durumlarıdır
+var blockingStatuses = []string{models.StatusRequested, models.StatusConfirmed, models.StatusCompleted, models.StatusNoShow}
+
+// Slot müsait bir zaman aralığıdır; zamanlar şirketin saat dilimindedir
+type Slot struct {
+	Start time.Time
+	End   time.Time
+}
+
+// SlotBooking müşterinin çalışma saatlerinden hesaplanan bir slotu almak
+// için gönderdiği bilgilerdir
+type SlotBooking struct {
+	ProviderEmail string
+	Start         time.Time
+	CustomerName  string
+	CustomerEmail string
+	Services      []string
+	ServiceIDs    []primitive.ObjectID
+}
+
+// clock "HH:MM" saatini verilen günün o anına çevirir
+func clock(day time.Time, hhmm string) (time.Time, error) {
+	t, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, day.Location()), nil
+}
+
+// endsAt randevunun bitiş anıdır; startsAt gibi yalnızca saat taşıyan kayıtları da çözer
+func endsAt(appointment models.Appointment) time.Time {
+	end := appointment.EndTime
+	if end.Year() > 1 {
+		return end
+	}
+	day := appointment.Date
+	return time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute(), 0, 0, day.Location())
+}
+
+func overlaps(aStart, aEnd, bStart, bEnd time.Time) bool {
+	return aStart.Before(bEnd) && bStart.Before(aEnd)
+}
+
+// validateRanges aralıkların HH:MM biçiminde ve başlangıcın bitişten önce olduğunu kontrol eder
+func validateRanges(ranges []models.TimeRange, what string) error {
+	for _, r := range ranges {
+		start, err := time.Parse("15:04", r.Start)
+		if err != nil {
+			return Invalid(fmt.Sprintf("Invalid %s start %q, expected HH:MM", what, r.Start))
+		}
+		end, err := time.Parse("15:04", r.End)
+		if err != nil {
+			return Invalid(fmt.Sprintf("Invalid %s end %q, expected HH:MM", what, r.End))
+		}
+		if !start.Before(end) {
+			return Invalid(fmt.Sprintf("%s %s-%s must start before it ends", what, r.Start, r.End))
+		}
+	}
+	return nil
+}
+
+func validateWorkingHours(hours models.WorkingHours) error {
+	if hours.SlotMinutes <= 0 {
+		return Invalid("Slot length must be a positive number of minutes")
+	}
+	return validateRules(hours.Rules)
+}
+
+// validateRules çalışma saati kurallarının gün ve aralıklarını kontrol eder
+func validateRules(rules []models.WorkingHoursRule) error {
+	for _, rule := range rules {
+		if len(rule.Weekdays) == 0 || len(rule.Intervals) == 0 {
+			return Invalid("Each rule needs at least one weekday and one interval")
+		}
+		for _, day := range rule.Weekdays {
+			if !isWeekday(day) {
+				return Invalid(fmt.Sprintf("Invalid weekday %q", day))
+			}
+		}
+		if err := validateRanges(rule.Intervals, "interval"); err != nil {
+			return err
+		}
+		if err := validateRanges(rule.Breaks, "break"); err != nil {
+			return err
+		}
+		if !rule.EffectiveFrom.IsZero() && !rule.EffectiveTo.IsZero() && rule.EffectiveTo.Before(rule.EffectiveFrom) {
+			return Invalid("Rule effective end is before its start")
+		}
+	}
+	return nil
+}
+
+func isWeekday(name string) bool {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if d.String() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rulesFor günün geçerli kurallarını döner: güne uyan kurallardan
+// geçerlilik başlangıcı en geç olanlar. Geçerlilik tarihleri takvim günü
+// olarak UTC gece yarısında saklanır ve günün tarihiyle karşılaştırılır.
+func rulesFor(hours models.WorkingHours, day time.Time) []models.WorkingHoursRule {
+	date := day.Format("2006-01-02")
+	var matched []models.WorkingHoursRule
+	var latest time.Time
+	for _, rule := range hours.Rules {
+		if !contains(rule.Weekdays, day.Weekday().String()) {
+			continue
+		}
+		if !rule.EffectiveFrom.IsZero() && date < rule.EffectiveFrom.UTC().Format("2006-01-02") {
+			continue
+		}
+		if !rule.EffectiveTo.IsZero() && date > rule.EffectiveTo.UTC().Format("2006-01-02") {
+			continue
+		}
+		switch {
+		case len(matched) == 0 || rule.EffectiveFrom.After(latest):
+			matched = []models.WorkingHoursRule{rule}
+			latest = rule.EffectiveFrom
+		case rule.EffectiveFrom.Equal(latest):
+			matched = append(matched, rule)
+		}
+	}
+	return matched
+}
+
+// truncateDay t'nin takvim gününün loc'taki başlangıcıdır
+func truncateDay(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// WorkingHours sağlayıcının çalışma saatlerini döner; kayıt yoksa boş kurallar
+func (s *AppointmentService) WorkingHours(ctx context.Context, actor Actor, providerEmail string) (models.WorkingHours, error) {
+	provider, err := providerByEmail(ctx, s.providers, actor, providerEmail)
+	if err != nil {
+		return models.WorkingHours{}, err
+	}
+
+	hours, err := s.workingHours.Get(ctx, provider.ID)
+	if err == repositories.ErrNotFound {
+		return models.WorkingHours{ID: provider.ID, ProviderEmail: provider.Email}, nil
+	}
+	return hours, err
+}
+
+// SetWorkingHours sağlayıcının çalışma saatleri kurallarını tamamen değiştirir
+func (s *AppointmentService) SetWorkingHours(ctx context.Context, actor Actor, providerEmail string, hours models.WorkingHours) (models.WorkingHours, error) {
+	provider, err := providerByEmail(ctx, s.providers, actor, providerEmail)
+	if err != nil {
+		return models.WorkingHours{}, err
+	}
+	if err := validateWorkingHours(hours); err != nil {
+		return models.WorkingHours{}, err
+	}
+
+	hours.ID = provider.ID
+	hours.ProviderEmail = provider.Email
+	hours.UpdatedAt = time.Now()
+	if err := s.workingHours.Save(ctx, hours); err != nil {
+		return models.WorkingHours{}, err
+	}
+	return hours, nil
+}
+
+// Availability sağlayıcının [from, to) takvim günlerindeki boş slotlarını
+// çalışma saatlerinden hesaplar: molalar, geçmiş saatler ve mevcut
+// rezervasyonlarla çakışan slotlar çıkarılır. from ve to'nun yalnızca
+// tarihi önemlidir; günler şirketin saat diliminde hesaplanır ve slotlar
+// o dilimde döner. Yetki gerektirmez.
+func (s *AppointmentService) Availability(ctx context.Context, providerEmail string, from, to time.Time) ([]Slot, error) {
+	provider, err := s.providers.FindByEmail(ctx, providerEmail)
+	if err == repositories.ErrNotFound {
+		return nil, NotFound("Provider not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, loc, err := s.providerLocation(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to = truncateDay(from, loc), truncateDay(to, loc)
+	if !from.Before(to) {
+		return nil, Invalid("Range end must be after its start")
+	}
+	if to.After(from.AddDate(0, 0, maxAvailabilityDays)) {
+		return nil, Invalid(fmt.Sprintf("Range cannot exceed %d days", maxAvailabilityDays))
+	}
+	return s.freeSlots(ctx, provider, from, to, time.Now())
+}
+
+// freeSlots [from, to) aralığındaki boş slotları from'un saat diliminde hesaplar
+func (s *AppointmentService) freeSlots(ctx context.Context, provider models.Provider, from, to, now time.Time) ([]Slot, error) {
+	hours, err := s.workingHours.Get(ctx, provider.ID)
+	if err == repositories.ErrNotFound {
+		return []Slot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	loc := from.Location()
+	booked, err := s.appointments.Find(ctx, repositories.AppointmentFilter{
+		ProviderEmail: provider.Email,
+		From:          truncateDay(from, loc),
+		To:            to,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// İzinler, kapalı günler ve dış takvimlerdeki etkinlikler de dolu sayılır
+	busy, err := s.unavailable(ctx, provider, from, to, loc)
+	if err != nil {
+		return nil, err
+	}
+	external, err := s.busyTimes(ctx, provider, from, to)
+	if err != nil {
+		return nil, err
+	}
+	busy = append(busy, external...)
+	for _, a := range booked {
+		if contains(blockingStatuses, a.Status) {
+			busy = append(busy, Slot{Start: startsAt(a), End: endsAt(a)})
+		}
+	}
+
+	length := time.Duration(hours.SlotMinutes) * time.Minute
+	slots := []Slot{}
+	for day := truncateDay(from, loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, rule := range rulesFor(hours, day) {
+			for _, interval := range rule.Intervals {
+				start, _ := clock(day, interval.Start)
+				end, _ := clock(day, interval.End)
+				for ; !start.Add(length).After(end); start = start.Add(length) {
+					slot := Slot{Start: start, End: start.Add(length)}
+					if slot.Start.Before(from) || !slot.Start.Before(to) || !slot.Start.After(now) {
+						continue
+					}
+					if inBreak(day, rule.Breaks, slot) || clashes(busy, slot) {
+						continue
+					}
+					slots = append(slots, slot)
+				}
+			}
+		}
+	}
+
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Start.Before(slots[j].Start) })
+	return slots, nil
+}
+
+func inBreak(day time.Time, breaks []models.TimeRange, slot Slot) bool {
+	for _, b := range breaks {
+		start, _ := clock(day, b.Start)
+		end, _ := clock(day, b.End)
+		if overlaps(slot.Start, slot.End, start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+func clashes(busy []Slot, slot Slot) bool {
+	for _, b := range busy {
+		if overlaps(slot.Start, slot.End, b.Start, b.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// slotKey bir sağlayıcının belirli bir andaki slotunu tekil olarak tanımlar
+func slotKey(providerEmail string, start time.Time) string {
+	return fmt.Sprintf("%s|%d", providerEmail, start.Unix())
+}
+
+// openSlotKey toplu üretilen boş slot kaydının anahtarıdır. Çalışma
+// saatlerinden alınan randevuların anahtarlarından ayrıdır; yalnızca aynı
+// anda iki kez slot üretilmesini engeller.
+func openSlotKey(providerEmail string, start time.Time) string {
+	return slotKey(providerEmail, start) + "|open"
+}
+
+// slotKeys [start, end) aralığının sağlayıcının çalışma saatlerindeki
+// slot uzunluğuyla bölünmüş anahtarlarıdır. Çalışma saati yoksa yalnızca
+// başlangıcın anahtarı döner.
+func (s *AppointmentService) slotKeys(ctx context.Context, providerEmail string, start, end time.Time) ([]string, error) {
+	keys := []string{slotKey(providerEmail, start)}
+	provider, err := s.providers.FindByEmail(ctx, providerEmail)
+	if err == repositories.ErrNotFound {
+		return nil, NotFound("Provider not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	hours, err := s.workingHours.Get(ctx, provider.ID)
+	if err == repositories.ErrNotFound || (err == nil && hours.SlotMinutes <= 0) {
+		return keys, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	length := time.Duration(hours.SlotMinutes) * time.Minute
+	for at := start.Add(length); at.Before(end); at = at.Add(length) {
+		keys = append(keys, slotKey(providerEmail, at))
+	}
+	return keys, nil
+}
+
+// fromWorkingHours randevunun çalışma saatlerinden hesaplanan bir slottan
+// alınıp alınmadığını söyler. Toplu üretilen slot kayıtları da anahtar
+// taşır ama BatchID'leriyle ayrılır.
+func fromWorkingHours(appointment models.Appointment) bool {
+	return appointment.SlotKey != "" && appointment.BatchID == ""
+}
+
+// chainSlots sıralı boş slotlardan start anında başlayıp need anına kadar
+// aralıksız devam edenleri döner. start boş değilse ya da zincir need'den
+// önce kopuyorsa Conflict döner.
+func chainSlots(free []Slot, start, need time.Time) ([]Slot, error) {
+	first := -1
+	for i := range free {
+		if free[i].Start.Equal(start) {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return nil, Conflict("Slot is not available")
+	}
+
+	slots := []Slot{free[first]}
+	for last := first; slots[len(slots)-1].End.Before(need); last++ {
+		if last+1 >= len(free) || !free[last+1].Start.Equal(free[last].End) {
+			return nil, Conflict(msgNotEnoughTime)
+		}
+		slots = append(slots, free[last+1])
+	}
+	return slots, nil
+}
+
+// BookSlot çalışma saatlerinden hesaplanan bir slotu müşteri adına alır ve
+// randevu kaydını o an oluşturur. Slot artık müsait değilse ya da aynı anda
+// başka bir müşteri aldıysa Conflict döner.
+func (s *AppointmentService) BookSlot(ctx context.Context, booking SlotBooking) (models.Appointment, error) {
+	provider, err := s.providers.FindByEmail(ctx, booking.ProviderEmail)
+	if err == repositories.ErrNotFound {
+		return models.Appointment{}, NotFound("Provider not found")
+	}
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	template := models.Appointment{
+		CustomerEmail: booking.CustomerEmail,
+		CustomerName:  booking.CustomerName,
+		Services:      booking.Services,
+	}
+	quote, err := bookingQuote(ctx, s.catalog, provider, booking.ServiceIDs, booking.Services)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if len(quote.ServiceIDs) > 0 {
+		template.Services = quote.Names
+		template.ServiceIDs = quote.ServiceIDs
+		template.Price = quote.Price
+		template.Currency = quote.Currency
+		template.DurationMinutes = quote.DurationMinutes
+		template.BufferMinutes = quote.BufferMinutes
+	}
+
+	return s.bookComputed(ctx, provider, booking.Start, template, models.StatusChange{
+		From:       models.StatusOpen,
+		To:         models.StatusRequested,
+		At:         time.Now(),
+		ActorEmail: booking.CustomerEmail,
+	})
+}
+
+// bookComputed start anındaki hesaplanan slot hâlâ müsaitse appointment
+// şablonundaki müşteri alanlarıyla requested durumunda bir randevu
+// kaydı oluşturur ve change'i geçmişe yazar. Şablondaki hizmet süresi ve
+// tampon slottan uzunsa ardışık slotlar birlikte alınır; her slotun
+// anahtarı kayıtta tutulur ve tekil indeks hepsini birden korur. Hizmetlerin
+// istediği kaynaklar kayıttan önce ayrılır.
+func (s *AppointmentService) bookComputed(ctx context.Context, provider models.Provider, start time.Time, appointment models.Appointment, change models.StatusChange) (models.Appointment, error) {
+	zone, loc, err := s.providerLocation(ctx, provider)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	now := change.At
+	day := truncateDay(start.In(loc), loc)
+	free, err := s.freeSlots(ctx, provider, day, day.AddDate(0, 0, 1), now)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	need := start.Add(time.Duration(appointment.DurationMinutes+appointment.BufferMinutes) * time.Minute)
+	slots, err := chainSlots(free, start, need)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	slot := Slot{Start: slots[0].Start, End: slots[len(slots)-1].End}
+	keys := make([]string, 0, len(slots))
+	for _, sl := range slots {
+		keys = append(keys, slotKey(provider.Email, sl.Start))
+	}
+
+	appointment.ID = primitive.NewObjectID()
+	appointment.ProviderEmail = provider.Email
+	appointment.ProviderName = provider.Name
+	appointment.CompanyName = provider.CompanyName
+	appointment.CompanyID = provider.CompanyId
+	appointment.Date = slot.Start
+	appointment.StartTime = slot.Start
+	appointment.EndTime = slot.End
+	appointment.Status = models.StatusRequested
+	appointment.History = []models.StatusChange{change}
+	appointment.SlotKey = keys[0]
+	appointment.SlotKeys = keys
+	appointment.SlotActive = true
+	appointment.TimeZone = zone
+	appointment.CreatedAt = now
+	appointment.UpdatedAt = now
+
+	// Kaynak yalnızca hizmetlerin süresi boyunca tutulur
+	end := need
+	if !end.After(start) {
+		end = slot.End
+	}
+	appointment.ResourceIDs, err = s.reserveResources(ctx, appointment, appointment.ServiceIDs, slot.Start, end)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	// Kontrol ile kayıt arasında aynı slotu alan olursa tekil indeks yakalar
+	err = s.appointments.Create(ctx, appointment)
+	if err != nil && len(appointment.ResourceIDs) > 0 {
+		s.releaseResources(ctx, appointment.ID)
+	}
+	if err == repositories.ErrDuplicate {
+		return models.Appointment{}, Conflict(msgSlotTaken)
+	}
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	return appointment, nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rtsback/internal/models"
+)
+
+func TestOpenSlotOverlappingComputedBooking(t *testing.T) {
+	svc, repos := newTestServices(t)
+	ctx := context.Background()
+	provider := addProvider(t, svc, repos, "p@example.com")
+	day := testDay()
+	at := day.Add(10 * time.Hour)
+
+	slot, err := svc.Appointments.Create(ctx, models.Appointment{
+		ProviderEmail: provider.Email,
+		StartTime:     at,
+		EndTime:       at.Add(30 * time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Appointments.BookSlot(ctx, SlotBooking{
+		ProviderEmail: provider.Email,
+		Start:         at,
+		CustomerName:  "A",
+		CustomerEmail: "a@example.com",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	open, err := svc.Appointments.AvailableSlots(ctx, provider.Email, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range open {
+		if a.ID == slot.ID {
+			t.Fatal("open slot overlapping a computed booking is listed")
+		}
+	}
+
+	_, err = svc.Appointments.Book(ctx, slot.ID, Booking{CustomerName: "B", CustomerEmail: "b@example.com"})
+	wantKind(t, err, KindConflict)
+}
+
+func TestComputedBookingOverlappingClaimedSlot(t *testing.T) {
+	svc, repos := newTestServices(t)
+	ctx := context.Background()
+	provider := addProvider(t, svc, repos, "p@example.com")
+	at := testDay().Add(11 * time.Hour)
+
+	slot, err := svc.Appointments.Create(ctx, models.Appointment{
+		ProviderEmail: provider.Email,
+		StartTime:     at,
+		EndTime:       at.Add(30 * time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Appointments.Book(ctx, slot.ID, Booking{CustomerName: "A", CustomerEmail: "a@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = svc.Appointments.BookSlot(ctx, SlotBooking{
+		ProviderEmail: provider.Email,
+		Start:         at,
+		CustomerName:  "B",
+		CustomerEmail: "b@example.com",
+	})
+	wantKind(t, err, KindConflict)
+}
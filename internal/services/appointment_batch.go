@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// slotHorizonMonths tarih aralığı verilmediğinde otomatik slot üretiminin
+// kaç ay ileriye gittiğidir
+const slotHorizonMonths = 1
+
+// maxBatchDays tek bir üretimin kapsayabileceği en uzun tarih aralığıdır
+const maxBatchDays = 366
+
+// SlotBatch bir toplu slot üretiminin sonucudur. DryRun ise Slots
+// kaydedilmemiştir ve BatchID boştur. Skipped var olan bir randevuyla ya
+// da aynı anda yapılan başka bir üretimle, Blocked sağlayıcının izni ya da
+// şirketin kapalı günüyle çakıştığı için üretilmeyen slot sayısıdır.
+type SlotBatch struct {
+	BatchID string
+	DryRun  bool
+	Created int
+	Skipped int
+	Blocked int
+	Slots   []models.Appointment
+}
+
+// batchRange isteğin tarih aralığını now'ın saat diliminde gün
+// başlangıçları olarak döner; to hariçtir
+func batchRange(req models.AutoAddRequest, now time.Time) (time.Time, time.Time, error) {
+	from := truncateDay(now, now.Location())
+	if req.StartDate != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", req.StartDate, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, Invalid("Invalid start date format, expected YYYY-MM-DD")
+		}
+		from = parsed
+	}
+
+	to := from.AddDate(0, slotHorizonMonths, 0)
+	if req.EndDate != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", req.EndDate, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, Invalid("Invalid end date format, expected YYYY-MM-DD")
+		}
+		to = parsed.AddDate(0, 0, 1)
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, Invalid("End date must not be before start date")
+	}
+	if to.After(from.AddDate(0, 0, maxBatchDays)) {
+		return time.Time{}, time.Time{}, Invalid(fmt.Sprintf("Date range cannot exceed %d days", maxBatchDays))
+	}
+	return from, to, nil
+}
+
+// validateAutoAdd isteğin saat, gün ve tarih alanlarını kontrol eder
+func validateAutoAdd(req models.AutoAddRequest) (map[string]bool, error) {
+	if req.Period <= 0 {
+		return nil, Invalid("Period must be a positive number of minutes")
+	}
+	if len(req.Weekdays) == 0 {
+		return nil, Invalid("At least one weekday is required")
+	}
+	for _, day := range req.Weekdays {
+		if !isWeekday(day) {
+			return nil, Invalid(fmt.Sprintf("Invalid weekday %q", day))
+		}
+	}
+	shift := []models.TimeRange{{Start: req.ShiftStart, End: req.ShiftEnd}}
+	if err := validateRanges(shift, "shift"); err != nil {
+		return nil, err
+	}
+	if err := validateRanges(req.Breaks, "break"); err != nil {
+		return nil, err
+	}
+
+	excluded := make(map[string]bool, len(req.ExcludedDates))
+	for _, day := range req.ExcludedDates {
+		if _, err := time.Parse("2006-01-02", day); err != nil {
+			return nil, Invalid(fmt.Sprintf("Invalid excluded date %q, expected YYYY-MM-DD", day))
+		}
+		excluded[day] = true
+	}
+	return excluded, nil
+}
+
+// GenerateSlots sağlayıcı için tarih aralığındaki seçilen hafta günlerinde
+// vardiyayı şirketin saat diliminde Period dakikalık boş slotlara böler. Molalara, geçmişe ya da
+// iptal edilmemiş bir randevuyla çakışan slotlar atlanır; böylece aynı
+// istek tekrar gönderildiğinde slotlar çoğalmaz. Her slot sağlayıcı ve
+// başlangıç anından oluşan tekil bir anahtar taşır; aynı istek aynı anda
+// iki kez gelirse ikinci yazmada anahtarı dolu slotlar atlanır. Slotlar
+// tek bir toplu yazmayla ve ortak bir BatchID ile kaydedilir. DryRun ise
+// hiçbir şey yazılmaz, üretilecek slotlar döner.
+func (s *AppointmentService) GenerateSlots(ctx context.Context, actor Actor, req models.AutoAddRequest, now time.Time) (SlotBatch, error) {
+	excluded, err := validateAutoAdd(req)
+	if err != nil {
+		return SlotBatch{}, err
+	}
+
+	provider, err := providerByEmail(ctx, s.providers, actor, req.ProviderEmail)
+	if err != nil {
+		return SlotBatch{}, err
+	}
+
+	// Günler ve vardiya saatleri şirketin saat diliminde yorumlanır
+	zone, loc, err := s.providerLocation(ctx, provider)
+	if err != nil {
+		return SlotBatch{}, err
+	}
+	now = now.In(loc)
+	from, to, err := batchRange(req, now)
+	if err != nil {
+		return SlotBatch{}, err
+	}
+
+	existing, err := s.appointments.Find(ctx, repositories.AppointmentFilter{
+		ProviderEmail: req.ProviderEmail,
+		From:          from,
+		To:            to,
+	})
+	if err != nil {
+		return SlotBatch{}, err
+	}
+	blocked, err := s.unavailable(ctx, provider, from, to, loc)
+	if err != nil {
+		return SlotBatch{}, err
+	}
+	var taken []Slot
+	for _, a := range existing {
+		if a.Status != models.StatusCancelled {
+			taken = append(taken, Slot{Start: startsAt(a), End: endsAt(a)})
+		}
+	}
+
+	batch := SlotBatch{DryRun: req.DryRun, Slots: []models.Appointment{}}
+	if !req.DryRun {
+		batch.BatchID = primitive.NewObjectID().Hex()
+	}
+
+	period := time.Duration(req.Period) * time.Minute
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		if !contains(req.Weekdays, day.Weekday().String()) || excluded[day.Format("2006-01-02")] {
+			continue
+		}
+
+		start, _ := clock(day, req.ShiftStart)
+		end, _ := clock(day, req.ShiftEnd)
+		for ; !start.Add(period).After(end); start = start.Add(period) {
+			slot := Slot{Start: start, End: start.Add(period)}
+			if !slot.Start.After(now) || inBreak(day, req.Breaks, slot) {
+				continue
+			}
+			if clashes(blocked, slot) {
+				batch.Blocked++
+				continue
+			}
+			if clashes(taken, slot) {
+				batch.Skipped++
+				continue
+			}
+
+			batch.Slots = append(batch.Slots, models.Appointment{
+				ID:            primitive.NewObjectID(),
+				ProviderEmail: req.ProviderEmail,
+				CompanyName:   req.CompanyName,
+				CompanyID:     provider.CompanyId,
+				Date:          slot.Start,
+				StartTime:     slot.Start,
+				EndTime:       slot.End,
+				Status:        models.StatusOpen,
+				BatchID:       batch.BatchID,
+				SlotKey:       openSlotKey(req.ProviderEmail, slot.Start),
+				SlotActive:    true,
+				TimeZone:      zone,
+				CreatedAt:     now,
+				UpdatedAt:     now,
+			})
+		}
+	}
+
+	if req.DryRun {
+		return batch, nil
+	}
+	created, err := s.appointments.CreateMany(ctx, batch.Slots)
+	if err != nil {
+		return SlotBatch{}, err
+	}
+	batch.Skipped += len(batch.Slots) - len(created)
+	batch.Slots = created
+	if batch.Slots == nil {
+		batch.Slots = []models.Appointment{}
+	}
+	batch.Created = len(batch.Slots)
+	go s.offerOpenings(batch.Slots)
+	return batch, nil
+}
+
+// ListBatch sağlayıcının bir üretimden gelen slotlarını döner
+func (s *AppointmentService) ListBatch(ctx context.Context, actor Actor, providerEmail, batchID string) ([]models.Appointment, error) {
+	if _, err := providerByEmail(ctx, s.providers, actor, providerEmail); err != nil {
+		return nil, err
+	}
+	return s.appointments.Find(ctx, repositories.AppointmentFilter{
+		ProviderEmail: providerEmail,
+		BatchID:       batchID,
+	})
+}
+
+// DeleteBatch bir üretimin hâlâ boş olan slotlarını siler. Müşterinin
+// aldığı ya da durumu değişmiş slotlar geçmişleriyle birlikte kalır; kaç
+// tanesinin silindiği ve kaç tanesinin kaldığı döner.
+func (s *AppointmentService) DeleteBatch(ctx context.Context, actor Actor, providerEmail, batchID string) (deleted, kept int64, err error) {
+	if _, err := providerByEmail(ctx, s.providers, actor, providerEmail); err != nil {
+		return 0, 0, err
+	}
+	deleted, err = s.appointments.DeleteMany(ctx, repositories.AppointmentFilter{
+		ProviderEmail: providerEmail,
+		BatchID:       batchID,
+		Status:        models.StatusOpen,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	remaining, err := s.appointments.Find(ctx, repositories.AppointmentFilter{
+		ProviderEmail: providerEmail,
+		BatchID:       batchID,
+	})
+	if err != nil {
+		return deleted, 0, err
+	}
+	if deleted == 0 && len(remaining) == 0 {
+		return 0, 0, NotFound("Batch not found")
+	}
+	return deleted, int64(len(remaining)), nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+)
+
+func TestGenerateSlotsConcurrently(t *testing.T) {
+	svc, repos := newTestServices(t)
+	ctx := context.Background()
+	provider := addProvider(t, svc, repos, "p@example.com")
+	day := testDay()
+	req := models.AutoAddRequest{
+		ProviderEmail: provider.Email,
+		Weekdays:      []string{day.Weekday().String()},
+		ShiftStart:    "09:00",
+		ShiftEnd:      "12:00",
+		Period:        30,
+		StartDate:     day.Format("2006-01-02"),
+		EndDate:       day.Format("2006-01-02"),
+	}
+
+	batches := make([]SlotBatch, 4)
+	var wg sync.WaitGroup
+	for i := range batches {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			batch, err := svc.Appointments.GenerateSlots(ctx, System(), req, time.Now())
+			if err != nil {
+				t.Error(err)
+			}
+			batches[i] = batch
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, batch := range batches {
+		created += batch.Created
+		if batch.Created+batch.Skipped != 6 {
+			t.Errorf("batch created %d and skipped %d, want 6 in total", batch.Created, batch.Skipped)
+		}
+	}
+	if created != 6 {
+		t.Fatalf("created %d slots across batches, want 6", created)
+	}
+	open, err := repos.Appointments.Find(ctx, repositories.AppointmentFilter{ProviderEmail: provider.Email, Status: models.StatusOpen})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(open) != 6 {
+		t.Fatalf("stored %d open slots, want 6", len(open))
+	}
+}
@@ -0,0 +1,494 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Dış takvimlerin etkinlikleri geçmişte busyPast, ileride busyAhead
+// kadarlık aralıkta açılır. Düzenli eşitleme aralığı ileri kaydırır.
+const (
+	busyPast  = 24 * time.Hour
+	busyAhead = 365 * 24 * time.Hour
+)
+
+// maxCalendarBytes bir takvim dosyasının en büyük boyutu, maxBusyBlocks
+// bir takvimden alınabilecek en fazla dolu aralık sayısıdır
+const (
+	maxCalendarBytes = 5 << 20
+	maxBusyBlocks    = 10000
+)
+
+// calendarFetchTimeout bir takvimin okunup eşitlenmesi için verilen süredir
+const calendarFetchTimeout = 30 * time.Second
+
+// maxCalendarRedirects takvim adresi okunurken izlenecek en fazla yönlendirmedir
+const maxCalendarRedirects = 5
+
+// errCalendarAddress takvim adresinin iç ağa çıktığını gösterir
+var errCalendarAddress = errors.New("calendar address is not public")
+
+// calendarClient dış takvimleri okur. Bağlantılar yalnızca genel
+// adreslere açılır; ad çözülen adres bağlanmadan önce kontrol edilir,
+// böylece yönlendirmeler de aynı kontrolden geçer. Ortamdaki vekil
+// sunucu ayarı kullanılmaz.
+var calendarClient = &http.Client{
+	Timeout: calendarFetchTimeout,
+	Transport: &http.Transport{
+		DialContext:           dialPublic,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: calendarFetchTimeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxCalendarRedirects {
+			return errors.New("too many redirects")
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return errCalendarAddress
+		}
+		return nil
+	},
+}
+
+// cgnatRange paylaşılan adres alanıdır (RFC 6598); genel ağdan erişilmez
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// publicIP adresin genel ağda olup olmadığını döner. Geri döngü, özel,
+// yerel bağlantı, çoklu yayın ve belirsiz adresler genel sayılmaz.
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		cgnatRange.Contains(ip) || ip.To4() != nil && ip.To4()[0] == 0)
+}
+
+// dialPublic adresi çözer ve yalnızca genel IP'lere bağlanır. Çözülen IP
+// doğrudan kullanılır; ad ikinci kez çözülüp iç adrese yönlenemez.
+func dialPublic(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	err = errCalendarAddress
+	for _, ip := range ips {
+		if !publicIP(ip.IP) {
+			continue
+		}
+		conn, dialErr := dialer.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+		if dialErr == nil {
+			return conn, nil
+		}
+		err = dialErr
+	}
+	return nil, err
+}
+
+// busyTimes sağlayıcının dış takvimlerinden alınan, [from, to) ile
+// çakışan dolu aralıklardır
+func (s *AppointmentService) busyTimes(ctx context.Context, provider models.Provider, from, to time.Time) ([]Slot, error) {
+	blocks, err := s.busyBlocks.Find(ctx, provider.ID, primitive.NilObjectID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Slot, 0, len(blocks))
+	for _, b := range blocks {
+		out = append(out, Slot{Start: b.Start, End: b.End})
+	}
+	return out, nil
+}
+
+// calendarLocation takvim kaynağının biçimini kontrol eder ve okunacak
+// hali döner. webcal:// adresleri https:// olarak okunur. Dosya yolları
+// yalnızca ayarlardaki içe aktarma dizininin altında olabilir.
+func (s *AppointmentService) calendarLocation(location string) (string, error) {
+	location = strings.TrimSpace(location)
+	if u, err := url.Parse(location); err == nil && u.Scheme != "" && u.Host != "" {
+		switch strings.ToLower(u.Scheme) {
+		case "webcal", "webcals":
+			u.Scheme = "https"
+			return u.String(), nil
+		case "http", "https":
+			return u.String(), nil
+		}
+		return "", Invalid("Calendar address must be an http, https or webcal URL")
+	}
+	if s.importDir == "" {
+		return "", Invalid("Calendar files can only be read from a URL")
+	}
+	if !filepath.IsAbs(location) {
+		return "", Invalid("Calendar file path must be absolute")
+	}
+	path, err := filepath.EvalSymlinks(filepath.Clean(location))
+	if err != nil {
+		return "", Invalid("Calendar file not found")
+	}
+	dir, err := filepath.EvalSymlinks(s.importDir)
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(dir, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", Invalid("Calendar file must be inside " + s.importDir)
+	}
+	return path, nil
+}
+
+// readCalendar takvimi adresinden ya da dosya yolundan okur
+func (s *AppointmentService) readCalendar(ctx context.Context, location string) ([]byte, error) {
+	location, err := s.calendarLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	if filepath.IsAbs(location) {
+		f, err := os.Open(location)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		body = f
+	} else {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "text/calendar")
+		resp, err := calendarClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, Invalid(fmt.Sprintf("Calendar server responded with status %d", resp.StatusCode))
+		}
+		body = resp.Body
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxCalendarBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxCalendarBytes {
+		return nil, Invalid(fmt.Sprintf("Calendar is larger than %d MB", maxCalendarBytes>>20))
+	}
+	return data, nil
+}
+
+// calendarBlocks takvim dosyasındaki etkinlikleri sağlayıcının dolu
+// aralıklarına çevirir. Saat dilimi olmayan zamanlar sağlayıcının
+// dilimindedir.
+func (s *AppointmentService) calendarBlocks(ctx context.Context, source models.BusySource, data []byte, now time.Time) ([]models.BusyBlock, error) {
+	provider, err := s.providers.FindByID(ctx, source.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	_, loc, err := s.providerLocation(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+	events, err := icsBusyTimes(ctx, data, loc, now.Add(-busyPast), now.Add(busyAhead), maxBusyBlocks)
+	if errors.Is(err, errTooManyEvents) {
+		return nil, Invalid(fmt.Sprintf("Calendar has more than %d events in the next year", maxBusyBlocks))
+	}
+	if err != nil {
+		return nil, err
+	}
+	blocks := make([]models.BusyBlock, 0, len(events))
+	for _, e := range events {
+		blocks = append(blocks, models.BusyBlock{
+			ID:         primitive.NewObjectID(),
+			SourceID:   source.ID,
+			ProviderID: source.ProviderID,
+			UID:        e.UID,
+			Summary:    e.Summary,
+			Start:      e.Start.UTC(),
+			End:        e.End.UTC(),
+		})
+	}
+	return blocks, nil
+}
+
+// readError takvim okunurken oluşan hatayı kullanıcıya gösterilecek
+// hataya çevirir. Ağ ve dosya hatalarının ayrıntıları yalnızca loglanır;
+// takvimde ve yanıtta genel bir mesaj görünür.
+func readError(source models.BusySource, err error) *Error {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, errCalendarAddress):
+		return Invalid("Calendar address must be publicly reachable")
+	case errors.Is(err, errNotCalendar):
+		return Invalid("Calendar file is not an iCalendar file")
+	}
+	log.Printf("Takvim okunamadı (%s, %s): %v", source.ProviderEmail, source.Name, err)
+	return Invalid("Calendar could not be read")
+}
+
+// syncSource takvimi okur ve dolu aralıklarını baştan yazar. Okuma
+// hatasında eski aralıklar korunur ve hata takvimin sync_error alanına
+// yazılır.
+func (s *AppointmentService) syncSource(ctx context.Context, source models.BusySource, now time.Time) (models.BusySource, error) {
+	data := source.Data
+	var err error
+	if source.Location != "" {
+		data, err = s.readCalendar(ctx, source.Location)
+	}
+	var blocks []models.BusyBlock
+	if err == nil {
+		blocks, err = s.calendarBlocks(ctx, source, data, now)
+	}
+	if err != nil {
+		e := readError(source, err)
+		source.SyncError = e.Message
+		if updateErr := s.busySources.UpdateByID(ctx, source.ID, repositories.Fields{"sync_error": source.SyncError}); updateErr != nil {
+			return source, updateErr
+		}
+		return source, e
+	}
+
+	return s.storeBlocks(ctx, source, blocks, now)
+}
+
+// storeBlocks takvimin dolu aralıklarını baştan yazar ve eşitleme
+// bilgisini günceller
+func (s *AppointmentService) storeBlocks(ctx context.Context, source models.BusySource, blocks []models.BusyBlock, now time.Time) (models.BusySource, error) {
+	if err := s.busyBlocks.Replace(ctx, source.ID, blocks); err != nil {
+		return source, err
+	}
+	source.Blocks = len(blocks)
+	source.SyncedAt = now
+	source.SyncError = ""
+	err := s.busySources.UpdateByID(ctx, source.ID, repositories.Fields{
+		"blocks":     source.Blocks,
+		"synced_at":  source.SyncedAt,
+		"sync_error": "",
+	})
+	return source, err
+}
+
+// ImportCalendar yüklenen iCalendar dosyasının etkinliklerini sağlayıcının
+// dolu aralıkları olarak ekler. Sağlayıcının aynı adla yüklediği takvim
+// varsa yeni dosya onun yerine geçer. Dosya saklanır; düzenli eşitleme
+// tekrarlayan etkinlikleri ileri tarihler için yeniden açar.
+func (s *AppointmentService) ImportCalendar(ctx context.Context, actor Actor, providerEmail, name string, data []byte) (models.BusySource, error) {
+	provider, err := providerByEmail(ctx, s.providers, actor, providerEmail)
+	if err != nil {
+		return models.BusySource{}, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return models.BusySource{}, Invalid("Calendar name is required")
+	}
+	if len(data) == 0 {
+		return models.BusySource{}, Invalid("Calendar file is empty")
+	}
+	if len(data) > maxCalendarBytes {
+		return models.BusySource{}, Invalid(fmt.Sprintf("Calendar file cannot exceed %d MB", maxCalendarBytes>>20))
+	}
+
+	existing, err := s.busySources.FindByProvider(ctx, provider.ID)
+	if err != nil {
+		return models.BusySource{}, err
+	}
+	source := models.BusySource{
+		ID:            primitive.NewObjectID(),
+		ProviderID:    provider.ID,
+		ProviderEmail: provider.Email,
+		CompanyID:     provider.CompanyId,
+		Name:          name,
+		CreatedBy:     actor.Email,
+	}
+	for _, other := range existing {
+		if other.Name != name {
+			continue
+		}
+		if other.Location != "" {
+			return models.BusySource{}, Conflict("A calendar with this name is read from " + other.Location)
+		}
+		source = other
+	}
+
+	now := time.Now()
+	// Okunamayan dosya kaydedilmez; aynı adlı takvimin aralıkları korunur
+	blocks, err := s.calendarBlocks(ctx, source, data, now)
+	if err != nil {
+		return models.BusySource{}, readError(source, err)
+	}
+	source.Data = data
+	if source.CreatedAt.IsZero() {
+		source.CreatedAt = now
+		err = s.createSource(ctx, source)
+	} else {
+		err = s.busySources.UpdateByID(ctx, source.ID, repositories.Fields{"data": data})
+	}
+	if err != nil {
+		return models.BusySource{}, err
+	}
+	return s.storeBlocks(ctx, source, blocks, now)
+}
+
+// AddCalendarSource sağlayıcıya düzenli olarak yeniden okunan bir takvim
+// ekler. location bir http(s)/webcal adresi ya da içe aktarma dizinindeki
+// bir dosya yoludur. Takvim eklenirken bir kez okunur; okunamazsa eklenmez.
+func (s *AppointmentService) AddCalendarSource(ctx context.Context, actor Actor, providerEmail, name, location string) (models.BusySource, error) {
+	provider, err := providerByEmail(ctx, s.providers, actor, providerEmail)
+	if err != nil {
+		return models.BusySource{}, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return models.BusySource{}, Invalid("Calendar name is required")
+	}
+	if _, err := s.calendarLocation(location); err != nil {
+		return models.BusySource{}, err
+	}
+
+	source := models.BusySource{
+		ID:            primitive.NewObjectID(),
+		ProviderID:    provider.ID,
+		ProviderEmail: provider.Email,
+		CompanyID:     provider.CompanyId,
+		Name:          name,
+		Location:      strings.TrimSpace(location),
+		CreatedBy:     actor.Email,
+		CreatedAt:     time.Now(),
+	}
+	data, err := s.readCalendar(ctx, source.Location)
+	var blocks []models.BusyBlock
+	if err == nil {
+		blocks, err = s.calendarBlocks(ctx, source, data, source.CreatedAt)
+	}
+	if err != nil {
+		return models.BusySource{}, readError(source, err)
+	}
+	if err := s.createSource(ctx, source); err != nil {
+		return models.BusySource{}, err
+	}
+	return s.storeBlocks(ctx, source, blocks, source.CreatedAt)
+}
+
+// createSource takvimi kaydeder; sağlayıcının aynı adlı takvimi varsa Conflict
+func (s *AppointmentService) createSource(ctx context.Context, source models.BusySource) error {
+	err := s.busySources.Create(ctx, source)
+	if err == repositories.ErrDuplicate {
+		return Conflict("A calendar with this name already exists")
+	}
+	return err
+}
+
+// authorizeSource takvimi bulur ve kimliğin takvimin sahibine erişimini kontrol eder
+func (s *AppointmentService) authorizeSource(ctx context.Context, actor Actor, id primitive.ObjectID) (models.BusySource, error) {
+	source, err := s.busySources.FindByID(ctx, id)
+	if err == repositories.ErrNotFound {
+		return models.BusySource{}, NotFound("Calendar not found")
+	}
+	if err != nil {
+		return models.BusySource{}, err
+	}
+	_, err = authorizeProvider(actor, func() (models.Provider, error) { return s.providers.FindByID(ctx, source.ProviderID) })
+	if err != nil {
+		return models.BusySource{}, err
+	}
+	return source, nil
+}
+
+// ListCalendarSources sağlayıcının dış takvimlerini döner
+func (s *AppointmentService) ListCalendarSources(ctx context.Context, actor Actor, providerEmail string) ([]models.BusySource, error) {
+	provider, err := providerByEmail(ctx, s.providers, actor, providerEmail)
+	if err != nil {
+		return nil, err
+	}
+	return s.busySources.FindByProvider(ctx, provider.ID)
+}
+
+// SyncCalendarSource takvimi hemen yeniden okur
+func (s *AppointmentService) SyncCalendarSource(ctx context.Context, actor Actor, id primitive.ObjectID) (models.BusySource, error) {
+	source, err := s.authorizeSource(ctx, actor, id)
+	if err != nil {
+		return models.BusySource{}, err
+	}
+	return s.syncSource(ctx, source, time.Now())
+}
+
+// DeleteCalendarSource takvimi ve dolu aralıklarını siler; aralıklardaki
+// slotlar tekrar müsait görünür
+func (s *AppointmentService) DeleteCalendarSource(ctx context.Context, actor Actor, id primitive.ObjectID) error {
+	if _, err := s.authorizeSource(ctx, actor, id); err != nil {
+		return err
+	}
+	if err := s.busyBlocks.DeleteBySource(ctx, id); err != nil {
+		return err
+	}
+	err := s.busySources.DeleteByID(ctx, id)
+	if err == repositories.ErrNotFound {
+		return NotFound("Calendar not found")
+	}
+	return err
+}
+
+// BusyBlocks sağlayıcının dış takvimlerinden alınan, [from, to) ile
+// çakışan dolu aralıkları döner. sourceID verilirse yalnızca o takvimin
+// aralıkları döner.
+func (s *AppointmentService) BusyBlocks(ctx context.Context, actor Actor, providerEmail string, sourceID primitive.ObjectID, from, to time.Time) ([]models.BusyBlock, error) {
+	provider, err := providerByEmail(ctx, s.providers, actor, providerEmail)
+	if err != nil {
+		return nil, err
+	}
+	if !to.After(from) {
+		return nil, Invalid("Range end must be after its start")
+	}
+	if to.After(from.AddDate(0, 0, maxTimeOffDays)) {
+		return nil, Invalid(fmt.Sprintf("Range cannot exceed %d days", maxTimeOffDays))
+	}
+	return s.busyBlocks.Find(ctx, provider.ID, sourceID, from, to)
+}
+
+// SyncCalendarSources tüm dış takvimleri yeniden okur ve eşitlenen takvim
+// sayısını döner. Yüklenen dosyalar da yeniden açılır; böylece tekrarlayan
+// etkinlikler hep now'dan bir yıl ilerisini kapsar. Bir takvimin hatası
+// diğerlerini durdurmaz; hatalar loglanır ve takvimde görünür. ctx iptal
+// edilirse kalan takvimler atlanır.
+func (s *AppointmentService) SyncCalendarSources(ctx context.Context, now time.Time) (int, error) {
+	sources, err := s.busySources.FindAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	synced := 0
+	for _, source := range sources {
+		if err := ctx.Err(); err != nil {
+			return synced, err
+		}
+		syncCtx, cancel := context.WithTimeout(ctx, calendarFetchTimeout)
+		_, err := s.syncSource(syncCtx, source, now)
+		cancel()
+		if err != nil {
+			log.Printf("Takvim eşitlenemedi (%s, %s): %v", source.ProviderEmail, source.Name, err)
+			continue
+		}
+		synced++
+	}
+	return synced, nil
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Feed'ler geçmişte calendarFeedPast, ileride calendarFeedAhead kadarlık
+// randevuları taşır
+const (
+	calendarFeedPast  = 30 * 24 * time.Hour
+	calendarFeedAhead = 365 * 24 * time.Hour
+)
+
+// calendarFeedPath feed'in servis edildiği yoldur; ayarlarda adres yoksa
+// bağlantı bu yola göre verilir
+const calendarFeedPath = "/calendar.ics"
+
+// CalendarFeedToken yeni oluşturulan aboneliğin token'ı ve bağlantısıdır.
+// Token yalnızca oluşturulduğunda gösterilir.
+type CalendarFeedToken struct {
+	Token     string
+	URL       string
+	CreatedAt time.Time
+}
+
+// calendarUID randevunun takvimlerdeki kimliğidir
+func calendarUID(appointment models.Appointment) string {
+	if appointment.CalendarUID != "" {
+		return appointment.CalendarUID
+	}
+	return appointment.ID.Hex() + "@rtsback"
+}
+
+// calendarSequence randevunun her güncellemesinde artar; Version da her
+// güncellemede arttığı için onun üzerine kurulur
+func calendarSequence(appointment models.Appointment) int64 {
+	return appointment.CalendarSequence + appointment.Version
+}
+
+// calendarEnd hizmetin bitişidir; temizlik süresi takvime yazılmaz
+func calendarEnd(appointment models.Appointment) time.Time {
+	if appointment.DurationMinutes > 0 {
+		return startsAt(appointment).Add(time.Duration(appointment.DurationMinutes) * time.Minute)
+	}
+	return endsAt(appointment)
+}
+
+func calendarStatus(status string) string {
+	switch status {
+	case models.StatusRequested:
+		return "TENTATIVE"
+	case models.StatusCancelled:
+		return "CANCELLED"
+	default:
+		return "CONFIRMED"
+	}
+}
+
+// calendarEvent randevuyu takvim etkinliğine çevirir. forProvider ise
+// başlık müşteriyi, değilse sağlayıcıyı gösterir.
+func calendarEvent(appointment models.Appointment, forProvider bool) icsEvent {
+	loc := appointment.Location()
+	services := strings.Join(appointment.Services, ", ")
+
+	var summary string
+	var details []string
+	switch {
+	case appointment.Capacity > 0 && forProvider:
+		summary = fmt.Sprintf("%s (%d/%d)", sessionName(appointment), appointment.SeatsTaken, appointment.Capacity)
+	case appointment.Capacity > 0:
+		summary = sessionName(appointment)
+		details = append(details, "Provider: "+firstNonEmpty(appointment.ProviderName, appointment.ProviderEmail))
+	case forProvider:
+		summary = firstNonEmpty(appointment.CustomerName, appointment.CustomerEmail)
+		details = append(details, "Customer: "+appointment.CustomerEmail)
+	default:
+		summary = "Appointment with " + firstNonEmpty(appointment.ProviderName, appointment.ProviderEmail)
+		details = append(details, "Provider: "+firstNonEmpty(appointment.ProviderName, appointment.ProviderEmail))
+	}
+	if services != "" {
+		if appointment.Capacity == 0 {
+			summary += " - " + services
+		}
+		details = append(details, "Services: "+services)
+	}
+	if appointment.Notes != "" && forProvider {
+		details = append(details, appointment.Notes)
+	}
+
+	stamp := appointment.UpdatedAt
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+	return icsEvent{
+		UID:           calendarUID(appointment),
+		Sequence:      calendarSequence(appointment),
+		Summary:       summary,
+		Description:   strings.Join(details, "\n"),
+		Location:      appointment.CompanyName,
+		Status:        calendarStatus(appointment.Status),
+		Start:         startsAt(appointment).In(loc),
+		End:           calendarEnd(appointment).In(loc),
+		Stamp:         stamp,
+		Organizer:     appointment.ProviderEmail,
+		OrganizerName: appointment.ProviderName,
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// calendarInvite müşteriye gönderilecek tek etkinlikli takvim dosyasıdır
+func calendarInvite(appointment models.Appointment, method string) Attachment {
+	event := calendarEvent(appointment, false)
+	event.Stamp = time.Now()
+	event.Attendee = appointment.CustomerEmail
+	event.AttendeeName = appointment.CustomerName
+	if method == icsCancel {
+		event.Status = "CANCELLED"
+	}
+	return Attachment{
+		Name:        "appointment.ics",
+		ContentType: "text/calendar; charset=utf-8; method=" + method,
+		Data:        icsCalendar(method, "", []icsEvent{event}),
+	}
+}
+
+// notifyCustomer müşteriye randevunun takvim dosyasıyla birlikte e-posta
+// gönderir. İsteği bekletmemek için arka planda çağrılır; gönderim hatası
+// işlemi geri almaz, yalnızca loglanır.
+func (s *AppointmentService) notifyCustomer(appointment models.Appointment, subject, body, method string) {
+	if appointment.CustomerEmail == "" {
+		return
+	}
+	if err := sendMail(s.mailer, appointment.CustomerEmail, subject, body, calendarInvite(appointment, method)); err != nil {
+		log.Printf("Müşteriye bildirim gönderilemedi (%s): %v", appointment.CustomerEmail, err)
+	}
+}
+
+// feedAccount aboneliği olabilecek kimliğin hesap ID'sidir. Feed'ler
+// yalnızca sağlayıcılar ve müşteriler içindir.
+func feedAccount(actor Actor) (primitive.ObjectID, error) {
+	if actor.Role != utils.RoleProvider && actor.Role != utils.RoleUser {
+		return primitive.NilObjectID, Forbidden("Calendar feeds are available to providers and customers")
+	}
+	id, err := primitive.ObjectIDFromHex(actor.ID)
+	if err != nil || actor.Email == "" {
+		return primitive.NilObjectID, ErrForbidden
+	}
+	return id, nil
+}
+
+// CalendarFeedStatus kimliğin aboneliğini döner; abonelik yoksa NotFound
+func (s *AppointmentService) CalendarFeedStatus(ctx context.Context, actor Actor) (models.CalendarFeed, error) {
+	id, err := feedAccount(actor)
+	if err != nil {
+		return models.CalendarFeed{}, err
+	}
+	feed, err := s.feeds.Get(ctx, id)
+	if err == repositories.ErrNotFound {
+		return models.CalendarFeed{}, NotFound("Calendar feed not found")
+	}
+	return feed, err
+}
+
+// RotateCalendarFeed kimlik için yeni bir abonelik token'ı üretir. Varsa
+// eski token hemen geçersiz olur; takvim uygulamasındaki abonelik yeni
+// bağlantıyla yenilenmelidir.
+func (s *AppointmentService) RotateCalendarFeed(ctx context.Context, actor Actor) (CalendarFeedToken, error) {
+	id, err := feedAccount(actor)
+	if err != nil {
+		return CalendarFeedToken{}, err
+	}
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return CalendarFeedToken{}, err
+	}
+	feed := models.CalendarFeed{
+		ID:        id,
+		TokenHash: utils.HashToken(token),
+		Role:      actor.Role,
+		Email:     actor.Email,
+		CreatedAt: time.Now(),
+	}
+	if err := s.feeds.Save(ctx, feed); err != nil {
+		return CalendarFeedToken{}, err
+	}
+
+	link := s.feedURL
+	if link == "" {
+		link = calendarFeedPath
+	}
+	separator := "?"
+	if strings.Contains(link, "?") {
+		separator = "&"
+	}
+	return CalendarFeedToken{
+		Token:     token,
+		URL:       link + separator + "token=" + url.QueryEscape(token),
+		CreatedAt: feed.CreatedAt,
+	}, nil
+}
+
+// RevokeCalendarFeed kimliğin aboneliğini siler; bağlantı artık çalışmaz
+func (s *AppointmentService) RevokeCalendarFeed(ctx context.Context, actor Actor) error {
+	id, err := feedAccount(actor)
+	if err != nil {
+		return err
+	}
+	if err := s.feeds.Delete(ctx, id); err != nil {
+		if err == repositories.ErrNotFound {
+			return NotFound("Calendar feed not found")
+		}
+		return err
+	}
+	return nil
+}
+
+// CalendarFeed token'ın sahibinin takvimini iCalendar olarak döner.
+// Sağlayıcı kendi randevularını ve grup seanslarını, müşteri kendi
+// randevularını ve katıldığı seansları görür. İptal edilenler takvimden
+// silinsin diye CANCELLED olarak kalır; ertelenenlerin eski kaydı yeni
+// kayıtla aynı UID'yi taşıdığı için atlanır. Bilinmeyen token NotFound döner.
+func (s *AppointmentService) CalendarFeed(ctx context.Context, token string) ([]byte, error) {
+	if token == "" {
+		return nil, NotFound("Calendar feed not found")
+	}
+	feed, err := s.feeds.FindByTokenHash(ctx, utils.HashToken(token))
+	if err == repositories.ErrNotFound {
+		return nil, NotFound("Calendar feed not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	filter := repositories.AppointmentFilter{From: now.Add(-calendarFeedPast), To: now.Add(calendarFeedAhead)}
+	forProvider := feed.Role == utils.RoleProvider
+	if forProvider {
+		filter.ProviderEmail = feed.Email
+	} else {
+		filter.CustomerEmail = feed.Email
+	}
+	found, err := s.appointments.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if !forProvider {
+		sessions, err := s.appointments.FindByParticipant(ctx, feed.Email)
+		if err != nil {
+			return nil, err
+		}
+		for _, session := range sessions {
+			if start := startsAt(session); start.Before(filter.From) || start.After(filter.To) {
+				continue
+			}
+			found = append(found, seatEvent(session, feed.Email))
+		}
+	}
+
+	events := []icsEvent{}
+	for _, a := range found {
+		if a.Status == models.StatusOpen || !a.RescheduledTo.IsZero() {
+			continue
+		}
+		events = append(events, calendarEvent(a, forProvider))
+	}
+	return icsCalendar("", "Appointments", events), nil
+}
+
+// seatEvent müşterinin grup seansındaki son kaydını seansın durumuna
+// yansıtır; yerini iptal eden müşteri için seans iptal görünür
+func seatEvent(session models.Appointment, email string) models.Appointment {
+	session = ownSeat(session, email)
+	if n := len(session.Participants); n > 0 && session.Participants[n-1].Status == models.ParticipantCancelled {
+		session.Status = models.StatusCancelled
+	}
+	return session
+}
@@ -0,0 +1,352 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// startsAt randevunun başlangıç anıdır. Elle eklenen ve sağlayıcının
+// taşıdığı randevularda start_time yalnızca saati taşır; gün Date'tedir.
+func startsAt(appointment models.Appointment) time.Time {
+	start := appointment.StartTime
+	if start.Year() > 1 {
+		return start
+	}
+	day := appointment.Date
+	return time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, day.Location())
+}
+
+// policyFor randevunun şirketinin iptal/erteleme politikasını döner.
+// Şirketi ya da politikası olmayan randevularda kısıtlama yoktur.
+func (s *AppointmentService) policyFor(ctx context.Context, appointment models.Appointment) (models.BookingPolicy, error) {
+	companyID := appointment.CompanyID
+	if companyID == "" {
+		provider, err := s.providers.FindByEmail(ctx, appointment.ProviderEmail)
+		if err == repositories.ErrNotFound {
+			return models.BookingPolicy{}, nil
+		}
+		if err != nil {
+			return models.BookingPolicy{}, err
+		}
+		companyID = provider.CompanyId
+	}
+
+	objID, err := primitive.ObjectIDFromHex(companyID)
+	if err != nil {
+		return models.BookingPolicy{}, nil
+	}
+	company, err := s.companies.FindByID(ctx, objID)
+	if err == repositories.ErrNotFound || (err == nil && company.BookingPolicy == nil) {
+		return models.BookingPolicy{}, nil
+	}
+	if err != nil {
+		return models.BookingPolicy{}, err
+	}
+	return *company.BookingPolicy, nil
+}
+
+// withinNotice randevunun politikadaki bildirim süresinin içinde kalıp kalmadığını döner
+func withinNotice(policy models.BookingPolicy, appointment models.Appointment, now time.Time) bool {
+	notice := time.Duration(policy.MinNoticeHours) * time.Hour
+	return now.Add(notice).After(startsAt(appointment))
+}
+
+// customerAppointment müşterinin kendi randevusunu döner. Başkasına ait
+// randevular var olup olmadıkları anlaşılmasın diye NotFound döner.
+func (s *AppointmentService) customerAppointment(ctx context.Context, actor Actor, id primitive.ObjectID) (models.Appointment, error) {
+	appointment, err := s.appointments.FindByID(ctx, id)
+	if err == repositories.ErrNotFound || (err == nil && (actor.Email == "" || appointment.CustomerEmail != actor.Email)) {
+		return models.Appointment{}, NotFound("Appointment not found")
+	}
+	return appointment, err
+}
+
+// reopenSlot müşterinin bıraktığı randevunun yerine aynı saatte boş bir
+// slot açar. İptal edilen kayıt geçmişiyle birlikte olduğu gibi kalır.
+// Çalışma saatlerinden alınan randevularda slot zaten hesaplanarak
+// müsait görünür, yeni kayıt açılmaz.
+func (s *AppointmentService) reopenSlot(ctx context.Context, appointment models.Appointment) {
+	if fromWorkingHours(appointment) {
+		return
+	}
+	_, err := s.Create(ctx, models.Appointment{
+		ProviderEmail: appointment.ProviderEmail,
+		ProviderName:  appointment.ProviderName,
+		CompanyName:   appointment.CompanyName,
+		CompanyID:     appointment.CompanyID,
+		Date:          appointment.Date,
+		StartTime:     appointment.StartTime,
+		EndTime:       ownEnd(appointment),
+		TimeZone:      appointment.TimeZone,
+	})
+	if err != nil {
+		log.Printf("Boşalan slot tekrar açılamadı (%s): %v", appointment.ID.Hex(), err)
+	}
+	// Uzun rezervasyonun kapladığı slotlar da ayrı ayrı tekrar açılır
+	pieces := mergedPieces(appointment)
+	s.restoreSlots(ctx, pieces)
+	go s.offerOpenings(pieces)
+}
+
+// notifyProvider sağlayıcıya randevu değişikliğini e-postayla bildirir.
+// İsteği bekletmemek için arka planda çağrılır; gönderim hatası işlemi geri
+// almaz, yalnızca loglanır.
+func (s *AppointmentService) notifyProvider(appointment models.Appointment, subject, body string) {
+	if err := s.mailer.Send(appointment.ProviderEmail, subject, body); err != nil {
+		log.Printf("Sağlayıcıya bildirim gönderilemedi (%s): %v", appointment.ProviderEmail, err)
+	}
+}
+
+// CancelByCustomer müşterinin kendi randevusunu iptal eder ve slotu tekrar
+// müsait hale getirir. Şirket politikasındaki bildirim süresi içindeki
+// iptaller geç iptal olarak işaretlenir ya da politika izin vermiyorsa reddedilir.
+func (s *AppointmentService) CancelByCustomer(ctx context.Context, actor Actor, id primitive.ObjectID, reason string, version *int64) (models.Appointment, error) {
+	appointment, err := s.customerAppointment(ctx, actor, id)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	return s.cancelOwn(ctx, actor, appointment, reason, version)
+}
+
+// cancelOwn müşterinin okunmuş randevusunu CancelByCustomer kurallarıyla iptal eder
+func (s *AppointmentService) cancelOwn(ctx context.Context, actor Actor, appointment models.Appointment, reason string, version *int64) (models.Appointment, error) {
+	policy, err := s.policyFor(ctx, appointment)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	late := withinNotice(policy, appointment, time.Now())
+	if late && policy.BlockLateCancel {
+		return models.Appointment{}, Forbidden(fmt.Sprintf("Appointments cannot be cancelled less than %d hours before start", policy.MinNoticeHours))
+	}
+
+	if reason == "" {
+		reason = "cancelled by customer"
+	}
+	cancelled, err := s.move(ctx, actor, appointment, models.StatusCancelled, reason, version, repositories.Fields{
+		"late_cancellation": late,
+	})
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	s.reopenSlot(ctx, cancelled)
+	go s.notifyProvider(cancelled, "Appointment cancelled",
+		fmt.Sprintf("%s (%s) cancelled the appointment on %s.",
+			cancelled.CustomerName, cancelled.CustomerEmail, localClock(cancelled, startsAt(cancelled))))
+	return cancelled, nil
+}
+
+// RescheduleTarget ertelemenin hedefidir: var olan boş bir slot kaydı (ID)
+// ya da çalışma saatlerinden hesaplanan bir slotun başlangıcı (Start)
+type RescheduleTarget struct {
+	ID    primitive.ObjectID
+	Start time.Time
+}
+
+// RescheduleByCustomer müşterinin randevusunu aynı sağlayıcının başka bir
+// boş slotuna taşır. Önce yeni slot atomik olarak alınır, sonra eski
+// randevu iptal edilip slotu tekrar açılır; eski randevu arada değiştiyse
+// yeni slot geri bırakılır. Politikadaki bildirim süresi ve erteleme
+// sınırı uygulanır.
+func (s *AppointmentService) RescheduleByCustomer(ctx context.Context, actor Actor, id primitive.ObjectID, target RescheduleTarget, version *int64) (models.Appointment, error) {
+	appointment, err := s.customerAppointment(ctx, actor, id)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if !CanTransition(appointment.Status, models.StatusCancelled) {
+		return models.Appointment{}, ConflictWith("Cannot reschedule a "+appointment.Status+" appointment", appointment)
+	}
+
+	policy, err := s.policyFor(ctx, appointment)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	now := time.Now()
+	if withinNotice(policy, appointment, now) {
+		return models.Appointment{}, Forbidden(fmt.Sprintf("Appointments cannot be rescheduled less than %d hours before start", policy.MinNoticeHours))
+	}
+	if policy.MaxReschedules > 0 && appointment.RescheduleCount >= policy.MaxReschedules {
+		return models.Appointment{}, Forbidden("Reschedule limit reached for this appointment")
+	}
+
+	change := models.StatusChange{
+		From:       models.StatusOpen,
+		To:         models.StatusRequested,
+		At:         now,
+		ActorID:    actor.ID,
+		ActorEmail: actor.Email,
+		ActorRole:  actor.Role,
+		Reason:     "rescheduled from " + appointment.ID.Hex(),
+	}
+
+	// Eski randevunun kaynakları yeni saatte de kullanılabilsin diye önce
+	// bırakılır; erteleme başarısız olursa geri eklenir
+	var held []models.ResourceReservation
+	if len(appointment.ResourceIDs) > 0 {
+		held, err = s.reservations.FindByAppointment(ctx, appointment.ID)
+		if err != nil {
+			return models.Appointment{}, err
+		}
+		s.releaseResources(ctx, appointment.ID)
+	}
+
+	var booked models.Appointment
+	if target.ID.IsZero() {
+		booked, err = s.rescheduleToComputed(ctx, appointment, target.Start, change)
+	} else {
+		booked, err = s.rescheduleToSlot(ctx, appointment, target.ID, change)
+	}
+	if err != nil {
+		s.restoreReservations(ctx, held)
+		return models.Appointment{}, err
+	}
+
+	cancelled, err := s.move(ctx, actor, appointment, models.StatusCancelled, "rescheduled by customer", version, repositories.Fields{
+		"rescheduled_to": booked.ID,
+	})
+	if err != nil {
+		s.releaseBooking(ctx, actor, booked, "reschedule rolled back")
+		s.restoreReservations(ctx, held)
+		return models.Appointment{}, err
+	}
+
+	s.reopenSlot(ctx, cancelled)
+	go s.notifyProvider(booked, "Appointment rescheduled",
+		fmt.Sprintf("%s (%s) moved the appointment from %s to %s.",
+			booked.CustomerName, booked.CustomerEmail,
+			localClock(cancelled, startsAt(cancelled)), localClock(booked, startsAt(booked))))
+	return booked, nil
+}
+
+// rescheduleToSlot var olan boş slot kaydını Book ile aynı koşulla, yalnızca hâlâ boşsa alır
+func (s *AppointmentService) rescheduleToSlot(ctx context.Context, appointment models.Appointment, targetID primitive.ObjectID, change models.StatusChange) (models.Appointment, error) {
+	target, err := s.appointments.FindByID(ctx, targetID)
+	if err == repositories.ErrNotFound {
+		return models.Appointment{}, NotFound("Target slot not found")
+	}
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if target.ProviderEmail != appointment.ProviderEmail {
+		return models.Appointment{}, Invalid("Target slot belongs to another provider")
+	}
+	if !startsAt(target).After(change.At) {
+		return models.Appointment{}, Invalid("Target slot is in the past")
+	}
+
+	fields := repositories.Fields{
+		"customer_name":    appointment.CustomerName,
+		"customer_email":   appointment.CustomerEmail,
+		"services":         appointment.Services,
+		"service_ids":      appointment.ServiceIDs,
+		"price":            appointment.Price,
+		"currency":         appointment.Currency,
+		"duration_minutes": appointment.DurationMinutes,
+		"buffer_minutes":   appointment.BufferMinutes,
+		"reschedule_count": appointment.RescheduleCount + 1,
+		"rescheduled_from": appointment.ID,
+		"updated_at":       change.At,
+	}
+	// Yeni kayıt takvimlerde eski etkinliği günceller
+	fields["calendar_uid"] = calendarUID(appointment)
+	fields["calendar_sequence"] = calendarSequence(appointment) + 1
+	// Ertelenen randevu serisinde aynı sırayı alır
+	if !appointment.SeriesID.IsZero() {
+		fields["series_id"] = appointment.SeriesID
+		fields["occurrence"] = appointment.Occurrence
+	}
+	cond := repositories.AppointmentCondition{Statuses: []string{models.StatusOpen}}
+	return s.claimSlot(ctx, target, appointment.DurationMinutes+appointment.BufferMinutes, cond, fields, change)
+}
+
+// rescheduleToComputed sağlayıcının çalışma saatlerinden hesaplanan slotu alır
+func (s *AppointmentService) rescheduleToComputed(ctx context.Context, appointment models.Appointment, start time.Time, change models.StatusChange) (models.Appointment, error) {
+	if start.IsZero() {
+		return models.Appointment{}, Invalid("Target slot or start time is required")
+	}
+	provider, err := s.providers.FindByEmail(ctx, appointment.ProviderEmail)
+	if err == repositories.ErrNotFound {
+		return models.Appointment{}, NotFound("Provider not found")
+	}
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	return s.bookComputed(ctx, provider, start, models.Appointment{
+		CustomerEmail:   appointment.CustomerEmail,
+		CustomerName:    appointment.CustomerName,
+		Services:        appointment.Services,
+		ServiceIDs:      appointment.ServiceIDs,
+		Price:           appointment.Price,
+		Currency:        appointment.Currency,
+		DurationMinutes: appointment.DurationMinutes,
+		BufferMinutes:   appointment.BufferMinutes,
+		RescheduleCount: appointment.RescheduleCount + 1,
+		RescheduledFrom: appointment.ID,
+		SeriesID:        appointment.SeriesID,
+		Occurrence:      appointment.Occurrence,
+		// Yeni kayıt takvimlerde eski etkinliği günceller
+		CalendarUID:      calendarUID(appointment),
+		CalendarSequence: calendarSequence(appointment) + 1,
+	}, change)
+}
+
+// releaseBooking başarısız bir erteleme ya da seri rezervasyonunda alınan
+// slotu geri boşaltır; reason geçmişe yazılır. Hesaplanan slottan
+// alınmışsa kayıt iptal edilir ve slot kendiliğinden boşalır.
+func (s *AppointmentService) releaseBooking(ctx context.Context, actor Actor, booked models.Appointment, reason string) {
+	to := models.StatusOpen
+	fields := repositories.Fields{
+		"customer_name":    "",
+		"customer_email":   "",
+		"services":         nil,
+		"service_ids":      nil,
+		"price":            int64(0),
+		"currency":         "",
+		"duration_minutes": 0,
+		"buffer_minutes":   0,
+		"reschedule_count": 0,
+		"rescheduled_from": primitive.NilObjectID,
+		"calendar_uid":     "",
+		"series_id":        nil,
+		"occurrence":       0,
+		"resource_ids":     nil,
+		"updated_at":       time.Now(),
+	}
+	if len(booked.MergedSlots) > 0 {
+		fields["end_time"] = ownEnd(booked)
+		fields["merged_slots"] = nil
+	}
+	if fromWorkingHours(booked) {
+		to = models.StatusCancelled
+		fields = repositories.Fields{"slot_active": false, "updated_at": time.Now()}
+	}
+
+	cond := repositories.AppointmentCondition{Version: &booked.Version}
+	_, err := s.updateIf(ctx, booked.ID, cond, fields, &models.StatusChange{
+		From:       booked.Status,
+		To:         to,
+		At:         time.Now(),
+		ActorID:    actor.ID,
+		ActorEmail: actor.Email,
+		ActorRole:  actor.Role,
+		Reason:     reason,
+	}, false)
+	if err != nil {
+		log.Printf("Ertelemede alınan slot geri bırakılamadı (%s): %v", booked.ID.Hex(), err)
+		return
+	}
+	if len(booked.ResourceIDs) > 0 {
+		s.releaseResources(ctx, booked.ID)
+	}
+	if !fromWorkingHours(booked) {
+		s.restoreSlots(ctx, mergedPieces(booked))
+	}
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// msgNotEnoughTime seçilen hizmetlere yetecek ardışık boş süre yoksa döner
+const msgNotEnoughTime = "Not enough consecutive free time for the selected services"
+
+// resolveServiceNames eski istemcilerin gönderdiği hizmet adlarını
+// sağlayıcının katalogdan sunduğu hizmetlere çevirir. Katalog hizmeti
+// olmayan sağlayıcılarda adlar eski hizmet listesiyle kontrol edilir ve
+// kimlik dönmez; hiç hizmet tanımlamamış sağlayıcılar adları olduğu gibi kabul eder.
+func resolveServiceNames(ctx context.Context, catalog repositories.ServiceRepository, provider models.Provider, names []string) ([]primitive.ObjectID, error) {
+	offerings, err := providerOfferings(ctx, catalog, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []primitive.ObjectID
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, o := range offerings {
+			if strings.EqualFold(o.Service.Name, name) {
+				ids = append(ids, o.Service.ID)
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		if len(offerings) > 0 || (len(provider.Services) > 0 && !contains(provider.Services, name)) {
+			return nil, Invalid(fmt.Sprintf("Service %q is not offered by this provider", name))
+		}
+	}
+	return ids, nil
+}
+
+// bookingQuote rezervasyondaki hizmetleri doğrular ve fiyatlandırır.
+// Kimlik verilmezse adlar katalogdan çözülür; katalogda karşılığı olmayan
+// eski hizmetlerin süresi bilinmez ve rezervasyon tek slot kaplar.
+func bookingQuote(ctx context.Context, catalog repositories.ServiceRepository, provider models.Provider, ids []primitive.ObjectID, names []string) (Quote, error) {
+	if len(ids) == 0 && len(names) > 0 {
+		resolved, err := resolveServiceNames(ctx, catalog, provider, names)
+		if err != nil {
+			return Quote{}, err
+		}
+		if len(resolved) == 0 {
+			return Quote{Names: names}, nil
+		}
+		ids = resolved
+	}
+	if len(ids) == 0 {
+		return Quote{}, nil
+	}
+	return quoteServices(ctx, catalog, provider, ids)
+}
+
+// claimSlot boş slot kaydını fields ve change ile alır. Rezervasyon
+// minutes dakikadan uzunsa sağlayıcının hemen ardından gelen boş slotları
+// da alınır: bu slotlar önce koşullu silinerek ayrılır, sonra ilk slotun
+// bitişi uzatılır. Herhangi bir adım başarısız olursa ayrılan slotlar geri
+// oluşturulur; iki müşteri aynı slotları alamaz. Sağlayıcının izinli ya da
+// şirketin kapalı olduğu saatlere rezervasyon yapılamaz. fields'taki
+// hizmetlerin istediği kaynaklar önce ayrılır, rezervasyon olmazsa bırakılır.
+func (s *AppointmentService) claimSlot(ctx context.Context, slot models.Appointment, minutes int, cond repositories.AppointmentCondition, fields repositories.Fields, change models.StatusChange) (models.Appointment, error) {
+	need := startsAt(slot).Add(time.Duration(minutes) * time.Minute)
+	if slot.Status != models.StatusOpen {
+		return s.mergeSlots(ctx, slot, minutes, need, cond, fields, change)
+	}
+
+	end := endsAt(slot)
+	if need.After(end) {
+		end = need
+	}
+	if err := s.checkAvailable(ctx, slot.ProviderEmail, startsAt(slot), end, slot.ID); err != nil {
+		return models.Appointment{}, err
+	}
+	// Kaynak yalnızca hizmetlerin süresi boyunca tutulur
+	if minutes > 0 {
+		end = need
+	}
+	serviceIDs, _ := fields["service_ids"].([]primitive.ObjectID)
+	resourceIDs, err := s.reserveResources(ctx, slot, serviceIDs, startsAt(slot), end)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if len(resourceIDs) > 0 {
+		fields["resource_ids"] = resourceIDs
+	}
+
+	booked, err := s.mergeSlots(ctx, slot, minutes, need, cond, fields, change)
+	if err != nil && len(resourceIDs) > 0 {
+		s.releaseResources(ctx, slot.ID)
+	}
+	return booked, err
+}
+
+// mergeSlots claimSlot'un slot kaydını ve gerekiyorsa ardışık slotları
+// alan adımıdır
+func (s *AppointmentService) mergeSlots(ctx context.Context, slot models.Appointment, minutes int, need time.Time, cond repositories.AppointmentCondition, fields repositories.Fields, change models.StatusChange) (models.Appointment, error) {
+	if minutes <= 0 || !endsAt(slot).Before(need) || slot.Status != models.StatusOpen {
+		return s.updateIf(ctx, slot.ID, cond, fields, &change, true)
+	}
+
+	following, err := s.followingSlots(ctx, slot, need)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	var claimed []models.Appointment
+	for _, next := range following {
+		version := next.Version
+		ok, err := s.appointments.DeleteIf(ctx, next.ID, repositories.AppointmentCondition{
+			Version:  &version,
+			Statuses: []string{models.StatusOpen},
+		})
+		if err != nil || !ok {
+			s.restoreSlots(ctx, claimed)
+			if err != nil {
+				return models.Appointment{}, err
+			}
+			return models.Appointment{}, Conflict(msgNotEnoughTime)
+		}
+		claimed = append(claimed, next)
+	}
+
+	merged := slot.MergedSlots
+	for _, next := range following {
+		merged = append(merged, models.MergedSlot{
+			ID:        next.ID,
+			StartTime: startsAt(next),
+			EndTime:   endsAt(next),
+			BatchID:   next.BatchID,
+		})
+	}
+	fields["end_time"] = endsAt(following[len(following)-1])
+	fields["merged_slots"] = merged
+
+	booked, err := s.updateIf(ctx, slot.ID, cond, fields, &change, true)
+	if err != nil {
+		s.restoreSlots(ctx, claimed)
+		return models.Appointment{}, err
+	}
+	return booked, nil
+}
+
+// followingSlots slotun bitişinden need anına kadar aralıksız devam eden
+// boş slot kayıtlarını döner; zincir bir yerde kopuyorsa Conflict döner
+func (s *AppointmentService) followingSlots(ctx context.Context, slot models.Appointment, need time.Time) ([]models.Appointment, error) {
+	loc := slot.Location()
+	candidates, err := s.appointments.Find(ctx, repositories.AppointmentFilter{
+		ProviderEmail: slot.ProviderEmail,
+		From:          truncateDay(startsAt(slot).In(loc), loc),
+		To:            need,
+	})
+	if err != nil {
+		return nil, err
+	}
+	open := make(map[int64]models.Appointment, len(candidates))
+	for _, a := range candidates {
+		if a.ID != slot.ID && a.Status == models.StatusOpen && !fromWorkingHours(a) {
+			open[startsAt(a).Unix()] = a
+		}
+	}
+
+	var out []models.Appointment
+	for end := endsAt(slot); end.Before(need); {
+		next, ok := open[end.Unix()]
+		if !ok {
+			return nil, Conflict(msgNotEnoughTime)
+		}
+		out = append(out, next)
+		end = endsAt(next)
+	}
+	return out, nil
+}
+
+// restoreSlots rezervasyon için ayrılmış slot kayıtlarını geri ekler.
+// Hata işlemi geri almaz, yalnızca loglanır.
+func (s *AppointmentService) restoreSlots(ctx context.Context, slots []models.Appointment) {
+	for _, slot := range slots {
+		if err := s.appointments.Create(ctx, slot); err != nil {
+			log.Printf("Ayrılan slot geri eklenemedi (%s): %v", slot.ID.Hex(), err)
+		}
+	}
+}
+
+// mergedPieces rezervasyonun kapladığı slotları özgün kimlikleriyle boş
+// slot kaydı olarak döner. Toplu üretilen slotlar anahtarlarını geri alır.
+func mergedPieces(appointment models.Appointment) []models.Appointment {
+	now := time.Now()
+	pieces := make([]models.Appointment, 0, len(appointment.MergedSlots))
+	for _, m := range appointment.MergedSlots {
+		piece := models.Appointment{
+			ID:            m.ID,
+			ProviderEmail: appointment.ProviderEmail,
+			ProviderName:  appointment.ProviderName,
+			CompanyName:   appointment.CompanyName,
+			CompanyID:     appointment.CompanyID,
+			Date:          m.StartTime,
+			StartTime:     m.StartTime,
+			EndTime:       m.EndTime,
+			Status:        models.StatusOpen,
+			BatchID:       m.BatchID,
+			TimeZone:      appointment.TimeZone,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if m.BatchID != "" {
+			piece.SlotKey = openSlotKey(appointment.ProviderEmail, m.StartTime)
+			piece.SlotActive = true
+		}
+		pieces = append(pieces, piece)
+	}
+	return pieces
+}
+
+// ownEnd birleştirilmiş bir rezervasyonda ilk slotun özgün bitişidir
+func ownEnd(appointment models.Appointment) time.Time {
+	if len(appointment.MergedSlots) > 0 {
+		return appointment.MergedSlots[0].StartTime
+	}
+	return endsAt(appointment)
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxGroupCapacity bir grup seansının alabileceği en fazla katılımcıdır
+const maxGroupCapacity = 500
+
+// GroupSession sağlayıcının açtığı grup seansı isteğidir. Hizmet verilirse
+// fiyat katılımcı başınadır.
+type GroupSession struct {
+	ProviderEmail string
+	Title         string
+	Start         time.Time
+	End           time.Time
+	Capacity      int
+	Services      []string
+	ServiceIDs    []primitive.ObjectID
+}
+
+// GroupJoin grup seansına katılım isteğidir. Müşteriler kendi adlarına
+// katılır; personel CustomerEmail ile müşteri adına yer ayırır.
+type GroupJoin struct {
+	CustomerEmail string
+	CustomerName  string
+}
+
+// bookedParticipant e-postanın seansta yer tutan kaydını döner
+func bookedParticipant(session models.Appointment, email string) (models.Participant, bool) {
+	for _, p := range session.Participants {
+		if p.Email == email && p.Status == models.ParticipantBooked {
+			return p, true
+		}
+	}
+	return models.Participant{}, false
+}
+
+// ownSeat seansı yalnızca müşterinin kendi katılımcı kayıtlarıyla döner;
+// diğer katılımcılar müşterilere gösterilmez
+func ownSeat(session models.Appointment, email string) models.Appointment {
+	var own []models.Participant
+	for _, p := range session.Participants {
+		if p.Email == email {
+			own = append(own, p)
+		}
+	}
+	session.Participants = own
+	session.History = nil
+	return session
+}
+
+// CreateGroupSession sağlayıcının takvimine onaylı bir grup seansı ekler.
+// Seans sağlayıcının izni, şirketin kapalı günü ya da başka bir randevu
+// veya boş slotla çakışamaz; çalışma saatlerinden hesaplanan slotları da
+// dolu tutar. Hizmetlerin istediği kaynaklar seans boyunca ayrılır.
+func (s *AppointmentService) CreateGroupSession(ctx context.Context, actor Actor, req GroupSession) (models.Appointment, error) {
+	provider, err := providerByEmail(ctx, s.providers, actor, req.ProviderEmail)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if req.Capacity < 1 || req.Capacity > maxGroupCapacity {
+		return models.Appointment{}, Invalid(fmt.Sprintf("Capacity must be between 1 and %d", maxGroupCapacity))
+	}
+	if req.Start.IsZero() || !req.End.After(req.Start) {
+		return models.Appointment{}, Invalid("End time must be after start time")
+	}
+	if !req.Start.After(time.Now()) {
+		return models.Appointment{}, Invalid("Group sessions must start in the future")
+	}
+	quote, err := bookingQuote(ctx, s.catalog, provider, req.ServiceIDs, req.Services)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if err := s.checkAvailable(ctx, provider.Email, req.Start, req.End, primitive.NilObjectID); err != nil {
+		return models.Appointment{}, err
+	}
+
+	existing, err := s.appointments.Find(ctx, repositories.AppointmentFilter{
+		ProviderEmail: provider.Email,
+		From:          req.Start.AddDate(0, 0, -1),
+		To:            req.End,
+	})
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	for _, a := range existing {
+		if (a.Status == models.StatusOpen || contains(conflictingStatuses, a.Status)) &&
+			overlaps(startsAt(a), endsAt(a), req.Start, req.End) {
+			return models.Appointment{}, Conflict("Provider already has an appointment or open slot at this time")
+		}
+	}
+
+	session := models.Appointment{
+		ID:            primitive.NewObjectID(),
+		ProviderEmail: provider.Email,
+		ProviderName:  provider.Name,
+		CompanyName:   provider.CompanyName,
+		CompanyID:     provider.CompanyId,
+		Title:         req.Title,
+		Date:          req.Start,
+		StartTime:     req.Start,
+		EndTime:       req.End,
+		Status:        models.StatusConfirmed,
+		Capacity:      req.Capacity,
+		Services:      req.Services,
+	}
+	if len(quote.ServiceIDs) > 0 {
+		session.Services = quote.Names
+		session.ServiceIDs = quote.ServiceIDs
+		session.Price = quote.Price
+		session.Currency = quote.Currency
+		session.DurationMinutes = quote.DurationMinutes
+		session.BufferMinutes = quote.BufferMinutes
+	}
+	session.ResourceIDs, err = s.reserveResources(ctx, session, session.ServiceIDs, req.Start, req.End)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	created, err := s.Create(ctx, session)
+	if err != nil && len(session.ResourceIDs) > 0 {
+		s.releaseResources(ctx, session.ID)
+	}
+	return created, err
+}
+
+// GroupSessions sağlayıcının verilen takvim günündeki, henüz başlamamış ve
+// boş yeri olan grup seanslarını döner. Katılımcılar gösterilmez; yetki
+// gerektirmez.
+func (s *AppointmentService) GroupSessions(ctx context.Context, providerEmail string, day time.Time) ([]models.Appointment, error) {
+	provider, err := s.providers.FindByEmail(ctx, providerEmail)
+	if err == repositories.ErrNotFound {
+		return nil, NotFound("Provider not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, loc, err := s.providerLocation(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := dayRange(day, loc)
+	found, err := s.appointments.Find(ctx, repositories.AppointmentFilter{
+		ProviderEmail: providerEmail,
+		From:          from,
+		To:            to,
+		Status:        models.StatusConfirmed,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sessions := []models.Appointment{}
+	for _, a := range found {
+		if a.Capacity == 0 || a.Seats() == 0 || !startsAt(a).After(now) {
+			continue
+		}
+		a.Participants = nil
+		a.History = nil
+		sessions = append(sessions, a)
+	}
+	return sessions, nil
+}
+
+// JoinGroup grup seansında bir yer ayırır. Yer sayısı kontrolü ve kayıt
+// tek bir koşullu yazmadır; aynı anda son yeri isteyenlerden yalnızca biri
+// alır. Müşteri aynı seansa ikinci kez katılamaz.
+func (s *AppointmentService) JoinGroup(ctx context.Context, actor Actor, id primitive.ObjectID, join GroupJoin) (models.Appointment, error) {
+	var session models.Appointment
+	var err error
+	email := join.CustomerEmail
+	if actor.Role == utils.RoleUser {
+		email = actor.Email
+		session, err = s.appointments.FindByID(ctx, id)
+		if err == repositories.ErrNotFound {
+			return models.Appointment{}, NotFound("Appointment not found")
+		}
+	} else {
+		session, err = s.authorize(ctx, actor, id)
+	}
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if session.Capacity == 0 {
+		return models.Appointment{}, Invalid("Appointment is not a group session")
+	}
+	if email == "" {
+		return models.Appointment{}, Invalid("Customer Email is required")
+	}
+
+	now := time.Now()
+	if !startsAt(session).After(now) {
+		return models.Appointment{}, Conflict("Group session has already started")
+	}
+	added, err := s.appointments.AddParticipant(ctx, id,
+		repositories.AppointmentCondition{Statuses: []string{models.StatusConfirmed}},
+		models.Participant{
+			Email:    email,
+			Name:     join.CustomerName,
+			Status:   models.ParticipantBooked,
+			BookedAt: now,
+		})
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	current, err := s.appointments.FindByID(ctx, id)
+	if err == repositories.ErrNotFound {
+		return models.Appointment{}, NotFound("Appointment not found")
+	}
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if !added {
+		switch _, booked := bookedParticipant(current, email); {
+		case current.Status != models.StatusConfirmed:
+			return models.Appointment{}, Conflict("Group session is " + current.Status)
+		case booked:
+			return models.Appointment{}, Conflict("Already booked for this group session")
+		default:
+			return models.Appointment{}, Conflict("Group session is full")
+		}
+	}
+	if actor.Role == utils.RoleUser {
+		return ownSeat(current, email), nil
+	}
+	return current, nil
+}
+
+// LeaveGroup müşterinin grup seansındaki yerini iptal eder ve yeri tekrar
+// müsait hale getirir. Şirket politikası geç iptali engelliyorsa bildirim
+// süresi içinde yer bırakılamaz.
+func (s *AppointmentService) LeaveGroup(ctx context.Context, actor Actor, id primitive.ObjectID) (models.Appointment, error) {
+	session, err := s.appointments.FindByID(ctx, id)
+	if err != nil && err != repositories.ErrNotFound {
+		return models.Appointment{}, err
+	}
+	participant, booked := bookedParticipant(session, actor.Email)
+	if err == repositories.ErrNotFound || actor.Email == "" || !booked {
+		return models.Appointment{}, NotFound("Appointment not found")
+	}
+
+	policy, err := s.policyFor(ctx, session)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if withinNotice(policy, session, time.Now()) && policy.BlockLateCancel {
+		return models.Appointment{}, Forbidden(fmt.Sprintf("Appointments cannot be cancelled less than %d hours before start", policy.MinNoticeHours))
+	}
+
+	left, err := s.appointments.SetParticipantStatus(ctx, id, actor.Email, models.ParticipantBooked, models.ParticipantCancelled, time.Now())
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if !left {
+		return models.Appointment{}, Conflict("Not booked for this group session")
+	}
+	current, err := s.appointments.FindByID(ctx, id)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	go s.notifyProvider(current, "Group session seat cancelled",
+		fmt.Sprintf("%s (%s) left %s on %s. %d of %d seats are taken.",
+			participant.Name, participant.Email, sessionName(current), localClock(current, startsAt(current)),
+			current.SeatsTaken, current.Capacity))
+	return ownSeat(current, actor.Email), nil
+}
+
+// Attendees grup seansının tüm katılımcı kayıtlarını döner
+func (s *AppointmentService) Attendees(ctx context.Context, actor Actor, id primitive.ObjectID) ([]models.Participant, error) {
+	session, err := s.authorize(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.Capacity == 0 {
+		return nil, Invalid("Appointment is not a group session")
+	}
+	if session.Participants == nil {
+		return []models.Participant{}, nil
+	}
+	return session.Participants, nil
+}
+
+// MarkAttendance yer ayırmış katılımcıyı seansa geldi (attended) ya da
+// gelmedi (no_show) olarak işaretler. Seans başlamadan işaretlenemez.
+func (s *AppointmentService) MarkAttendance(ctx context.Context, actor Actor, id primitive.ObjectID, email, status string) (models.Appointment, error) {
+	if status != models.ParticipantAttended && status != models.ParticipantNoShow {
+		return models.Appointment{}, Invalid("Status must be attended or no_show")
+	}
+	session, err := s.authorize(ctx, actor, id)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if session.Capacity == 0 {
+		return models.Appointment{}, Invalid("Appointment is not a group session")
+	}
+	if startsAt(session).After(time.Now()) {
+		return models.Appointment{}, Conflict("Group session has not started yet")
+	}
+
+	marked, err := s.appointments.SetParticipantStatus(ctx, id, email, models.ParticipantBooked, status, time.Now())
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if !marked {
+		return models.Appointment{}, Conflict("Participant is not booked for this group session")
+	}
+	return s.appointments.FindByID(ctx, id)
+}
+
+// sessionName bildirimlerde seansın adıdır
+func sessionName(session models.Appointment) string {
+	if session.Title != "" {
+		return session.Title
+	}
+	return "the group session"
+}
+
+// notifyParticipants yer ayırmış katılımcılara e-posta gönderir. İsteği
+// bekletmemek için arka planda çağrılır; gönderim hatası işlemi geri almaz,
+// yalnızca loglanır.
+func (s *AppointmentService) notifyParticipants(session models.Appointment, subject, body string) {
+	for _, p := range session.Participants {
+		if p.Status != models.ParticipantBooked {
+			continue
+		}
+		if err := s.mailer.Send(p.Email, subject, body); err != nil {
+			log.Printf("Katılımcıya bildirim gönderilemedi (%s): %v", p.Email, err)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// reservationStep kaynak ayırmalarının zaman dilimidir. Ayırma kapladığı
+// her dilim için bir anahtar taşır; dilimin içine düşen başlangıç ve
+// bitişler bütün dilimi tutar.
+const reservationStep = 5 * time.Minute
+
+// reservationKeys kaynağın [start, end) aralığındaki dilim anahtarlarıdır
+func reservationKeys(resourceID primitive.ObjectID, start, end time.Time) []string {
+	var keys []string
+	for t := start.Truncate(reservationStep); t.Before(end); t = t.Add(reservationStep) {
+		keys = append(keys, fmt.Sprintf("%s|%d", resourceID.Hex(), t.Unix()))
+	}
+	return keys
+}
+
+// resourceOpen kaynağın [start, end) aralığında kendi saatleri içinde
+// olduğunu döner. Saat tanımlanmamış kaynak her zaman açıktır.
+func resourceOpen(resource models.Resource, start, end time.Time, loc *time.Location) bool {
+	if len(resource.Hours) == 0 {
+		return true
+	}
+	day := truncateDay(start.In(loc), loc)
+	slot := Slot{Start: start, End: end}
+	for _, rule := range rulesFor(models.WorkingHours{Rules: resource.Hours}, day) {
+		for _, interval := range rule.Intervals {
+			from, _ := clock(day, interval.Start)
+			to, _ := clock(day, interval.End)
+			if !start.Before(from) && !end.After(to) && !inBreak(day, rule.Breaks, slot) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requiredResources hizmetlerin istediği kaynak türleridir
+func (s *AppointmentService) requiredResources(ctx context.Context, serviceIDs []primitive.ObjectID) ([]string, error) {
+	if len(serviceIDs) == 0 {
+		return nil, nil
+	}
+	services, err := s.catalog.FindByIDs(ctx, serviceIDs)
+	if err != nil {
+		return nil, err
+	}
+	var types []string
+	for _, service := range services {
+		types = append(types, service.ResourceTypes...)
+	}
+	return resourceTypes(types), nil
+}
+
+// reserveResources hizmetlerin istediği her kaynak türünden, [start, end)
+// aralığında saatleri uygun ve boş olan ilk etkin kaynağı randevuya
+// ayırır. Boş kaynağı kalmayan bir tür varsa o ana kadar ayrılanlar
+// bırakılır ve Conflict döner. İki randevu aynı kaynağı aynı anda alamaz;
+// ayırmanın dilim anahtarları tekildir.
+func (s *AppointmentService) reserveResources(ctx context.Context, appointment models.Appointment, serviceIDs []primitive.ObjectID, start, end time.Time) ([]primitive.ObjectID, error) {
+	types, err := s.requiredResources(ctx, serviceIDs)
+	if err != nil || len(types) == 0 {
+		return nil, err
+	}
+	companyID := appointment.CompanyID
+	if companyID == "" {
+		if provider, err := s.providers.FindByEmail(ctx, appointment.ProviderEmail); err == nil {
+			companyID = provider.CompanyId
+		}
+	}
+	loc, err := s.companyLocation(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var reserved []primitive.ObjectID
+	for _, t := range types {
+		id, err := s.reserveOne(ctx, appointment, companyID, t, start, end, loc)
+		if err != nil {
+			if len(reserved) > 0 {
+				s.releaseResources(ctx, appointment.ID)
+			}
+			return nil, err
+		}
+		reserved = append(reserved, id)
+	}
+	return reserved, nil
+}
+
+// reserveOne t türündeki kaynaklardan ilk boş olanı ayırır
+func (s *AppointmentService) reserveOne(ctx context.Context, appointment models.Appointment, companyID, t string, start, end time.Time, loc *time.Location) (primitive.ObjectID, error) {
+	candidates, err := s.resources.List(ctx, companyID, t, true)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	for _, resource := range candidates {
+		if !resourceOpen(resource, start, end, loc) {
+			continue
+		}
+		err := s.reservations.Create(ctx, models.ResourceReservation{
+			ID:            primitive.NewObjectID(),
+			ResourceID:    resource.ID,
+			AppointmentID: appointment.ID,
+			CompanyID:     companyID,
+			ProviderEmail: appointment.ProviderEmail,
+			Start:         start,
+			End:           end,
+			Keys:          reservationKeys(resource.ID, start, end),
+			CreatedAt:     time.Now(),
+		})
+		if err == repositories.ErrDuplicate {
+			continue
+		}
+		if err != nil {
+			return primitive.NilObjectID, err
+		}
+		return resource.ID, nil
+	}
+	return primitive.NilObjectID, Conflict(fmt.Sprintf("No %s is available at this time", t))
+}
+
+// releaseResources randevunun tüm kaynak ayırmalarını bırakır. Hata
+// işlemi geri almaz, yalnızca loglanır.
+func (s *AppointmentService) releaseResources(ctx context.Context, appointmentID primitive.ObjectID) {
+	if _, err := s.reservations.DeleteByAppointment(ctx, appointmentID); err != nil {
+		log.Printf("Kaynak ayırmaları bırakılamadı (%s): %v", appointmentID.Hex(), err)
+	}
+}
+
+// restoreReservations bırakılan ayırmaları geri ekler
+func (s *AppointmentService) restoreReservations(ctx context.Context, reservations []models.ResourceReservation) {
+	for _, r := range reservations {
+		if err := s.reservations.Create(ctx, r); err != nil {
+			log.Printf("Kaynak ayırması geri eklenemedi (%s): %v", r.ID.Hex(), err)
+		}
+	}
+}
+
+// moveReservations randevunun kaynaklarını yeni [start, end) aralığına
+// taşır. Eski ayırmalar önce bırakılır; yeni aralıkta boş kaynak yoksa
+// geri eklenir ve Conflict döner. Dönen geri alma fonksiyonu sonraki bir
+// adım başarısız olursa eski ayırmaları geri getirir.
+func (s *AppointmentService) moveReservations(ctx context.Context, appointment models.Appointment, start, end time.Time) ([]primitive.ObjectID, func(), error) {
+	previous, err := s.reservations.FindByAppointment(ctx, appointment.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.releaseResources(ctx, appointment.ID)
+	ids, err := s.reserveResources(ctx, appointment, appointment.ServiceIDs, start, end)
+	if err != nil {
+		s.restoreReservations(ctx, previous)
+		return nil, nil, err
+	}
+	undo := func() {
+		s.releaseResources(ctx, appointment.ID)
+		s.restoreReservations(ctx, previous)
+	}
+	return ids, undo, nil
+}
+
+// ResourceSchedule kaynağın verilen takvim günleri arasındaki ayırmalarını
+// döner; günler şirketin saat diliminde yorumlanır ve to dahildir
+func (s *AppointmentService) ResourceSchedule(ctx context.Context, actor Actor, id primitive.ObjectID, from, to time.Time) ([]models.ResourceReservation, error) {
+	resource, err := resourceByID(ctx, s.resources, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if to.Before(from) {
+		return nil, Invalid("End date must not be before start date")
+	}
+	if to.Sub(from) > maxAvailabilityDays*24*time.Hour {
+		return nil, Invalid(fmt.Sprintf("Date range cannot exceed %d days", maxAvailabilityDays))
+	}
+	loc, err := s.companyLocation(ctx, resource.CompanyID)
+	if err != nil {
+		return nil, err
+	}
+	start, _ := dayRange(from, loc)
+	_, end := dayRange(to, loc)
+	list, err := s.reservations.FindByResource(ctx, id, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []models.ResourceReservation{}
+	}
+	return list, nil
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// addResource şirkete verilen türde etkin bir kaynak ekler
+func addResource(t *testing.T, repos *repositories.Repositories, companyID, name, kind string) models.Resource {
+	t.Helper()
+	resource := models.Resource{ID: primitive.NewObjectID(), CompanyID: companyID, Name: name, Type: kind, Active: true}
+	if err := repos.Resources.Create(context.Background(), resource); err != nil {
+		t.Fatal(err)
+	}
+	return resource
+}
+
+// addRoomProviders aynı şirkette oda isteyen bir hizmeti sunan iki sağlayıcı ekler
+func addRoomProviders(t *testing.T, svc *Services, repos *repositories.Repositories) (string, models.Service, []models.Provider) {
+	t.Helper()
+	ctx := context.Background()
+	first := addZonedProvider(t, repos, "a@example.com", "UTC")
+	service := addService(t, repos, models.Service{CompanyID: first.CompanyId, Name: "Massage", DurationMinutes: 30, Price: 500, Active: true, ResourceTypes: []string{"room"}})
+	second := models.Provider{ID: primitive.NewObjectID(), Name: "Test", Email: "b@example.com", CompanyId: first.CompanyId}
+	if err := repos.Providers.Create(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	days := []string{}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		days = append(days, d.String())
+	}
+	providers := []models.Provider{first, second}
+	for _, provider := range providers {
+		if err := repos.Providers.SetOffering(ctx, provider.ID, models.Offering{ServiceID: service.ID}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.Appointments.SetWorkingHours(ctx, System(), provider.Email, models.WorkingHours{
+			SlotMinutes: 30,
+			Rules:       []models.WorkingHoursRule{{Weekdays: days, Intervals: []models.TimeRange{{Start: "09:00", End: "12:00"}}}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return first.CompanyId, service, providers
+}
+
+func TestBookSlotDoesNotDoubleReserveResource(t *testing.T) {
+	svc, repos := newTestServices(t)
+	ctx := context.Background()
+	companyID, service, providers := addRoomProviders(t, svc, repos)
+	room := addResource(t, repos, companyID, "Room 1", "room")
+	nine := testDay().Add(9 * time.Hour)
+	book := func(provider models.Provider, start time.Time, email string) (models.Appointment, error) {
+		return svc.Appointments.BookSlot(ctx, SlotBooking{ProviderEmail: provider.Email, Start: start, CustomerName: "C", CustomerEmail: email, ServiceIDs: []primitive.ObjectID{service.ID}})
+	}
+
+	first, err := book(providers[0], nine, "c1@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.ResourceIDs) != 1 || first.ResourceIDs[0] != room.ID {
+		t.Fatalf("resources = %v, want the only room", first.ResourceIDs)
+	}
+
+	// Diğer sağlayıcı aynı saatte boş oda bulamaz ve slotu da almaz
+	_, err = book(providers[1], nine, "c2@example.com")
+	wantKind(t, err, KindConflict)
+	if booked, _ := repos.Appointments.Find(ctx, repositories.AppointmentFilter{CustomerEmail: "c2@example.com"}); len(booked) != 0 {
+		t.Fatalf("%d appointments stored for the rejected booking, want none", len(booked))
+	}
+
+	// Bitişik aralık odayı paylaşabilir
+	if _, err := book(providers[1], nine.Add(30*time.Minute), "c2@example.com"); err != nil {
+		t.Fatalf("adjacent booking: %v", err)
+	}
+
+	// İkinci oda eklenince aynı saat alınabilir
+	second := addResource(t, repos, companyID, "Room 2", "room")
+	booked, err := book(providers[1], nine, "c3@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(booked.ResourceIDs) != 1 || booked.ResourceIDs[0] != second.ID {
+		t.Fatalf("resources = %v, want the second room", booked.ResourceIDs)
+	}
+
+	// Bırakılan ayırma odayı boşaltır
+	if reservations, err := repos.Reservations.FindByResource(ctx, room.ID, nine, nine.Add(time.Hour)); err != nil || len(reservations) != 2 {
+		t.Fatalf("room has %d reservations, err %v, want 2", len(reservations), err)
+	}
+	svc.Appointments.releaseResources(ctx, first.ID)
+	if reservations, _ := repos.Reservations.FindByResource(ctx, room.ID, nine, nine.Add(30*time.Minute)); len(reservations) != 0 {
+		t.Fatalf("room still has %d reservations after release", len(reservations))
+	}
+}
+
+func TestReserveResourcesConcurrent(t *testing.T) {
+	svc, repos := newTestServices(t)
+	ctx := context.Background()
+	companyID, service, providers := addRoomProviders(t, svc, repos)
+	room := addResource(t, repos, companyID, "Room 1", "room")
+	start := testDay().Add(9 * time.Hour)
+
+	// Kısmen çakışan aralıklar da aynı odayı aynı anda alamaz
+	var wins int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			from := start.Add(time.Duration(i) * 3 * time.Minute)
+			appointment := models.Appointment{ID: primitive.NewObjectID(), ProviderEmail: providers[i%2].Email, CompanyID: companyID}
+			ids, err := svc.Appointments.reserveResources(ctx, appointment, []primitive.ObjectID{service.ID}, from, from.Add(30*time.Minute))
+			if KindOf(err) == KindConflict {
+				return
+			}
+			if err != nil || len(ids) != 1 || ids[0] != room.ID {
+				t.Errorf("reserve: ids %v, err %v", ids, err)
+				return
+			}
+			mu.Lock()
+			wins++
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+	if wins != 1 {
+		t.Fatalf("%d overlapping reservations won, want 1", wins)
+	}
+}
@@ -0,0 +1,529 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Bir serinin sınırları: en fazla maxSeriesOccurrences randevu ve ilk
+// randevudan itibaren en fazla maxSeriesDays gün
+const (
+	maxSeriesOccurrences = 52
+	maxSeriesDays        = 366
+)
+
+// Seri iptalinin kapsamları
+const (
+	SeriesScopeOne       = "one"       // Yalnızca verilen randevu
+	SeriesScopeFollowing = "following" // Verilen randevu ve sonrakiler
+	SeriesScopeAll       = "all"       // Serinin henüz gerçekleşmemiş tüm randevuları
+)
+
+// SeriesBooking tekrarlayan bir randevu serisi isteğidir. Rule RRULE alt
+// kümesidir: FREQ=WEEKLY ya da MONTHLY, isteğe bağlı INTERVAL ve COUNT ya
+// da UNTIL. Start ilk randevunun başlangıcıdır. AllOrNothing true ise
+// tekrarlardan biri alınamazsa hiçbiri alınmaz; aksi halde alınabilenler
+// alınır ve diğerleri Conflicts'te döner.
+type SeriesBooking struct {
+	ProviderEmail string
+	Start         time.Time
+	Rule          string
+	CustomerName  string
+	CustomerEmail string
+	Services      []string
+	ServiceIDs    []primitive.ObjectID
+	AllOrNothing  bool
+}
+
+// SeriesConflict serinin alınamayan ya da iptal edilemeyen bir tekrarıdır
+type SeriesConflict struct {
+	Occurrence int
+	Start      time.Time
+	Reason     string
+}
+
+// SeriesResult seri ve serinin randevularıdır. Conflicts rezervasyonda
+// alınamayan tekrarlardır.
+type SeriesResult struct {
+	Series       models.AppointmentSeries
+	Appointments []models.Appointment
+	Conflicts    []SeriesConflict
+}
+
+// SeriesCancel seri iptal isteğidir. one ve following kapsamında
+// AppointmentID serinin bir randevusu olmalıdır.
+type SeriesCancel struct {
+	Scope         string
+	AppointmentID primitive.ObjectID
+	Reason        string
+}
+
+// SeriesCancelResult iptal edilen randevular ve iptal edilemeyenlerdir,
+// örneğin politika süresi içinde kalanlar
+type SeriesCancelResult struct {
+	Series    models.AppointmentSeries
+	Cancelled []models.Appointment
+	Skipped   []SeriesConflict
+}
+
+// recurrence ayrıştırılmış tekrar kuralıdır. until boş değilse dahildir.
+type recurrence struct {
+	freq     string
+	interval int
+	count    int
+	until    time.Time
+}
+
+// RecurrenceRule basit sıklık adından kural yazar: weekly, biweekly ya da
+// monthly. until YYYY-MM-DD biçiminde ve dahildir; count ile birlikte
+// verilemez.
+func RecurrenceRule(frequency string, count int, until string) (string, error) {
+	var rule string
+	switch strings.ToLower(frequency) {
+	case "weekly":
+		rule = "FREQ=WEEKLY"
+	case "biweekly":
+		rule = "FREQ=WEEKLY;INTERVAL=2"
+	case "monthly":
+		rule = "FREQ=MONTHLY"
+	default:
+		return "", Invalid("Frequency must be weekly, biweekly or monthly")
+	}
+	if count > 0 && until != "" {
+		return "", Invalid("Use either count or until, not both")
+	}
+	if count > 0 {
+		return rule + ";COUNT=" + strconv.Itoa(count), nil
+	}
+	if until != "" {
+		day, err := time.Parse("2006-01-02", until)
+		if err != nil {
+			return "", Invalid("Invalid until date, expected YYYY-MM-DD")
+		}
+		return rule + ";UNTIL=" + day.Format("20060102"), nil
+	}
+	return "", Invalid("Count or until is required")
+}
+
+// parseRule RRULE alt kümesini ayrıştırır. Tarih biçimli UNTIL o günün
+// sonuna kadar, yerel saatli UNTIL loc'ta yorumlanır.
+func parseRule(rule string, loc *time.Location) (recurrence, error) {
+	r := recurrence{interval: 1}
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return recurrence{}, Invalid("Recurrence rule is required")
+	}
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return recurrence{}, Invalid(fmt.Sprintf("Invalid rule part %q", part))
+		}
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.freq = strings.ToUpper(value)
+			if r.freq != "WEEKLY" && r.freq != "MONTHLY" {
+				return recurrence{}, Invalid("Only weekly and monthly recurrence is supported")
+			}
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(value)
+			if err != nil || r.interval < 1 {
+				return recurrence{}, Invalid("Interval must be a positive number")
+			}
+		case "COUNT":
+			r.count, err = strconv.Atoi(value)
+			if err != nil || r.count < 1 {
+				return recurrence{}, Invalid("Count must be a positive number")
+			}
+		case "UNTIL":
+			r.until, err = parseUntil(value, loc)
+			if err != nil {
+				return recurrence{}, Invalid("Invalid until, expected YYYYMMDD or YYYYMMDDTHHMMSSZ")
+			}
+		default:
+			return recurrence{}, Invalid(fmt.Sprintf("Unsupported rule part %s", key))
+		}
+	}
+	if r.freq == "" {
+		return recurrence{}, Invalid("FREQ is required")
+	}
+	if (r.count > 0) == !r.until.IsZero() {
+		return recurrence{}, Invalid("Exactly one of COUNT or UNTIL is required")
+	}
+	if r.count > maxSeriesOccurrences {
+		return recurrence{}, Invalid(fmt.Sprintf("A series cannot have more than %d occurrences", maxSeriesOccurrences))
+	}
+	return r, nil
+}
+
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", value, loc); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation("20060102", value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Second), nil
+}
+
+// String kuralı saklandığı biçimde yazar
+func (r recurrence) String() string {
+	rule := "FREQ=" + r.freq
+	if r.interval > 1 {
+		rule += ";INTERVAL=" + strconv.Itoa(r.interval)
+	}
+	if r.count > 0 {
+		return rule + ";COUNT=" + strconv.Itoa(r.count)
+	}
+	return rule + ";UNTIL=" + r.until.UTC().Format("20060102T150405Z")
+}
+
+// dates serinin randevu başlangıçlarını loc'ta aynı yerel saatte üretir;
+// yaz saati geçişlerinde yerel saat korunur. RFC 5545'teki gibi aylık
+// seride günü olmayan aylar (örneğin 31 Şubat) atlanır ve sayılmaz.
+func (r recurrence) dates(start time.Time, loc *time.Location) ([]time.Time, error) {
+	local := start.In(loc)
+	limit := local.AddDate(0, 0, maxSeriesDays)
+	var out []time.Time
+	for i := 0; r.count == 0 || len(out) < r.count; i++ {
+		var t time.Time
+		if r.freq == "WEEKLY" {
+			t = time.Date(local.Year(), local.Month(), local.Day()+7*r.interval*i, local.Hour(), local.Minute(), 0, 0, loc)
+		} else {
+			t = time.Date(local.Year(), local.Month()+time.Month(r.interval*i), local.Day(), local.Hour(), local.Minute(), 0, 0, loc)
+		}
+		if !r.until.IsZero() && t.After(r.until) {
+			break
+		}
+		if t.After(limit) {
+			return nil, Invalid(fmt.Sprintf("A series cannot span more than %d days", maxSeriesDays))
+		}
+		if r.freq == "MONTHLY" && t.Day() != local.Day() {
+			continue
+		}
+		out = append(out, t)
+		if len(out) > maxSeriesOccurrences {
+			return nil, Invalid(fmt.Sprintf("A series cannot have more than %d occurrences", maxSeriesOccurrences))
+		}
+	}
+	return out, nil
+}
+
+// BookSeries müşteri için tekrarlayan bir randevu serisi alır. Her tekrar
+// BookSlot ile aynı koşullu yazmayla alınır: o anda üretilmiş boş slot
+// kaydı varsa o, yoksa çalışma saatlerinden hesaplanan slot. Müşteri kendi
+// adına, sağlayıcı ve yöneticiler erişebildikleri sağlayıcının müşterisi
+// adına seri oluşturabilir. Hiçbir tekrar alınamazsa ya da AllOrNothing
+// ile biri alınamazsa alınanlar geri bırakılır ve çakışmalarla Conflict döner.
+func (s *AppointmentService) BookSeries(ctx context.Context, actor Actor, req SeriesBooking) (SeriesResult, error) {
+	provider, err := s.providers.FindByEmail(ctx, req.ProviderEmail)
+	if err == repositories.ErrNotFound {
+		return SeriesResult{}, NotFound("Provider not found")
+	}
+	if err != nil {
+		return SeriesResult{}, err
+	}
+	customerEmail := req.CustomerEmail
+	if actor.Role == utils.RoleUser {
+		customerEmail = actor.Email
+	} else if !actor.CanAccessProvider(provider) {
+		return SeriesResult{}, ErrForbidden
+	}
+	if customerEmail == "" {
+		return SeriesResult{}, Invalid("Customer email is required")
+	}
+
+	zone, loc, err := s.providerLocation(ctx, provider)
+	if err != nil {
+		return SeriesResult{}, err
+	}
+	rule, err := parseRule(req.Rule, loc)
+	if err != nil {
+		return SeriesResult{}, err
+	}
+	dates, err := rule.dates(req.Start, loc)
+	if err != nil {
+		return SeriesResult{}, err
+	}
+	now := time.Now()
+	if len(dates) == 0 || !dates[0].After(now) {
+		return SeriesResult{}, Invalid("Series must start in the future")
+	}
+
+	template := models.Appointment{
+		CustomerEmail: customerEmail,
+		CustomerName:  req.CustomerName,
+		Services:      req.Services,
+	}
+	quote, err := bookingQuote(ctx, s.catalog, provider, req.ServiceIDs, req.Services)
+	if err != nil {
+		return SeriesResult{}, err
+	}
+	if len(quote.ServiceIDs) > 0 {
+		template.Services = quote.Names
+		template.ServiceIDs = quote.ServiceIDs
+		template.Price = quote.Price
+		template.Currency = quote.Currency
+		template.DurationMinutes = quote.DurationMinutes
+		template.BufferMinutes = quote.BufferMinutes
+	}
+
+	series := models.AppointmentSeries{
+		ID:            primitive.NewObjectID(),
+		CustomerEmail: customerEmail,
+		CustomerName:  req.CustomerName,
+		ProviderEmail: provider.Email,
+		CompanyID:     provider.CompanyId,
+		Rule:          rule.String(),
+		Start:         dates[0].UTC(),
+		TimeZone:      zone,
+		Services:      template.Services,
+		ServiceIDs:    template.ServiceIDs,
+		Status:        models.SeriesActive,
+		CreatedBy:     actor.Email,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	result := SeriesResult{Series: series, Appointments: []models.Appointment{}, Conflicts: []SeriesConflict{}}
+	for i, start := range dates {
+		appointment := template
+		appointment.SeriesID = series.ID
+		appointment.Occurrence = i + 1
+		booked, err := s.bookOccurrence(ctx, provider, start, appointment, models.StatusChange{
+			From:       models.StatusOpen,
+			To:         models.StatusRequested,
+			At:         now,
+			ActorID:    actor.ID,
+			ActorEmail: actor.Email,
+			ActorRole:  actor.Role,
+			Reason:     "series " + series.ID.Hex(),
+		})
+		if KindOf(err) != 0 {
+			result.Conflicts = append(result.Conflicts, SeriesConflict{Occurrence: i + 1, Start: start, Reason: err.Error()})
+			continue
+		}
+		if err != nil {
+			s.releaseSeries(ctx, actor, result.Appointments)
+			return SeriesResult{}, err
+		}
+		result.Appointments = append(result.Appointments, booked)
+	}
+
+	if len(result.Appointments) == 0 {
+		return SeriesResult{}, ConflictWith("None of the occurrences are available", result.Conflicts)
+	}
+	if req.AllOrNothing && len(result.Conflicts) > 0 {
+		s.releaseSeries(ctx, actor, result.Appointments)
+		return SeriesResult{}, ConflictWith("Some occurrences are not available, nothing was booked", result.Conflicts)
+	}
+	if err := s.series.Create(ctx, series); err != nil {
+		s.releaseSeries(ctx, actor, result.Appointments)
+		return SeriesResult{}, err
+	}
+	return result, nil
+}
+
+// bookOccurrence start anında başlayan boş slot kaydı varsa onu Book ile
+// aynı koşulla alır, yoksa çalışma saatlerinden hesaplanan slotu alır.
+// appointment şablonundaki müşteri, hizmet ve seri alanları yazılır.
+func (s *AppointmentService) bookOccurrence(ctx context.Context, provider models.Provider, start time.Time, appointment models.Appointment, change models.StatusChange) (models.Appointment, error) {
+	open, err := s.appointments.Find(ctx, repositories.AppointmentFilter{
+		ProviderEmail: provider.Email,
+		From:          start,
+		To:            start.Add(time.Minute),
+		Status:        models.StatusOpen,
+	})
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	for _, slot := range open {
+		if fromWorkingHours(slot) || !startsAt(slot).Equal(start) {
+			continue
+		}
+		fields := repositories.Fields{
+			"customer_name":  appointment.CustomerName,
+			"customer_email": appointment.CustomerEmail,
+			"services":       appointment.Services,
+			"series_id":      appointment.SeriesID,
+			"occurrence":     appointment.Occurrence,
+			"updated_at":     change.At,
+		}
+		if len(appointment.ServiceIDs) > 0 {
+			fields["service_ids"] = appointment.ServiceIDs
+			fields["price"] = appointment.Price
+			fields["currency"] = appointment.Currency
+			fields["duration_minutes"] = appointment.DurationMinutes
+			fields["buffer_minutes"] = appointment.BufferMinutes
+		}
+		cond := repositories.AppointmentCondition{Version: &slot.Version, Statuses: []string{models.StatusOpen}}
+		return s.claimSlot(ctx, slot, appointment.DurationMinutes+appointment.BufferMinutes, cond, fields, change)
+	}
+	return s.bookComputed(ctx, provider, start, appointment, change)
+}
+
+// releaseSeries başarısız bir seri rezervasyonunda alınan randevuları geri bırakır
+func (s *AppointmentService) releaseSeries(ctx context.Context, actor Actor, booked []models.Appointment) {
+	for _, appointment := range booked {
+		s.releaseBooking(ctx, actor, appointment, "series rolled back")
+	}
+}
+
+// authorizeSeries seriyi bulur. Müşteriler yalnızca kendi serilerini
+// görebilir, başkasınınki NotFound döner; diğer roller serinin
+// sağlayıcısına erişebilmelidir.
+func (s *AppointmentService) authorizeSeries(ctx context.Context, actor Actor, id primitive.ObjectID) (models.AppointmentSeries, error) {
+	series, err := s.series.FindByID(ctx, id)
+	if err == repositories.ErrNotFound {
+		return models.AppointmentSeries{}, NotFound("Series not found")
+	}
+	if err != nil {
+		return models.AppointmentSeries{}, err
+	}
+	if actor.Role == utils.RoleUser {
+		if actor.Email == "" || series.CustomerEmail != actor.Email {
+			return models.AppointmentSeries{}, NotFound("Series not found")
+		}
+		return series, nil
+	}
+	if _, err := providerByEmail(ctx, s.providers, actor, series.ProviderEmail); err != nil {
+		return models.AppointmentSeries{}, err
+	}
+	return series, nil
+}
+
+// seriesAppointments serinin müşterisine ait randevularını sırasıyla döner.
+// Seriden tekrar açılıp başkasına verilmiş slotlar dahil edilmez.
+func (s *AppointmentService) seriesAppointments(ctx context.Context, series models.AppointmentSeries) ([]models.Appointment, error) {
+	found, err := s.appointments.Find(ctx, repositories.AppointmentFilter{SeriesID: series.ID})
+	if err != nil {
+		return nil, err
+	}
+	out := []models.Appointment{}
+	for _, a := range found {
+		if a.CustomerEmail == series.CustomerEmail {
+			out = append(out, a)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Occurrence < out[j].Occurrence })
+	return out, nil
+}
+
+// Series seriyi ve randevularını döner
+func (s *AppointmentService) Series(ctx context.Context, actor Actor, id primitive.ObjectID) (SeriesResult, error) {
+	series, err := s.authorizeSeries(ctx, actor, id)
+	if err != nil {
+		return SeriesResult{}, err
+	}
+	appointments, err := s.seriesAppointments(ctx, series)
+	if err != nil {
+		return SeriesResult{}, err
+	}
+	return SeriesResult{Series: series, Appointments: appointments, Conflicts: []SeriesConflict{}}, nil
+}
+
+// CancelSeries serinin bir randevusunu, verilen randevu ve sonrakileri ya
+// da tümünü iptal eder. Müşteri iptalleri CancelByCustomer kurallarına
+// tabidir; sağlayıcı ve yöneticiler randevuyu doğrudan iptal eder. Toplu
+// iptalde geçmiş ve sonuçlanmış randevulara dokunulmaz, iptal edilemeyenler
+// Skipped'te döner. following ve all serinin yeni randevu üretmediğini
+// de kaydeder.
+func (s *AppointmentService) CancelSeries(ctx context.Context, actor Actor, id primitive.ObjectID, req SeriesCancel) (SeriesCancelResult, error) {
+	series, err := s.authorizeSeries(ctx, actor, id)
+	if err != nil {
+		return SeriesCancelResult{}, err
+	}
+	members, err := s.seriesAppointments(ctx, series)
+	if err != nil {
+		return SeriesCancelResult{}, err
+	}
+
+	var from *models.Appointment
+	if req.Scope == SeriesScopeOne || req.Scope == SeriesScopeFollowing {
+		for i := range members {
+			if members[i].ID == req.AppointmentID {
+				from = &members[i]
+				break
+			}
+		}
+		if from == nil {
+			return SeriesCancelResult{}, NotFound("Appointment not found in this series")
+		}
+	}
+
+	var targets []models.Appointment
+	switch req.Scope {
+	case SeriesScopeOne:
+		targets = []models.Appointment{*from}
+	case SeriesScopeFollowing:
+		for _, a := range members {
+			if a.Occurrence >= from.Occurrence {
+				targets = append(targets, a)
+			}
+		}
+	case SeriesScopeAll:
+		targets = members
+	default:
+		return SeriesCancelResult{}, Invalid("Scope must be one, following or all")
+	}
+
+	now := time.Now()
+	result := SeriesCancelResult{Cancelled: []models.Appointment{}, Skipped: []SeriesConflict{}}
+	for _, a := range targets {
+		if req.Scope != SeriesScopeOne && (!contains(conflictingStatuses, a.Status) || !startsAt(a).After(now)) {
+			continue
+		}
+		cancelled, err := s.cancelOccurrence(ctx, actor, a, req.Reason)
+		if err != nil && (req.Scope == SeriesScopeOne || KindOf(err) == 0) {
+			return SeriesCancelResult{}, err
+		}
+		if err != nil {
+			result.Skipped = append(result.Skipped, SeriesConflict{Occurrence: a.Occurrence, Start: startsAt(a), Reason: err.Error()})
+			continue
+		}
+		result.Cancelled = append(result.Cancelled, cancelled)
+	}
+
+	fields := repositories.Fields{"updated_at": now}
+	switch {
+	case req.Scope == SeriesScopeAll || (req.Scope == SeriesScopeFollowing && from.Occurrence == 1):
+		fields["status"] = models.SeriesCancelled
+	case req.Scope == SeriesScopeFollowing:
+		fields["ends_before"] = startsAt(*from)
+	}
+	if len(fields) > 1 {
+		if err := s.series.UpdateByID(ctx, series.ID, fields); err != nil {
+			return SeriesCancelResult{}, err
+		}
+		if series, err = s.series.FindByID(ctx, series.ID); err != nil {
+			return SeriesCancelResult{}, err
+		}
+	}
+	result.Series = series
+	return result, nil
+}
+
+// cancelOccurrence serinin tek bir randevusunu çağıranın rolüne göre iptal eder
+func (s *AppointmentService) cancelOccurrence(ctx context.Context, actor Actor, appointment models.Appointment, reason string) (models.Appointment, error) {
+	if actor.Role == utils.RoleUser {
+		return s.cancelOwn(ctx, actor, appointment, reason, nil)
+	}
+	if reason == "" {
+		reason = "series cancelled"
+	}
+	return s.move(ctx, actor, appointment, models.StatusCancelled, reason, nil, repositories.Fields{})
+}
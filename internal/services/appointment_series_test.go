@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+	"rtsback/pkg/utils"
+)
+
+func TestRecurrenceRule(t *testing.T) {
+	istanbul := mustZone(t, "Europe/Istanbul")
+	rule, err := RecurrenceRule("biweekly", 3, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := parseRule(rule, istanbul)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.String() != rule {
+		t.Fatalf("rule %q written back as %q", rule, r.String())
+	}
+	dates, err := r.dates(time.Date(2026, 3, 20, 10, 0, 0, 0, istanbul), istanbul)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dates) != 3 || !dates[2].Equal(time.Date(2026, 4, 17, 10, 0, 0, 0, istanbul)) {
+		t.Fatalf("biweekly dates %v", dates)
+	}
+
+	// Aylık seride 31'i olmayan aylar atlanır; UNTIL o günün sonuna kadardır
+	rule, err = RecurrenceRule("monthly", 0, "2026-07-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = parseRule(rule, istanbul)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dates, err = r.dates(time.Date(2026, 1, 31, 10, 0, 0, 0, istanbul), istanbul)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var days []string
+	for _, d := range dates {
+		days = append(days, d.Format("01-02"))
+	}
+	if got := strings.Join(days, " "); got != "01-31 03-31 05-31 07-31" {
+		t.Fatalf("monthly dates %s", got)
+	}
+
+	if _, err := RecurrenceRule("weekly", 2, "2026-07-31"); KindOf(err) != KindValidation {
+		t.Fatalf("count and until together: %v", err)
+	}
+	if _, err := parseRule("FREQ=WEEKLY;COUNT=100", istanbul); KindOf(err) != KindValidation {
+		t.Fatalf("too many occurrences: %v", err)
+	}
+}
+
+func TestBookSeriesPartialAndAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	customer := Actor{ID: "c1", Email: "c@example.com", Role: utils.RoleUser}
+	for _, allOrNothing := range []bool{false, true} {
+		t.Run(fmt.Sprintf("allOrNothing=%v", allOrNothing), func(t *testing.T) {
+			svc, repos := newTestServices(t)
+			provider := addProvider(t, svc, repos, "p@example.com")
+			start := testDay().Add(9 * time.Hour)
+			// İkinci haftanın slotu başka bir müşteride
+			taken := start.AddDate(0, 0, 7)
+			if _, err := svc.Appointments.BookSlot(ctx, SlotBooking{ProviderEmail: provider.Email, Start: taken, CustomerName: "O", CustomerEmail: "o@example.com"}); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := svc.Appointments.BookSeries(ctx, customer, SeriesBooking{
+				ProviderEmail: provider.Email,
+				Start:         start,
+				Rule:          "FREQ=WEEKLY;COUNT=3",
+				CustomerName:  "C",
+				AllOrNothing:  allOrNothing,
+			})
+			booked, findErr := repos.Appointments.Find(ctx, repositories.AppointmentFilter{CustomerEmail: customer.Email, Status: models.StatusRequested})
+			if findErr != nil {
+				t.Fatal(findErr)
+			}
+
+			if allOrNothing {
+				wantKind(t, err, KindConflict)
+				var e *Error
+				if !errors.As(err, &e) {
+					t.Fatalf("got %T, want *Error", err)
+				}
+				if conflicts, _ := e.Current.([]SeriesConflict); len(conflicts) != 1 || conflicts[0].Occurrence != 2 {
+					t.Fatalf("conflicts = %+v, want the second occurrence", e.Current)
+				}
+				if len(booked) != 0 {
+					t.Fatalf("%d occurrences kept after rollback, want none", len(booked))
+				}
+				// Geri bırakılan slotlar yeniden alınabilir
+				if _, err := svc.Appointments.BookSlot(ctx, SlotBooking{ProviderEmail: provider.Email, Start: start, CustomerName: "D", CustomerEmail: "d@example.com"}); err != nil {
+					t.Fatalf("released slot: %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Appointments) != 2 || len(result.Conflicts) != 1 || result.Conflicts[0].Occurrence != 2 || !result.Conflicts[0].Start.Equal(taken) {
+				t.Fatalf("booked %d, conflicts %+v; want 2 booked and the second occurrence in conflict", len(result.Appointments), result.Conflicts)
+			}
+			if len(booked) != 2 {
+				t.Fatalf("%d occurrences stored, want 2", len(booked))
+			}
+			for _, a := range booked {
+				if a.SeriesID != result.Series.ID || a.Occurrence == 2 {
+					t.Errorf("occurrence %d: series %s, want %s", a.Occurrence, a.SeriesID.Hex(), result.Series.ID.Hex())
+				}
+			}
+			if _, err := svc.Appointments.Series(ctx, customer, result.Series.ID); err != nil {
+				t.Fatalf("series not stored: %v", err)
+			}
+		})
+	}
+}
+
+func TestBookSeriesNothingAvailable(t *testing.T) {
+	ctx := context.Background()
+	svc, repos := newTestServices(t)
+	provider := addProvider(t, svc, repos, "p@example.com")
+	// Çalışma saatleri dışında hiçbir tekrar alınamaz
+	_, err := svc.Appointments.BookSeries(ctx, Actor{ID: "c1", Email: "c@example.com", Role: utils.RoleUser}, SeriesBooking{
+		ProviderEmail: provider.Email,
+		Start:         testDay().Add(20 * time.Hour),
+		Rule:          "FREQ=WEEKLY;COUNT=2",
+	})
+	wantKind(t, err, KindConflict)
+}
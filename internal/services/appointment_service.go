@@ -1 +1,493 @@
-package services
\ No newline at end of file
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AppointmentService randevu slotlarının üretilmesi, rezervasyonu ve
+// yönetimi kurallarıdır
+type AppointmentService struct {
+	appointments repositories.AppointmentRepository
+	providers    repositories.ProviderRepository
+	companies    repositories.CompanyRepository
+	workingHours repositories.WorkingHoursRepository
+	catalog      repositories.ServiceRepository
+	timeOff      repositories.TimeOffRepository
+	closures     repositories.ClosureRepository
+	waitlist     repositories.WaitlistRepository
+	series       repositories.SeriesRepository
+	resources    repositories.ResourceRepository
+	reservations repositories.ReservationRepository
+	// feeds iCalendar abonelikleridir; feedURL bağlantıların adresidir
+	feeds   repositories.CalendarFeedRepository
+	feedURL string
+	mailer  Mailer
+	// busySources sağlayıcıların dış takvimleri, busyBlocks onlardan alınan
+	// dolu aralıklardır; importDir takvim dosyalarının okunabileceği dizindir
+	busySources repositories.BusySourceRepository
+	busyBlocks  repositories.BusyBlockRepository
+	importDir   string
+	// defaultZone saat dilimi ayarlanmamış şirketlerin dilimidir
+	defaultZone string
+	// offerTTL bekleme listesi tekliflerinin geçerlilik süresi, claimURL
+	// teklif e-postasındaki bağlantının adresidir
+	offerTTL time.Duration
+	claimURL string
+}
+
+// AppointmentUpdate bir randevunun müşteri alanlarında yapılacak
+// değişikliktir. Version verilirse kayıt o sürümde olmalıdır.
+type AppointmentUpdate struct {
+	CustomerName  string
+	CustomerEmail string
+	Services      []string
+	ServiceIDs    []primitive.ObjectID
+	Activate      *bool
+	Version       *int64
+}
+
+// Booking müşterinin boş bir slotu almak için gönderdiği bilgilerdir.
+// ServiceIDs verilirse hizmet adları, fiyat ve süre katalogdan hesaplanır;
+// Services yalnızca katalog kullanmayan eski istemciler içindir. Version
+// verilirse slot müşterinin gördüğü sürümde olmalıdır.
+type Booking struct {
+	CustomerName  string
+	CustomerEmail string
+	Services      []string
+	ServiceIDs    []primitive.ObjectID
+	Version       *int64
+}
+
+// Koşullu güncellemeler başarısız olduğunda dönen çakışma mesajları
+const (
+	msgSlotTaken = "Slot is already booked"
+	msgStale     = "Appointment was modified by another request, reload and retry"
+)
+
+// dayRange day'in takvim gününün loc'taki başlangıcını ve ertesi günün
+// başlangıcını döner. Yaz saati geçişlerinde gün 23 ya da 25 saat olabilir.
+func dayRange(day time.Time, loc *time.Location) (time.Time, time.Time) {
+	start := truncateDay(day, loc)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// authorize randevuyu bulur ve kimliğin ona erişimi olup olmadığını
+// kontrol eder. Eski kayıtlarda company_id boş olabildiği için
+// admin/manager kontrolü gerekirse randevunun sağlayıcısı üzerinden yapılır.
+func (s *AppointmentService) authorize(ctx context.Context, actor Actor, id primitive.ObjectID) (models.Appointment, error) {
+	appointment, err := s.appointments.FindByID(ctx, id)
+	if err == repositories.ErrNotFound {
+		return models.Appointment{}, NotFound("Appointment not found")
+	}
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	allowed := false
+	switch actor.Role {
+	case utils.RoleSuperUser:
+		allowed = true
+	case utils.RoleProvider:
+		allowed = actor.Email != "" && appointment.ProviderEmail == actor.Email
+	case utils.RoleAdmin, utils.RoleManager:
+		companyID := appointment.CompanyID
+		if companyID == "" {
+			if provider, err := s.providers.FindByEmail(ctx, appointment.ProviderEmail); err == nil {
+				companyID = provider.CompanyId
+			}
+		}
+		allowed = actor.CanAccessCompany(companyID)
+	}
+
+	if !allowed {
+		return models.Appointment{}, ErrForbidden
+	}
+	return appointment, nil
+}
+
+// ListForCustomer müşterinin aldığı randevuları ve katıldığı grup
+// seanslarını döner. Grup seanslarında yalnızca müşterinin kendi katılımcı
+// kayıtları gösterilir.
+func (s *AppointmentService) ListForCustomer(ctx context.Context, email string) ([]models.Appointment, error) {
+	appointments, err := s.appointments.Find(ctx, repositories.AppointmentFilter{CustomerEmail: email})
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.appointments.FindByParticipant(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		appointments = append(appointments, ownSeat(session, email))
+	}
+	return appointments, nil
+}
+
+// ListForProvider sağlayıcının tüm randevularını döner
+func (s *AppointmentService) ListForProvider(ctx context.Context, actor Actor, providerEmail string) ([]models.Appointment, error) {
+	if _, err := providerByEmail(ctx, s.providers, actor, providerEmail); err != nil {
+		return nil, err
+	}
+	return s.appointments.Find(ctx, repositories.AppointmentFilter{ProviderEmail: providerEmail})
+}
+
+// ProviderDay sağlayıcının verilen takvim günündeki randevularını döner.
+// Gün şirketin saat diliminde hesaplanır; day'in yalnızca tarihi önemlidir.
+func (s *AppointmentService) ProviderDay(ctx context.Context, actor Actor, providerEmail string, day time.Time) ([]models.Appointment, error) {
+	provider, err := providerByEmail(ctx, s.providers, actor, providerEmail)
+	if err != nil {
+		return nil, err
+	}
+	_, loc, err := s.providerLocation(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := dayRange(day, loc)
+	return s.appointments.Find(ctx, repositories.AppointmentFilter{
+		ProviderEmail: providerEmail,
+		From:          from,
+		To:            to,
+	})
+}
+
+// AvailableSlots sağlayıcının verilen takvim günündeki henüz alınmamış
+// slotlarını döner; sağlayıcının izinli olduğu, dış takviminde dolu
+// göründüğü ve şirketin kapalı olduğu saatlerdeki slotlar gösterilmez. Müşterilerin randevu seçebilmesi için
+// yetki gerektirmez. Çalışma saatlerinden alınmış rezervasyonlarla
+// çakışan slotlar da gösterilmez.
+func (s *AppointmentService) AvailableSlots(ctx context.Context, providerEmail string, day time.Time) ([]models.Appointment, error) {
+	provider, err := s.providers.FindByEmail(ctx, providerEmail)
+	if err == repositories.ErrNotFound {
+		return nil, NotFound("Provider not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, loc, err := s.providerLocation(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := dayRange(day, loc)
+	open, err := s.appointments.Find(ctx, repositories.AppointmentFilter{
+		ProviderEmail: providerEmail,
+		From:          from,
+		To:            to,
+		Status:        models.StatusOpen,
+	})
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := s.unavailable(ctx, provider, from, to, loc)
+	if err != nil {
+		return nil, err
+	}
+	busy, err := s.busyTimes(ctx, provider, from, to)
+	if err != nil {
+		return nil, err
+	}
+	blocked = append(blocked, busy...)
+	taken, err := s.bookedTimes(ctx, provider, from, to, primitive.NilObjectID)
+	if err != nil {
+		return nil, err
+	}
+	blocked = append(blocked, taken...)
+
+	slots := []models.Appointment{}
+	for _, a := range open {
+		if !clashes(blocked, Slot{Start: startsAt(a), End: endsAt(a)}) {
+			slots = append(slots, a)
+		}
+	}
+	return slots, nil
+}
+
+// Create randevuyu kaydeder. Durum verilmemişse slot boş (open) başlar.
+// Saat dilimi şirketten alınır; yalnızca saat taşıyan başlangıç ve bitiş
+// Date'in takvim günüyle o dilimde birleştirilir ve date başlangıç anı olur.
+// Boş slotlar bekleme listesinde sırası gelen müşteriye teklif edilir.
+func (s *AppointmentService) Create(ctx context.Context, appointment models.Appointment) (models.Appointment, error) {
+	if appointment.Status == "" {
+		appointment.Status = models.StatusOpen
+	}
+	// Yeni randevu boş slot ya da sağlayıcının doğrudan yazdığı onaylı randevudur
+	if appointment.Status != models.StatusOpen && appointment.Status != models.StatusConfirmed {
+		return models.Appointment{}, Invalid("New appointments must be open or confirmed")
+	}
+
+	if appointment.TimeZone == "" {
+		zone, err := s.zoneFor(ctx, appointment.CompanyID, appointment.ProviderEmail)
+		if err != nil {
+			return models.Appointment{}, err
+		}
+		appointment.TimeZone = zone
+	}
+	loc := appointment.Location()
+	if clockOnly(appointment.StartTime) {
+		appointment.StartTime = atClock(appointment.Date, appointment.StartTime, loc)
+	}
+	if clockOnly(appointment.EndTime) {
+		appointment.EndTime = atClock(appointment.Date, appointment.EndTime, loc)
+	}
+	if !appointment.StartTime.IsZero() {
+		appointment.Date = appointment.StartTime
+		if !appointment.EndTime.After(appointment.StartTime) {
+			return models.Appointment{}, Invalid("End time must be after start time")
+		}
+	}
+
+	if appointment.ID.IsZero() {
+		appointment.ID = primitive.NewObjectID()
+	}
+	appointment.CreatedAt = time.Now()
+	appointment.UpdatedAt = time.Now()
+
+	if err := s.appointments.Create(ctx, appointment); err != nil {
+		return models.Appointment{}, err
+	}
+	if appointment.Status == models.StatusOpen {
+		go s.offerOpenings([]models.Appointment{appointment})
+	}
+	return appointment, nil
+}
+
+// AddForProvider sağlayıcının takvimine tek bir randevu ekler. Randevunun
+// şirketi her zaman sağlayıcının kaydından alınır.
+func (s *AppointmentService) AddForProvider(ctx context.Context, actor Actor, appointment models.Appointment) (models.Appointment, error) {
+	provider, err := providerByEmail(ctx, s.providers, actor, appointment.ProviderEmail)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	appointment.CompanyID = provider.CompanyId
+	return s.Create(ctx, appointment)
+}
+
+// updateIf koşullu güncellemeyi yapar ve güncel kaydı döner. change
+// verilmişse güncelleme bir durum geçişidir ve geçmişe yazılır. Koşul
+// sağlanmazsa kaydın güncel haliyle Conflict döner; kayıt yoksa NotFound.
+// redact true ise çakışmada başka müşterinin bilgileri gizlenir.
+func (s *AppointmentService) updateIf(ctx context.Context, id primitive.ObjectID, cond repositories.AppointmentCondition, fields repositories.Fields, change *models.StatusChange, redact bool) (models.Appointment, error) {
+	var updated bool
+	var err error
+	if change != nil {
+		updated, err = s.appointments.Transition(ctx, id, cond, fields, *change)
+	} else {
+		updated, err = s.appointments.UpdateIf(ctx, id, cond, fields)
+	}
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	current, err := s.appointments.FindByID(ctx, id)
+	if err == repositories.ErrNotFound {
+		return models.Appointment{}, NotFound("Appointment not found")
+	}
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if updated {
+		return current, nil
+	}
+
+	message := msgStale
+	if len(cond.Statuses) > 0 && !contains(cond.Statuses, current.Status) {
+		message = "Appointment is " + current.Status
+		if change != nil && change.To == models.StatusRequested {
+			message = msgSlotTaken
+		}
+	}
+	if redact {
+		current.CustomerName = ""
+		current.CustomerEmail = ""
+		current.Services = nil
+		current.Notes = ""
+		current.History = nil
+		current.Participants = nil
+	}
+	return models.Appointment{}, ConflictWith(message, current)
+}
+
+// versionOr istemcinin gönderdiği sürümü, yoksa okunan kaydın sürümünü döner
+func versionOr(version *int64, appointment models.Appointment) *int64 {
+	if version != nil {
+		return version
+	}
+	return &appointment.Version
+}
+
+// Reschedule randevunun başlangıç ve bitiş saatini aynı gün içinde
+// değiştirir; start ve end'in yalnızca saati kullanılır ve randevunun saat
+// diliminde yorumlanır. Yalnızca henüz sonuçlanmamış randevular taşınabilir.
+// Yeni saatler izin, kapalı gün, dış takvim ya da başka bir randevuyla
+// çakışıyorsa Conflict döner; çalışma saatlerinden alınmış randevunun slot
+// anahtarları yeni saatlere göre yeniden hesaplanır ve ayrılmış kaynaklar da
+// taşınır. Onaylı randevunun müşterisine güncel takvim dosyası gönderilir.
+// version nil ise okunan sürüm kullanılır; arada yapılan değişiklikler yine
+// Conflict döner.
+func (s *AppointmentService) Reschedule(ctx context.Context, actor Actor, id primitive.ObjectID, start, end time.Time, version *int64) (models.Appointment, error) {
+	appointment, err := s.authorize(ctx, actor, id)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	loc := appointment.Location()
+	day := startsAt(appointment).In(loc)
+	start = atClock(day, start, loc)
+	end = atClock(day, end, loc)
+	if !end.After(start) {
+		return models.Appointment{}, Invalid("End time must be after start time")
+	}
+	if err := s.checkAvailable(ctx, appointment.ProviderEmail, start, end, appointment.ID); err != nil {
+		return models.Appointment{}, err
+	}
+
+	// Yalnızca saatler değişir; rezervasyon durumu ve müşteri korunur
+	cond := repositories.AppointmentCondition{
+		Version:  versionOr(version, appointment),
+		Statuses: []string{models.StatusOpen, models.StatusRequested, models.StatusConfirmed},
+	}
+	fields := repositories.Fields{
+		"date":       start,
+		"start_time": start,
+		"end_time":   end,
+		"updated_at": time.Now(),
+	}
+	// Slotu tutan randevu yeni saatlerin anahtarlarını alır; aynı anda o
+	// saatlere yapılan bir rezervasyonu tekil indeks yakalar
+	if fromWorkingHours(appointment) {
+		keys, err := s.slotKeys(ctx, appointment.ProviderEmail, start, end)
+		if err != nil {
+			return models.Appointment{}, err
+		}
+		fields["slot_key"] = keys[0]
+		fields["slot_keys"] = keys
+	}
+	// Ayrılmış kaynaklar yeni saatlere taşınır; taşınamazsa randevu da taşınmaz
+	undo := func() {}
+	if len(appointment.ResourceIDs) > 0 && contains(conflictingStatuses, appointment.Status) {
+		resourceIDs, restore, err := s.moveReservations(ctx, appointment, start, end)
+		if err != nil {
+			return models.Appointment{}, err
+		}
+		fields["resource_ids"] = resourceIDs
+		undo = restore
+	}
+	moved, err := s.updateIf(ctx, id, cond, fields, nil, false)
+	if err == repositories.ErrDuplicate {
+		err = Conflict(msgSlotTaken)
+	}
+	if err != nil {
+		undo()
+		return models.Appointment{}, err
+	}
+	if moved.Status == models.StatusConfirmed && moved.Capacity == 0 {
+		go s.notifyCustomer(moved, "Appointment rescheduled",
+			fmt.Sprintf("Your appointment with %s has been moved to %s. Open the attached file to update your calendar.",
+				firstNonEmpty(moved.ProviderName, moved.ProviderEmail), localClock(moved, startsAt(moved))), icsRequest)
+	}
+	return moved, nil
+}
+
+// Delete randevuyu siler ve kaynak ayırmalarını bırakır
+func (s *AppointmentService) Delete(ctx context.Context, actor Actor, id primitive.ObjectID) error {
+	appointment, err := s.authorize(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+	if err := s.appointments.DeleteByID(ctx, id); err != nil {
+		return err
+	}
+	if len(appointment.ResourceIDs) > 0 {
+		s.releaseResources(ctx, id)
+	}
+	return nil
+}
+
+// Update randevunun müşteri alanlarını günceller. Bu uç nokta yalnızca
+// rezervasyon içindir; slotu boşaltmak iptal geçişiyle yapılır.
+func (s *AppointmentService) Update(ctx context.Context, id primitive.ObjectID, update AppointmentUpdate) (models.Appointment, error) {
+	if update.Activate != nil && !*update.Activate {
+		return models.Appointment{}, Invalid("Use the cancel endpoint to release a booking")
+	}
+	return s.Book(ctx, id, Booking{
+		CustomerName:  update.CustomerName,
+		CustomerEmail: update.CustomerEmail,
+		Services:      update.Services,
+		ServiceIDs:    update.ServiceIDs,
+		Version:       update.Version,
+	})
+}
+
+// Book müşteriyi slota yazar ve randevuyu requested durumuna geçirir.
+// Kontrol ve yazma tek işlemdir: aynı slotu aynı anda alan iki müşteriden
+// yalnızca biri başarılı olur, diğeri slotun güncel haliyle Conflict alır.
+// Seçilen hizmetlerin süresi ve tamponu slottan uzunsa sağlayıcının
+// ardışık boş slotları da alınır; sağlayıcının sunmadığı hizmetler reddedilir.
+func (s *AppointmentService) Book(ctx context.Context, id primitive.ObjectID, booking Booking) (models.Appointment, error) {
+	slot, err := s.appointments.FindByID(ctx, id)
+	if err == repositories.ErrNotFound {
+		return models.Appointment{}, NotFound("Appointment not found")
+	}
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	quote, err := s.quote(ctx, slot.ProviderEmail, booking.ServiceIDs, booking.Services)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	now := time.Now()
+	fields := repositories.Fields{
+		"customer_name":  booking.CustomerName,
+		"customer_email": booking.CustomerEmail,
+		"services":       booking.Services,
+		"updated_at":     now,
+	}
+	if len(quote.ServiceIDs) > 0 {
+		setQuote(fields, quote)
+	}
+
+	cond := repositories.AppointmentCondition{Version: booking.Version, Statuses: []string{models.StatusOpen}}
+	change := models.StatusChange{
+		From:       models.StatusOpen,
+		To:         models.StatusRequested,
+		At:         now,
+		ActorEmail: booking.CustomerEmail,
+	}
+	return s.claimSlot(ctx, slot, quote.Minutes(), cond, fields, change)
+}
+
+// quote sağlayıcının verilen hizmetler için fiyat ve süresini hesaplar
+func (s *AppointmentService) quote(ctx context.Context, providerEmail string, ids []primitive.ObjectID, names []string) (Quote, error) {
+	if len(ids) == 0 && len(names) == 0 {
+		return Quote{}, nil
+	}
+	provider, err := s.providers.FindByEmail(ctx, providerEmail)
+	if err == repositories.ErrNotFound {
+		return Quote{}, NotFound("Provider not found")
+	}
+	if err != nil {
+		return Quote{}, err
+	}
+	return bookingQuote(ctx, s.catalog, provider, ids, names)
+}
+
+// setQuote hesaplanan hizmetleri randevu alanlarına yazar
+func setQuote(fields repositories.Fields, quote Quote) {
+	fields["services"] = quote.Names
+	fields["service_ids"] = quote.ServiceIDs
+	fields["price"] = quote.Price
+	fields["currency"] = quote.Currency
+	fields["duration_minutes"] = quote.DurationMinutes
+	fields["buffer_minutes"] = quote.BufferMinutes
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// addOpenSlots start'tan itibaren ardışık 30 dakikalık boş slotlar ekler
+func addOpenSlots(t *testing.T, svc *Services, provider string, start time.Time, n int) []models.Appointment {
+	t.Helper()
+	var slots []models.Appointment
+	for i := 0; i < n; i++ {
+		at := start.Add(time.Duration(i) * 30 * time.Minute)
+		slot, err := svc.Appointments.Create(context.Background(), models.Appointment{
+			ProviderEmail: provider,
+			StartTime:     at,
+			EndTime:       at.Add(30 * time.Minute),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		slots = append(slots, slot)
+	}
+	return slots
+}
+
+func TestBookIsAtomic(t *testing.T) {
+	svc, repos := newTestServices(t)
+	ctx := context.Background()
+	provider := addProvider(t, svc, repos, "p@example.com")
+	slot := addOpenSlots(t, svc, provider.Email, testDay().Add(14*time.Hour), 1)[0]
+
+	var wins, conflicts int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := svc.Appointments.Book(ctx, slot.ID, Booking{CustomerName: "C", CustomerEmail: "c@example.com"})
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				wins++
+			case KindOf(err) == KindConflict:
+				conflicts++
+			default:
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if wins != 1 || conflicts != 7 {
+		t.Fatalf("%d bookings won and %d conflicted, want 1 and 7", wins, conflicts)
+	}
+}
+
+func TestBookChecksVersion(t *testing.T) {
+	svc, repos := newTestServices(t)
+	ctx := context.Background()
+	provider := addProvider(t, svc, repos, "p@example.com")
+	slot := addOpenSlots(t, svc, provider.Email, testDay().Add(14*time.Hour), 1)[0]
+
+	stale := slot.Version + 1
+	_, err := svc.Appointments.Book(ctx, slot.ID, Booking{CustomerName: "C", CustomerEmail: "c@example.com", Version: &stale})
+	wantKind(t, err, KindConflict)
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatal(err)
+	}
+	if current, ok := e.Current.(models.Appointment); !ok || current.ID != slot.ID {
+		t.Fatalf("conflict does not carry the current slot: %v", err)
+	}
+
+	current := slot.Version
+	booked, err := svc.Appointments.Book(ctx, slot.ID, Booking{CustomerName: "C", CustomerEmail: "c@example.com", Version: &current})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if booked.Status != models.StatusRequested || booked.Version != slot.Version+1 {
+		t.Fatalf("booked status %s version %d", booked.Status, booked.Version)
+	}
+}
+
+func TestBookConsumesConsecutiveSlots(t *testing.T) {
+	svc, repos := newTestServices(t)
+	ctx := context.Background()
+	provider := addProvider(t, svc, repos, "p@example.com")
+	service := models.Service{ID: primitive.NewObjectID(), Name: "Massage", DurationMinutes: 50, BufferMinutes: 10, Price: 100, Currency: "TRY", Active: true}
+	if err := repos.Services.Create(ctx, service); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Catalog.SetOffering(ctx, System(), provider.ID, models.Offering{ServiceID: service.ID}); err != nil {
+		t.Fatal(err)
+	}
+	start := testDay().Add(14 * time.Hour)
+	slots := addOpenSlots(t, svc, provider.Email, start, 3)
+
+	_, err := svc.Appointments.Book(ctx, slots[0].ID, Booking{CustomerName: "C", CustomerEmail: "c@example.com", ServiceIDs: []primitive.ObjectID{primitive.NewObjectID()}})
+	wantKind(t, err, KindValidation)
+
+	// Son slottan sonra boş süre yoktur
+	_, err = svc.Appointments.Book(ctx, slots[2].ID, Booking{CustomerName: "C", CustomerEmail: "c@example.com", ServiceIDs: []primitive.ObjectID{service.ID}})
+	wantKind(t, err, KindConflict)
+
+	booked, err := svc.Appointments.Book(ctx, slots[0].ID, Booking{CustomerName: "C", CustomerEmail: "c@example.com", ServiceIDs: []primitive.ObjectID{service.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !endsAt(booked).Equal(start.Add(time.Hour)) || len(booked.MergedSlots) != 1 || booked.Price != 100 {
+		t.Fatalf("booking ends %v with %d merged slots and price %d", endsAt(booked), len(booked.MergedSlots), booked.Price)
+	}
+	if _, err := repos.Appointments.FindByID(ctx, slots[1].ID); err != repositories.ErrNotFound {
+		t.Fatalf("merged slot still stored: %v", err)
+	}
+	if left, err := repos.Appointments.FindByID(ctx, slots[2].ID); err != nil || left.Status != models.StatusOpen {
+		t.Fatalf("following slot status %q err %v", left.Status, err)
+	}
+}
+
+func TestRescheduleChecksAvailability(t *testing.T) {
+	svc, repos := newTestServices(t)
+	ctx := context.Background()
+	provider := addProvider(t, svc, repos, "p@example.com")
+	day := testDay()
+	book := func(hour int, email string) models.Appointment {
+		t.Helper()
+		a, err := svc.Appointments.BookSlot(ctx, SlotBooking{ProviderEmail: provider.Email, Start: day.Add(time.Duration(hour) * time.Hour), CustomerName: "C", CustomerEmail: email})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	moved := book(9, "a@example.com")
+	book(10, "b@example.com")
+	if _, err := svc.Appointments.AddTimeOff(ctx, System(), provider.Email, day.Add(11*time.Hour+30*time.Minute), day.Add(12*time.Hour), "dentist"); err != nil {
+		t.Fatal(err)
+	}
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	// Başka bir randevu ve izinle çakışan saatlere taşınamaz
+	_, err := svc.Appointments.Reschedule(ctx, System(), moved.ID, at(10, 15), at(10, 45), nil)
+	wantKind(t, err, KindConflict)
+	_, err = svc.Appointments.Reschedule(ctx, System(), moved.ID, at(11, 30), at(12, 0), nil)
+	wantKind(t, err, KindConflict)
+
+	// Kendi saatleriyle çakışması engel değildir
+	moved, err = svc.Appointments.Reschedule(ctx, System(), moved.ID, at(9, 15), at(9, 45), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved, err = svc.Appointments.Reschedule(ctx, System(), moved.ID, at(11, 0), at(11, 30), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.SlotKey != slotKey(provider.Email, at(11, 0)) || len(moved.SlotKeys) != 1 {
+		t.Fatalf("slot keys after reschedule: %q %v", moved.SlotKey, moved.SlotKeys)
+	}
+
+	// Eski saat boşalır, yeni saat dolar
+	if _, err := svc.Appointments.BookSlot(ctx, SlotBooking{ProviderEmail: provider.Email, Start: at(9, 0), CustomerName: "D", CustomerEmail: "d@example.com"}); err != nil {
+		t.Fatalf("old slot not released: %v", err)
+	}
+	_, err = svc.Appointments.BookSlot(ctx, SlotBooking{ProviderEmail: provider.Email, Start: at(11, 0), CustomerName: "E", CustomerEmail: "e@example.com"})
+	wantKind(t, err, KindConflict)
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// transitions her durumdan izin verilen geçişlerdir. Tamamlanan ve gelmeyen
+// randevular son durumdur; iptal edilen slot yalnızca tekrar açılabilir.
+var transitions = map[string][]string{
+	models.StatusOpen:      {models.StatusRequested},
+	models.StatusRequested: {models.StatusConfirmed, models.StatusCancelled},
+	models.StatusConfirmed: {models.StatusCancelled, models.StatusCompleted, models.StatusNoShow},
+	models.StatusCancelled: {models.StatusOpen},
+}
+
+// CanTransition from durumundan to durumuna geçişe izin verilip verilmediğini döner
+func CanTransition(from, to string) bool {
+	return contains(transitions[from], to)
+}
+
+// Transition randevuyu verilen duruma geçirir ve geçişi kimlikle birlikte
+// geçmişe yazar. İzin verilmeyen geçişler ve eşzamanlı değişiklikler
+// randevunun güncel haliyle Conflict döner. Tekrar açılan slotun müşteri
+// bilgileri temizlenir; birden fazla slot kaplamışsa slotlar ayrıştırılır.
+// Tekrar açılan slotlar bekleme listesine teklif edilir. Onaylanan randevu
+// müşteriye takvim dosyasıyla bildirilir. İptal edilen grup seansının
+// katılımcılarına e-posta gönderilir; grup seansları tekrar açılamaz.
+func (s *AppointmentService) Transition(ctx context.Context, actor Actor, id primitive.ObjectID, to, reason string, version *int64) (models.Appointment, error) {
+	appointment, err := s.authorize(ctx, actor, id)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	fields := repositories.Fields{}
+	if to == models.StatusOpen && fromWorkingHours(appointment) {
+		return models.Appointment{}, ConflictWith("Bookings from working hours free their slot when cancelled and cannot be reopened", appointment)
+	}
+	if to == models.StatusOpen && appointment.Capacity > 0 {
+		return models.Appointment{}, ConflictWith("Group sessions cannot be reopened", appointment)
+	}
+	if to == models.StatusOpen {
+		fields["customer_name"] = ""
+		fields["customer_email"] = ""
+		fields["services"] = nil
+		fields["service_ids"] = nil
+		fields["price"] = int64(0)
+		fields["currency"] = ""
+		fields["duration_minutes"] = 0
+		fields["buffer_minutes"] = 0
+		fields["series_id"] = nil
+		fields["occurrence"] = 0
+		fields["resource_ids"] = nil
+		fields["calendar_uid"] = ""
+		if len(appointment.MergedSlots) > 0 {
+			fields["end_time"] = ownEnd(appointment)
+			fields["merged_slots"] = nil
+		}
+	}
+	moved, err := s.move(ctx, actor, appointment, to, reason, version, fields)
+	if err == nil && to == models.StatusOpen {
+		pieces := mergedPieces(appointment)
+		s.restoreSlots(ctx, pieces)
+		go s.offerOpenings(append([]models.Appointment{moved}, pieces...))
+	}
+	if err == nil && to == models.StatusConfirmed && moved.Capacity == 0 {
+		go s.notifyCustomer(moved, "Appointment confirmed",
+			fmt.Sprintf("Your appointment with %s on %s is confirmed. Open the attached file to add it to your calendar.",
+				firstNonEmpty(moved.ProviderName, moved.ProviderEmail), localClock(moved, startsAt(moved))), icsRequest)
+	}
+	if err == nil && to == models.StatusCancelled && moved.Capacity > 0 {
+		go s.notifyParticipants(moved, "Group session cancelled",
+			fmt.Sprintf("%s on %s has been cancelled.", sessionName(moved), localClock(moved, startsAt(moved))))
+	}
+	return moved, err
+}
+
+// move okunmuş randevuyu to durumuna geçirir; fields geçişle birlikte
+// yazılır. İptal edilen randevunun kaynak ayırmaları bırakılır; çalışma
+// saatlerinden alınmışsa boşalan zaman bekleme listesine teklif edilir.
+// Onaylı randevunun iptali müşteriye takvim dosyasıyla bildirilir.
+func (s *AppointmentService) move(ctx context.Context, actor Actor, appointment models.Appointment, to, reason string, version *int64, fields repositories.Fields) (models.Appointment, error) {
+	if !CanTransition(appointment.Status, to) {
+		return models.Appointment{}, ConflictWith("Cannot move appointment from "+appointment.Status+" to "+to, appointment)
+	}
+
+	now := time.Now()
+	fields["updated_at"] = now
+	if to == models.StatusCancelled {
+		// Çalışma saatlerinden alınan slot iptalle tekrar müsait olur
+		fields["slot_active"] = false
+	}
+
+	cond := repositories.AppointmentCondition{
+		Version:  versionOr(version, appointment),
+		Statuses: []string{appointment.Status},
+	}
+	change := models.StatusChange{
+		From:       appointment.Status,
+		To:         to,
+		At:         now,
+		ActorID:    actor.ID,
+		ActorEmail: actor.Email,
+		ActorRole:  actor.Role,
+		Reason:     reason,
+	}
+	moved, err := s.updateIf(ctx, appointment.ID, cond, fields, &change, false)
+	if err == nil && to == models.StatusCancelled && len(appointment.ResourceIDs) > 0 {
+		s.releaseResources(ctx, appointment.ID)
+	}
+	if err == nil && to == models.StatusCancelled && fromWorkingHours(appointment) {
+		go s.offerFreed(moved)
+	}
+	// Onaylı randevu müşterinin takviminden silinir; ertelemede yeni kayıt
+	// aynı etkinliği güncelleyeceği için iptal gönderilmez
+	if err == nil && to == models.StatusCancelled && appointment.Status == models.StatusConfirmed && fields["rescheduled_to"] == nil {
+		go s.notifyCustomer(moved, "Appointment cancelled",
+			fmt.Sprintf("Your appointment with %s on %s has been cancelled.",
+				firstNonEmpty(moved.ProviderName, moved.ProviderEmail), localClock(moved, startsAt(moved))), icsCancel)
+	}
+	return moved, err
+}
+
+// MigrateStatuses durum alanı olmayan eski randevulara activate değerine
+// göre durum atar: alınmış slotlar confirmed, diğerleri open olur
+func (s *AppointmentService) MigrateStatuses(ctx context.Context) (int64, error) {
+	return s.appointments.MigrateStatuses(ctx)
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rtsback/internal/models"
+)
+
+func TestCanTransition(t *testing.T) {
+	statuses := []string{
+		models.StatusOpen, models.StatusRequested, models.StatusConfirmed,
+		models.StatusCancelled, models.StatusCompleted, models.StatusNoShow,
+	}
+	allowed := map[[2]string]bool{
+		{models.StatusOpen, models.StatusRequested}:      true,
+		{models.StatusRequested, models.StatusConfirmed}: true,
+		{models.StatusRequested, models.StatusCancelled}: true,
+		{models.StatusConfirmed, models.StatusCancelled}: true,
+		{models.StatusConfirmed, models.StatusCompleted}: true,
+		{models.StatusConfirmed, models.StatusNoShow}:    true,
+		{models.StatusCancelled, models.StatusOpen}:      true,
+	}
+	for _, from := range statuses {
+		for _, to := range statuses {
+			if got := CanTransition(from, to); got != allowed[[2]string{from, to}] {
+				t.Errorf("CanTransition(%s, %s) = %v", from, to, got)
+			}
+		}
+	}
+	if CanTransition("", models.StatusOpen) || CanTransition(models.StatusOpen, "archived") {
+		t.Error("unknown statuses must not transition")
+	}
+}
+
+func TestTransitionRecordsHistory(t *testing.T) {
+	svc, repos := newTestServices(t)
+	ctx := context.Background()
+	provider := addProvider(t, svc, repos, "p@example.com")
+	slot := addOpenSlots(t, svc, provider.Email, testDay().Add(14*time.Hour), 1)[0]
+	if _, err := svc.Appointments.Book(ctx, slot.ID, Booking{CustomerName: "C", CustomerEmail: "c@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Onaylanmamış randevu tamamlanamaz ve kayıt değişmez
+	_, err := svc.Appointments.Transition(ctx, System(), slot.ID, models.StatusCompleted, "", nil)
+	wantKind(t, err, KindConflict)
+
+	for _, to := range []string{models.StatusConfirmed, models.StatusCancelled, models.StatusOpen} {
+		if _, err := svc.Appointments.Transition(ctx, System(), slot.ID, to, "test", nil); err != nil {
+			t.Fatalf("to %s: %v", to, err)
+		}
+	}
+	reopened, err := repos.Appointments.FindByID(ctx, slot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Status != models.StatusOpen || reopened.CustomerEmail != "" {
+		t.Fatalf("reopened slot has status %s and customer %q", reopened.Status, reopened.CustomerEmail)
+	}
+	var path []string
+	for _, change := range reopened.History {
+		path = append(path, change.From+">"+change.To)
+	}
+	want := []string{"open>requested", "requested>confirmed", "confirmed>cancelled", "cancelled>open"}
+	if len(path) != len(want) {
+		t.Fatalf("history %v, want %v", path, want)
+	}
+	for i := range want {
+		if path[i] != want[i] {
+			t.Fatalf("history %v, want %v", path, want)
+		}
+	}
+}
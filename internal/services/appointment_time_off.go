@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxTimeOffDays tek bir izin kaydının kapsayabileceği gün sayısıdır
+const maxTimeOffDays = 366
+
+// conflictingStatuses izin ya da kapalı günle çakıştığında müşteriye
+// haber verilmesi gereken randevu durumlarıdır
+var conflictingStatuses = []string{models.StatusRequested, models.StatusConfirmed}
+
+// TimeOffResult eklenen izin ve o aralıkta zaten alınmış randevulardır
+type TimeOffResult struct {
+	TimeOff   models.TimeOff
+	Conflicts []models.Appointment
+}
+
+// ClosureResult eklenen kapalı günler ve o günlerde zaten alınmış
+// randevulardır. Skipped zaten kapalı olan günlerin sayısıdır; Partial
+// tatil listesinin o yıl için eksik olduğunu gösterir.
+type ClosureResult struct {
+	Closures  []models.Closure
+	Skipped   int
+	Partial   bool
+	Conflicts []models.Appointment
+}
+
+// calendarDay t'nin loc'taki tarihini kapalı günlerin saklandığı biçimde,
+// UTC gece yarısı olarak döner
+func calendarDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// unavailable sağlayıcının [from, to) aralığındaki izinlerini ve şirketin
+// kapalı günlerini loc'taki zaman aralıkları olarak döner
+func (s *AppointmentService) unavailable(ctx context.Context, provider models.Provider, from, to time.Time, loc *time.Location) ([]Slot, error) {
+	timeOff, err := s.timeOff.Find(ctx, provider.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var out []Slot
+	for _, t := range timeOff {
+		out = append(out, Slot{Start: t.Start, End: t.End})
+	}
+
+	if provider.CompanyId == "" {
+		return out, nil
+	}
+	closures, err := s.closures.Find(ctx, provider.CompanyId, calendarDay(from, loc), calendarDay(to, loc).AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range closures {
+		start, end := dayRange(c.Date, loc)
+		out = append(out, Slot{Start: start, End: end})
+	}
+	return out, nil
+}
+
+// checkAvailable [start, end) sağlayıcının izni, dış takvimindeki bir
+// etkinlik, şirketin kapalı günü ya da alınmış bir randevusuyla
+// çakışıyorsa Conflict döner. Boş slot kayıtları böylece çalışma
+// saatlerinden alınmış bir rezervasyonun üstüne yazılamaz. except
+// verilmişse o randevu alınmış sayılmaz; taşınan randevu kendisiyle çakışmaz.
+func (s *AppointmentService) checkAvailable(ctx context.Context, providerEmail string, start, end time.Time, except primitive.ObjectID) error {
+	provider, err := s.providers.FindByEmail(ctx, providerEmail)
+	if err == repositories.ErrNotFound {
+		return NotFound("Provider not found")
+	}
+	if err != nil {
+		return err
+	}
+	_, loc, err := s.providerLocation(ctx, provider)
+	if err != nil {
+		return err
+	}
+	blocked, err := s.unavailable(ctx, provider, start, end, loc)
+	if err != nil {
+		return err
+	}
+	busy, err := s.busyTimes(ctx, provider, start, end)
+	if err != nil {
+		return err
+	}
+	if clashes(busy, Slot{Start: start, End: end}) {
+		return Conflict("Provider is busy in another calendar at this time")
+	}
+	if clashes(blocked, Slot{Start: start, End: end}) {
+		return Conflict("Provider is not available at this time")
+	}
+	taken, err := s.bookedTimes(ctx, provider, start, end, except)
+	if err != nil {
+		return err
+	}
+	if clashes(taken, Slot{Start: start, End: end}) {
+		return Conflict("Provider already has a booking at this time")
+	}
+	return nil
+}
+
+// bookedTimes sağlayıcının [from, to) ile çakışan, slotu dolu tutan
+// randevularının aralıklarıdır; except randevusu sayılmaz
+func (s *AppointmentService) bookedTimes(ctx context.Context, provider models.Provider, from, to time.Time, except primitive.ObjectID) ([]Slot, error) {
+	// Bir gün önce başlayıp aralığa taşan randevular da aranır
+	found, err := s.appointments.Find(ctx, repositories.AppointmentFilter{
+		ProviderEmail: provider.Email,
+		From:          from.AddDate(0, 0, -1),
+		To:            to,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out []Slot
+	for _, a := range found {
+		if a.ID != except && contains(blockingStatuses, a.Status) && overlaps(startsAt(a), endsAt(a), from, to) {
+			out = append(out, Slot{Start: startsAt(a), End: endsAt(a)})
+		}
+	}
+	return out, nil
+}
+
+// booked [start, end) ile çakışan, alınmış randevuları döner. filter'ın
+// sağlayıcı ya da şirket alanı kullanılır; tarih aralığı burada belirlenir.
+func (s *AppointmentService) booked(ctx context.Context, filter repositories.AppointmentFilter, start, end time.Time) ([]models.Appointment, error) {
+	// Randevular date alanındaki başlangıca göre aranır; aralıktan önce
+	// başlayıp içine taşanlar için bir gün geriden başlanır
+	filter.From = start.AddDate(0, 0, -1)
+	filter.To = end
+	found, err := s.appointments.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	out := []models.Appointment{}
+	for _, a := range found {
+		if contains(conflictingStatuses, a.Status) && overlaps(startsAt(a), endsAt(a), start, end) {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+// AddTimeOff sağlayıcıya izin ekler ve o aralıkta zaten alınmış randevuları
+// döner. Randevular otomatik iptal edilmez; müşterilerle iletişime geçmek
+// personele bırakılır.
+func (s *AppointmentService) AddTimeOff(ctx context.Context, actor Actor, providerEmail string, start, end time.Time, reason string) (TimeOffResult, error) {
+	provider, err := providerByEmail(ctx, s.providers, actor, providerEmail)
+	if err != nil {
+		return TimeOffResult{}, err
+	}
+	if start.IsZero() || !end.After(start) {
+		return TimeOffResult{}, Invalid("Time off must end after it starts")
+	}
+	if end.After(start.AddDate(0, 0, maxTimeOffDays)) {
+		return TimeOffResult{}, Invalid(fmt.Sprintf("Time off cannot exceed %d days", maxTimeOffDays))
+	}
+
+	timeOff := models.TimeOff{
+		ID:            primitive.NewObjectID(),
+		ProviderID:    provider.ID,
+		ProviderEmail: provider.Email,
+		CompanyID:     provider.CompanyId,
+		Start:         start.UTC(),
+		End:           end.UTC(),
+		Reason:        reason,
+		CreatedBy:     actor.Email,
+		CreatedAt:     time.Now(),
+	}
+	if err := s.timeOff.Create(ctx, timeOff); err != nil {
+		return TimeOffResult{}, err
+	}
+
+	conflicts, err := s.booked(ctx, repositories.AppointmentFilter{ProviderEmail: provider.Email}, timeOff.Start, timeOff.End)
+	if err != nil {
+		return TimeOffResult{}, err
+	}
+	return TimeOffResult{TimeOff: timeOff, Conflicts: conflicts}, nil
+}
+
+// ListTimeOff sağlayıcının [from, to) ile çakışan izinlerini döner
+func (s *AppointmentService) ListTimeOff(ctx context.Context, actor Actor, providerEmail string, from, to time.Time) ([]models.TimeOff, error) {
+	provider, err := providerByEmail(ctx, s.providers, actor, providerEmail)
+	if err != nil {
+		return nil, err
+	}
+	return s.timeOff.Find(ctx, provider.ID, from, to)
+}
+
+// authorizeTimeOff izni bulur ve kimliğin izin sahibine erişimini kontrol eder
+func (s *AppointmentService) authorizeTimeOff(ctx context.Context, actor Actor, id primitive.ObjectID) (models.TimeOff, error) {
+	timeOff, err := s.timeOff.FindByID(ctx, id)
+	if err == repositories.ErrNotFound {
+		return models.TimeOff{}, NotFound("Time off not found")
+	}
+	if err != nil {
+		return models.TimeOff{}, err
+	}
+	_, err = authorizeProvider(actor, func() (models.Provider, error) { return s.providers.FindByID(ctx, timeOff.ProviderID) })
+	if err != nil {
+		return models.TimeOff{}, err
+	}
+	return timeOff, nil
+}
+
+// TimeOffConflicts iznin aralığında hâlâ alınmış olan randevuları döner
+func (s *AppointmentService) TimeOffConflicts(ctx context.Context, actor Actor, id primitive.ObjectID) ([]models.Appointment, error) {
+	timeOff, err := s.authorizeTimeOff(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.booked(ctx, repositories.AppointmentFilter{ProviderEmail: timeOff.ProviderEmail}, timeOff.Start, timeOff.End)
+}
+
+// DeleteTimeOff izni siler; aralıktaki slotlar tekrar müsait olur
+func (s *AppointmentService) DeleteTimeOff(ctx context.Context, actor Actor, id primitive.ObjectID) error {
+	if _, err := s.authorizeTimeOff(ctx, actor, id); err != nil {
+		return err
+	}
+	err := s.timeOff.DeleteByID(ctx, id)
+	if err == repositories.ErrNotFound {
+		return NotFound("Time off not found")
+	}
+	return err
+}
+
+// companyLocation şirketin saat dilimidir
+func (s *AppointmentService) companyLocation(ctx context.Context, companyID string) (*time.Location, error) {
+	zone, err := s.zoneFor(ctx, companyID, "")
+	if err != nil {
+		return nil, err
+	}
+	return models.Location(zone), nil
+}
+
+// AddClosures şirketi verilen takvim günlerinde kapalı işaretler. Günlerin
+// yalnızca tarihi önemlidir ve şirketin saat diliminde yorumlanır; zaten
+// kapalı olan günler atlanır. O günlerde alınmış randevular döner.
+func (s *AppointmentService) AddClosures(ctx context.Context, actor Actor, companyID string, holidays []Holiday, source string) (ClosureResult, error) {
+	companyID = actor.DefaultCompanyID(companyID)
+	if companyID == "" {
+		return ClosureResult{}, Invalid("Company ID is required")
+	}
+	if !actor.CanAccessCompany(companyID) {
+		return ClosureResult{}, ErrForbidden
+	}
+	if len(holidays) == 0 {
+		return ClosureResult{}, Invalid("At least one date is required")
+	}
+	loc, err := s.companyLocation(ctx, companyID)
+	if err != nil {
+		return ClosureResult{}, err
+	}
+
+	result := ClosureResult{Closures: []models.Closure{}, Conflicts: []models.Appointment{}}
+	for _, h := range holidays {
+		closure := models.Closure{
+			ID:        primitive.NewObjectID(),
+			CompanyID: companyID,
+			Date:      time.Date(h.Date.Year(), h.Date.Month(), h.Date.Day(), 0, 0, 0, 0, time.UTC),
+			Name:      h.Name,
+			Source:    source,
+			CreatedAt: time.Now(),
+		}
+		err := s.closures.Create(ctx, closure)
+		if err == repositories.ErrDuplicate {
+			result.Skipped++
+			continue
+		}
+		if err != nil {
+			return ClosureResult{}, err
+		}
+		result.Closures = append(result.Closures, closure)
+
+		start, end := dayRange(closure.Date, loc)
+		conflicts, err := s.booked(ctx, repositories.AppointmentFilter{CompanyID: companyID}, start, end)
+		if err != nil {
+			return ClosureResult{}, err
+		}
+		result.Conflicts = append(result.Conflicts, conflicts...)
+	}
+	return result, nil
+}
+
+// ImportTurkishHolidays yılın Türkiye resmi tatillerini şirketin kapalı
+// günlerine ekler. Dini bayram tarihleri o yıl için bilinmiyorsa yalnızca
+// sabit tarihli tatiller eklenir ve sonuç Partial olur.
+func (s *AppointmentService) ImportTurkishHolidays(ctx context.Context, actor Actor, companyID string, year int) (ClosureResult, error) {
+	if year < 2000 || year > 2100 {
+		return ClosureResult{}, Invalid("Invalid year")
+	}
+	holidays, complete := TurkishHolidays(year)
+	result, err := s.AddClosures(ctx, actor, companyID, holidays, models.ClosureTRHolidays)
+	if err != nil {
+		return ClosureResult{}, err
+	}
+	result.Partial = !complete
+	return result, nil
+}
+
+// ListClosures şirketin [from, to) aralığındaki kapalı günlerini döner.
+// Müşterilerin de görebilmesi için yetki gerektirmez.
+func (s *AppointmentService) ListClosures(ctx context.Context, companyID string, from, to time.Time) ([]models.Closure, error) {
+	if companyID == "" {
+		return nil, Invalid("Company ID is required")
+	}
+	return s.closures.Find(ctx, companyID, from, to)
+}
+
+// DeleteClosure şirketin kapalı gününü kaldırır
+func (s *AppointmentService) DeleteClosure(ctx context.Context, actor Actor, companyID string, day time.Time) error {
+	companyID = actor.DefaultCompanyID(companyID)
+	if !actor.CanAccessCompany(companyID) {
+		return ErrForbidden
+	}
+	err := s.closures.Delete(ctx, companyID, time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC))
+	if err == repositories.ErrNotFound {
+		return NotFound("Closure not found")
+	}
+	return err
+}
@@ -0,0 +1,545 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxWaitlistDays bir bekleme listesi kaydının kapsayabileceği gün sayısıdır
+const maxWaitlistDays = 90
+
+// activeWaitlist listede hâlâ sırası olan kayıtların durumlarıdır
+var activeWaitlist = []string{models.WaitlistWaiting, models.WaitlistOffered}
+
+// WaitlistRequest müşterinin bekleme listesine girmek için gönderdiği
+// bilgilerdir. ProviderEmail boşsa şirketin herhangi bir sağlayıcısı kabul
+// edilir. From ve To dahil takvim günleridir; yalnızca tarihleri önemlidir.
+type WaitlistRequest struct {
+	CompanyID     string
+	ProviderEmail string
+	From          time.Time
+	To            time.Time
+	ServiceIDs    []primitive.ObjectID
+	CustomerName  string
+}
+
+// opening boşalan ya da yeni açılan bir slottur. slotID boşsa slot
+// sağlayıcının çalışma saatlerinden hesaplanır.
+type opening struct {
+	slotID   primitive.ObjectID
+	provider models.Provider
+	start    time.Time
+	end      time.Time
+}
+
+// JoinWaitlist müşteriyi sağlayıcının ya da şirketin bekleme listesine
+// ekler. Aynı sağlayıcı veya şirket için tarihleri çakışan ikinci bir kayıt
+// Conflict döner. Hizmet verilirse yalnızca o hizmetlere yetecek slotlar
+// teklif edilir.
+func (s *AppointmentService) JoinWaitlist(ctx context.Context, actor Actor, req WaitlistRequest) (models.WaitlistEntry, error) {
+	if actor.Email == "" {
+		return models.WaitlistEntry{}, Invalid("Customer email is required")
+	}
+
+	companyID := req.CompanyID
+	var provider *models.Provider
+	if req.ProviderEmail != "" {
+		found, err := s.providers.FindByEmail(ctx, req.ProviderEmail)
+		if err == repositories.ErrNotFound {
+			return models.WaitlistEntry{}, NotFound("Provider not found")
+		}
+		if err != nil {
+			return models.WaitlistEntry{}, err
+		}
+		if companyID != "" && companyID != found.CompanyId {
+			return models.WaitlistEntry{}, Invalid("Provider does not belong to this company")
+		}
+		companyID = found.CompanyId
+		provider = &found
+	} else {
+		if companyID == "" {
+			return models.WaitlistEntry{}, Invalid("Company ID or provider email is required")
+		}
+		objID, err := primitive.ObjectIDFromHex(companyID)
+		if err != nil {
+			return models.WaitlistEntry{}, Invalid("Invalid company ID")
+		}
+		if _, err := s.companies.FindByID(ctx, objID); err == repositories.ErrNotFound {
+			return models.WaitlistEntry{}, NotFound("Company not found")
+		} else if err != nil {
+			return models.WaitlistEntry{}, err
+		}
+	}
+
+	if req.From.IsZero() || req.To.IsZero() {
+		return models.WaitlistEntry{}, Invalid("From and to dates are required")
+	}
+	from := time.Date(req.From.Year(), req.From.Month(), req.From.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(req.To.Year(), req.To.Month(), req.To.Day(), 0, 0, 0, 0, time.UTC)
+	if to.Before(from) {
+		return models.WaitlistEntry{}, Invalid("Range end must not be before its start")
+	}
+	if to.After(from.AddDate(0, 0, maxWaitlistDays)) {
+		return models.WaitlistEntry{}, Invalid(fmt.Sprintf("Range cannot exceed %d days", maxWaitlistDays))
+	}
+	zone, err := s.zoneFor(ctx, companyID, req.ProviderEmail)
+	if err != nil {
+		return models.WaitlistEntry{}, err
+	}
+	if to.Before(calendarDay(time.Now(), models.Location(zone))) {
+		return models.WaitlistEntry{}, Invalid("Date range is in the past")
+	}
+
+	if err := s.checkWaitlistServices(ctx, companyID, provider, req.ServiceIDs); err != nil {
+		return models.WaitlistEntry{}, err
+	}
+
+	existing, err := s.waitlist.Find(ctx, repositories.WaitlistFilter{CustomerEmail: actor.Email, Statuses: activeWaitlist})
+	if err != nil {
+		return models.WaitlistEntry{}, err
+	}
+	for _, e := range existing {
+		if e.CompanyID == companyID && e.ProviderEmail == req.ProviderEmail && !e.To.Before(from) && !to.Before(e.From) {
+			return models.WaitlistEntry{}, Conflict("You are already on the waitlist for these dates")
+		}
+	}
+
+	now := time.Now()
+	entry := models.WaitlistEntry{
+		ID:            primitive.NewObjectID(),
+		CustomerEmail: actor.Email,
+		CustomerName:  req.CustomerName,
+		CompanyID:     companyID,
+		ProviderEmail: req.ProviderEmail,
+		From:          from,
+		To:            to,
+		ServiceIDs:    req.ServiceIDs,
+		Status:        models.WaitlistWaiting,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.waitlist.Create(ctx, entry); err != nil {
+		return models.WaitlistEntry{}, err
+	}
+	return entry, nil
+}
+
+// checkWaitlistServices hizmetlerin sağlayıcı tarafından sunulduğunu, sağlayıcı
+// verilmemişse şirketin kataloğunda etkin olduğunu kontrol eder
+func (s *AppointmentService) checkWaitlistServices(ctx context.Context, companyID string, provider *models.Provider, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if provider != nil {
+		_, err := quoteServices(ctx, s.catalog, *provider, ids)
+		return err
+	}
+	found, err := s.catalog.FindByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	active := 0
+	for _, service := range found {
+		if service.CompanyID == companyID && service.Active {
+			active++
+		}
+	}
+	if active != len(ids) {
+		return Invalid("Service is not in this company's catalog")
+	}
+	return nil
+}
+
+// MyWaitlist müşterinin bekleme listesi kayıtlarını döner
+func (s *AppointmentService) MyWaitlist(ctx context.Context, actor Actor) ([]models.WaitlistEntry, error) {
+	if actor.Email == "" {
+		return nil, Invalid("Customer email is required")
+	}
+	return s.waitlist.Find(ctx, repositories.WaitlistFilter{CustomerEmail: actor.Email})
+}
+
+// CompanyWaitlist şirketin bekleme listesinde sırası olan kayıtları sıra düzeniyle döner
+func (s *AppointmentService) CompanyWaitlist(ctx context.Context, actor Actor, companyID string) ([]models.WaitlistEntry, error) {
+	companyID = actor.DefaultCompanyID(companyID)
+	if companyID == "" {
+		return nil, Invalid("Company ID is required")
+	}
+	if !actor.CanAccessCompany(companyID) {
+		return nil, ErrForbidden
+	}
+	return s.waitlist.Find(ctx, repositories.WaitlistFilter{CompanyID: companyID, Statuses: activeWaitlist})
+}
+
+// LeaveWaitlist müşteriyi bekleme listesinden çıkarır. Kayda açık bir
+// teklif varsa slot sıradaki kayda teklif edilir.
+func (s *AppointmentService) LeaveWaitlist(ctx context.Context, actor Actor, id primitive.ObjectID) error {
+	entry, err := s.waitlist.FindByID(ctx, id)
+	if err == repositories.ErrNotFound || (err == nil && (actor.Email == "" || entry.CustomerEmail != actor.Email)) {
+		return NotFound("Waitlist entry not found")
+	}
+	if err != nil {
+		return err
+	}
+	if !contains(activeWaitlist, entry.Status) {
+		return Conflict("Waitlist entry is " + entry.Status)
+	}
+
+	now := time.Now()
+	ok, err := s.waitlist.UpdateIf(ctx, id, repositories.WaitlistCondition{
+		Statuses:  []string{entry.Status},
+		TokenHash: entry.OfferTokenHash,
+	}, repositories.Fields{
+		"status":     models.WaitlistCancelled,
+		"updated_at": now,
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return Conflict("Waitlist entry was modified by another request, reload and retry")
+	}
+	if entry.Status == models.WaitlistOffered {
+		go s.passOn(entry, now)
+	}
+	return nil
+}
+
+// ClaimWaitlistOffer e-postadaki token ile teklif edilen slotu müşteri
+// adına alır. Token tek kullanımlıktır ve atomik olarak tüketilir; slot
+// rezervasyonu Book ve BookSlot ile aynı koşullu yazmadır. Slot bu arada
+// başka biri tarafından alındıysa kayıt sıradaki yerine döner ve Conflict döner.
+func (s *AppointmentService) ClaimWaitlistOffer(ctx context.Context, token string) (models.Appointment, error) {
+	if token == "" {
+		return models.Appointment{}, Invalid("Token is required")
+	}
+	hash := utils.HashToken(token)
+	entry, err := s.waitlist.FindByTokenHash(ctx, hash)
+	if err == repositories.ErrNotFound {
+		return models.Appointment{}, NotFound("Offer not found")
+	}
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if entry.Status != models.WaitlistOffered {
+		return models.Appointment{}, Conflict("Offer is no longer valid")
+	}
+	now := time.Now()
+	if !entry.OfferExpiresAt.After(now) {
+		s.expireOffer(ctx, entry, now)
+		return models.Appointment{}, Conflict("Offer has expired")
+	}
+
+	ok, err := s.waitlist.UpdateIf(ctx, entry.ID, repositories.WaitlistCondition{
+		Statuses:  []string{models.WaitlistOffered},
+		TokenHash: hash,
+	}, repositories.Fields{
+		"status":     models.WaitlistBooked,
+		"updated_at": now,
+	})
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if !ok {
+		return models.Appointment{}, Conflict("Offer is no longer valid")
+	}
+
+	var appointment models.Appointment
+	if !entry.OfferAppointmentID.IsZero() {
+		appointment, err = s.Book(ctx, entry.OfferAppointmentID, Booking{
+			CustomerName:  entry.CustomerName,
+			CustomerEmail: entry.CustomerEmail,
+			ServiceIDs:    entry.ServiceIDs,
+		})
+	} else {
+		appointment, err = s.BookSlot(ctx, SlotBooking{
+			ProviderEmail: entry.OfferProviderEmail,
+			Start:         entry.OfferStart,
+			CustomerName:  entry.CustomerName,
+			CustomerEmail: entry.CustomerEmail,
+			ServiceIDs:    entry.ServiceIDs,
+		})
+	}
+
+	booked := repositories.WaitlistCondition{Statuses: []string{models.WaitlistBooked}}
+	if err != nil {
+		// Müşteri sırasını kaybetmez, bir sonraki slot yine ona teklif edilir
+		if _, revertErr := s.waitlist.UpdateIf(ctx, entry.ID, booked, repositories.Fields{
+			"status":     models.WaitlistWaiting,
+			"updated_at": time.Now(),
+		}); revertErr != nil {
+			log.Printf("Bekleme listesi kaydı sıraya geri alınamadı (%s): %v", entry.ID.Hex(), revertErr)
+		}
+		return models.Appointment{}, err
+	}
+	if _, err := s.waitlist.UpdateIf(ctx, entry.ID, booked, repositories.Fields{"appointment_id": appointment.ID}); err != nil {
+		log.Printf("Bekleme listesi kaydına randevu yazılamadı (%s): %v", entry.ID.Hex(), err)
+	}
+	return appointment, nil
+}
+
+// ExpireWaitlistOffers süresi dolmuş teklifleri kapatır ve slotları
+// sıradaki kayıtlara teklif eder. Kapatılan teklif sayısını döner; arka
+// planda düzenli çalıştırılmak içindir.
+func (s *AppointmentService) ExpireWaitlistOffers(ctx context.Context, now time.Time) (int, error) {
+	entries, err := s.waitlist.Find(ctx, repositories.WaitlistFilter{
+		Statuses:           []string{models.WaitlistOffered},
+		OfferExpiredBefore: now,
+	})
+	if err != nil {
+		return 0, err
+	}
+	expired := 0
+	for _, entry := range entries {
+		if s.expireOffer(ctx, entry, now) {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// expireOffer kaydın teklifini süresi dolmuş olarak kapatır ve slotu
+// sıradakine geçirir. Teklif bu arada kabul edildiyse bir şey yapmaz.
+func (s *AppointmentService) expireOffer(ctx context.Context, entry models.WaitlistEntry, now time.Time) bool {
+	ok, err := s.waitlist.UpdateIf(ctx, entry.ID, repositories.WaitlistCondition{
+		Statuses:  []string{models.WaitlistOffered},
+		TokenHash: entry.OfferTokenHash,
+	}, repositories.Fields{
+		"status":     models.WaitlistExpired,
+		"updated_at": now,
+	})
+	if err != nil {
+		log.Printf("Bekleme listesi teklifi kapatılamadı (%s): %v", entry.ID.Hex(), err)
+		return false
+	}
+	if ok {
+		go s.passOn(entry, now)
+	}
+	return ok
+}
+
+// passOn kaydın kullanılmayan teklifindeki slotu sıradaki kayda teklif
+// eder. Teklif e-postasıyla birlikte arka planda, kendi context'iyle çalışır.
+func (s *AppointmentService) passOn(entry models.WaitlistEntry, now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), backgroundTimeout)
+	defer cancel()
+
+	provider, err := s.providers.FindByEmail(ctx, entry.OfferProviderEmail)
+	if err != nil {
+		log.Printf("Teklif edilen slotun sağlayıcısı bulunamadı (%s): %v", entry.OfferProviderEmail, err)
+		return
+	}
+	o := opening{slotID: entry.OfferAppointmentID, provider: provider, start: entry.OfferStart, end: entry.OfferEnd}
+	s.offer(ctx, o, s.waitingFor(ctx, provider), now)
+}
+
+// offerOpenings yeni açılan ya da boşalan slot kayıtlarını sırayla bekleme
+// listesindeki uygun kayıtlara teklif eder. Slotu açan isteği bekletmemek
+// için arka planda çalışır; isteğin context'i yanıtla kapandığı için kendi
+// context'ini kullanır. Hatalar işlemi geri almaz, yalnızca loglanır.
+func (s *AppointmentService) offerOpenings(slots []models.Appointment) {
+	if len(slots) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), backgroundTimeout)
+	defer cancel()
+
+	now := time.Now()
+	providers := map[string]models.Provider{}
+	waiting := map[string][]models.WaitlistEntry{}
+	for _, slot := range slots {
+		provider, ok := providers[slot.ProviderEmail]
+		if !ok {
+			found, err := s.providers.FindByEmail(ctx, slot.ProviderEmail)
+			if err != nil {
+				if err != repositories.ErrNotFound {
+					log.Printf("Bekleme listesi için sağlayıcı okunamadı (%s): %v", slot.ProviderEmail, err)
+				}
+				continue
+			}
+			provider = found
+			providers[slot.ProviderEmail] = found
+			waiting[slot.ProviderEmail] = s.waitingFor(ctx, found)
+		}
+
+		list := waiting[slot.ProviderEmail]
+		if len(list) == 0 {
+			continue
+		}
+		offered := s.offer(ctx, opening{slotID: slot.ID, provider: provider, start: startsAt(slot), end: endsAt(slot)}, list, now)
+		if offered.IsZero() {
+			continue
+		}
+		rest := make([]models.WaitlistEntry, 0, len(list)-1)
+		for _, entry := range list {
+			if entry.ID != offered {
+				rest = append(rest, entry)
+			}
+		}
+		waiting[slot.ProviderEmail] = rest
+	}
+}
+
+// offerFreed iptal edilen, çalışma saatlerinden alınmış bir randevunun
+// boşalttığı zamanı bekleme listesine teklif eder; offerOpenings gibi arka
+// planda çalışır
+func (s *AppointmentService) offerFreed(appointment models.Appointment) {
+	ctx, cancel := context.WithTimeout(context.Background(), backgroundTimeout)
+	defer cancel()
+
+	provider, err := s.providers.FindByEmail(ctx, appointment.ProviderEmail)
+	if err != nil {
+		if err != repositories.ErrNotFound {
+			log.Printf("Bekleme listesi için sağlayıcı okunamadı (%s): %v", appointment.ProviderEmail, err)
+		}
+		return
+	}
+	o := opening{provider: provider, start: startsAt(appointment), end: endsAt(appointment)}
+	s.offer(ctx, o, s.waitingFor(ctx, provider), time.Now())
+}
+
+// waitingFor sağlayıcının slotlarını bekleyebilecek kayıtları sıra düzeniyle döner
+func (s *AppointmentService) waitingFor(ctx context.Context, provider models.Provider) []models.WaitlistEntry {
+	filter := repositories.WaitlistFilter{Statuses: []string{models.WaitlistWaiting}}
+	if provider.CompanyId != "" {
+		filter.CompanyID = provider.CompanyId
+	} else {
+		filter.ProviderEmail = provider.Email
+	}
+	entries, err := s.waitlist.Find(ctx, filter)
+	if err != nil {
+		log.Printf("Bekleme listesi okunamadı (%s): %v", provider.Email, err)
+		return nil
+	}
+	return entries
+}
+
+// offer slotu waiting içinde sırası gelen ilk uygun kayda teklif eder ve
+// müşteriye tek kullanımlık bağlantıyı e-postayla gönderir. Uygun kayıt
+// tarih aralığı slotun gününü kapsayan, sağlayıcıyı kabul eden ve
+// hizmetleri slota sığan kayıttır. Teklif edilen kaydın kimliğini, kimse
+// uygun değilse boş kimlik döner. Slot teklif süresince ayrılmaz.
+func (s *AppointmentService) offer(ctx context.Context, o opening, waiting []models.WaitlistEntry, now time.Time) primitive.ObjectID {
+	if len(waiting) == 0 || !o.start.After(now) || !s.fits(ctx, o, 0, now) {
+		return primitive.NilObjectID
+	}
+	_, loc, err := s.providerLocation(ctx, o.provider)
+	if err != nil {
+		log.Printf("Bekleme listesi için saat dilimi okunamadı (%s): %v", o.provider.Email, err)
+		return primitive.NilObjectID
+	}
+	day := calendarDay(o.start, loc)
+
+	for _, entry := range waiting {
+		if entry.ProviderEmail != "" && entry.ProviderEmail != o.provider.Email {
+			continue
+		}
+		if day.Before(entry.From) || day.After(entry.To) {
+			continue
+		}
+		if len(entry.ServiceIDs) > 0 {
+			quote, err := quoteServices(ctx, s.catalog, o.provider, entry.ServiceIDs)
+			if err != nil || !s.fits(ctx, o, quote.Minutes(), now) {
+				continue
+			}
+		}
+
+		token, err := utils.RandomToken(32)
+		if err != nil {
+			log.Printf("Teklif token'ı üretilemedi: %v", err)
+			return primitive.NilObjectID
+		}
+		expiresAt := now.Add(s.offerTTL)
+		ok, err := s.waitlist.UpdateIf(ctx, entry.ID, repositories.WaitlistCondition{
+			Statuses: []string{models.WaitlistWaiting},
+		}, repositories.Fields{
+			"status":               models.WaitlistOffered,
+			"offer_token_hash":     utils.HashToken(token),
+			"offer_appointment_id": o.slotID,
+			"offer_provider_email": o.provider.Email,
+			"offer_start":          o.start,
+			"offer_end":            o.end,
+			"offer_expires_at":     expiresAt,
+			"updated_at":           now,
+		})
+		if err != nil {
+			log.Printf("Bekleme listesi teklifi yazılamadı (%s): %v", entry.ID.Hex(), err)
+			return primitive.NilObjectID
+		}
+		if !ok {
+			// Kayıt bu arada başka bir slot için teklif aldı ya da listeden çıktı
+			continue
+		}
+		s.sendOffer(entry, o, token, expiresAt, loc)
+		return entry.ID
+	}
+	return primitive.NilObjectID
+}
+
+// fits slotun hâlâ boş olduğunu ve minutes dakikalık bir rezervasyona
+// yettiğini kontrol eder. Uzun rezervasyonlarda ardışık boş slotlara bakılır.
+func (s *AppointmentService) fits(ctx context.Context, o opening, minutes int, now time.Time) bool {
+	need := o.start.Add(time.Duration(minutes) * time.Minute)
+	if o.slotID.IsZero() {
+		_, loc, err := s.providerLocation(ctx, o.provider)
+		if err != nil {
+			return false
+		}
+		day := truncateDay(o.start.In(loc), loc)
+		free, err := s.freeSlots(ctx, o.provider, day, day.AddDate(0, 0, 1), now)
+		if err != nil {
+			return false
+		}
+		_, err = chainSlots(free, o.start, need)
+		return err == nil
+	}
+
+	slot, err := s.appointments.FindByID(ctx, o.slotID)
+	if err != nil || slot.Status != models.StatusOpen {
+		return false
+	}
+	end := endsAt(slot)
+	if need.After(end) {
+		end = need
+	}
+	if s.checkAvailable(ctx, slot.ProviderEmail, startsAt(slot), end, slot.ID) != nil {
+		return false
+	}
+	if !need.After(endsAt(slot)) {
+		return true
+	}
+	_, err = s.followingSlots(ctx, slot, need)
+	return err == nil
+}
+
+// sendOffer müşteriye teklif e-postasını gönderir. Gönderim hatası teklifi
+// geri almaz, yalnızca loglanır; kabul edilmeyen teklif süresi dolunca
+// sıradakine geçer.
+func (s *AppointmentService) sendOffer(entry models.WaitlistEntry, o opening, token string, expiresAt time.Time, loc *time.Location) {
+	claim := "Use this code to claim it: " + token
+	if s.claimURL != "" {
+		separator := "?"
+		if strings.Contains(s.claimURL, "?") {
+			separator = "&"
+		}
+		claim = "Claim it here: " + s.claimURL + separator + "token=" + url.QueryEscape(token)
+	}
+	name := o.provider.Name
+	if name == "" {
+		name = o.provider.Email
+	}
+	body := fmt.Sprintf("A slot with %s opened up on %s.\n\n%s\n\nThe offer expires at %s; after that the slot is offered to the next person on the waitlist. The slot is not held for you, so someone else may book it first.",
+		name, o.start.In(loc).Format("2006-01-02 15:04 MST"), claim, expiresAt.In(loc).Format("2006-01-02 15:04 MST"))
+	if err := s.mailer.Send(entry.CustomerEmail, "A slot is available", body); err != nil {
+		log.Printf("Bekleme listesi teklifi gönderilemedi (%s): %v", entry.CustomerEmail, err)
+	}
+}
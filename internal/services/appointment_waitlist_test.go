@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rtsback/config"
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+)
+
+// blockingMailer release kapanana kadar gönderimi bekletir
+type blockingMailer struct {
+	release chan struct{}
+	sent    chan string
+}
+
+func (m blockingMailer) Send(to, subject, body string) error {
+	<-m.release
+	m.sent <- to
+	return nil
+}
+
+func TestGenerateSlotsOffersInBackground(t *testing.T) {
+	repos := repositories.NewMemory()
+	mailer := blockingMailer{release: make(chan struct{}), sent: make(chan string, 1)}
+	svc := New(repos, config.Config{Scheduling: config.SchedulingConfig{WaitlistOfferTTL: time.Hour}}, mailer)
+	ctx := context.Background()
+	provider := addProvider(t, svc, repos, "p@example.com")
+	day := testDay()
+	entry, err := svc.Appointments.JoinWaitlist(ctx, Actor{Email: "c@example.com"}, WaitlistRequest{ProviderEmail: provider.Email, From: day, To: day})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Teklif e-postası gönderilemezken de slot üretimi yanıt döner
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.Appointments.GenerateSlots(ctx, System(), models.AutoAddRequest{
+			ProviderEmail: provider.Email,
+			Weekdays:      []string{day.Weekday().String()},
+			ShiftStart:    "14:00",
+			ShiftEnd:      "15:00",
+			Period:        30,
+			StartDate:     day.Format("2006-01-02"),
+			EndDate:       day.Format("2006-01-02"),
+		}, time.Now())
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GenerateSlots waited for the offer email")
+	}
+
+	close(mailer.release)
+	select {
+	case to := <-mailer.sent:
+		if to != "c@example.com" {
+			t.Fatalf("offer sent to %s", to)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("offer email was not sent")
+	}
+	offered, err := repos.Waitlist.FindByID(ctx, entry.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offered.Status != models.WaitlistOffered {
+		t.Fatalf("entry status %q, want %q", offered.Status, models.WaitlistOffered)
+	}
+}
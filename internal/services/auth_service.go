@@ -1 +1,430 @@
-package services
\ No newline at end of file
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const MinPasswordLength = 8
+
+// dummyHash bulunamayan hesapların girişinde karşılaştırılır; böylece
+// yanıt süresi yanlış şifreyle aynı olur
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+
+// HashPassword şifreyi bcrypt ile hash'ler
+func HashPassword(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(bytes), err
+}
+
+// ValidatePassword yeni şifrenin kurallara uyup uymadığını kontrol eder
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return Invalid(fmt.Sprintf("password must be at least %d characters", MinPasswordLength))
+	}
+	return nil
+}
+
+// AuthService giriş, oturum, şifre ve iki adımlı doğrulama kurallarıdır
+type AuthService struct {
+	users          repositories.UserRepository
+	admins         repositories.AdminRepository
+	managers       repositories.ManagerRepository
+	providers      repositories.ProviderRepository
+	companies      repositories.CompanyRepository
+	sessions       repositories.RefreshTokenRepository
+	passwordResets repositories.PasswordResetRepository
+	securityEvents repositories.SecurityEventRepository
+	mailer         Mailer
+	resetTTL       time.Duration
+}
+
+// Session girişte verilen erişim ve yenileme token çiftidir
+type Session struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// Authenticated şifresi doğrulanmış hesaptır
+type Authenticated struct {
+	Actor
+	TwoFactorEnabled bool
+}
+
+// accountStore role göre hesap repository'sini döner. Superuser'lar
+// kullanıcı koleksiyonunda tutulur.
+func (s *AuthService) accountStore(role string) (repositories.AccountRepository, bool) {
+	switch role {
+	case utils.RoleUser, utils.RoleSuperUser:
+		return s.users, true
+	case utils.RoleProvider:
+		return s.providers, true
+	case utils.RoleManager:
+		return s.managers, true
+	case utils.RoleAdmin:
+		return s.admins, true
+	default:
+		return nil, false
+	}
+}
+
+// Login verilen roldeki hesabın şifresini doğrular. Hesap yoksa da şifre
+// yanlışsa da ErrInvalidCredentials döner; hesap yokken de bir hash
+// karşılaştırılır ki yanıt süresi hesabın varlığını belli etmesin.
+func (s *AuthService) Login(ctx context.Context, role, email, password string) (Authenticated, error) {
+	store, ok := s.accountStore(role)
+	if !ok {
+		return Authenticated{}, Invalid("Invalid role")
+	}
+
+	account, err := store.FindAccountByEmail(ctx, email)
+	if err != nil {
+		if err != repositories.ErrNotFound {
+			log.Printf("Giriş için hesap aranamadı: %v", err)
+		}
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return Authenticated{}, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(password)); err != nil {
+		return Authenticated{}, ErrInvalidCredentials
+	}
+
+	if role == utils.RoleSuperUser {
+		user, err := s.users.FindByEmail(ctx, email)
+		if err != nil {
+			return Authenticated{}, err
+		}
+		if !user.SuperUser {
+			return Authenticated{}, Forbidden("Access denied: User is not a SuperUser")
+		}
+	}
+
+	return Authenticated{
+		Actor: Actor{
+			ID:        account.ID.Hex(),
+			Email:     account.Email,
+			Role:      role,
+			CompanyID: account.CompanyID,
+		},
+		TwoFactorEnabled: account.TOTPEnabled,
+	}, nil
+}
+
+// StartSession giriş yapan hesap için yeni bir oturum ailesi açar, kısa
+// ömürlü erişim token'ı ile yenileme token'ı üretir
+func (s *AuthService) StartSession(ctx context.Context, actor Actor) (Session, error) {
+	familyID, err := utils.RandomToken(16)
+	if err != nil {
+		return Session{}, err
+	}
+
+	refreshToken, _, err := s.createRefreshToken(ctx, familyID, actor.ID, actor.Role, actor.Email, actor.CompanyID)
+	if err != nil {
+		return Session{}, err
+	}
+
+	tokenString, expirationTime, err := utils.GenerateToken(actor.ID, actor.Role, actor.Email, actor.CompanyID, familyID)
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{AccessToken: tokenString, RefreshToken: refreshToken, ExpiresAt: expirationTime}, nil
+}
+
+// createRefreshToken verilen oturum ailesinde yeni bir yenileme token'ı
+// üretir ve özetini kaydeder
+func (s *AuthService) createRefreshToken(ctx context.Context, familyID, subject, role, email, companyID string) (string, models.RefreshToken, error) {
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return "", models.RefreshToken{}, err
+	}
+
+	now := time.Now()
+	record := models.RefreshToken{
+		ID:        primitive.NewObjectID(),
+		TokenHash: utils.HashToken(raw),
+		FamilyID:  familyID,
+		Subject:   subject,
+		Role:      role,
+		Email:     email,
+		CompanyID: companyID,
+		ExpiresAt: now.Add(utils.RefreshTokenTTL()),
+		CreatedAt: now,
+	}
+
+	if err := s.sessions.Create(ctx, record); err != nil {
+		return "", models.RefreshToken{}, err
+	}
+	return raw, record, nil
+}
+
+// IsSessionRevoked erişim token'ının bağlı olduğu oturumda geçerli bir
+// yenileme token'ı kalıp kalmadığına bakar
+func (s *AuthService) IsSessionRevoked(ctx context.Context, claims *utils.Claims) (bool, error) {
+	if claims.SessionID == "" {
+		return true, nil
+	}
+
+	active, err := s.sessions.HasActive(ctx, claims.SessionID, claims.Subject, time.Now())
+	if err != nil {
+		return false, err
+	}
+	return !active, nil
+}
+
+// findRefreshToken ham yenileme token'ının kaydını bulur
+func (s *AuthService) findRefreshToken(ctx context.Context, raw string) (models.RefreshToken, error) {
+	current, err := s.sessions.FindByHash(ctx, utils.HashToken(raw))
+	if err == repositories.ErrNotFound {
+		return models.RefreshToken{}, Unauthorized("Invalid refresh token")
+	}
+	return current, err
+}
+
+// revokeReused tekrar kullanılan token'ın ailesini iptal eder
+func (s *AuthService) revokeReused(ctx context.Context, familyID string) error {
+	if err := s.sessions.RevokeFamily(ctx, familyID); err != nil {
+		return err
+	}
+	return Unauthorized("Refresh token reuse detected, session revoked")
+}
+
+// Refresh yenileme token'ını döndürür (rotation) ve yeni bir erişim
+// token'ı verir. Daha önce kullanılmış bir token tekrar gelirse token
+// çalınmış kabul edilir ve tüm aile iptal edilir.
+func (s *AuthService) Refresh(ctx context.Context, raw string) (Session, error) {
+	current, err := s.findRefreshToken(ctx, raw)
+	if err != nil {
+		return Session{}, err
+	}
+
+	if current.Revoked {
+		return Session{}, s.revokeReused(ctx, current.FamilyID)
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return Session{}, Unauthorized("Refresh token expired")
+	}
+
+	nextRaw, next, err := s.createRefreshToken(ctx, current.FamilyID, current.Subject, current.Role, current.Email, current.CompanyID)
+	if err != nil {
+		return Session{}, err
+	}
+
+	// Eski token'ı yalnızca hâlâ geçerliyse iptal et; eşzamanlı iki yenileme
+	// isteğinden yalnızca biri başarılı olabilir
+	rotated, err := s.sessions.RevokeIfActive(ctx, current.ID, next.ID)
+	if err != nil {
+		return Session{}, err
+	}
+	if !rotated {
+		return Session{}, s.revokeReused(ctx, current.FamilyID)
+	}
+
+	tokenString, expirationTime, err := utils.GenerateToken(current.Subject, current.Role, current.Email, current.CompanyID, current.FamilyID)
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{AccessToken: tokenString, RefreshToken: nextRaw, ExpiresAt: expirationTime}, nil
+}
+
+// Logout verilen yenileme token'ının ait olduğu oturumu kapatır
+func (s *AuthService) Logout(ctx context.Context, raw string) error {
+	current, err := s.findRefreshToken(ctx, raw)
+	if err != nil {
+		return err
+	}
+	return s.sessions.RevokeFamily(ctx, current.FamilyID)
+}
+
+// LogoutAll kimliğin bu roldeki tüm oturumlarını kapatır ve kapatılan
+// token sayısını döner
+func (s *AuthService) LogoutAll(ctx context.Context, actor Actor) (int64, error) {
+	return s.sessions.RevokeBySubject(ctx, actor.ID, actor.Role)
+}
+
+// setPassword şifreyi hash'leyip hesabın şifre alanına yazar ve hesabın
+// tüm oturumlarını kapatır
+func (s *AuthService) setPassword(ctx context.Context, store repositories.AccountRepository, role string, accountID primitive.ObjectID, password string) error {
+	hashed, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	if err := store.SetPassword(ctx, accountID, hashed); err != nil {
+		return err
+	}
+
+	_, err = s.sessions.RevokeBySubject(ctx, accountID.Hex(), role)
+	return err
+}
+
+// RequestPasswordReset hesap varsa tek kullanımlık bir sıfırlama kodu
+// üretip e-postayla gönderir. Hesabın var olup olmadığı dışarı sızmasın
+// diye rol geçerliyse her istek aynı yanıtı alır: hesap arama, kod kaydı
+// ve gönderim arka planda yapılır, hataları yalnızca loglanır. Böylece
+// yanıt süresi de hesabın varlığını belli etmez.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email, role string) error {
+	if role == "" {
+		role = utils.RoleUser
+	}
+	if _, ok := s.accountStore(role); !ok {
+		return Invalid("Invalid role")
+	}
+
+	go s.sendPasswordReset(email, role)
+	return nil
+}
+
+// sendPasswordReset RequestPasswordReset'in arka plan işidir; isteğin
+// context'i yanıtla birlikte kapandığı için kendi context'iyle çalışır
+func (s *AuthService) sendPasswordReset(email, role string) {
+	ctx, cancel := context.WithTimeout(context.Background(), backgroundTimeout)
+	defer cancel()
+
+	store, _ := s.accountStore(role)
+	account, err := store.FindAccountByEmail(ctx, email)
+	if err != nil {
+		if err != repositories.ErrNotFound {
+			log.Printf("Şifre sıfırlama için hesap aranamadı: %v", err)
+		}
+		return
+	}
+
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		log.Printf("Şifre sıfırlama kodu üretilemedi: %v", err)
+		return
+	}
+
+	reset := models.PasswordReset{
+		ID:        primitive.NewObjectID(),
+		TokenHash: utils.HashToken(token),
+		AccountID: account.ID,
+		Role:      role,
+		Email:     account.Email,
+		ExpiresAt: time.Now().Add(s.resetTTL),
+		CreatedAt: time.Now(),
+	}
+
+	// Aynı hesap için önceki kullanılmamış token'lar geçersiz olur
+	if err := s.passwordResets.InvalidateForAccount(ctx, account.ID, role); err != nil {
+		log.Printf("Önceki şifre sıfırlama kodları geçersiz kılınamadı: %v", err)
+		return
+	}
+
+	if err := s.passwordResets.Create(ctx, reset); err != nil {
+		log.Printf("Şifre sıfırlama kodu kaydedilemedi: %v", err)
+		return
+	}
+
+	body := fmt.Sprintf("Use this code to reset your password: %s\n\nThe code expires in %d minutes. If you did not request a reset you can ignore this email.",
+		token, int(s.resetTTL.Minutes()))
+	if err := s.mailer.Send(reset.Email, "Password Reset", body); err != nil {
+		log.Printf("Şifre sıfırlama e-postası gönderilemedi (%s): %v", reset.Email, err)
+	}
+}
+
+// ResetPassword sıfırlama token'ını tüketir ve yeni şifreyi kaydeder.
+// Token geçersizse ErrInvalidResetToken döner.
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) (models.PasswordReset, error) {
+	if err := ValidatePassword(newPassword); err != nil {
+		return models.PasswordReset{}, err
+	}
+
+	// Token tek seferde ve atomik olarak kullanılmış işaretlenir
+	reset, err := s.passwordResets.Consume(ctx, utils.HashToken(token), time.Now())
+	if err == repositories.ErrNotFound {
+		return models.PasswordReset{}, ErrInvalidResetToken
+	}
+	if err != nil {
+		return models.PasswordReset{}, err
+	}
+
+	store, ok := s.accountStore(reset.Role)
+	if !ok {
+		return models.PasswordReset{}, Invalid("Invalid role")
+	}
+
+	if err := s.setPassword(ctx, store, reset.Role, reset.AccountID, newPassword); err != nil {
+		return models.PasswordReset{}, err
+	}
+	return reset, nil
+}
+
+// ChangePassword kimliğin şifresini eski şifreyi doğrulayarak değiştirir.
+// Eski şifre yanlışsa ErrInvalidPassword döner.
+func (s *AuthService) ChangePassword(ctx context.Context, actor Actor, oldPassword, newPassword string) error {
+	if err := ValidatePassword(newPassword); err != nil {
+		return err
+	}
+
+	store, ok := s.accountStore(actor.Role)
+	if !ok {
+		return Invalid("Invalid role")
+	}
+
+	accountID, err := primitive.ObjectIDFromHex(actor.ID)
+	if err != nil {
+		return Invalid("Invalid account ID")
+	}
+
+	account, err := store.FindAccountByID(ctx, accountID)
+	if err == repositories.ErrNotFound {
+		return NotFound("Account not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(oldPassword)); err != nil {
+		return ErrInvalidPassword
+	}
+
+	return s.setPassword(ctx, store, actor.Role, accountID, newPassword)
+}
+
+// AuthorizeUnlock kimliğin e-postası verilen hesabın giriş kilidini
+// kaldırıp kaldıramayacağını kontrol eder. Admin yalnızca kendi
+// şirketindeki hesapları açabilir.
+func (s *AuthService) AuthorizeUnlock(ctx context.Context, actor Actor, email string) error {
+	if actor.Role == utils.RoleSuperUser {
+		return nil
+	}
+
+	for _, store := range []repositories.AccountRepository{s.providers, s.managers, s.admins, s.users} {
+		if account, err := store.FindAccountByEmail(ctx, email); err == nil {
+			if !actor.CanAccessCompany(account.CompanyID) {
+				return ErrForbidden
+			}
+			return nil
+		}
+	}
+	return NotFound("Account not found")
+}
+
+// RecordSecurityEvent kilitleme ve kilit açma olaylarını kaydeder
+func (s *AuthService) RecordSecurityEvent(ctx context.Context, event models.SecurityEvent) error {
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	return s.securityEvents.Create(ctx, event)
+}
+
+// SecurityEvents olayları en yeniden eskiye en fazla limit kadar listeler
+func (s *AuthService) SecurityEvents(ctx context.Context, filter repositories.SecurityEventFilter, limit int) ([]models.SecurityEvent, error) {
+	return s.securityEvents.List(ctx, filter, limit)
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"rtsback/config"
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// failingMailer her gönderimde hata döner
+type failingMailer struct{ sent chan string }
+
+func (m failingMailer) Send(to, subject, body string) error {
+	m.sent <- to
+	return errors.New("smtp unavailable")
+}
+
+func TestPasswordResetAnswersAlike(t *testing.T) {
+	repos := repositories.NewMemory()
+	mailer := failingMailer{sent: make(chan string, 1)}
+	svc := New(repos, config.Config{Auth: config.AuthConfig{PasswordResetTTL: time.Hour}}, mailer)
+	ctx := context.Background()
+	if err := repos.Users.Create(ctx, models.User{ID: primitive.NewObjectID(), Email: "known@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.Auth.RequestPasswordReset(ctx, "unknown@example.com", ""); err != nil {
+		t.Fatalf("unknown account: %v", err)
+	}
+	// İş arka planda kendi context'iyle yapılır; isteğin bitmesi onu durdurmaz
+	done, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := svc.Auth.RequestPasswordReset(done, "known@example.com", ""); err != nil {
+		t.Fatalf("known account with failing mailer: %v", err)
+	}
+	select {
+	case to := <-mailer.sent:
+		if to != "known@example.com" {
+			t.Fatalf("reset sent to %s", to)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("reset email was not sent")
+	}
+}
+
+func TestLoginAnswersAlike(t *testing.T) {
+	svc, repos := newTestServices(t)
+	ctx := context.Background()
+	hash, err := HashPassword("correct-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Users.Create(ctx, models.User{ID: primitive.NewObjectID(), Email: "known@example.com", PasswordHash: hash}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, unknown := svc.Auth.Login(ctx, utils.RoleUser, "unknown@example.com", "correct-password")
+	_, wrong := svc.Auth.Login(ctx, utils.RoleUser, "known@example.com", "wrong-password")
+	if unknown != ErrInvalidCredentials || wrong != ErrInvalidCredentials {
+		t.Fatalf("unknown account: %v, wrong password: %v", unknown, wrong)
+	}
+	if account, err := svc.Auth.Login(ctx, utils.RoleUser, "known@example.com", "correct-password"); err != nil || account.Email != "known@example.com" {
+		t.Fatalf("login: %+v, %v", account, err)
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	utils.SetJWTSecret([]byte("test-secret-that-is-at-least-32-bytes"))
+	svc, _ := newTestServices(t)
+	ctx := context.Background()
+	actor := Actor{ID: primitive.NewObjectID().Hex(), Email: "a@example.com", Role: utils.RoleUser}
+
+	first, err := svc.Auth.StartSession(ctx, actor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := svc.Auth.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := utils.ParseToken(second.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked, err := svc.Auth.IsSessionRevoked(ctx, claims); err != nil || revoked {
+		t.Fatalf("rotated session revoked=%v err=%v", revoked, err)
+	}
+
+	// Kullanılmış token tekrar gelirse tüm aile kapanır
+	_, err = svc.Auth.Refresh(ctx, first.RefreshToken)
+	wantKind(t, err, KindUnauthorized)
+	_, err = svc.Auth.Refresh(ctx, second.RefreshToken)
+	wantKind(t, err, KindUnauthorized)
+	if revoked, err := svc.Auth.IsSessionRevoked(ctx, claims); err != nil || !revoked {
+		t.Fatalf("reused session revoked=%v err=%v", revoked, err)
+	}
+
+	// Başka bir oturum etkilenmez
+	other, err := svc.Auth.StartSession(ctx, actor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Auth.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Fatalf("independent session: %v", err)
+	}
+}
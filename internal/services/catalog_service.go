@@ -0,0 +1,323 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultCurrency para birimi verilmeyen hizmetlerin para birimidir
+const defaultCurrency = "TRY"
+
+// CatalogService şirketlerin hizmet kataloğu ve sağlayıcıların bu
+// katalogdan sundukları hizmetlerin kurallarıdır
+type CatalogService struct {
+	services  repositories.ServiceRepository
+	providers repositories.ProviderRepository
+}
+
+// OfferedService sağlayıcının sunduğu bir hizmetin sağlayıcıya özel fiyat
+// ve süre uygulanmış halidir
+type OfferedService struct {
+	Service         models.Service
+	Price           int64
+	DurationMinutes int
+}
+
+// Quote rezervasyondaki hizmetlerin toplam fiyatı ve süresidir.
+// BufferMinutes hizmetlerin temizlik sürelerinin toplamıdır.
+type Quote struct {
+	ServiceIDs      []primitive.ObjectID
+	Names           []string
+	Price           int64
+	Currency        string
+	DurationMinutes int
+	BufferMinutes   int
+}
+
+// Minutes rezervasyonun takvimde kapladığı toplam süredir
+func (q Quote) Minutes() int {
+	return q.DurationMinutes + q.BufferMinutes
+}
+
+func validateService(service models.Service) error {
+	if strings.TrimSpace(service.Name) == "" {
+		return Invalid("Service name is required")
+	}
+	if service.DurationMinutes <= 0 {
+		return Invalid("Duration must be a positive number of minutes")
+	}
+	if service.Price < 0 {
+		return Invalid("Price cannot be negative")
+	}
+	if service.BufferMinutes < 0 {
+		return Invalid("Buffer cannot be negative")
+	}
+	if len(service.Currency) != 3 || strings.ToUpper(service.Currency) != service.Currency {
+		return Invalid(fmt.Sprintf("Invalid currency %q, expected an ISO 4217 code such as TRY", service.Currency))
+	}
+	return nil
+}
+
+func validateOffering(offering models.Offering) error {
+	if offering.Price != nil && *offering.Price < 0 {
+		return Invalid("Price cannot be negative")
+	}
+	if offering.DurationMinutes != nil && *offering.DurationMinutes <= 0 {
+		return Invalid("Duration must be a positive number of minutes")
+	}
+	return nil
+}
+
+// offered hizmete sağlayıcının fiyat ve süre değişikliklerini uygular
+func offered(service models.Service, offering models.Offering) OfferedService {
+	out := OfferedService{Service: service, Price: service.Price, DurationMinutes: service.DurationMinutes}
+	if offering.Price != nil {
+		out.Price = *offering.Price
+	}
+	if offering.DurationMinutes != nil {
+		out.DurationMinutes = *offering.DurationMinutes
+	}
+	return out
+}
+
+// List şirketin hizmetlerini döner. Pasif hizmetleri yalnızca şirkete
+// erişimi olanlar görebilir.
+func (s *CatalogService) List(ctx context.Context, actor Actor, companyID string, includeInactive bool) ([]models.Service, error) {
+	if companyID == "" {
+		return nil, Invalid("Company ID is required")
+	}
+	if includeInactive && !actor.CanAccessCompany(companyID) {
+		return nil, ErrForbidden
+	}
+	return s.services.List(ctx, companyID, !includeInactive)
+}
+
+// authorize hizmeti bulur ve kimliğin hizmetin şirketine erişimini kontrol eder
+func (s *CatalogService) authorize(ctx context.Context, actor Actor, id primitive.ObjectID) (models.Service, error) {
+	service, err := s.services.FindByID(ctx, id)
+	if err == repositories.ErrNotFound {
+		return models.Service{}, NotFound("Service not found")
+	}
+	if err != nil {
+		return models.Service{}, err
+	}
+	if !actor.CanAccessCompany(service.CompanyID) {
+		return models.Service{}, ErrForbidden
+	}
+	return service, nil
+}
+
+// Create hizmeti kimliğin şirketinin kataloğuna ekler
+func (s *CatalogService) Create(ctx context.Context, actor Actor, service models.Service) (models.Service, error) {
+	service.CompanyID = actor.DefaultCompanyID(service.CompanyID)
+	if !actor.CanAccessCompany(service.CompanyID) {
+		return models.Service{}, ErrForbidden
+	}
+	if service.Currency == "" {
+		service.Currency = defaultCurrency
+	}
+	service.ResourceTypes = resourceTypes(service.ResourceTypes)
+	if err := validateService(service); err != nil {
+		return models.Service{}, err
+	}
+
+	service.ID = primitive.NewObjectID()
+	service.CreatedAt = time.Now()
+	service.UpdatedAt = time.Now()
+	if err := s.services.Create(ctx, service); err != nil {
+		return models.Service{}, err
+	}
+	return service, nil
+}
+
+// Update hizmetin düzenlenebilir alanlarını değiştirir. Şirketi değişmez;
+// alınmış randevular rezervasyon anındaki fiyatlarını korur.
+func (s *CatalogService) Update(ctx context.Context, actor Actor, id primitive.ObjectID, update models.Service) (models.Service, error) {
+	current, err := s.authorize(ctx, actor, id)
+	if err != nil {
+		return models.Service{}, err
+	}
+	if update.Currency == "" {
+		update.Currency = current.Currency
+	}
+	if err := validateService(update); err != nil {
+		return models.Service{}, err
+	}
+
+	current.Name = update.Name
+	current.Description = update.Description
+	current.DurationMinutes = update.DurationMinutes
+	current.Price = update.Price
+	current.Currency = update.Currency
+	current.Category = update.Category
+	current.Active = update.Active
+	current.BufferMinutes = update.BufferMinutes
+	current.ResourceTypes = resourceTypes(update.ResourceTypes)
+	current.UpdatedAt = time.Now()
+
+	err = s.services.UpdateByID(ctx, id, repositories.Fields{
+		"name":             current.Name,
+		"description":      current.Description,
+		"duration_minutes": current.DurationMinutes,
+		"price":            current.Price,
+		"currency":         current.Currency,
+		"category":         current.Category,
+		"active":           current.Active,
+		"buffer_minutes":   current.BufferMinutes,
+		"resource_types":   current.ResourceTypes,
+		"updated_at":       current.UpdatedAt,
+	})
+	if err == repositories.ErrNotFound {
+		return models.Service{}, NotFound("Service not found")
+	}
+	if err != nil {
+		return models.Service{}, err
+	}
+	return current, nil
+}
+
+// Delete hizmeti katalogdan ve onu sunan sağlayıcılardan kaldırır. Geçmiş
+// randevular hizmetin adını ve fiyatını kendi kayıtlarında taşır.
+func (s *CatalogService) Delete(ctx context.Context, actor Actor, id primitive.ObjectID) error {
+	if _, err := s.authorize(ctx, actor, id); err != nil {
+		return err
+	}
+	if err := s.services.DeleteByID(ctx, id); err != nil {
+		if err == repositories.ErrNotFound {
+			return NotFound("Service not found")
+		}
+		return err
+	}
+	return s.providers.RemoveOfferingEverywhere(ctx, id)
+}
+
+// ProviderOfferings sağlayıcının sunduğu etkin hizmetleri fiyat ve
+// süreleriyle döner. Müşterilerin hizmet seçebilmesi için yetki gerektirmez.
+func (s *CatalogService) ProviderOfferings(ctx context.Context, providerID primitive.ObjectID) ([]OfferedService, error) {
+	provider, err := s.providers.FindByID(ctx, providerID)
+	if err == repositories.ErrNotFound {
+		return nil, NotFound("Provider not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return providerOfferings(ctx, s.services, provider)
+}
+
+// providerOfferings sağlayıcının etkin ve kendi şirketine ait hizmetlerini döner
+func providerOfferings(ctx context.Context, catalog repositories.ServiceRepository, provider models.Provider) ([]OfferedService, error) {
+	out := []OfferedService{}
+	if len(provider.Offerings) == 0 {
+		return out, nil
+	}
+	ids := make([]primitive.ObjectID, len(provider.Offerings))
+	for i, o := range provider.Offerings {
+		ids[i] = o.ServiceID
+	}
+	found, err := catalog.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]models.Service, len(found))
+	for _, service := range found {
+		byID[service.ID] = service
+	}
+
+	for _, offering := range provider.Offerings {
+		service, ok := byID[offering.ServiceID]
+		if !ok || !service.Active || service.CompanyID != provider.CompanyId {
+			continue
+		}
+		out = append(out, offered(service, offering))
+	}
+	return out, nil
+}
+
+// SetOffering sağlayıcının şirket kataloğundaki bir hizmeti sunmasını
+// sağlar; fiyat ve süre verilirse katalogdakinin yerine geçer
+func (s *CatalogService) SetOffering(ctx context.Context, actor Actor, providerID primitive.ObjectID, offering models.Offering) error {
+	provider, err := authorizeProvider(actor, func() (models.Provider, error) { return s.providers.FindByID(ctx, providerID) })
+	if err != nil {
+		return err
+	}
+	if err := validateOffering(offering); err != nil {
+		return err
+	}
+
+	service, err := s.services.FindByID(ctx, offering.ServiceID)
+	if err == repositories.ErrNotFound || (err == nil && service.CompanyID != provider.CompanyId) {
+		return NotFound("Service not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.providers.SetOffering(ctx, providerID, offering)
+	if err == repositories.ErrNotFound {
+		return NotFound("Provider not found")
+	}
+	return err
+}
+
+// RemoveOffering hizmeti sağlayıcının sunduklarından çıkarır
+func (s *CatalogService) RemoveOffering(ctx context.Context, actor Actor, providerID, serviceID primitive.ObjectID) error {
+	if _, err := authorizeProvider(actor, func() (models.Provider, error) { return s.providers.FindByID(ctx, providerID) }); err != nil {
+		return err
+	}
+	err := s.providers.RemoveOffering(ctx, providerID, serviceID)
+	if err == repositories.ErrNotFound {
+		return NotFound("Provider not found")
+	}
+	return err
+}
+
+// quoteServices sağlayıcının sunduğu hizmetlerin toplam fiyatını ve
+// süresini hesaplar. Sağlayıcının sunmadığı, pasif ya da farklı para
+// birimindeki hizmetler reddedilir.
+func quoteServices(ctx context.Context, catalog repositories.ServiceRepository, provider models.Provider, ids []primitive.ObjectID) (Quote, error) {
+	offerings, err := providerOfferings(ctx, catalog, provider)
+	if err != nil {
+		return Quote{}, err
+	}
+	byID := make(map[primitive.ObjectID]OfferedService, len(offerings))
+	for _, o := range offerings {
+		byID[o.Service.ID] = o
+	}
+
+	var quote Quote
+	for _, id := range ids {
+		if containsObjectID(quote.ServiceIDs, id) {
+			continue
+		}
+		o, ok := byID[id]
+		if !ok {
+			return Quote{}, Invalid(fmt.Sprintf("Service %s is not offered by this provider", id.Hex()))
+		}
+		if quote.Currency != "" && quote.Currency != o.Service.Currency {
+			return Quote{}, Invalid("Services with different currencies cannot be booked together")
+		}
+		quote.ServiceIDs = append(quote.ServiceIDs, id)
+		quote.Names = append(quote.Names, o.Service.Name)
+		quote.Price += o.Price
+		quote.Currency = o.Service.Currency
+		quote.DurationMinutes += o.DurationMinutes
+		quote.BufferMinutes += o.Service.BufferMinutes
+	}
+	return quote, nil
+}
+
+func containsObjectID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// addService şirketin kataloğuna hizmet ekler
+func addService(t *testing.T, repos *repositories.Repositories, service models.Service) models.Service {
+	t.Helper()
+	service.ID = primitive.NewObjectID()
+	if service.Currency == "" {
+		service.Currency = defaultCurrency
+	}
+	if err := repos.Services.Create(context.Background(), service); err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+func TestQuoteServices(t *testing.T) {
+	_, repos := newTestServices(t)
+	ctx := context.Background()
+	const company = "company-1"
+	cut := addService(t, repos, models.Service{CompanyID: company, Name: "Cut", DurationMinutes: 30, Price: 500, Active: true, BufferMinutes: 10})
+	color := addService(t, repos, models.Service{CompanyID: company, Name: "Color", DurationMinutes: 60, Price: 1200, Active: true, BufferMinutes: 15})
+	euro := addService(t, repos, models.Service{CompanyID: company, Name: "Euro", DurationMinutes: 30, Price: 40, Currency: "EUR", Active: true})
+	retired := addService(t, repos, models.Service{CompanyID: company, Name: "Retired", DurationMinutes: 30, Price: 100})
+	foreign := addService(t, repos, models.Service{CompanyID: "company-2", Name: "Foreign", DurationMinutes: 30, Price: 100, Active: true})
+	unoffered := addService(t, repos, models.Service{CompanyID: company, Name: "Unoffered", DurationMinutes: 30, Price: 100, Active: true})
+
+	price, minutes := int64(800), 45
+	provider := models.Provider{ID: primitive.NewObjectID(), CompanyId: company, Offerings: []models.Offering{
+		{ServiceID: cut.ID, Price: &price, DurationMinutes: &minutes},
+		{ServiceID: color.ID},
+		{ServiceID: euro.ID},
+		{ServiceID: retired.ID},
+		{ServiceID: foreign.ID},
+	}}
+
+	tests := []struct {
+		name    string
+		ids     []primitive.ObjectID
+		want    Quote
+		wantErr bool
+	}{
+		{
+			name: "provider override",
+			ids:  []primitive.ObjectID{cut.ID},
+			want: Quote{ServiceIDs: []primitive.ObjectID{cut.ID}, Names: []string{"Cut"}, Price: 800, Currency: "TRY", DurationMinutes: 45, BufferMinutes: 10},
+		},
+		{
+			name: "catalog price",
+			ids:  []primitive.ObjectID{color.ID},
+			want: Quote{ServiceIDs: []primitive.ObjectID{color.ID}, Names: []string{"Color"}, Price: 1200, Currency: "TRY", DurationMinutes: 60, BufferMinutes: 15},
+		},
+		{
+			name: "totals and duplicates",
+			ids:  []primitive.ObjectID{cut.ID, color.ID, cut.ID},
+			want: Quote{ServiceIDs: []primitive.ObjectID{cut.ID, color.ID}, Names: []string{"Cut", "Color"}, Price: 2000, Currency: "TRY", DurationMinutes: 105, BufferMinutes: 25},
+		},
+		{name: "mixed currencies", ids: []primitive.ObjectID{cut.ID, euro.ID}, wantErr: true},
+		{name: "inactive service", ids: []primitive.ObjectID{retired.ID}, wantErr: true},
+		{name: "other company's service", ids: []primitive.ObjectID{foreign.ID}, wantErr: true},
+		{name: "not offered", ids: []primitive.ObjectID{unoffered.ID}, wantErr: true},
+		{name: "unknown service", ids: []primitive.ObjectID{primitive.NewObjectID()}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := quoteServices(ctx, repos.Services, provider, tt.ids)
+			if tt.wantErr {
+				wantKind(t, err, KindValidation)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("quote = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if got, _ := quoteServices(ctx, repos.Services, provider, []primitive.ObjectID{cut.ID, color.ID}); got.Minutes() != 130 {
+		t.Fatalf("Minutes() = %d, want 130", got.Minutes())
+	}
+}
+
+func TestSetOffering(t *testing.T) {
+	svc, repos := newTestServices(t)
+	ctx := context.Background()
+	const company = "company-1"
+	cut := addService(t, repos, models.Service{CompanyID: company, Name: "Cut", DurationMinutes: 30, Price: 500, Active: true})
+	foreign := addService(t, repos, models.Service{CompanyID: "company-2", Name: "Foreign", DurationMinutes: 30, Price: 100, Active: true})
+	provider := models.Provider{ID: primitive.NewObjectID(), Email: "p@example.com", CompanyId: company}
+	if err := repos.Providers.Create(ctx, provider); err != nil {
+		t.Fatal(err)
+	}
+	manager := Actor{Role: utils.RoleManager, CompanyID: company}
+	negative, zero := int64(-1), 0
+
+	wantKind(t, svc.Catalog.SetOffering(ctx, manager, provider.ID, models.Offering{ServiceID: cut.ID, Price: &negative}), KindValidation)
+	wantKind(t, svc.Catalog.SetOffering(ctx, manager, provider.ID, models.Offering{ServiceID: cut.ID, DurationMinutes: &zero}), KindValidation)
+	wantKind(t, svc.Catalog.SetOffering(ctx, manager, provider.ID, models.Offering{ServiceID: foreign.ID}), KindNotFound)
+	other := Actor{Role: utils.RoleManager, CompanyID: "company-2"}
+	wantKind(t, svc.Catalog.SetOffering(ctx, other, provider.ID, models.Offering{ServiceID: cut.ID}), KindForbidden)
+
+	price := int64(650)
+	if err := svc.Catalog.SetOffering(ctx, manager, provider.ID, models.Offering{ServiceID: cut.ID, Price: &price}); err != nil {
+		t.Fatal(err)
+	}
+	offerings, err := svc.Catalog.ProviderOfferings(ctx, provider.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offerings) != 1 || offerings[0].Price != 650 || offerings[0].DurationMinutes != 30 {
+		t.Fatalf("offerings = %+v, want Cut at 650 for 30 minutes", offerings)
+	}
+
+	// Pasifleştirilen hizmet sunulanlardan düşer
+	if _, err := svc.Catalog.Update(ctx, manager, cut.ID, models.Service{Name: "Cut", DurationMinutes: 30, Price: 500}); err != nil {
+		t.Fatal(err)
+	}
+	if offerings, err := svc.Catalog.ProviderOfferings(ctx, provider.ID); err != nil || len(offerings) != 0 {
+		t.Fatalf("after deactivation: %d offerings, err %v", len(offerings), err)
+	}
+}
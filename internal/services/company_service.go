@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CompanyService şirket kayıtlarının kurallarıdır
+type CompanyService struct {
+	companies    repositories.CompanyRepository
+	appointments repositories.AppointmentRepository
+}
+
+func (s *CompanyService) List(ctx context.Context) ([]models.Company, error) {
+	return s.companies.List(ctx)
+}
+
+func (s *CompanyService) Create(ctx context.Context, company models.Company) (models.Company, error) {
+	if err := validateTimeZone(company.TimeZone); err != nil {
+		return models.Company{}, err
+	}
+	company.ID = primitive.NewObjectID()
+	company.CreatedAt = time.Now()
+	company.UpdatedAt = time.Now()
+
+	if err := s.companies.Create(ctx, company); err != nil {
+		return models.Company{}, err
+	}
+	return company, nil
+}
+
+// errTimeZoneEndpoint saat dilimi randevularla birlikte değişmesi gerektiği
+// için genel güncellemelerle değiştirilemez
+var errTimeZoneEndpoint = Invalid("Use the company time zone endpoint to change the time zone")
+
+// UpdateMany şirketleri ID ile günceller; eşleşmeyen kayıtlar atlanır
+func (s *CompanyService) UpdateMany(ctx context.Context, companies []models.Company) error {
+	for _, company := range companies {
+		if company.TimeZone != "" {
+			return errTimeZoneEndpoint
+		}
+		fields, err := repositories.FieldsOf(company)
+		if err != nil {
+			return Invalid("Geçersiz veri formatı")
+		}
+
+		err = s.companies.UpdateByID(ctx, company.ID, fields)
+		if err != nil && err != repositories.ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CompanyService) FindByName(ctx context.Context, name string) (models.Company, error) {
+	company, err := s.companies.FindByName(ctx, name)
+	if err == repositories.ErrNotFound {
+		return models.Company{}, NotFound("Şirket bulunamadı")
+	}
+	return company, err
+}
+
+// FindByID kimliğin erişebildiği şirketi döner
+func (s *CompanyService) FindByID(ctx context.Context, actor Actor, id primitive.ObjectID) (models.Company, error) {
+	if !actor.CanAccessCompany(id.Hex()) {
+		return models.Company{}, ErrForbidden
+	}
+
+	company, err := s.companies.FindByID(ctx, id)
+	if err == repositories.ErrNotFound {
+		return models.Company{}, NotFound("Company not found")
+	}
+	return company, err
+}
+
+// UpdateByName şirketi ismiyle günceller. Şirket yoksa oluşturulmaz.
+func (s *CompanyService) UpdateByName(ctx context.Context, company models.Company) error {
+	if company.Name == "" {
+		return Invalid("Şirket adı gerekli")
+	}
+	if company.TimeZone != "" {
+		return errTimeZoneEndpoint
+	}
+
+	fields, err := repositories.FieldsOf(company)
+	if err != nil {
+		return Invalid("Geçersiz veri formatı")
+	}
+
+	err = s.companies.UpdateByName(ctx, company.Name, fields)
+	if err == repositories.ErrNotFound {
+		return NotFound("Şirket bulunamadı")
+	}
+	return err
+}
+
+// SetRequireAdmin2FA şirketin tüm adminleri için 2FA zorunluluğunu açar veya kapatır
+func (s *CompanyService) SetRequireAdmin2FA(ctx context.Context, id primitive.ObjectID, required bool) error {
+	err := s.companies.UpdateByID(ctx, id, repositories.Fields{"require_admin_2fa": required, "updated_at": time.Now()})
+	if err == repositories.ErrNotFound {
+		return NotFound("Company not found")
+	}
+	return err
+}
+
+// SetBookingPolicy şirketin müşteri iptal/erteleme politikasını değiştirir
+func (s *CompanyService) SetBookingPolicy(ctx context.Context, actor Actor, id primitive.ObjectID, policy models.BookingPolicy) error {
+	if !actor.CanAccessCompany(id.Hex()) {
+		return ErrForbidden
+	}
+	if policy.MinNoticeHours < 0 || policy.MaxReschedules < 0 {
+		return Invalid("Policy values cannot be negative")
+	}
+
+	err := s.companies.UpdateByID(ctx, id, repositories.Fields{"booking_policy": policy, "updated_at": time.Now()})
+	if err == repositories.ErrNotFound {
+		return NotFound("Company not found")
+	}
+	return err
+}
+
+// SetTimeZone şirketin saat dilimini değiştirir ve şirketin randevularının
+// yerel gösterimini yeni dilime taşır. Saklanan anlar değişmez; çalışma
+// saatlerinden hesaplanan slotlar bundan sonra yeni dilimde üretilir.
+// Dilimi güncellenen randevu sayısını döner.
+func (s *CompanyService) SetTimeZone(ctx context.Context, actor Actor, id primitive.ObjectID, zone string) (int64, error) {
+	if !actor.CanAccessCompany(id.Hex()) {
+		return 0, ErrForbidden
+	}
+	if zone == "" {
+		return 0, Invalid("Time zone is required")
+	}
+	if err := validateTimeZone(zone); err != nil {
+		return 0, err
+	}
+
+	err := s.companies.UpdateByID(ctx, id, repositories.Fields{"time_zone": zone, "updated_at": time.Now()})
+	if err == repositories.ErrNotFound {
+		return 0, NotFound("Company not found")
+	}
+	if err != nil {
+		return 0, err
+	}
+	return s.appointments.SetCompanyTimeZone(ctx, id.Hex(), zone)
+}
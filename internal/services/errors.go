@@ -0,0 +1,56 @@
+package services
+
+import "errors"
+
+// Kind bir servis hatasının türüdür; HTTP katmanı bunu durum koduna çevirir
+type Kind int
+
+const (
+	KindValidation Kind = iota + 1
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+)
+
+// Error servislerin döndüğü alan (domain) hatasıdır. Message istemciye
+// gösterilebilir. Veritabanı gibi altyapı hataları bu türe sarılmadan
+// döner ve çağıran tarafından iç hata olarak ele alınır. Current doluysa
+// çakışan kaydın güncel halidir ve istemciye hatayla birlikte döner.
+type Error struct {
+	Kind    Kind
+	Message string
+	Current interface{}
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func Invalid(message string) *Error      { return &Error{Kind: KindValidation, Message: message} }
+func Unauthorized(message string) *Error { return &Error{Kind: KindUnauthorized, Message: message} }
+func Forbidden(message string) *Error    { return &Error{Kind: KindForbidden, Message: message} }
+func NotFound(message string) *Error     { return &Error{Kind: KindNotFound, Message: message} }
+func Conflict(message string) *Error     { return &Error{Kind: KindConflict, Message: message} }
+
+// ConflictWith kaydın güncel halini taşıyan bir çakışma hatasıdır
+func ConflictWith(message string, current interface{}) *Error {
+	return &Error{Kind: KindConflict, Message: message, Current: current}
+}
+
+// KindOf err bir servis hatasıysa türünü, değilse 0 döner
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return 0
+}
+
+// Çağıranların ayırt etmesi gereken hatalar, örneğin yalnızca yanlış şifre
+// veya kod denemelerinin sayaca yazılması için
+var (
+	ErrForbidden          = Forbidden("Forbidden")
+	ErrInvalidCredentials = Unauthorized("Invalid email or password")
+	ErrInvalidPassword    = Unauthorized("Invalid password")
+	ErrInvalidCode        = Unauthorized("Invalid code")
+	ErrInvalidResetToken  = Invalid("Invalid or expired reset token")
+)
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"sort"
+	"time"
+)
+
+// Holiday tek günlük bir resmi tatildir
+type Holiday struct {
+	Date time.Time // takvim günü, UTC gece yarısı
+	Name string
+}
+
+// trFixedHolidays her yıl aynı tarihte olan resmi tatillerdir
+var trFixedHolidays = []struct {
+	month time.Month
+	day   int
+	name  string
+}{
+	{time.January, 1, "Yılbaşı"},
+	{time.April, 23, "Ulusal Egemenlik ve Çocuk Bayramı"},
+	{time.May, 1, "Emek ve Dayanışma Günü"},
+	{time.May, 19, "Atatürk'ü Anma, Gençlik ve Spor Bayramı"},
+	{time.July, 15, "Demokrasi ve Milli Birlik Günü"},
+	{time.August, 30, "Zafer Bayramı"},
+	{time.October, 29, "Cumhuriyet Bayramı"},
+}
+
+// trReligiousHolidays dini bayramların Diyanet takvimine göre ilk
+// günleridir. Ramazan Bayramı üç, Kurban Bayramı dört gündür; yarım gün
+// olan arifeler dahil edilmez. Listede olmayan yıllar için bu günler
+// şirket tarafından elle eklenmelidir.
+var trReligiousHolidays = map[int]struct{ ramazan, kurban string }{
+	2024: {"2024-04-10", "2024-06-16"},
+	2025: {"2025-03-30", "2025-06-06"},
+	2026: {"2026-03-20", "2026-05-27"},
+	2027: {"2027-03-09", "2027-05-16"},
+	2028: {"2028-02-26", "2028-05-04"},
+}
+
+// TurkishHolidays yılın Türkiye resmi tatil günlerini tarih sırasıyla
+// döner. complete false ise dini bayram tarihleri bu yıl için bilinmiyor
+// ve yalnızca sabit tarihli tatiller dönmüştür.
+func TurkishHolidays(year int) (holidays []Holiday, complete bool) {
+	for _, h := range trFixedHolidays {
+		holidays = append(holidays, Holiday{Date: time.Date(year, h.month, h.day, 0, 0, 0, 0, time.UTC), Name: h.name})
+	}
+
+	religious, complete := trReligiousHolidays[year]
+	if complete {
+		ramazan, _ := time.Parse("2006-01-02", religious.ramazan)
+		for i := 0; i < 3; i++ {
+			holidays = append(holidays, Holiday{Date: ramazan.AddDate(0, 0, i), Name: "Ramazan Bayramı"})
+		}
+		kurban, _ := time.Parse("2006-01-02", religious.kurban)
+		for i := 0; i < 4; i++ {
+			holidays = append(holidays, Holiday{Date: kurban.AddDate(0, 0, i), Name: "Kurban Bayramı"})
+		}
+	}
+
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i].Date.Before(holidays[j].Date) })
+	return holidays, complete
+}
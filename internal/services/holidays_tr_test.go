@@ -0,0 +1,22 @@
+package services
+
+import "testing"
+
+func TestTurkishHolidays(t *testing.T) {
+	for year := 2024; year <= 2028; year++ {
+		holidays, complete := TurkishHolidays(year)
+		if !complete || len(holidays) != len(trFixedHolidays)+7 {
+			t.Errorf("%d: %d holidays, complete %v", year, len(holidays), complete)
+		}
+		for i := 1; i < len(holidays); i++ {
+			if holidays[i].Date.Before(holidays[i-1].Date) {
+				t.Errorf("%d: holidays out of order at %v", year, holidays[i].Date)
+			}
+		}
+	}
+
+	holidays, complete := TurkishHolidays(2029)
+	if complete || len(holidays) != len(trFixedHolidays) {
+		t.Fatalf("2029: %d holidays, complete %v", len(holidays), complete)
+	}
+}
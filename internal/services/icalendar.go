@@ -0,0 +1,219 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// iCalendar (RFC 5545) yöntemleri. Feed'ler yöntem taşımaz; e-posta
+// ekleri REQUEST ile eklenir ya da güncellenir, CANCEL ile silinir.
+const (
+	icsRequest = "REQUEST"
+	icsCancel  = "CANCEL"
+)
+
+const (
+	icsProdID     = "-//rtsback//Appointments//EN"
+	icsLocalTime  = "20060102T150405"
+	icsUTCTime    = "20060102T150405Z"
+	icsLineOctets = 75
+)
+
+// icsEvent takvim dosyasındaki bir etkinliktir. Start ve End'in saat
+// dilimi etkinliğin TZID'si olur. Aynı UID'li daha yüksek Sequence'lı
+// etkinlik takvimdeki kaydın yerine geçer.
+type icsEvent struct {
+	UID           string
+	Sequence      int64
+	Summary       string
+	Description   string
+	Location      string
+	Status        string // TENTATIVE, CONFIRMED veya CANCELLED
+	Start         time.Time
+	End           time.Time
+	Stamp         time.Time
+	Organizer     string
+	OrganizerName string
+	Attendee      string
+	AttendeeName  string
+}
+
+// icsWriter satırları CRLF ile biter ve 75 baytta katlanır
+type icsWriter struct{ buf bytes.Buffer }
+
+func (w *icsWriter) line(name, value string) {
+	s := name + ":" + value
+	for len(s) > icsLineOctets {
+		cut := icsLineOctets
+		// UTF-8 karakterinin ortasından bölünmez
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.buf.WriteString(s[:cut] + "\r\n")
+		s = " " + s[cut:]
+	}
+	w.buf.WriteString(s + "\r\n")
+}
+
+// icsText metin değerlerindeki özel karakterleri kaçırır
+func icsText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// icsParam parametre değerini gerekirse tırnak içine alır
+func icsParam(s string) string {
+	s = strings.NewReplacer(`"`, "'", "\r", "", "\n", " ").Replace(s)
+	if strings.ContainsAny(s, ":;,") {
+		return `"` + s + `"`
+	}
+	return s
+}
+
+func icsOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+}
+
+// time UTC'deki anları Z ile, diğerlerini TZID ile yazar
+func (w *icsWriter) time(name string, t time.Time) {
+	if zone := t.Location().String(); zone != "UTC" && zone != "" {
+		w.line(name+";TZID="+icsParam(zone), t.Format(icsLocalTime))
+		return
+	}
+	w.line(name, t.UTC().Format(icsUTCTime))
+}
+
+// timezone loc'un [from, to] aralığındaki ofsetlerini VTIMEZONE olarak
+// yazar. İlk bileşen from anındaki ofsettir; aralıktaki her yaz saati
+// geçişi ayrı bir STANDARD ya da DAYLIGHT bileşenidir. Geçiş anları,
+// RFC 5545'e göre geçişten önceki ofsetle yerel saat olarak yazılır.
+func (w *icsWriter) timezone(loc *time.Location, from, to time.Time) {
+	w.line("BEGIN", "VTIMEZONE")
+	w.line("TZID", icsParam(loc.String()))
+
+	t := from.In(loc)
+	name, offset := t.Zone()
+	// İlk bileşen from'u içeren dönemin başlangıcından geçerlidir; dönemi
+	// başlatan bir geçiş yoksa 1970'ten
+	start, previous := "19700101T000000", offset
+	if begin, _ := t.ZoneBounds(); !begin.IsZero() {
+		_, previous = begin.Add(-time.Second).In(loc).Zone()
+		start = begin.UTC().Add(time.Duration(previous) * time.Second).Format(icsLocalTime)
+	}
+	w.observance(t.IsDST(), start, previous, offset, name)
+	for i := 0; i < 100; i++ {
+		_, end := t.ZoneBounds()
+		if end.IsZero() || end.After(to) {
+			break
+		}
+		next := end.In(loc)
+		nextName, nextOffset := next.Zone()
+		start = end.UTC().Add(time.Duration(offset) * time.Second).Format(icsLocalTime)
+		w.observance(next.IsDST(), start, offset, nextOffset, nextName)
+		t, offset = next, nextOffset
+	}
+	w.line("END", "VTIMEZONE")
+}
+
+func (w *icsWriter) observance(dst bool, start string, from, to int, name string) {
+	kind := "STANDARD"
+	if dst {
+		kind = "DAYLIGHT"
+	}
+	w.line("BEGIN", kind)
+	w.line("DTSTART", start)
+	w.line("TZOFFSETFROM", icsOffset(from))
+	w.line("TZOFFSETTO", icsOffset(to))
+	if name != "" {
+		w.line("TZNAME", icsText(name))
+	}
+	w.line("END", kind)
+}
+
+func (w *icsWriter) event(e icsEvent) {
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", e.UID)
+	w.line("SEQUENCE", fmt.Sprint(e.Sequence))
+	w.line("DTSTAMP", e.Stamp.UTC().Format(icsUTCTime))
+	w.time("DTSTART", e.Start)
+	w.time("DTEND", e.End)
+	w.line("SUMMARY", icsText(e.Summary))
+	if e.Description != "" {
+		w.line("DESCRIPTION", icsText(e.Description))
+	}
+	if e.Location != "" {
+		w.line("LOCATION", icsText(e.Location))
+	}
+	if e.Status != "" {
+		w.line("STATUS", e.Status)
+	}
+	if e.Organizer != "" {
+		w.line("ORGANIZER;CN="+icsParam(e.OrganizerName), "mailto:"+e.Organizer)
+	}
+	if e.Attendee != "" {
+		w.line("ATTENDEE;CN="+icsParam(e.AttendeeName)+";ROLE=REQ-PARTICIPANT;PARTSTAT=ACCEPTED;RSVP=FALSE", "mailto:"+e.Attendee)
+	}
+	w.line("END", "VEVENT")
+}
+
+// icsCalendar etkinlikleri bir VCALENDAR olarak yazar. method boşsa
+// abonelik feed'idir ve name takvimin görünen adı olur. Etkinliklerin
+// kullandığı her saat dilimi için bir VTIMEZONE eklenir.
+func icsCalendar(method, name string, events []icsEvent) []byte {
+	w := &icsWriter{}
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", icsProdID)
+	w.line("CALSCALE", "GREGORIAN")
+	if method != "" {
+		w.line("METHOD", method)
+	}
+	if name != "" {
+		w.line("X-WR-CALNAME", icsText(name))
+	}
+
+	// Her dilimin VTIMEZONE'u o dilimdeki etkinliklerin aralığını kapsar
+	type span struct {
+		loc      *time.Location
+		from, to time.Time
+	}
+	zones := map[string]*span{}
+	for _, e := range events {
+		zone := e.Start.Location().String()
+		if zone == "UTC" || zone == "" {
+			continue
+		}
+		sp, ok := zones[zone]
+		if !ok {
+			zones[zone] = &span{loc: e.Start.Location(), from: e.Start, to: e.End}
+			continue
+		}
+		if e.Start.Before(sp.from) {
+			sp.from = e.Start
+		}
+		if e.End.After(sp.to) {
+			sp.to = e.End
+		}
+	}
+	names := make([]string, 0, len(zones))
+	for zone := range zones {
+		names = append(names, zone)
+	}
+	sort.Strings(names)
+	for _, zone := range names {
+		w.timezone(zones[zone].loc, zones[zone].from, zones[zone].to)
+	}
+
+	for _, e := range events {
+		w.event(e)
+	}
+	w.line("END", "VCALENDAR")
+	return w.buf.Bytes()
+}
@@ -0,0 +1,704 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// icsMaxPeriods bir takvimin tüm tekrar kuralları açılırken gezilebilecek
+// en fazla dönem sayısıdır; hiç eşleşmeyen ya da çok sayıda kural
+// içeren dosyalarda açılım burada biter
+const icsMaxPeriods = 200000
+
+var (
+	// errNotCalendar dosyanın bir VCALENDAR içermediğini gösterir
+	errNotCalendar = errors.New("file is not an iCalendar file")
+	// errTooManyEvents takvimin açılım sınırlarını aştığını gösterir
+	errTooManyEvents = errors.New("calendar has too many events")
+)
+
+// icsBusy dış takvimdeki bir etkinliğin tek bir tekrarıdır
+type icsBusy struct {
+	UID     string
+	Summary string
+	Start   time.Time
+	End     time.Time
+}
+
+// icsProperty takvim dosyasındaki bir satırdır
+type icsProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// icsWindowsZones Outlook'un TZID olarak yazdığı Windows saat dilimi
+// adlarından sık görülenlerin IANA karşılıklarıdır
+var icsWindowsZones = map[string]string{
+	"Turkey Standard Time":         "Europe/Istanbul",
+	"GTB Standard Time":            "Europe/Bucharest",
+	"FLE Standard Time":            "Europe/Kiev",
+	"W. Europe Standard Time":      "Europe/Berlin",
+	"Central Europe Standard Time": "Europe/Budapest",
+	"Romance Standard Time":        "Europe/Paris",
+	"GMT Standard Time":            "Europe/London",
+	"Russian Standard Time":        "Europe/Moscow",
+	"Eastern Standard Time":        "America/New_York",
+	"Pacific Standard Time":        "America/Los_Angeles",
+	"UTC":                          "UTC",
+}
+
+// icsUnfold satır sonlarını ayırır ve katlanmış satırları birleştirir
+func icsUnfold(data []byte) []string {
+	text := strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(string(data))
+	var lines []string
+	for _, l := range strings.Split(text, "\n") {
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		if strings.TrimSpace(l) != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// icsParseLine satırı ad, parametreler ve değer olarak ayırır. Tırnak
+// içindeki ":" ve ";" ayırıcı sayılmaz.
+func icsParseLine(line string) (icsProperty, bool) {
+	quoted := false
+	var parts []string
+	last := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				parts = append(parts, line[last:i])
+				last = i + 1
+			}
+		case ':':
+			if !quoted {
+				parts = append(parts, line[last:i])
+				p := icsProperty{Name: strings.ToUpper(parts[0]), Params: map[string]string{}, Value: line[i+1:]}
+				for _, param := range parts[1:] {
+					key, value, _ := strings.Cut(param, "=")
+					p.Params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+				}
+				return p, true
+			}
+		}
+	}
+	return icsProperty{}, false
+}
+
+// icsUnescape metin değerlerindeki kaçırılmış karakterleri geri çevirir
+func icsUnescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";").Replace(s)
+}
+
+// icsZone TZID'nin saat dilimidir; bilinmeyen dilimler def olarak yorumlanır
+func icsZone(tzid string, def *time.Location) *time.Location {
+	tzid = strings.TrimPrefix(tzid, "/")
+	if loc, err := time.LoadLocation(tzid); err == nil && tzid != "" {
+		return loc
+	}
+	if name, ok := icsWindowsZones[tzid]; ok {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return def
+}
+
+// icsParseTime tarih ya da tarih-saat değerini okur. Z ile biten değerler
+// UTC, TZID'li olanlar o dilimde, diğerleri def'tedir. Tarih değerleri
+// def'teki gece yarısıdır ve allDay true döner.
+func icsParseTime(value string, params map[string]string, def *time.Location) (t time.Time, allDay bool, err error) {
+	value = strings.TrimSpace(value)
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err = time.ParseInLocation("20060102", value, def)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse(icsUTCTime, value)
+		return t, false, err
+	}
+	loc := def
+	if tzid := params["TZID"]; tzid != "" {
+		loc = icsZone(tzid, def)
+	}
+	t, err = time.ParseInLocation(icsLocalTime, value, loc)
+	return t, false, err
+}
+
+// icsParseTimes virgülle ayrılmış tarih listesini okur (EXDATE, RDATE)
+func icsParseTimes(p icsProperty, def *time.Location) []time.Time {
+	var out []time.Time
+	for _, v := range strings.Split(p.Value, ",") {
+		if t, _, err := icsParseTime(v, p.Params, def); err == nil {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// icsAddDuration t'ye RFC 5545 süresini (örneğin PT1H30M, P1D, P2W)
+// ekler. Gün ve hafta takvim günü olarak eklenir; yaz saati geçişinde
+// saat değişmez.
+func icsAddDuration(t time.Time, value string) (time.Time, error) {
+	v := strings.TrimSpace(value)
+	sign := 1
+	if strings.HasPrefix(v, "-") {
+		sign = -1
+	}
+	v = strings.TrimLeft(v, "+-")
+	if !strings.HasPrefix(v, "P") || len(v) < 3 {
+		return t, fmt.Errorf("invalid duration %q", value)
+	}
+	days := 0
+	var d time.Duration
+	number := ""
+	for _, c := range v[1:] {
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+			continue
+		case c == 'T':
+			continue
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return t, fmt.Errorf("invalid duration %q", value)
+		}
+		switch c {
+		case 'W':
+			days += 7 * n
+		case 'D':
+			days += n
+		case 'H':
+			d += time.Duration(n) * time.Hour
+		case 'M':
+			d += time.Duration(n) * time.Minute
+		case 'S':
+			d += time.Duration(n) * time.Second
+		default:
+			return t, fmt.Errorf("invalid duration %q", value)
+		}
+		number = ""
+	}
+	if number != "" {
+		return t, fmt.Errorf("invalid duration %q", value)
+	}
+	return t.AddDate(0, 0, sign*days).Add(time.Duration(sign) * d), nil
+}
+
+// icsWeekday BYDAY değeridir; N sıfırsa ayın ya da haftanın her o günü,
+// pozitifse baştan, negatifse sondan N'inci o gündür
+type icsWeekday struct {
+	N   int
+	Day time.Weekday
+}
+
+var icsDays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// icsRule etkinliğin RRULE kuralıdır. Until hariçtir; tarih olarak
+// verilen UNTIL o günün sonuna çevrilir.
+type icsRule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []icsWeekday
+	ByMonthDay []int
+	ByMonth    []int
+	BySetPos   []int
+	WeekStart  time.Weekday
+}
+
+// icsParseRule RRULE değerini okur. Desteklenmeyen parçalar yok sayılır.
+func icsParseRule(value string, loc *time.Location) (icsRule, error) {
+	rule := icsRule{Interval: 1, WeekStart: time.Monday}
+	ints := func(s string) ([]int, error) {
+		var out []int
+		for _, part := range strings.Split(s, ",") {
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, n)
+		}
+		return out, nil
+	}
+	for _, part := range strings.Split(value, ";") {
+		key, v, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(v)
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(v)
+			if rule.Interval < 1 {
+				rule.Interval = 1
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(v)
+		case "UNTIL":
+			var allDay bool
+			rule.Until, allDay, err = icsParseTime(v, nil, loc)
+			if allDay {
+				rule.Until = rule.Until.AddDate(0, 0, 1)
+			} else {
+				rule.Until = rule.Until.Add(time.Nanosecond)
+			}
+		case "BYDAY":
+			for _, d := range strings.Split(strings.ToUpper(v), ",") {
+				if len(d) < 2 {
+					return rule, fmt.Errorf("invalid BYDAY %q", d)
+				}
+				day, ok := icsDays[d[len(d)-2:]]
+				if !ok {
+					return rule, fmt.Errorf("invalid BYDAY %q", d)
+				}
+				n := 0
+				if len(d) > 2 {
+					if n, err = strconv.Atoi(d[:len(d)-2]); err != nil {
+						return rule, err
+					}
+				}
+				rule.ByDay = append(rule.ByDay, icsWeekday{N: n, Day: day})
+			}
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = ints(v)
+		case "BYMONTH":
+			rule.ByMonth, err = ints(v)
+		case "BYSETPOS":
+			rule.BySetPos, err = ints(v)
+		case "WKST":
+			if day, ok := icsDays[strings.ToUpper(v)]; ok {
+				rule.WeekStart = day
+			}
+		}
+		if err != nil {
+			return rule, fmt.Errorf("invalid RRULE %s: %v", key, err)
+		}
+	}
+	if rule.Freq == "" {
+		return rule, errors.New("RRULE has no FREQ")
+	}
+	return rule, nil
+}
+
+func (r icsRule) inMonth(m time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, n := range r.ByMonth {
+		if time.Month(n) == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (r icsRule) onDay(d time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, w := range r.ByDay {
+		if w.Day == d {
+			return true
+		}
+	}
+	return false
+}
+
+// monthDays kuralın verilen aydaki günleridir. BYMONTHDAY ve BYDAY
+// yoksa başlangıcın ayın kaçıncı günü olduğu kullanılır; o gün ayda
+// yoksa ay atlanır.
+func (r icsRule) monthDays(year int, month time.Month, loc *time.Location, startDay int) []int {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	set := map[int]bool{}
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = last + 1 + d
+			}
+			if d >= 1 && d <= last && r.onDay(time.Date(year, month, d, 0, 0, 0, 0, loc).Weekday()) {
+				set[d] = true
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, w := range r.ByDay {
+			var matches []int
+			for d := 1; d <= last; d++ {
+				if time.Date(year, month, d, 0, 0, 0, 0, loc).Weekday() == w.Day {
+					matches = append(matches, d)
+				}
+			}
+			switch {
+			case w.N == 0:
+				for _, d := range matches {
+					set[d] = true
+				}
+			case w.N > 0 && w.N <= len(matches):
+				set[matches[w.N-1]] = true
+			case w.N < 0 && -w.N <= len(matches):
+				set[matches[len(matches)+w.N]] = true
+			}
+		}
+	case startDay <= last:
+		set[startDay] = true
+	}
+
+	days := make([]int, 0, len(set))
+	for d := range set {
+		days = append(days, d)
+	}
+	sort.Ints(days)
+	if len(r.BySetPos) == 0 {
+		return days
+	}
+	var picked []int
+	for _, pos := range r.BySetPos {
+		switch {
+		case pos > 0 && pos <= len(days):
+			picked = append(picked, days[pos-1])
+		case pos < 0 && -pos <= len(days):
+			picked = append(picked, days[len(days)+pos])
+		}
+	}
+	sort.Ints(picked)
+	return picked
+}
+
+// periodsBefore lower'dan önce tamamen biten dönemlerin sayısıdır.
+// Dönemler start'tan itibaren Interval adımlıdır; bir dönem geriden
+// başlanır ki lower'ı içeren dönem atlanmasın.
+func (r icsRule) periodsBefore(start, lower time.Time) int {
+	if !lower.After(start) {
+		return 0
+	}
+	var n int
+	switch r.Freq {
+	case "DAILY":
+		n = int(lower.Sub(start).Hours()/24) / r.Interval
+	case "WEEKLY":
+		n = int(lower.Sub(start).Hours()/24/7) / r.Interval
+	case "MONTHLY":
+		n = ((lower.Year()-start.Year())*12 + int(lower.Month()) - int(start.Month())) / r.Interval
+	case "YEARLY":
+		n = (lower.Year() - start.Year()) / r.Interval
+	}
+	if n > 0 {
+		n--
+	}
+	return n
+}
+
+// expand start'ta başlayan kuralın [lower, to) aralığındaki tekrarlarını
+// sıralı döner. start her zaman ilk tekrardır ve COUNT'a dahildir.
+// Tekrarlar start'ın saat diliminde aynı yerel saattedir. COUNT yoksa
+// lower'dan önceki dönemler gezilmeden atlanır; COUNT varsa sayım için
+// gezilir ama saklanmaz. Gezilen her dönem budget'tan düşer; budget
+// biterse errTooManyEvents döner. Saatlik ve daha sık kurallar
+// desteklenmez; yalnızca start döner.
+func (r icsRule) expand(ctx context.Context, start, lower, to time.Time, budget *int) ([]time.Time, error) {
+	loc := start.Location()
+	hour, minute, second := start.Clock()
+	var out []time.Time
+	done := false
+	counted := 1
+	emit := func(t time.Time) {
+		if done || !t.After(start) {
+			return
+		}
+		if !t.Before(to) || (!r.Until.IsZero() && !t.Before(r.Until)) || (r.Count > 0 && counted >= r.Count) {
+			done = true
+			return
+		}
+		counted++
+		if !t.Before(lower) {
+			out = append(out, t)
+		}
+	}
+	if !start.Before(to) || (!r.Until.IsZero() && !start.Before(r.Until)) {
+		return nil, nil
+	}
+	if !start.Before(lower) {
+		out = append(out, start)
+	}
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, minute, second, 0, loc)
+	}
+
+	first := 0
+	if r.Count == 0 {
+		first = r.periodsBefore(start, lower)
+	}
+	year, month, day := start.Date()
+	for i := first; !done; i++ {
+		*budget--
+		if *budget < 0 {
+			return nil, errTooManyEvents
+		}
+		if i%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		switch r.Freq {
+		case "DAILY":
+			t := at(year, month, day+i*r.Interval)
+			if !t.Before(to) {
+				done = true
+			} else if r.inMonth(t.Month()) && r.onDay(t.Weekday()) {
+				emit(t)
+			}
+		case "WEEKLY":
+			offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+			week := at(year, month, day-offset+7*i*r.Interval)
+			if !week.Before(to) {
+				done = true
+				break
+			}
+			days := r.ByDay
+			if len(days) == 0 {
+				days = []icsWeekday{{Day: start.Weekday()}}
+			}
+			offsets := make([]int, 0, len(days))
+			for _, w := range days {
+				offsets = append(offsets, (int(w.Day)-int(r.WeekStart)+7)%7)
+			}
+			sort.Ints(offsets)
+			for _, o := range offsets {
+				if t := at(week.Year(), week.Month(), week.Day()+o); r.inMonth(t.Month()) {
+					emit(t)
+				}
+			}
+		case "MONTHLY":
+			first := time.Date(year, month+time.Month(i*r.Interval), 1, 0, 0, 0, 0, loc)
+			if !first.Before(to) {
+				done = true
+			} else if r.inMonth(first.Month()) {
+				for _, d := range r.monthDays(first.Year(), first.Month(), loc, day) {
+					emit(at(first.Year(), first.Month(), d))
+				}
+			}
+		case "YEARLY":
+			y := year + i*r.Interval
+			if !time.Date(y, 1, 1, 0, 0, 0, 0, loc).Before(to) {
+				done = true
+				break
+			}
+			months := r.ByMonth
+			if len(months) == 0 {
+				months = []int{int(month)}
+			}
+			sorted := append([]int(nil), months...)
+			sort.Ints(sorted)
+			for _, m := range sorted {
+				for _, d := range r.monthDays(y, time.Month(m), loc, day) {
+					emit(at(y, time.Month(m), d))
+				}
+			}
+		default:
+			done = true
+		}
+	}
+	return out, nil
+}
+
+// icsEventData VEVENT'in dolu zaman hesabı için gereken alanlarıdır
+type icsEventData struct {
+	UID          string
+	Summary      string
+	Status       string
+	Transparent  bool
+	Start        time.Time
+	AllDay       bool
+	End          time.Time
+	Duration     string
+	Rule         string
+	RDates       []time.Time
+	ExDates      []time.Time
+	RecurrenceID time.Time
+}
+
+// endOf tekrarın bitişidir. Süre DTEND'den hesaplanırsa tüm gün
+// etkinliklerinde gün sayısı, diğerlerinde süre korunur. DTEND ve
+// DURATION yoksa tüm gün etkinlikleri bir gün sürer.
+func (e icsEventData) endOf(start time.Time) time.Time {
+	switch {
+	case e.Duration != "":
+		if end, err := icsAddDuration(start, e.Duration); err == nil {
+			return end
+		}
+		return start
+	case !e.End.IsZero() && e.AllDay:
+		days := int(e.End.Sub(e.Start).Hours()/24 + 0.5)
+		return start.AddDate(0, 0, days)
+	case !e.End.IsZero():
+		return start.Add(e.End.Sub(e.Start))
+	case e.AllDay:
+		return start.AddDate(0, 0, 1)
+	default:
+		return start
+	}
+}
+
+// icsBusyTimes takvim dosyasındaki etkinlikleri [from, to) ile çakışan
+// dolu aralıklara çevirir. Tekrarlayan etkinlikler RRULE, RDATE ve
+// EXDATE'e göre açılır; RECURRENCE-ID taşıyan etkinlikler ilgili tekrarın
+// yerine geçer. İptal edilmiş (STATUS:CANCELLED) ve meşgul göstermeyen
+// (TRANSP:TRANSPARENT) etkinlikler atlanır. Saat dilimi olmayan zamanlar
+// ve tüm gün etkinlikleri def'te yorumlanır. Okunamayan etkinlikler
+// atlanır; dosya bir takvim değilse hata döner. Sonuç maxBlocks aralığı
+// ya da açılım icsMaxPeriods dönemi aşarsa errTooManyEvents döner.
+func icsBusyTimes(ctx context.Context, data []byte, def *time.Location, from, to time.Time, maxBlocks int) ([]icsBusy, error) {
+	var events []icsEventData
+	var stack []string
+	var current *icsEventData
+	valid := true
+	calendar := false
+
+	for _, line := range icsUnfold(data) {
+		p, ok := icsParseLine(line)
+		if !ok {
+			continue
+		}
+		switch p.Name {
+		case "BEGIN":
+			component := strings.ToUpper(p.Value)
+			stack = append(stack, component)
+			if component == "VCALENDAR" {
+				calendar = true
+			}
+			if component == "VEVENT" && len(stack) == 2 {
+				current, valid = &icsEventData{}, true
+			}
+			continue
+		case "END":
+			if len(stack) == 2 && current != nil {
+				if valid && !current.Start.IsZero() {
+					events = append(events, *current)
+				}
+				current = nil
+			}
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+		// VALARM gibi iç bileşenlerin alanları etkinliğe ait değildir
+		if current == nil || len(stack) != 2 {
+			continue
+		}
+
+		var err error
+		switch p.Name {
+		case "UID":
+			current.UID = p.Value
+		case "SUMMARY":
+			current.Summary = icsUnescape(p.Value)
+		case "STATUS":
+			current.Status = strings.ToUpper(p.Value)
+		case "TRANSP":
+			current.Transparent = strings.EqualFold(p.Value, "TRANSPARENT")
+		case "DTSTART":
+			current.Start, current.AllDay, err = icsParseTime(p.Value, p.Params, def)
+		case "DTEND":
+			current.End, _, err = icsParseTime(p.Value, p.Params, def)
+		case "DURATION":
+			current.Duration = p.Value
+		case "RRULE":
+			current.Rule = p.Value
+		case "RDATE":
+			if p.Params["VALUE"] != "PERIOD" {
+				current.RDates = append(current.RDates, icsParseTimes(p, def)...)
+			}
+		case "EXDATE":
+			current.ExDates = append(current.ExDates, icsParseTimes(p, def)...)
+		case "RECURRENCE-ID":
+			current.RecurrenceID, _, err = icsParseTime(p.Value, p.Params, def)
+		}
+		if err != nil {
+			valid = false
+		}
+	}
+	if !calendar {
+		return nil, errNotCalendar
+	}
+
+	// Tekrar yerine geçen etkinliklerin RECURRENCE-ID'leri ana
+	// etkinliğin açılımından çıkarılır
+	overridden := map[string]map[int64]bool{}
+	for _, e := range events {
+		if !e.RecurrenceID.IsZero() {
+			if overridden[e.UID] == nil {
+				overridden[e.UID] = map[int64]bool{}
+			}
+			overridden[e.UID][e.RecurrenceID.Unix()] = true
+		}
+	}
+
+	out := []icsBusy{}
+	budget := icsMaxPeriods
+	for _, e := range events {
+		if e.Status == "CANCELLED" || e.Transparent {
+			continue
+		}
+		starts := []time.Time{e.Start}
+		if e.Rule != "" && e.RecurrenceID.IsZero() {
+			rule, err := icsParseRule(e.Rule, e.Start.Location())
+			if err != nil {
+				continue
+			}
+			// from'dan önce başlayıp from'a taşan tekrarlar da gerekir
+			lower := from.Add(-e.endOf(e.Start).Sub(e.Start))
+			if starts, err = rule.expand(ctx, e.Start, lower, to, &budget); err != nil {
+				return nil, err
+			}
+		}
+		if e.RecurrenceID.IsZero() {
+			starts = append(starts, e.RDates...)
+		}
+
+		skip := map[int64]bool{}
+		for _, t := range e.ExDates {
+			skip[t.Unix()] = true
+		}
+		if e.RecurrenceID.IsZero() {
+			for t := range overridden[e.UID] {
+				skip[t] = true
+			}
+		}
+		for _, start := range starts {
+			if skip[start.Unix()] {
+				continue
+			}
+			skip[start.Unix()] = true
+			end := e.endOf(start)
+			if !end.After(start) || !end.After(from) || !start.Before(to) {
+				continue
+			}
+			if len(out) >= maxBlocks {
+				return nil, errTooManyEvents
+			}
+			out = append(out, icsBusy{UID: e.UID, Summary: e.Summary, Start: start, End: end})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out, nil
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBusyTimesExpandsRules(t *testing.T) {
+	data := []byte(strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:weekly",
+		"DTSTART;TZID=Europe/Istanbul:20260105T090000",
+		"DTEND;TZID=Europe/Istanbul:20260105T100000",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=6",
+		"EXDATE;TZID=Europe/Istanbul:20260107T090000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:weekly",
+		"RECURRENCE-ID;TZID=Europe/Istanbul:20260112T090000",
+		"DTSTART;TZID=Europe/Istanbul:20260112T140000",
+		"DTEND;TZID=Europe/Istanbul:20260112T150000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:free",
+		"DTSTART:20260106T090000Z",
+		"DTEND:20260106T100000Z",
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n"))
+	istanbul := mustZone(t, "Europe/Istanbul")
+	busy, err := icsBusyTimes(context.Background(), data, time.UTC, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// COUNT=6: 5, 7, 12, 14, 19, 21 Ocak; 7'si EXDATE, 12'si taşınmış
+	want := []time.Time{
+		time.Date(2026, 1, 5, 9, 0, 0, 0, istanbul),
+		time.Date(2026, 1, 12, 14, 0, 0, 0, istanbul),
+		time.Date(2026, 1, 14, 9, 0, 0, 0, istanbul),
+		time.Date(2026, 1, 19, 9, 0, 0, 0, istanbul),
+		time.Date(2026, 1, 21, 9, 0, 0, 0, istanbul),
+	}
+	if len(busy) != len(want) {
+		t.Fatalf("got %d busy blocks, want %d: %v", len(busy), len(want), busy)
+	}
+	for i, b := range busy {
+		if !b.Start.Equal(want[i]) || b.End.Sub(b.Start) != time.Hour {
+			t.Errorf("block %d is %v–%v, want %v for an hour", i, b.Start, b.End, want[i])
+		}
+	}
+}
+
+func TestBusyTimesLimits(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	// 1300'den bu yana icsMaxPeriods'tan fazla gün geçmiştir
+	calendar := func(rule string) []byte {
+		return []byte("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:x\r\nDTSTART:13000101T090000Z\r\nDTEND:13000101T100000Z\r\nRRULE:" + rule + "\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n")
+	}
+
+	// COUNT'suz kural aralığa kadar gezilmeden atlanır
+	busy, err := icsBusyTimes(context.Background(), calendar("FREQ=DAILY"), time.UTC, from, to, 100)
+	if err != nil || len(busy) != 31 {
+		t.Fatalf("got %d blocks and %v, want 31", len(busy), err)
+	}
+	if _, err := icsBusyTimes(context.Background(), calendar("FREQ=DAILY"), time.UTC, from, to, 10); !errors.Is(err, errTooManyEvents) {
+		t.Fatalf("block limit: got %v", err)
+	}
+	// COUNT'lu kural sayım için gezilir ve dönem bütçesine takılır
+	if _, err := icsBusyTimes(context.Background(), calendar("FREQ=DAILY;COUNT=1000000000"), time.UTC, from, to, 100); !errors.Is(err, errTooManyEvents) {
+		t.Fatalf("period budget: got %v", err)
+	}
+	if _, err := icsBusyTimes(context.Background(), []byte("not a calendar"), time.UTC, from, to, 100); !errors.Is(err, errNotCalendar) {
+		t.Fatalf("non-calendar: got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"rtsback/config"
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+)
+
+func TestCalendarRoundTrip(t *testing.T) {
+	istanbul := mustZone(t, "Europe/Istanbul")
+	newYork := mustZone(t, "America/New_York")
+	// New York'ta 8 Mart 2026 yaz saatine geçiş günüdür
+	events := []icsEvent{
+		{UID: "a", Summary: "Kontrol, 1; özet", Start: time.Date(2026, 3, 7, 10, 0, 0, 0, newYork), End: time.Date(2026, 3, 7, 11, 0, 0, 0, newYork)},
+		{UID: "b", Summary: "Sonraki", Start: time.Date(2026, 3, 9, 10, 0, 0, 0, newYork), End: time.Date(2026, 3, 9, 10, 30, 0, 0, newYork)},
+		{UID: "c", Summary: strings.Repeat("uzun açıklama ", 10), Start: time.Date(2026, 3, 8, 9, 0, 0, 0, istanbul), End: time.Date(2026, 3, 8, 9, 45, 0, 0, istanbul)},
+		{UID: "d", Summary: "İptal", Status: "CANCELLED", Start: time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC), End: time.Date(2026, 3, 8, 13, 0, 0, 0, time.UTC)},
+	}
+	data := icsCalendar("", "Takvim", events)
+	for _, line := range strings.Split(string(data), "\r\n") {
+		if len(line) > icsLineOctets {
+			t.Fatalf("line longer than %d octets: %q", icsLineOctets, line)
+		}
+	}
+
+	busy, err := icsBusyTimes(context.Background(), data, time.UTC, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(busy) != 3 {
+		t.Fatalf("got %d busy blocks, want 3 (cancelled one skipped)", len(busy))
+	}
+	want := map[string]icsEvent{}
+	for _, e := range events {
+		want[e.UID] = e
+	}
+	for _, b := range busy {
+		e := want[b.UID]
+		if !b.Start.Equal(e.Start) || !b.End.Equal(e.End) || b.Summary != e.Summary {
+			t.Errorf("%s read back as %v–%v %q, want %v–%v %q", b.UID, b.Start, b.End, b.Summary, e.Start, e.End, e.Summary)
+		}
+	}
+}
+
+func TestConfirmationMailInBackground(t *testing.T) {
+	repos := repositories.NewMemory()
+	mailer := blockingMailer{release: make(chan struct{}), sent: make(chan string, 1)}
+	svc := New(repos, config.Config{}, mailer)
+	ctx := context.Background()
+	provider := addProvider(t, svc, repos, "p@example.com")
+	booked, err := svc.Appointments.BookSlot(ctx, SlotBooking{ProviderEmail: provider.Email, Start: testDay().Add(9 * time.Hour), CustomerName: "C", CustomerEmail: "c@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Onay, takvim dosyalı e-posta gönderilemezken de yanıt döner
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.Appointments.Transition(ctx, System(), booked.ID, models.StatusConfirmed, "", nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Transition waited for the confirmation email")
+	}
+
+	close(mailer.release)
+	select {
+	case to := <-mailer.sent:
+		if to != "c@example.com" {
+			t.Fatalf("confirmation sent to %s", to)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("confirmation email was not sent")
+	}
+}
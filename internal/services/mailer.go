@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log"
+
+	"rtsback/config"
+
+	"gopkg.in/gomail.v2"
+)
+
+// Mailer düz metin e-posta gönderir. Testlerde veya CLI'da farklı bir
+// gönderici verilebilir.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// Attachment e-postaya eklenen dosyadır
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// AttachmentMailer ek gönderebilen Mailer'dır
+type AttachmentMailer interface {
+	Mailer
+	SendWithAttachments(to, subject, body string, attachments ...Attachment) error
+}
+
+// sendMail ekleri destekleyen göndericiyle ekleriyle, desteklemeyenle
+// yalnızca metniyle gönderir
+func sendMail(mailer Mailer, to, subject, body string, attachments ...Attachment) error {
+	if m, ok := mailer.(AttachmentMailer); ok && len(attachments) > 0 {
+		return m.SendWithAttachments(to, subject, body, attachments...)
+	}
+	if len(attachments) > 0 {
+		log.Printf("E-posta göndericisi ek desteklemiyor, %d ek atlandı (%s)", len(attachments), to)
+	}
+	return mailer.Send(to, subject, body)
+}
+
+// SMTPMailer e-postaları ayarlardaki SMTP sunucusu üzerinden gönderir
+type SMTPMailer struct {
+	cfg config.MailConfig
+}
+
+func NewSMTPMailer(cfg config.MailConfig) *SMTPMailer {
+	return &SMTPMailer{cfg: cfg}
+}
+
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	return m.SendWithAttachments(to, subject, body)
+}
+
+func (m *SMTPMailer) SendWithAttachments(to, subject, body string, attachments ...Attachment) error {
+	cfg := m.cfg
+	if cfg.Username == "" {
+		return errors.New("mail is not configured")
+	}
+	from := cfg.From
+	if from == "" {
+		from = cfg.Username
+	}
+
+	msg := gomail.NewMessage()
+	msg.SetHeader("From", from)
+	msg.SetHeader("To", to)
+	msg.SetHeader("Subject", subject)
+	msg.SetBody("text/plain", body)
+	for _, a := range attachments {
+		data := a.Data
+		msg.Attach(a.Name,
+			gomail.SetHeader(map[string][]string{"Content-Type": {a.ContentType}}),
+			gomail.SetCopyFunc(func(w io.Writer) error {
+				_, err := w.Write(data)
+				return err
+			}))
+	}
+
+	// SMTP sunucu ayarları
+	d := gomail.NewDialer(cfg.Host, cfg.Port, cfg.Username, cfg.Password)
+
+	// E-posta gönderme işlemi
+	if err := d.DialAndSend(msg); err != nil {
+		log.Printf("E-posta gönderilemedi (%s): %v", to, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+	"rtsback/pkg/utils"
+)
+
+// Actor servis çağrısını yapan kimliktir. HTTP katmanı bunu token'dan
+// doldurur; CLI ve arka plan işleri System kullanabilir.
+//
+// Yetki kuralları:
+//   - superuser her kaynağa erişebilir
+//   - provider yalnızca kendi kaydına, randevularına ve servislerine erişebilir
+//   - admin ve manager yalnızca kendi CompanyID'lerine ait kaynaklara erişebilir
+type Actor struct {
+	ID        string
+	Email     string
+	Role      string
+	CompanyID string
+}
+
+// System iç işler için tüm kaynaklara erişebilen kimliktir
+func System() Actor {
+	return Actor{Role: utils.RoleSuperUser}
+}
+
+// CanAccessCompany kimliğin verilen şirket üzerinde işlem yapıp yapamayacağını söyler
+func (a Actor) CanAccessCompany(companyID string) bool {
+	switch a.Role {
+	case utils.RoleSuperUser:
+		return true
+	case utils.RoleAdmin, utils.RoleManager:
+		return a.CompanyID != "" && a.CompanyID == companyID
+	default:
+		return false
+	}
+}
+
+// CanAccessProvider kimliğin verilen sağlayıcı üzerinde işlem yapıp yapamayacağını söyler
+func (a Actor) CanAccessProvider(provider models.Provider) bool {
+	if a.Role == utils.RoleProvider {
+		return a.ID == provider.ID.Hex()
+	}
+	return a.CanAccessCompany(provider.CompanyId)
+}
+
+// DefaultCompanyID boş gelen şirket ID'sini admin ve manager için kendi
+// şirketleriyle doldurur
+func (a Actor) DefaultCompanyID(companyID string) string {
+	if companyID == "" && (a.Role == utils.RoleAdmin || a.Role == utils.RoleManager) {
+		return a.CompanyID
+	}
+	return companyID
+}
+
+// authorizeProvider find ile sağlayıcıyı bulur ve kimliğin ona erişimi
+// olup olmadığını kontrol eder
+func authorizeProvider(actor Actor, find func() (models.Provider, error)) (models.Provider, error) {
+	provider, err := find()
+	if err == repositories.ErrNotFound {
+		return models.Provider{}, NotFound("Provider not found")
+	}
+	if err != nil {
+		return models.Provider{}, err
+	}
+
+	if !actor.CanAccessProvider(provider) {
+		return models.Provider{}, ErrForbidden
+	}
+	return provider, nil
+}
+
+// providerByEmail e-posta ile sağlayıcı erişimini kontrol eder
+func providerByEmail(ctx context.Context, providers repositories.ProviderRepository, actor Actor, email string) (models.Provider, error) {
+	return authorizeProvider(actor, func() (models.Provider, error) { return providers.FindByEmail(ctx, email) })
+}
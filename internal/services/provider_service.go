@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProviderService hizmet sağlayıcıların ve sundukları hizmetlerin kurallarıdır
+type ProviderService struct {
+	providers repositories.ProviderRepository
+}
+
+// Create sağlayıcıyı kimliğin kendi şirketine şifresini hash'leyerek ekler
+func (s *ProviderService) Create(ctx context.Context, actor Actor, provider models.Provider) (models.Provider, error) {
+	provider.CompanyId = actor.DefaultCompanyID(provider.CompanyId)
+	if !actor.CanAccessCompany(provider.CompanyId) {
+		return models.Provider{}, ErrForbidden
+	}
+
+	hashedPassword, err := HashPassword(provider.Password)
+	if err != nil {
+		return models.Provider{}, err
+	}
+	provider.Password = hashedPassword
+
+	provider.ID = primitive.NewObjectID()
+	provider.CreatedAt = time.Now()
+	provider.UpdatedAt = time.Now()
+
+	if err := s.providers.Create(ctx, provider); err != nil {
+		return models.Provider{}, err
+	}
+	return provider, nil
+}
+
+// List superuser için tüm sağlayıcıları, diğerleri için yalnızca kendi
+// şirketlerinin sağlayıcılarını döner
+func (s *ProviderService) List(ctx context.Context, actor Actor) ([]models.Provider, error) {
+	if actor.Role == utils.RoleSuperUser {
+		return s.providers.List(ctx, "")
+	}
+	if actor.CompanyID == "" {
+		return nil, nil
+	}
+	return s.providers.List(ctx, actor.CompanyID)
+}
+
+// ListByCompany şirketin sağlayıcılarını listeler. Müşterilerin randevu
+// alırken sağlayıcı seçebilmesi için yetki gerektirmez.
+func (s *ProviderService) ListByCompany(ctx context.Context, companyID string) ([]models.Provider, error) {
+	return s.providers.List(ctx, companyID)
+}
+
+// EmailsByCompany şirketteki sağlayıcıların yalnızca e-postalarını döner
+func (s *ProviderService) EmailsByCompany(ctx context.Context, actor Actor, companyID string) ([]string, error) {
+	if !actor.CanAccessCompany(companyID) {
+		return nil, ErrForbidden
+	}
+
+	providers, err := s.providers.List(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []string
+	for _, provider := range providers {
+		emails = append(emails, provider.Email)
+	}
+	return emails, nil
+}
+
+func (s *ProviderService) FindByEmail(ctx context.Context, actor Actor, email string) (models.Provider, error) {
+	return providerByEmail(ctx, s.providers, actor, email)
+}
+
+func (s *ProviderService) FindByID(ctx context.Context, actor Actor, id primitive.ObjectID) (models.Provider, error) {
+	return authorizeProvider(actor, func() (models.Provider, error) { return s.providers.FindByID(ctx, id) })
+}
+
+// AddServices yeni hizmetleri sağlayıcının listesinde yoksa ekler
+func (s *ProviderService) AddServices(ctx context.Context, actor Actor, id primitive.ObjectID, services []string) error {
+	if _, err := s.FindByID(ctx, actor, id); err != nil {
+		return err
+	}
+
+	err := s.providers.AddServiceNames(ctx, id, services)
+	if err == repositories.ErrNotFound {
+		return NotFound("Provider not found")
+	}
+	return err
+}
+
+// RemoveService sağlayıcının listesindeki index'teki hizmeti siler. Eski
+// istemciler için tutulur; index okunduğu andaki listeye göre isme çevrilir,
+// böylece araya giren bir silme başka bir hizmetin gitmesine yol açmaz.
+func (s *ProviderService) RemoveService(ctx context.Context, actor Actor, id primitive.ObjectID, index int) error {
+	provider, err := s.FindByID(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+
+	if index < 0 || index >= len(provider.Services) {
+		return Invalid("Index out of range")
+	}
+	return s.removeServiceName(ctx, id, provider.Services[index])
+}
+
+// RemoveServiceByName hizmeti adıyla sağlayıcının listesinden siler
+func (s *ProviderService) RemoveServiceByName(ctx context.Context, actor Actor, id primitive.ObjectID, name string) error {
+	provider, err := s.FindByID(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+
+	if !contains(provider.Services, name) {
+		return NotFound("Service not found")
+	}
+	return s.removeServiceName(ctx, id, name)
+}
+
+func (s *ProviderService) removeServiceName(ctx context.Context, id primitive.ObjectID, name string) error {
+	err := s.providers.RemoveServiceName(ctx, id, name)
+	if err == repositories.ErrNotFound {
+		return NotFound("Provider not found")
+	}
+	return err
+}
+
+func contains(slice []string, item string) bool {
+	for _, v := range slice {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
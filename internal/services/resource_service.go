@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ResourceService şirketlerin oda, koltuk ve cihaz gibi paylaşılan
+// kaynaklarının yönetimidir. Kaynakların randevulara ayrılması
+// AppointmentService'tedir.
+type ResourceService struct {
+	resources    repositories.ResourceRepository
+	reservations repositories.ReservationRepository
+}
+
+// resourceType kaynak türünü karşılaştırılabilir biçime getirir
+func resourceType(t string) string {
+	return strings.ToLower(strings.TrimSpace(t))
+}
+
+// resourceTypes türleri normalleştirir; boş ve tekrar edenler atlanır
+func resourceTypes(types []string) []string {
+	var out []string
+	for _, t := range types {
+		if t = resourceType(t); t != "" && !contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func validateResource(resource models.Resource) error {
+	if strings.TrimSpace(resource.Name) == "" {
+		return Invalid("Resource name is required")
+	}
+	if resource.Type == "" {
+		return Invalid("Resource type is required")
+	}
+	return validateRules(resource.Hours)
+}
+
+// List şirketin kaynaklarını döner; kind verilirse yalnızca o türdekiler
+func (s *ResourceService) List(ctx context.Context, actor Actor, companyID, kind string) ([]models.Resource, error) {
+	if companyID == "" {
+		return nil, Invalid("Company ID is required")
+	}
+	if !actor.CanAccessCompany(companyID) {
+		return nil, ErrForbidden
+	}
+	return s.resources.List(ctx, companyID, resourceType(kind), false)
+}
+
+// resourceByID kaynağı bulur ve kimliğin kaynağın şirketine erişimini kontrol eder
+func resourceByID(ctx context.Context, resources repositories.ResourceRepository, actor Actor, id primitive.ObjectID) (models.Resource, error) {
+	resource, err := resources.FindByID(ctx, id)
+	if err == repositories.ErrNotFound {
+		return models.Resource{}, NotFound("Resource not found")
+	}
+	if err != nil {
+		return models.Resource{}, err
+	}
+	if !actor.CanAccessCompany(resource.CompanyID) {
+		return models.Resource{}, ErrForbidden
+	}
+	return resource, nil
+}
+
+// Create kaynağı kimliğin şirketine ekler
+func (s *ResourceService) Create(ctx context.Context, actor Actor, resource models.Resource) (models.Resource, error) {
+	resource.CompanyID = actor.DefaultCompanyID(resource.CompanyID)
+	if !actor.CanAccessCompany(resource.CompanyID) {
+		return models.Resource{}, ErrForbidden
+	}
+	resource.Type = resourceType(resource.Type)
+	if err := validateResource(resource); err != nil {
+		return models.Resource{}, err
+	}
+
+	resource.ID = primitive.NewObjectID()
+	resource.CreatedAt = time.Now()
+	resource.UpdatedAt = time.Now()
+	if err := s.resources.Create(ctx, resource); err != nil {
+		return models.Resource{}, err
+	}
+	return resource, nil
+}
+
+// Update kaynağın adını, türünü, saatlerini ve etkinliğini değiştirir.
+// Var olan ayırmalar etkilenmez; pasif kaynak yeni randevulara ayrılmaz.
+func (s *ResourceService) Update(ctx context.Context, actor Actor, id primitive.ObjectID, update models.Resource) (models.Resource, error) {
+	current, err := resourceByID(ctx, s.resources, actor, id)
+	if err != nil {
+		return models.Resource{}, err
+	}
+	update.Type = resourceType(update.Type)
+	if err := validateResource(update); err != nil {
+		return models.Resource{}, err
+	}
+
+	current.Name = update.Name
+	current.Type = update.Type
+	current.Description = update.Description
+	current.Active = update.Active
+	current.Hours = update.Hours
+	current.UpdatedAt = time.Now()
+
+	err = s.resources.UpdateByID(ctx, id, repositories.Fields{
+		"name":        current.Name,
+		"type":        current.Type,
+		"description": current.Description,
+		"active":      current.Active,
+		"hours":       current.Hours,
+		"updated_at":  current.UpdatedAt,
+	})
+	if err == repositories.ErrNotFound {
+		return models.Resource{}, NotFound("Resource not found")
+	}
+	if err != nil {
+		return models.Resource{}, err
+	}
+	return current, nil
+}
+
+// Delete kaynağı siler. Gelecekte ayrılmış zamanı olan kaynak silinemez;
+// önce pasif yapılıp randevular taşınmalıdır.
+func (s *ResourceService) Delete(ctx context.Context, actor Actor, id primitive.ObjectID) error {
+	if _, err := resourceByID(ctx, s.resources, actor, id); err != nil {
+		return err
+	}
+	upcoming, err := s.reservations.FindByResource(ctx, id, time.Now(), time.Time{})
+	if err != nil {
+		return err
+	}
+	if len(upcoming) > 0 {
+		return Conflict("Resource has upcoming reservations; deactivate it instead")
+	}
+	if err := s.resources.DeleteByID(ctx, id); err != nil {
+		if err == repositories.ErrNotFound {
+			return NotFound("Resource not found")
+		}
+		return err
+	}
+	return nil
+}
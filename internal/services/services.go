@@ -0,0 +1,83 @@
+package services
+
+import (
+	"time"
+
+	"rtsback/config"
+	"rtsback/internal/repositories"
+)
+
+// backgroundTimeout istekten sonra arka planda süren işlerin, örneğin
+// e-posta gönderiminin, süre sınırıdır
+const backgroundTimeout = time.Minute
+
+// Services iş kurallarını taşıyan servislerin tamamıdır. HTTP handler'ları,
+// bir CLI veya arka plan işleri aynı servisleri kullanabilir.
+type Services struct {
+	Auth          *AuthService
+	Users         *UserService
+	Admins        *AdminService
+	Managers      *ManagerService
+	Providers     *ProviderService
+	Companies     *CompanyService
+	Appointments  *AppointmentService
+	Catalog       *CatalogService
+	Resources     *ResourceService
+	Verifications *VerificationService
+}
+
+// New servisleri verilen repository'ler ve e-posta göndericisi üzerine kurar
+func New(repos *repositories.Repositories, cfg config.Config, mailer Mailer) *Services {
+	return &Services{
+		Auth: &AuthService{
+			users:          repos.Users,
+			admins:         repos.Admins,
+			managers:       repos.Managers,
+			providers:      repos.Providers,
+			companies:      repos.Companies,
+			sessions:       repos.RefreshTokens,
+			passwordResets: repos.PasswordResets,
+			securityEvents: repos.SecurityEvents,
+			mailer:         mailer,
+			resetTTL:       cfg.Auth.PasswordResetTTL,
+		},
+		Users:     &UserService{users: repos.Users},
+		Admins:    &AdminService{admins: repos.Admins, users: repos.Users},
+		Managers:  &ManagerService{managers: repos.Managers},
+		Providers: &ProviderService{providers: repos.Providers},
+		Companies: &CompanyService{companies: repos.Companies, appointments: repos.Appointments},
+		Appointments: &AppointmentService{
+			appointments: repos.Appointments,
+			providers:    repos.Providers,
+			companies:    repos.Companies,
+			workingHours: repos.WorkingHours,
+			catalog:      repos.Services,
+			timeOff:      repos.TimeOff,
+			closures:     repos.Closures,
+			waitlist:     repos.Waitlist,
+			series:       repos.Series,
+			resources:    repos.Resources,
+			reservations: repos.Reservations,
+			feeds:        repos.CalendarFeeds,
+			feedURL:      cfg.Scheduling.CalendarFeedURL,
+			busySources:  repos.BusySources,
+			busyBlocks:   repos.BusyBlocks,
+			importDir:    cfg.Scheduling.CalendarImportDir,
+			mailer:       mailer,
+			defaultZone:  defaultZone(cfg),
+			offerTTL:     cfg.Scheduling.WaitlistOfferTTL,
+			claimURL:     cfg.Scheduling.WaitlistClaimURL,
+		},
+		Catalog:       &CatalogService{services: repos.Services, providers: repos.Providers},
+		Resources:     &ResourceService{resources: repos.Resources, reservations: repos.Reservations},
+		Verifications: &VerificationService{verifications: repos.Verifications, mailer: mailer, codeTTL: cfg.Auth.VerifyCodeTTL},
+	}
+}
+
+// defaultZone ayarlardaki varsayılan saat dilimidir; ayar boşsa UTC
+func defaultZone(cfg config.Config) string {
+	if cfg.Scheduling.DefaultTimeZone == "" {
+		return "UTC"
+	}
+	return cfg.Scheduling.DefaultTimeZone
+}
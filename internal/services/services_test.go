@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"rtsback/config"
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testMailer gönderilen e-postaları bellekte tutar
+type testMailer struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (m *testMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, to)
+	return nil
+}
+
+// newTestServices servisleri bellek repository'leri üzerine kurar
+func newTestServices(t *testing.T) (*Services, *repositories.Repositories) {
+	t.Helper()
+	repos := repositories.NewMemory()
+	return New(repos, config.Config{}, &testMailer{}), repos
+}
+
+// addProvider her gün 09:00-12:00 arası 30 dakikalık slotlarla çalışan bir sağlayıcı ekler
+func addProvider(t *testing.T, svc *Services, repos *repositories.Repositories, email string) models.Provider {
+	t.Helper()
+	ctx := context.Background()
+	provider := models.Provider{ID: primitive.NewObjectID(), Name: "Test", Email: email}
+	if err := repos.Providers.Create(ctx, provider); err != nil {
+		t.Fatal(err)
+	}
+	days := []string{}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		days = append(days, d.String())
+	}
+	_, err := svc.Appointments.SetWorkingHours(ctx, System(), email, models.WorkingHours{
+		SlotMinutes: 30,
+		Rules: []models.WorkingHoursRule{{
+			Weekdays:  days,
+			Intervals: []models.TimeRange{{Start: "09:00", End: "12:00"}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+// testDay iki gün sonrasının UTC gece yarısıdır
+func testDay() time.Time {
+	return time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 2)
+}
+
+// wantKind err'in verilen türde bir servis hatası olduğunu kontrol eder
+func wantKind(t *testing.T, err error, kind Kind) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected error of kind %d, got nil", kind)
+	}
+	if KindOf(err) != kind {
+		t.Fatalf("expected error of kind %d, got %v", kind, err)
+	}
+}
+
+// mustZone saat dilimini yükler; sistemde yoksa testi atlar
+func mustZone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skip(err)
+	}
+	return loc
+}
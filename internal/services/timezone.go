@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// validateTimeZone boş olmayan saat dilimi adının IANA veritabanında olduğunu kontrol eder
+func validateTimeZone(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return Invalid("Unknown time zone " + name + ", expected an IANA name such as Europe/Istanbul")
+	}
+	return nil
+}
+
+// zoneFor şirketin saat dilimi adını döner. Eski kayıtlarda company_id boş
+// olabildiği için şirket gerekirse sağlayıcı üzerinden bulunur; şirketin
+// dilimi yoksa varsayılan dilim kullanılır.
+func (s *AppointmentService) zoneFor(ctx context.Context, companyID, providerEmail string) (string, error) {
+	if companyID == "" && providerEmail != "" {
+		provider, err := s.providers.FindByEmail(ctx, providerEmail)
+		if err != nil && err != repositories.ErrNotFound {
+			return "", err
+		}
+		companyID = provider.CompanyId
+	}
+	if objID, err := primitive.ObjectIDFromHex(companyID); err == nil {
+		company, err := s.companies.FindByID(ctx, objID)
+		if err != nil && err != repositories.ErrNotFound {
+			return "", err
+		}
+		if company.TimeZone != "" {
+			return company.TimeZone, nil
+		}
+	}
+	return s.defaultZone, nil
+}
+
+// providerLocation sağlayıcının şirketinin saat dilimidir
+func (s *AppointmentService) providerLocation(ctx context.Context, provider models.Provider) (string, *time.Location, error) {
+	zone, err := s.zoneFor(ctx, provider.CompanyId, provider.Email)
+	if err != nil {
+		return "", nil, err
+	}
+	return zone, models.Location(zone), nil
+}
+
+// clockOnly eski kayıtlardaki gibi yalnızca saat taşıyan (yıl 0) değerlerdir
+func clockOnly(t time.Time) bool {
+	return !t.IsZero() && t.Year() <= 1
+}
+
+// atClock day'in loc'taki takvim gününde clock'un saat ve dakikasıdır
+func atClock(day, clock time.Time, loc *time.Location) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+}
+
+// localClock bir anı randevunun saat diliminde okunur biçimde yazar
+func localClock(appointment models.Appointment, t time.Time) string {
+	return t.In(appointment.Location()).Format("2006-01-02 15:04 MST")
+}
+
+// NormalizeTimes saat dilimi atanmamış eski randevulara şirketlerinin
+// dilimini yazar ve yalnızca saat taşıyan start_time/end_time değerlerini
+// date günüyle birleştirip tam UTC anlara çevirir. date alanı başlangıç
+// anı olur. Güncellenen kayıt sayısını döner; tekrar çalıştırmak güvenlidir.
+func (s *AppointmentService) NormalizeTimes(ctx context.Context) (int, error) {
+	appointments, err := s.appointments.FindWithoutTimeZone(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	zones := map[string]string{}
+	updated := 0
+	for _, a := range appointments {
+		key := a.CompanyID + "|" + a.ProviderEmail
+		zone, ok := zones[key]
+		if !ok {
+			if zone, err = s.zoneFor(ctx, a.CompanyID, a.ProviderEmail); err != nil {
+				return updated, err
+			}
+			zones[key] = zone
+		}
+		loc := models.Location(zone)
+
+		// Eski kayıtlarda gün time.Parse ile UTC gece yarısı, saatler yerel saattir
+		day := a.Date.UTC()
+		start, end := a.StartTime, a.EndTime
+		if clockOnly(start) {
+			start = atClock(day, start.UTC(), loc)
+		}
+		if clockOnly(end) {
+			end = atClock(day, end.UTC(), loc)
+		}
+
+		fields := repositories.Fields{"time_zone": zone}
+		if !start.IsZero() {
+			fields["start_time"] = start
+			fields["date"] = start
+		}
+		if !end.IsZero() {
+			fields["end_time"] = end
+		}
+
+		ok, err = s.appointments.UpdateIf(ctx, a.ID, repositories.AppointmentCondition{Version: &a.Version}, fields)
+		if err != nil {
+			return updated, err
+		}
+		if !ok {
+			// Arada değişen kayıt bir sonraki çalıştırmada tekrar denenir
+			log.Printf("Randevu saat dilimi güncellenemedi, kayıt değişmiş (%s)", a.ID.Hex())
+			continue
+		}
+		updated++
+	}
+	return updated, nil
+}
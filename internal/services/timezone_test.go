@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// addZonedProvider zone saat dilimindeki bir şirkete bağlı sağlayıcı ekler
+func addZonedProvider(t *testing.T, repos *repositories.Repositories, email, zone string) models.Provider {
+	t.Helper()
+	ctx := context.Background()
+	company := models.Company{ID: primitive.NewObjectID(), Name: zone, TimeZone: zone}
+	if err := repos.Companies.Create(ctx, company); err != nil {
+		t.Fatal(err)
+	}
+	provider := models.Provider{ID: primitive.NewObjectID(), Name: "Test", Email: email, CompanyId: company.ID.Hex()}
+	if err := repos.Providers.Create(ctx, provider); err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestAtClockAcrossDST(t *testing.T) {
+	newYork := mustZone(t, "America/New_York")
+	istanbul := mustZone(t, "Europe/Istanbul")
+	nine := time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		loc  *time.Location
+		day  time.Time
+		want time.Time
+	}{
+		{"new york before spring forward", newYork, time.Date(2030, 3, 9, 0, 0, 0, 0, time.UTC), time.Date(2030, 3, 9, 14, 0, 0, 0, time.UTC)},
+		{"new york spring forward", newYork, time.Date(2030, 3, 10, 0, 0, 0, 0, time.UTC), time.Date(2030, 3, 10, 13, 0, 0, 0, time.UTC)},
+		{"new york before fall back", newYork, time.Date(2030, 11, 2, 0, 0, 0, 0, time.UTC), time.Date(2030, 11, 2, 13, 0, 0, 0, time.UTC)},
+		{"new york fall back", newYork, time.Date(2030, 11, 3, 0, 0, 0, 0, time.UTC), time.Date(2030, 11, 3, 14, 0, 0, 0, time.UTC)},
+		// Türkiye 2016'ya kadar yaz saati uyguluyordu; 2015'te geri dönüş seçim nedeniyle 8 Kasım'a ertelendi
+		{"istanbul 2015 spring forward", istanbul, time.Date(2015, 3, 29, 0, 0, 0, 0, time.UTC), time.Date(2015, 3, 29, 6, 0, 0, 0, time.UTC)},
+		{"istanbul 2015 before fall back", istanbul, time.Date(2015, 11, 7, 0, 0, 0, 0, time.UTC), time.Date(2015, 11, 7, 6, 0, 0, 0, time.UTC)},
+		{"istanbul 2015 fall back", istanbul, time.Date(2015, 11, 8, 0, 0, 0, 0, time.UTC), time.Date(2015, 11, 8, 7, 0, 0, 0, time.UTC)},
+		{"istanbul permanent +03", istanbul, time.Date(2030, 3, 31, 0, 0, 0, 0, time.UTC), time.Date(2030, 3, 31, 6, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := atClock(tt.day, nine, tt.loc); !got.Equal(tt.want) {
+				t.Fatalf("atClock = %v, want %v", got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateSlotsAcrossDST(t *testing.T) {
+	mustZone(t, "America/New_York")
+	mustZone(t, "Europe/Istanbul")
+	everyDay := []string{}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		everyDay = append(everyDay, d.String())
+	}
+	tests := []struct {
+		name       string
+		zone       string
+		from, to   string
+		shiftStart string
+		shiftEnd   string
+		want       []string // slotların yerel başlangıçları
+	}{
+		{
+			name: "new york morning shift around spring forward", zone: "America/New_York",
+			from: "2030-03-09", to: "2030-03-10", shiftStart: "09:00", shiftEnd: "10:00",
+			want: []string{"2030-03-09 09:00 EST", "2030-03-09 09:30 EST", "2030-03-10 09:00 EDT", "2030-03-10 09:30 EDT"},
+		},
+		{
+			name: "new york night shift on spring forward", zone: "America/New_York",
+			from: "2030-03-10", to: "2030-03-10", shiftStart: "01:00", shiftEnd: "04:00",
+			want: []string{"2030-03-10 01:00 EST", "2030-03-10 01:30 EST", "2030-03-10 03:00 EDT", "2030-03-10 03:30 EDT"},
+		},
+		{
+			name: "new york night shift on fall back", zone: "America/New_York",
+			from: "2030-11-03", to: "2030-11-03", shiftStart: "00:00", shiftEnd: "02:00",
+			want: []string{"2030-11-03 00:00 EDT", "2030-11-03 00:30 EDT", "2030-11-03 01:00 EDT", "2030-11-03 01:30 EDT",
+				"2030-11-03 01:00 EST", "2030-11-03 01:30 EST"},
+		},
+		{
+			name: "istanbul 2015 fall back", zone: "Europe/Istanbul",
+			from: "2015-11-07", to: "2015-11-08", shiftStart: "09:00", shiftEnd: "09:30",
+			want: []string{"2015-11-07 09:00 EEST", "2015-11-08 09:00 EET"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repos := newTestServices(t)
+			provider := addZonedProvider(t, repos, "p@example.com", tt.zone)
+			now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			batch, err := svc.Appointments.GenerateSlots(context.Background(), System(), models.AutoAddRequest{
+				ProviderEmail: provider.Email,
+				Weekdays:      everyDay,
+				ShiftStart:    tt.shiftStart,
+				ShiftEnd:      tt.shiftEnd,
+				Period:        30,
+				StartDate:     tt.from,
+				EndDate:       tt.to,
+				DryRun:        true,
+			}, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(batch.Slots) != len(tt.want) {
+				t.Fatalf("got %d slots, want %d", len(batch.Slots), len(tt.want))
+			}
+			for i, slot := range batch.Slots {
+				if got := localClock(slot, slot.StartTime); got != tt.want[i] {
+					t.Errorf("slot %d starts %s, want %s", i, got, tt.want[i])
+				}
+				if slot.TimeZone != tt.zone || slot.EndTime.Sub(slot.StartTime) != 30*time.Minute {
+					t.Errorf("slot %d: zone %q, length %v", i, slot.TimeZone, slot.EndTime.Sub(slot.StartTime))
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeTimesAcrossDST(t *testing.T) {
+	mustZone(t, "America/New_York")
+	mustZone(t, "Europe/Istanbul")
+	tests := []struct {
+		zone string
+		day  time.Time // eski kayıtlardaki gibi UTC gece yarısı
+		want time.Time
+	}{
+		{"America/New_York", time.Date(2030, 3, 9, 0, 0, 0, 0, time.UTC), time.Date(2030, 3, 9, 14, 0, 0, 0, time.UTC)},
+		{"America/New_York", time.Date(2030, 3, 10, 0, 0, 0, 0, time.UTC), time.Date(2030, 3, 10, 13, 0, 0, 0, time.UTC)},
+		{"America/New_York", time.Date(2030, 11, 3, 0, 0, 0, 0, time.UTC), time.Date(2030, 11, 3, 14, 0, 0, 0, time.UTC)},
+		{"Europe/Istanbul", time.Date(2015, 3, 28, 0, 0, 0, 0, time.UTC), time.Date(2015, 3, 28, 7, 0, 0, 0, time.UTC)},
+		{"Europe/Istanbul", time.Date(2015, 3, 29, 0, 0, 0, 0, time.UTC), time.Date(2015, 3, 29, 6, 0, 0, 0, time.UTC)},
+	}
+	svc, repos := newTestServices(t)
+	ctx := context.Background()
+	providers := map[string]models.Provider{}
+	ids := make([]primitive.ObjectID, len(tests))
+	for i, tt := range tests {
+		provider, ok := providers[tt.zone]
+		if !ok {
+			provider = addZonedProvider(t, repos, tt.zone+"@example.com", tt.zone)
+			providers[tt.zone] = provider
+		}
+		legacy := models.Appointment{
+			ID:            primitive.NewObjectID(),
+			ProviderEmail: provider.Email,
+			CompanyID:     provider.CompanyId,
+			Date:          tt.day,
+			StartTime:     time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC),
+			EndTime:       time.Date(0, 1, 1, 9, 30, 0, 0, time.UTC),
+			Status:        models.StatusOpen,
+		}
+		if err := repos.Appointments.Create(ctx, legacy); err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = legacy.ID
+	}
+
+	updated, err := svc.Appointments.NormalizeTimes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated != len(tests) {
+		t.Fatalf("updated %d appointments, want %d", updated, len(tests))
+	}
+	for i, tt := range tests {
+		got, err := repos.Appointments.FindByID(ctx, ids[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.StartTime.Equal(tt.want) || !got.Date.Equal(tt.want) || !got.EndTime.Equal(tt.want.Add(30*time.Minute)) || got.TimeZone != tt.zone {
+			t.Errorf("%s %s: start %v date %v end %v zone %q, want start %v",
+				tt.zone, tt.day.Format("2006-01-02"), got.StartTime.UTC(), got.Date.UTC(), got.EndTime.UTC(), got.TimeZone, tt.want)
+		}
+	}
+
+	// Tekrar çalıştırmak bir şey değiştirmez
+	if updated, err := svc.Appointments.NormalizeTimes(ctx); err != nil || updated != 0 {
+		t.Fatalf("second run updated %d, err %v", updated, err)
+	}
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"rtsback/internal/repositories"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	totpIssuer        = "RTS"
+	recoveryCodeCount = 10
+)
+
+// Enrollment yeni üretilen ve onay bekleyen TOTP anahtarıdır
+type Enrollment struct {
+	Secret     string
+	OTPAuthURI string
+}
+
+// twoFactorAccount yalnızca admin ve superuser için hesap repository'sini
+// ve hesap ID'sini döner
+func (s *AuthService) twoFactorAccount(actor Actor) (repositories.AccountRepository, primitive.ObjectID, error) {
+	if actor.Role != utils.RoleAdmin && actor.Role != utils.RoleSuperUser {
+		return nil, primitive.NilObjectID, Forbidden("Two-factor authentication is only available for admins and superusers")
+	}
+	store, _ := s.accountStore(actor.Role)
+
+	accountID, err := primitive.ObjectIDFromHex(actor.ID)
+	if err != nil {
+		return nil, primitive.NilObjectID, Invalid("Invalid account ID")
+	}
+	return store, accountID, nil
+}
+
+// findTwoFactorAccount hesabı bulur, yoksa NotFound döner
+func findTwoFactorAccount(ctx context.Context, store repositories.AccountRepository, accountID primitive.ObjectID) (repositories.Account, error) {
+	account, err := store.FindAccountByID(ctx, accountID)
+	if err == repositories.ErrNotFound {
+		return repositories.Account{}, NotFound("Account not found")
+	}
+	return account, err
+}
+
+// CompanyRequiresAdmin2FA superuser'ın şirket için 2FA zorunluluğu koyup koymadığını söyler
+func (s *AuthService) CompanyRequiresAdmin2FA(ctx context.Context, companyID string) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(companyID)
+	if err != nil {
+		return false, nil
+	}
+
+	company, err := s.companies.FindByID(ctx, objID)
+	if err == repositories.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return company.RequireAdmin2FA, nil
+}
+
+// SecondFactorPurpose şifresi doğrulanmış hesabın oturum açmadan önce hangi
+// ikinci adımı tamamlaması gerektiğini döner. Hesapta 2FA açıksa doğrulama,
+// kapalı ama şirket zorunlu kılmışsa kayıt (enrollment) gerekir; hiçbiri
+// gerekmiyorsa boş döner.
+func (s *AuthService) SecondFactorPurpose(ctx context.Context, login Authenticated) (string, error) {
+	if login.TwoFactorEnabled {
+		return utils.PurposeTwoFactor, nil
+	}
+	if login.Role != utils.RoleAdmin {
+		return "", nil
+	}
+
+	required, err := s.CompanyRequiresAdmin2FA(ctx, login.CompanyID)
+	if err != nil || !required {
+		return "", err
+	}
+	return utils.PurposeTwoFactorEnroll, nil
+}
+
+// EnrollTwoFactor yeni bir TOTP anahtarı üretir ve onay bekleyen olarak saklar
+func (s *AuthService) EnrollTwoFactor(ctx context.Context, actor Actor) (Enrollment, error) {
+	store, accountID, err := s.twoFactorAccount(actor)
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	err = store.SetPendingTOTP(ctx, accountID, secret)
+	if err == repositories.ErrNotFound {
+		return Enrollment{}, NotFound("Account not found")
+	}
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	return Enrollment{Secret: secret, OTPAuthURI: utils.TOTPURI(totpIssuer, actor.Email, secret)}, nil
+}
+
+// ConfirmTwoFactor bekleyen anahtarı bir kodla doğrulayıp etkinleştirir ve
+// kullanıcıya bir kez gösterilecek kurtarma kodlarını döner. Kod yanlışsa
+// ErrInvalidCode döner.
+func (s *AuthService) ConfirmTwoFactor(ctx context.Context, actor Actor, code string) ([]string, error) {
+	store, accountID, err := s.twoFactorAccount(actor)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := findTwoFactorAccount(ctx, store, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.TOTPPendingSecret == "" {
+		return nil, Conflict("No pending two-factor enrollment")
+	}
+
+	step, valid := utils.ValidateTOTP(account.TOTPPendingSecret, code, time.Now())
+	if !valid {
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.EnableTOTP(ctx, accountID, account.TOTPPendingSecret, step, hashes)
+	if err == repositories.ErrNotFound {
+		// Bu arada yeni bir kayıt başlatıldıysa eski anahtar onaylanamaz
+		return nil, Conflict("No pending two-factor enrollment")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifyTwoFactor girişin ikinci adımıdır: TOTP kodunu veya bir kurtarma
+// kodunu tek seferlik olarak tüketir. Kod yanlışsa ErrInvalidCode döner.
+func (s *AuthService) VerifyTwoFactor(ctx context.Context, actor Actor, code, recoveryCode string) error {
+	store, accountID, err := s.twoFactorAccount(actor)
+	if err != nil {
+		return Unauthorized("Invalid challenge")
+	}
+
+	account, err := store.FindAccountByID(ctx, accountID)
+	if err != nil {
+		return Unauthorized("Account not found")
+	}
+	if !account.TOTPEnabled {
+		return Conflict("Two-factor authentication is not enabled")
+	}
+
+	var valid bool
+	if code != "" {
+		valid, err = consumeTOTP(ctx, store, account, code)
+	} else {
+		valid, err = store.ConsumeRecoveryCode(ctx, accountID, utils.HashToken(strings.TrimSpace(recoveryCode)))
+	}
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// DisableTwoFactor geçerli bir kodla 2FA'yı kapatır. Şirketi 2FA'yı zorunlu
+// kılmış adminler kapatamaz.
+func (s *AuthService) DisableTwoFactor(ctx context.Context, actor Actor, code string) error {
+	store, accountID, err := s.twoFactorAccount(actor)
+	if err != nil {
+		return err
+	}
+
+	if actor.Role == utils.RoleAdmin {
+		required, err := s.CompanyRequiresAdmin2FA(ctx, actor.CompanyID)
+		if err != nil {
+			return err
+		}
+		if required {
+			return Forbidden("Two-factor authentication is required by your company")
+		}
+	}
+
+	account, err := findTwoFactorAccount(ctx, store, accountID)
+	if err != nil {
+		return err
+	}
+
+	valid, err := consumeTOTP(ctx, store, account, code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrInvalidCode
+	}
+
+	return store.DisableTOTP(ctx, accountID)
+}
+
+// consumeTOTP kodu doğrular ve aynı adımın tekrar kullanılmasını engeller
+func consumeTOTP(ctx context.Context, store repositories.AccountRepository, account repositories.Account, code string) (bool, error) {
+	step, ok := utils.ValidateTOTP(account.TOTPSecret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return store.ConsumeTOTPStep(ctx, account.ID, step)
+}
+
+// newRecoveryCodes kullanıcıya bir kez gösterilecek kodları ve saklanacak özetlerini üretir
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := utils.RandomToken(8)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, utils.HashToken(code))
+	}
+	return codes, hashes, nil
+}
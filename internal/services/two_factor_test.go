@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// totpAt doğrulayıcı uygulamanın at anında göstereceği kodu hesaplar
+func totpAt(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(utils.TOTPStep(at)))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:])&0x7fffffff)%1000000)
+}
+
+func TestTwoFactorCodesAreSingleUse(t *testing.T) {
+	svc, repos := newTestServices(t)
+	ctx := context.Background()
+	user := models.User{ID: primitive.NewObjectID(), Email: "root@example.com", SuperUser: true}
+	if err := repos.Users.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	actor := Actor{ID: user.ID.Hex(), Email: user.Email, Role: utils.RoleSuperUser}
+
+	enrollment, err := svc.Auth.EnrollTwoFactor(ctx, actor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if _, err := svc.Auth.ConfirmTwoFactor(ctx, actor, totpAt(t, enrollment.Secret, now.Add(time.Hour))); err != ErrInvalidCode {
+		t.Fatalf("code from another time: %v", err)
+	}
+	codes, err := svc.Auth.ConfirmTwoFactor(ctx, actor, totpAt(t, enrollment.Secret, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("%d recovery codes", len(codes))
+	}
+
+	// Onayda kullanılan adım girişte tekrar kullanılamaz; sonraki adım kayma
+	// toleransı içindedir ve bir kez kabul edilir
+	err = svc.Auth.VerifyTwoFactor(ctx, actor, totpAt(t, enrollment.Secret, now), "")
+	wantKind(t, err, KindUnauthorized)
+	next := totpAt(t, enrollment.Secret, now.Add(30*time.Second))
+	if err := svc.Auth.VerifyTwoFactor(ctx, actor, next, ""); err != nil {
+		t.Fatalf("next step: %v", err)
+	}
+	if err := svc.Auth.VerifyTwoFactor(ctx, actor, next, ""); err != ErrInvalidCode {
+		t.Fatalf("replayed step: %v", err)
+	}
+
+	if err := svc.Auth.VerifyTwoFactor(ctx, actor, "", codes[0]); err != nil {
+		t.Fatalf("recovery code: %v", err)
+	}
+	if err := svc.Auth.VerifyTwoFactor(ctx, actor, "", codes[0]); err != ErrInvalidCode {
+		t.Fatalf("reused recovery code: %v", err)
+	}
+	if err := svc.Auth.VerifyTwoFactor(ctx, actor, "", codes[1]); err != nil {
+		t.Fatalf("other recovery code: %v", err)
+	}
+}
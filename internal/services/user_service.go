@@ -1 +1,288 @@
-package services
\ No newline at end of file
+package services
+
+import (
+	"context"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserService müşteri ve superuser hesaplarının kurallarıdır
+type UserService struct {
+	users repositories.UserRepository
+}
+
+// Registration herkese açık kayıt formunun alanlarıdır. Yetki, rol ve şirket
+// alanları bilerek yoktur; kayıt olan her kullanıcı sıradan müşteridir.
+type Registration struct {
+	Name     string
+	Email    string
+	Phone    string
+	Password string
+}
+
+// Create yeni müşteri hesabını şifresini hash'leyerek kaydeder
+func (s *UserService) Create(ctx context.Context, reg Registration) (models.User, error) {
+	if reg.Email == "" {
+		return models.User{}, Invalid("email is required")
+	}
+	if err := ValidatePassword(reg.Password); err != nil {
+		return models.User{}, err
+	}
+	hashedPassword, err := HashPassword(reg.Password)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{
+		ID:           primitive.NewObjectID(),
+		Name:         reg.Name,
+		Email:        reg.Email,
+		Phone:        reg.Phone,
+		PasswordHash: hashedPassword,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	if err := s.users.Create(ctx, user); err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// CreateWithoutPassword randevu alan misafir müşteri için şifresiz bir
+// kullanıcı kaydı açar
+func (s *UserService) CreateWithoutPassword(ctx context.Context, email, name, phone string) (models.User, error) {
+	user := models.User{
+		ID:        primitive.NewObjectID(),
+		Name:      name,
+		Email:     email,
+		Phone:     phone,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := s.users.Create(ctx, user); err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+func (s *UserService) FindByEmail(ctx context.Context, email string) (models.User, error) {
+	user, err := s.users.FindByEmail(ctx, email)
+	if err == repositories.ErrNotFound {
+		return models.User{}, NotFound("User not found")
+	}
+	return user, err
+}
+
+func (s *UserService) List(ctx context.Context) ([]models.User, error) {
+	return s.users.List(ctx)
+}
+
+// UpdateMany kullanıcıları ID ile günceller, olmayanları oluşturur. Şifre
+// düz metin olarak gelir ve her zaman hash'lenerek saklanır.
+func (s *UserService) UpdateMany(ctx context.Context, users []models.User) error {
+	for _, user := range users {
+		fields := repositories.Fields{
+			"name":       user.Name,
+			"email":      user.Email,
+			"role":       user.Role,
+			"phone":      user.Phone,
+			"company_id": user.CompanyID,
+			"super_user": user.SuperUser,
+			"updated_at": time.Now(),
+		}
+
+		if user.PasswordHash != "" {
+			hashedPassword, err := HashPassword(user.PasswordHash)
+			if err != nil {
+				return err
+			}
+			fields["password_hash"] = hashedPassword
+		}
+
+		if err := s.users.UpsertByID(ctx, user.ID, fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProfileUpdate bir adminin kullanıcı üzerinde değiştirebildiği alanlardır.
+// Boş alanlar değişmez; rol, şirket, şifre ve superuser yetkisi buradan
+// değiştirilemez.
+type ProfileUpdate struct {
+	Name  string
+	Phone string
+}
+
+// UpdateProfile kullanıcıyı e-postasıyla günceller. Admin yalnızca kendi
+// şirketindeki kullanıcıları güncelleyebilir.
+func (s *UserService) UpdateProfile(ctx context.Context, actor Actor, email string, update ProfileUpdate) error {
+	user, err := s.users.FindByEmail(ctx, email)
+	if err == repositories.ErrNotFound {
+		return NotFound("User not found")
+	}
+	if err != nil {
+		return err
+	}
+	if !actor.CanAccessCompany(user.CompanyID) {
+		return ErrForbidden
+	}
+
+	fields := repositories.Fields{"updated_at": time.Now()}
+	if update.Name != "" {
+		fields["name"] = update.Name
+	}
+	if update.Phone != "" {
+		fields["phone"] = update.Phone
+	}
+	err = s.users.UpdateByEmail(ctx, email, fields)
+	if err == repositories.ErrNotFound {
+		return NotFound("User not found")
+	}
+	return err
+}
+
+// AdminService şirket adminlerinin kurallarıdır
+type AdminService struct {
+	admins repositories.AdminRepository
+	users  repositories.UserRepository
+}
+
+// AdminUpdate superuser'ın bir admin üzerinde değiştirebildiği alanlardır
+type AdminUpdate struct {
+	Name     string
+	Role     string
+	Password string
+}
+
+// Create aynı e-postayla kayıtlı bir kullanıcı varsa admini şifresini
+// hash'leyerek ekler
+func (s *AdminService) Create(ctx context.Context, admin models.Admin) (models.Admin, error) {
+	_, err := s.users.FindByEmail(ctx, admin.Email)
+	if err == repositories.ErrNotFound {
+		return models.Admin{}, Invalid("Kullanıcı bulunamadı")
+	}
+	if err != nil {
+		return models.Admin{}, err
+	}
+
+	hashedPassword, err := HashPassword(admin.Password)
+	if err != nil {
+		return models.Admin{}, err
+	}
+	admin.Password = hashedPassword
+
+	admin.ID = primitive.NewObjectID()
+	admin.CreatedAt = time.Now()
+	admin.UpdatedAt = time.Now()
+
+	if err := s.admins.Create(ctx, admin); err != nil {
+		return models.Admin{}, err
+	}
+	return admin, nil
+}
+
+func (s *AdminService) List(ctx context.Context) ([]models.Admin, error) {
+	return s.admins.List(ctx)
+}
+
+func (s *AdminService) FindByEmail(ctx context.Context, email string) (models.Admin, error) {
+	admin, err := s.admins.FindByEmail(ctx, email)
+	if err == repositories.ErrNotFound {
+		return models.Admin{}, NotFound("Admin bulunamadı")
+	}
+	return admin, err
+}
+
+// UpdateMany adminleri ID ile günceller; eşleşmeyen kayıtlar atlanır
+func (s *AdminService) UpdateMany(ctx context.Context, admins []models.Admin) error {
+	for _, admin := range admins {
+		if admin.Password != "" {
+			hashedPassword, err := HashPassword(admin.Password)
+			if err != nil {
+				return err
+			}
+			admin.Password = hashedPassword
+		}
+
+		fields, err := repositories.FieldsOf(admin)
+		if err != nil {
+			return Invalid("Geçersiz veri formatı")
+		}
+
+		err = s.admins.UpdateByID(ctx, admin.ID, fields)
+		if err != nil && err != repositories.ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateByEmail admini e-postasıyla günceller. Şifre düz metin olarak
+// gelir, kurallara göre doğrulanır ve hash'lenerek saklanır.
+func (s *AdminService) UpdateByEmail(ctx context.Context, email string, update AdminUpdate) error {
+	fields := repositories.Fields{
+		"name":       update.Name,
+		"role":       update.Role,
+		"updated_at": time.Now(),
+	}
+
+	if update.Password != "" {
+		if err := ValidatePassword(update.Password); err != nil {
+			return err
+		}
+		hashedPassword, err := HashPassword(update.Password)
+		if err != nil {
+			return err
+		}
+		fields["password_hash"] = hashedPassword
+	}
+
+	err := s.admins.UpdateByEmail(ctx, email, fields)
+	if err == repositories.ErrNotFound {
+		return NotFound("Admin bulunamadı")
+	}
+	return err
+}
+
+// ManagerService şirket yöneticilerinin kurallarıdır
+type ManagerService struct {
+	managers repositories.ManagerRepository
+}
+
+// Create yöneticiyi kimliğin kendi şirketine şifresini hash'leyerek ekler
+func (s *ManagerService) Create(ctx context.Context, actor Actor, manager models.Manager) (models.Manager, error) {
+	manager.CompanyID = actor.DefaultCompanyID(manager.CompanyID)
+	if !actor.CanAccessCompany(manager.CompanyID) {
+		return models.Manager{}, ErrForbidden
+	}
+
+	hashedPassword, err := HashPassword(manager.Password)
+	if err != nil {
+		return models.Manager{}, err
+	}
+	manager.Password = hashedPassword
+
+	manager.ID = primitive.NewObjectID()
+	manager.CreatedAt = time.Now()
+	manager.UpdatedAt = time.Now()
+
+	if err := s.managers.Create(ctx, manager); err != nil {
+		return models.Manager{}, err
+	}
+	return manager, nil
+}
+
+// ListByCompany şirketin yöneticilerini listeler
+func (s *ManagerService) ListByCompany(ctx context.Context, actor Actor, companyID string) ([]models.Manager, error) {
+	if !actor.CanAccessCompany(companyID) {
+		return nil, ErrForbidden
+	}
+	return s.managers.ListByCompany(ctx, companyID)
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"time"
+
+	"rtsback/internal/models"
+	"rtsback/internal/repositories"
+)
+
+// ErrInvalidVerificationCode e-posta doğrulama kodu yanlış olduğunda döner
+var ErrInvalidVerificationCode = Invalid("Invalid verification code")
+
+// VerificationService e-posta doğrulama kodlarının kurallarıdır. Kodlar
+// codeTTL süresince geçerlidir.
+type VerificationService struct {
+	verifications repositories.VerificationRepository
+	mailer        Mailer
+	codeTTL       time.Duration
+}
+
+func generateCode() string {
+	n, _ := rand.Int(rand.Reader, big.NewInt(1000000))
+	return fmt.Sprintf("%06d", n.Int64()) // 6 haneli kod
+}
+
+// SendCode kullanıcı için yeni bir kod üretip kaydeder ve e-postayla
+// gönderir. Önceki kod geçersiz olur.
+func (s *VerificationService) SendCode(ctx context.Context, userID, email string) (models.Verification, error) {
+	verification := models.Verification{
+		UserID:     userID,
+		Email:      email,
+		EmailCode:  generateCode(),
+		EmailVer:   false,
+		CreateTime: time.Now(),
+	}
+
+	if err := s.verifications.SaveCode(ctx, verification); err != nil {
+		return models.Verification{}, err
+	}
+
+	body := fmt.Sprintf("Your verification code is: %s", verification.EmailCode)
+	if err := s.mailer.Send(email, "Email Verification Code", body); err != nil {
+		return models.Verification{}, err
+	}
+	return verification, nil
+}
+
+// VerifyCode kodu kontrol eder ve e-postayı doğrulanmış işaretler. Kod
+// yanlışsa ErrInvalidVerificationCode, süresi dolmuşsa Invalid döner.
+func (s *VerificationService) VerifyCode(ctx context.Context, userID, code string) error {
+	verification, err := s.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if verification.EmailCode != code {
+		return ErrInvalidVerificationCode
+	}
+	if !verification.EmailVer && s.codeTTL > 0 && time.Since(verification.CreateTime) > s.codeTTL {
+		return Invalid("Verification code has expired, request a new one")
+	}
+
+	return s.verifications.MarkEmailVerified(ctx, userID, time.Now())
+}
+
+func (s *VerificationService) FindByUserID(ctx context.Context, userID string) (models.Verification, error) {
+	verification, err := s.verifications.FindByUserID(ctx, userID)
+	if err == repositories.ErrNotFound {
+		return models.Verification{}, NotFound("Verification not found")
+	}
+	return verification, err
+}
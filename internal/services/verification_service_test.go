@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rtsback/internal/repositories"
+)
+
+func TestVerificationResendAndExpiry(t *testing.T) {
+	repos := repositories.NewMemory()
+	svc := &VerificationService{verifications: repos.Verifications, mailer: &testMailer{}, codeTTL: time.Minute}
+	ctx := context.Background()
+
+	first, err := svc.SendCode(ctx, "u1", "u1@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := svc.SendCode(ctx, "u1", "u1@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.EmailCode != second.EmailCode {
+		if err := svc.VerifyCode(ctx, "u1", first.EmailCode); err != ErrInvalidVerificationCode {
+			t.Fatalf("replaced code accepted: %v", err)
+		}
+	}
+
+	svc.codeTTL = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	wantKind(t, svc.VerifyCode(ctx, "u1", second.EmailCode), KindValidation)
+
+	svc.codeTTL = time.Minute
+	if err := svc.VerifyCode(ctx, "u1", second.EmailCode); err != nil {
+		t.Fatal(err)
+	}
+	verification, err := svc.FindByUserID(ctx, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verification.EmailVer {
+		t.Fatal("email not marked verified")
+	}
+}
@@ -1 +1,200 @@
-package utils
\ No newline at end of file
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Sistemdeki roller
+const (
+	RoleUser      = "user"
+	RoleProvider  = "provider"
+	RoleManager   = "manager"
+	RoleAdmin     = "admin"
+	RoleSuperUser = "superuser"
+)
+
+// İki aşamalı giriş için kısa ömürlü token amaçları
+const (
+	PurposeTwoFactor       = "2fa"
+	PurposeTwoFactorEnroll = "2fa_enroll"
+)
+
+const challengeTokenTTL = 5 * time.Minute
+
+var (
+	jwtSecret       []byte
+	tokenTTL        = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var ErrSecretNotConfigured = errors.New("jwt secret is not configured")
+
+// Claims token içinde taşınan kimlik bilgileridir
+type Claims struct {
+	Role      string `json:"role"`
+	Email     string `json:"email,omitempty"`
+	CompanyID string `json:"company_id,omitempty"`
+	SessionID string `json:"sid"`
+	Purpose   string `json:"purpose,omitempty"`
+	jwt.StandardClaims
+}
+
+// SetJWTSecret imzalama anahtarını ayarlar, sunucu başlarken bir kez çağrılır
+func SetJWTSecret(secret []byte) {
+	jwtSecret = secret
+}
+
+// SetTokenTTL erişim token'larının geçerlilik süresini ayarlar
+func SetTokenTTL(ttl time.Duration) {
+	if ttl > 0 {
+		tokenTTL = ttl
+	}
+}
+
+// SetRefreshTokenTTL yenileme token'larının geçerlilik süresini ayarlar
+func SetRefreshTokenTTL(ttl time.Duration) {
+	if ttl > 0 {
+		refreshTokenTTL = ttl
+	}
+}
+
+// RefreshTokenTTL yenileme token'larının geçerlilik süresini döner
+func RefreshTokenTTL() time.Duration {
+	return refreshTokenTTL
+}
+
+// GenerateToken verilen kimlik ve oturum için imzalı bir erişim token'ı
+// ve bitiş zamanını döner
+func GenerateToken(subject, role, email, companyID, sessionID string) (string, time.Time, error) {
+	if len(jwtSecret) == 0 {
+		return "", time.Time{}, ErrSecretNotConfigured
+	}
+
+	tokenID, err := RandomToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expirationTime := now.Add(tokenTTL)
+	claims := &Claims{
+		Role:      role,
+		Email:     email,
+		CompanyID: companyID,
+		SessionID: sessionID,
+		StandardClaims: jwt.StandardClaims{
+			Id:        tokenID,
+			Subject:   subject,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expirationTime.Unix(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString(jwtSecret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return tokenString, expirationTime, nil
+}
+
+// GenerateChallengeToken şifresi doğrulanmış ama ikinci adımı
+// tamamlanmamış giriş için kısa ömürlü bir token üretir. Bu token'lar
+// ParseToken tarafından kabul edilmez.
+func GenerateChallengeToken(subject, role, email, companyID, purpose string) (string, time.Time, error) {
+	if len(jwtSecret) == 0 {
+		return "", time.Time{}, ErrSecretNotConfigured
+	}
+
+	now := time.Now()
+	expirationTime := now.Add(challengeTokenTTL)
+	claims := &Claims{
+		Role:      role,
+		Email:     email,
+		CompanyID: companyID,
+		Purpose:   purpose,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   subject,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expirationTime.Unix(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString(jwtSecret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return tokenString, expirationTime, nil
+}
+
+// ParseChallengeToken verilen amaç için üretilmiş challenge token'ını doğrular
+func ParseChallengeToken(tokenString, purpose string) (*Claims, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purpose {
+		return nil, errors.New("unexpected token purpose")
+	}
+	return claims, nil
+}
+
+// ParseToken erişim token'ını doğrular ve içindeki claim'leri döner
+func ParseToken(tokenString string) (*Claims, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, errors.New("challenge tokens cannot be used for access")
+	}
+	return claims, nil
+}
+
+func parseClaims(tokenString string) (*Claims, error) {
+	if len(jwtSecret) == 0 {
+		return nil, ErrSecretNotConfigured
+	}
+
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return jwtSecret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if claims.Subject == "" || claims.Role == "" {
+		return nil, errors.New("token is missing subject or role")
+	}
+	return claims, nil
+}
+
+// RandomToken n baytlık kriptografik rastgele değeri URL güvenli biçimde döner
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken veritabanında saklanacak token özetini üretir
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
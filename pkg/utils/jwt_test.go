@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func TestTokenRoundTrip(t *testing.T) {
+	SetJWTSecret([]byte("test-secret-that-is-at-least-32-bytes"))
+
+	token, expires, err := GenerateToken("u1", RoleManager, "m@example.com", "c1", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(expires); d <= 0 || d > tokenTTL {
+		t.Fatalf("token expires in %v", d)
+	}
+	claims, err := ParseToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "u1" || claims.Role != RoleManager || claims.Email != "m@example.com" || claims.CompanyID != "c1" || claims.SessionID != "s1" {
+		t.Fatalf("claims %+v", claims)
+	}
+	if _, err := ParseChallengeToken(token, PurposeTwoFactor); err == nil {
+		t.Fatal("access token accepted as a challenge")
+	}
+}
+
+func TestChallengeTokenPurpose(t *testing.T) {
+	SetJWTSecret([]byte("test-secret-that-is-at-least-32-bytes"))
+
+	token, _, err := GenerateChallengeToken("u1", RoleAdmin, "a@example.com", "", PurposeTwoFactorEnroll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseToken(token); err == nil {
+		t.Fatal("challenge token accepted for access")
+	}
+	if _, err := ParseChallengeToken(token, PurposeTwoFactor); err == nil {
+		t.Fatal("enrollment token accepted for the login challenge")
+	}
+	if claims, err := ParseChallengeToken(token, PurposeTwoFactorEnroll); err != nil || claims.Subject != "u1" {
+		t.Fatalf("claims %+v, err %v", claims, err)
+	}
+}
+
+func TestParseTokenRejects(t *testing.T) {
+	secret := []byte("test-secret-that-is-at-least-32-bytes")
+	SetJWTSecret(secret)
+	sign := func(method jwt.SigningMethod, key interface{}, claims Claims) string {
+		t.Helper()
+		s, err := jwt.NewWithClaims(method, &claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	valid := Claims{Role: RoleUser, StandardClaims: jwt.StandardClaims{Subject: "u1", ExpiresAt: time.Now().Add(time.Minute).Unix()}}
+	expired := valid
+	expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	noRole := valid
+	noRole.Role = ""
+	noSubject := valid
+	noSubject.Subject = ""
+
+	cases := map[string]string{
+		"other secret": sign(jwt.SigningMethodHS256, []byte("another-secret-that-is-32-bytes!!"), valid),
+		"none alg":     sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid),
+		"expired":      sign(jwt.SigningMethodHS256, secret, expired),
+		"no role":      sign(jwt.SigningMethodHS256, secret, noRole),
+		"no subject":   sign(jwt.SigningMethodHS256, secret, noSubject),
+		"garbage":      "not.a.token",
+	}
+	for name, token := range cases {
+		if _, err := ParseToken(token); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+
+	SetJWTSecret(nil)
+	defer SetJWTSecret(secret)
+	if _, _, err := GenerateToken("u1", RoleUser, "", "", ""); err != ErrSecretNotConfigured {
+		t.Fatalf("generate without secret: %v", err)
+	}
+	if _, err := ParseToken(sign(jwt.SigningMethodHS256, secret, valid)); err != ErrSecretNotConfigured {
+		t.Fatalf("parse without secret: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret RFC 6238 ek B'deki SHA-1 anahtarıdır ("12345678901234567890")
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPVectors(t *testing.T) {
+	// RFC 6238 ek B, SHA-1; 8 haneli kodların son 6 hanesi
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, v := range vectors {
+		at := time.Unix(v.unix, 0)
+		step, ok := ValidateTOTP(rfc6238Secret, v.code, at)
+		if !ok || step != v.unix/totpPeriod {
+			t.Errorf("%d: code %s gave step %d, ok %v", v.unix, v.code, step, ok)
+		}
+	}
+}
+
+func TestTOTPSkew(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1234567890, 0)
+	current := TOTPStep(now)
+	for offset, want := range map[int64]bool{-2: false, -1: true, 0: true, 1: true, 2: false} {
+		step, ok := ValidateTOTP(rfc6238Secret, totpCode(key, current+offset), now)
+		if ok != want || (ok && step != current+offset) {
+			t.Errorf("offset %d: step %d, ok %v", offset, step, ok)
+		}
+	}
+
+	for _, code := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := ValidateTOTP(rfc6238Secret, code, now); ok {
+			t.Errorf("code %q accepted", code)
+		}
+	}
+	if _, ok := ValidateTOTP("not base32!", totpCode(key, current), now); ok {
+		t.Error("invalid secret accepted")
+	}
+}